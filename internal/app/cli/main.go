@@ -14,7 +14,6 @@ import (
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
 	"github.com/inhzus/go-redis-impl/internal/pkg/client"
-	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
 
 func getOption() *client.Option {
@@ -62,6 +61,52 @@ func formArray(a []string) []byte {
 	return data
 }
 
+// formArgs forms arguments of cmd by kinds, 's' for string, 'b' for bulked
+// and 'n' for number. Kinds after '|' are optional, and the last kind repeats
+// for the rest arguments if kinds ends with '*'.
+func formArgs(cmd []string, kinds string) bool {
+	required := kinds
+	if i := strings.IndexByte(kinds, '|'); i >= 0 {
+		required = kinds[:i]
+		kinds = kinds[:i] + kinds[i+1:]
+	}
+	required = strings.TrimSuffix(required, "*")
+	repeat := strings.HasSuffix(kinds, "*")
+	kinds = strings.TrimSuffix(kinds, "*")
+	if len(cmd)-1 < len(required) {
+		fmt.Printf("missing argument of \"%s\"\n", cmd[0])
+		return false
+	}
+	if !repeat && len(cmd)-1 > len(kinds) {
+		fmt.Printf("too many arguments of \"%s\"\n", cmd[0])
+		return false
+	}
+	for i := 1; i < len(cmd); i++ {
+		kind := kinds[len(kinds)-1]
+		if i-1 < len(kinds) {
+			kind = kinds[i-1]
+		}
+		switch kind {
+		case 's':
+			cmd[i] = formStr(cmd[i])
+		case 'b':
+			cmd[i] = formBulked(cmd[i])
+		case 'n':
+			cmd[i] = formNum(cmd[i])
+		}
+	}
+	return true
+}
+
+// isZAddOption reports whether s is an option of zadd
+func isZAddOption(s string) bool {
+	switch strings.ToUpper(s) {
+	case cds.IfNotExist, cds.IfExist, cds.IfGreater, cds.IfLess, cds.Changed, cds.Increment:
+		return true
+	}
+	return false
+}
+
 func main() {
 	opt := getOption()
 	cli := client.NewClient(opt)
@@ -94,11 +139,350 @@ func main() {
 		}
 		var data []byte
 		switch cmd[0] {
-		case cds.Discard, cds.Exec, cds.Multi, cds.Ping, cds.Unwatch:
+		case cds.Discard, cds.Exec, cds.Multi, cds.Ping, cds.Unwatch, cds.DBSize, cds.RandomKey:
 			cmd = cmd[:1]
-		case cds.Desc, cds.Get, cds.Incr, cds.Watch:
+		case cds.FlushDB, cds.FlushAll:
+			if !formArgs(cmd, "|s") {
+				continue
+			}
+		case cds.SwapDB:
+			if !formArgs(cmd, "nn") {
+				continue
+			}
+		case cds.Move:
+			if !formArgs(cmd, "sn") {
+				continue
+			}
+		case cds.Rename, cds.RenameNX, cds.Object:
+			if !formArgs(cmd, "ss") {
+				continue
+			}
+		case cds.Copy:
+			// numbers are database indexes, others are keys or options
+			for i := 1; i < len(cmd); i++ {
+				if _, err := strconv.ParseInt(cmd[i], 10, 64); err == nil && i > 2 {
+					cmd[i] = formNum(cmd[i])
+				} else {
+					cmd[i] = formStr(cmd[i])
+				}
+			}
+		case cds.Decr, cds.Desc, cds.Get, cds.Incr, cds.Watch,
+			cds.TTL, cds.PTTL, cds.ExpireTime, cds.PExpireTime, cds.Persist, cds.KeyType:
 			cmd = cmd[:2]
 			cmd[1] = formStr(cmd[1])
+		case cds.Del, cds.Exists, cds.Unlink, cds.Touch:
+			for i := 1; i < len(cmd); i++ {
+				cmd[i] = formStr(cmd[i])
+			}
+		case cds.Expire, cds.PExpire, cds.ExpireAt, cds.PExpireAt:
+			if len(cmd) < 3 {
+				fmt.Printf("missing argument of \"%s\"\n", cmd[0])
+				continue
+			}
+			cmd[1] = formStr(cmd[1])
+			cmd[2] = formNum(cmd[2])
+			for i := 3; i < len(cmd); i++ {
+				cmd[i] = formStr(cmd[i])
+			}
+		case cds.IncrBy, cds.DecrBy:
+			if !formArgs(cmd, "sn") {
+				continue
+			}
+		case cds.IncrByFlt:
+			if !formArgs(cmd, "sb") {
+				continue
+			}
+		case cds.Append, cds.GetSet, cds.SetNX:
+			if !formArgs(cmd, "sb") {
+				continue
+			}
+		case cds.StrLen, cds.GetDel:
+			if !formArgs(cmd, "s") {
+				continue
+			}
+		case cds.GetRange:
+			if !formArgs(cmd, "snn") {
+				continue
+			}
+		case cds.SetRange, cds.SetEx, cds.PSetEx:
+			if !formArgs(cmd, "snb") {
+				continue
+			}
+		case cds.GetEx:
+			if !formArgs(cmd, "s|sn") {
+				continue
+			}
+		case cds.MGet:
+			if !formArgs(cmd, "ss*") {
+				continue
+			}
+		case cds.MSet, cds.MSetNX:
+			// keys and values alternate
+			for i := 1; i < len(cmd); i++ {
+				if i%2 == 1 {
+					cmd[i] = formStr(cmd[i])
+				} else {
+					cmd[i] = formBulked(cmd[i])
+				}
+			}
+		case cds.SetBit:
+			if !formArgs(cmd, "snn") {
+				continue
+			}
+		case cds.GetBit:
+			if !formArgs(cmd, "sn") {
+				continue
+			}
+		case cds.BitCount:
+			if !formArgs(cmd, "s|nns") {
+				continue
+			}
+		case cds.BitPos:
+			if !formArgs(cmd, "sn|nns") {
+				continue
+			}
+		case cds.BitOp:
+			if !formArgs(cmd, "sss*") {
+				continue
+			}
+		case cds.BitField, cds.BitFieldRO:
+			// numbers are values or offsets, others are subcommands, types or offsets like "#1"
+			for i := 1; i < len(cmd); i++ {
+				if _, err := strconv.ParseInt(cmd[i], 10, 64); err == nil && i > 1 {
+					cmd[i] = formNum(cmd[i])
+				} else {
+					cmd[i] = formStr(cmd[i])
+				}
+			}
+		case cds.XAck, cds.XAdd, cds.XAutoClaim, cds.XClaim, cds.XDel, cds.XGroup, cds.XInfo, cds.XLen,
+			cds.XPending, cds.XRange, cds.XRead, cds.XReadGroup, cds.XRevRange, cds.XSetID, cds.XTrim:
+			// numbers are counts, times or IDs, others are keys, names, options or IDs like "1-0"
+			for i := 1; i < len(cmd); i++ {
+				if _, err := strconv.ParseInt(cmd[i], 10, 64); err == nil && i > 1 {
+					cmd[i] = formNum(cmd[i])
+				} else {
+					cmd[i] = formStr(cmd[i])
+				}
+			}
+		case cds.GeoAdd, cds.GeoDist, cds.GeoHash, cds.GeoPos, cds.GeoSearch, cds.GeoSearchSt:
+			// numbers are counts or coordinates, others are keys, members, options or coordinates
+			for i := 1; i < len(cmd); i++ {
+				if _, err := strconv.ParseInt(cmd[i], 10, 64); err == nil && i > 1 {
+					cmd[i] = formNum(cmd[i])
+				} else {
+					cmd[i] = formStr(cmd[i])
+				}
+			}
+		case cds.PFAdd:
+			if !formArgs(cmd, "s|b*") {
+				continue
+			}
+		case cds.PFCount, cds.PFMerge:
+			if !formArgs(cmd, "ss*") {
+				continue
+			}
+		case cds.LPush, cds.RPush, cds.LPushX, cds.RPushX:
+			if !formArgs(cmd, "sbb*") {
+				continue
+			}
+		case cds.LPop, cds.RPop:
+			if !formArgs(cmd, "s|n") {
+				continue
+			}
+		case cds.LLen:
+			if !formArgs(cmd, "s") {
+				continue
+			}
+		case cds.LRange, cds.LTrim:
+			if !formArgs(cmd, "snn") {
+				continue
+			}
+		case cds.LIndex:
+			if !formArgs(cmd, "sn") {
+				continue
+			}
+		case cds.LSet, cds.LRem:
+			if !formArgs(cmd, "snb") {
+				continue
+			}
+		case cds.LInsert:
+			if !formArgs(cmd, "ssbb") {
+				continue
+			}
+		case cds.LMove:
+			if !formArgs(cmd, "ssss") {
+				continue
+			}
+		case cds.BLMove:
+			if !formArgs(cmd, "sssss") {
+				continue
+			}
+		case cds.BLPop, cds.BRPop, cds.BZPopMin, cds.BZPopMax:
+			// keys and the timeout in seconds
+			if !formArgs(cmd, "ss*") {
+				continue
+			}
+		case cds.HSet, cds.HMSet, cds.HSetNX, cds.HDel, cds.HMGet:
+			if !formArgs(cmd, "sbb*") {
+				continue
+			}
+		case cds.HGet, cds.HExists, cds.HStrLen:
+			if !formArgs(cmd, "sb") {
+				continue
+			}
+		case cds.HLen, cds.HKeys, cds.HVals, cds.HGetAll:
+			if !formArgs(cmd, "s") {
+				continue
+			}
+		case cds.HIncrBy:
+			if !formArgs(cmd, "sbn") {
+				continue
+			}
+		case cds.HIncrByFlt:
+			if !formArgs(cmd, "sbb") {
+				continue
+			}
+		case cds.HRandField:
+			if !formArgs(cmd, "s|ns") {
+				continue
+			}
+		case cds.HScan:
+			if !formArgs(cmd, "sn|sbsn") {
+				continue
+			}
+		case cds.SAdd, cds.SRem, cds.SMIsMember:
+			if !formArgs(cmd, "sbb*") {
+				continue
+			}
+		case cds.SCard, cds.SMembers:
+			if !formArgs(cmd, "s") {
+				continue
+			}
+		case cds.SIsMember:
+			if !formArgs(cmd, "sb") {
+				continue
+			}
+		case cds.SPop, cds.SRandMember:
+			if !formArgs(cmd, "s|n") {
+				continue
+			}
+		case cds.SMove:
+			if !formArgs(cmd, "ssb") {
+				continue
+			}
+		case cds.SInter, cds.SUnion, cds.SDiff, cds.SInterStore, cds.SUnionStore, cds.SDiffStore:
+			if !formArgs(cmd, "ss*") {
+				continue
+			}
+		case cds.SInterCard:
+			if !formArgs(cmd, "nss*") {
+				continue
+			}
+		case cds.SScan:
+			if !formArgs(cmd, "sn|sbsn") {
+				continue
+			}
+		case cds.ZAdd:
+			i := 2
+			for ; i < len(cmd) && isZAddOption(cmd[i]); i++ {
+				cmd[i] = formStr(cmd[i])
+			}
+			if len(cmd)-i < 2 {
+				fmt.Printf("missing argument of \"%s\"\n", cmd[0])
+				continue
+			}
+			cmd[1] = formStr(cmd[1])
+			for ; i < len(cmd); i++ {
+				cmd[i] = formBulked(cmd[i])
+			}
+		case cds.ZRem, cds.ZMScore:
+			if !formArgs(cmd, "sbb*") {
+				continue
+			}
+		case cds.ZScore, cds.ZRank, cds.ZRevRank:
+			if !formArgs(cmd, "sb") {
+				continue
+			}
+		case cds.ZIncrBy:
+			if !formArgs(cmd, "sbb") {
+				continue
+			}
+		case cds.ZCard:
+			if !formArgs(cmd, "s") {
+				continue
+			}
+		case cds.ZCount, cds.ZLexCount, cds.ZRemRngScr, cds.ZRemRngLex:
+			if !formArgs(cmd, "sbb") {
+				continue
+			}
+		case cds.ZRemRngRank:
+			if !formArgs(cmd, "snn") {
+				continue
+			}
+		case cds.ZPopMin, cds.ZPopMax:
+			if !formArgs(cmd, "s|n") {
+				continue
+			}
+		case cds.ZRange, cds.ZRangeStore, cds.ZUnionStore, cds.ZInterStore:
+			// options are strings and the others are numbers if possible
+			for i := 1; i < len(cmd); i++ {
+				if _, err := strconv.ParseInt(cmd[i], 10, 64); err == nil {
+					cmd[i] = formNum(cmd[i])
+				} else {
+					cmd[i] = formStr(cmd[i])
+				}
+			}
+		case cds.ZScan:
+			if !formArgs(cmd, "sn|sbsn") {
+				continue
+			}
+		case cds.Publish:
+			if !formArgs(cmd, "bb") {
+				continue
+			}
+		case cds.Subscribe, cds.PSubscribe:
+			if !formArgs(cmd, "b*") {
+				continue
+			}
+		case cds.PubSub:
+			if !formArgs(cmd, "s|b*") {
+				continue
+			}
+		case cds.Config:
+			if !formArgs(cmd, "sb|b") {
+				continue
+			}
+		case cds.Keys:
+			if !formArgs(cmd, "b") {
+				continue
+			}
+		case cds.Scan:
+			if len(cmd) < 2 || len(cmd)%2 != 0 {
+				fmt.Printf("wrong number of arguments of \"%s\"\n", cmd[0])
+				continue
+			}
+			cmd[1] = formNum(cmd[1])
+			// values of options are bulked except count
+			for i := 2; i < len(cmd); i += 2 {
+				if strings.ToUpper(cmd[i]) == cds.Count {
+					cmd[i+1] = formNum(cmd[i+1])
+				} else {
+					cmd[i+1] = formBulked(cmd[i+1])
+				}
+				cmd[i] = formStr(cmd[i])
+			}
+		case cds.Info:
+			if !formArgs(cmd, "|s*") {
+				continue
+			}
+		case cds.Command:
+			if !formArgs(cmd, "|sb*") {
+				continue
+			}
+		case cds.Hello:
+			if !formArgs(cmd, "|ns*") {
+				continue
+			}
 		case cds.Select:
 			cmd = cmd[:2]
 			n, err := strconv.ParseInt(cmd[1], 10, 64)
@@ -112,15 +496,18 @@ func main() {
 			cmd[1] = formStr(cmd[1])
 			cmd[2] = formBulked(cmd[2])
 			cnt := 3
-			for i := cnt; i < len(cmd); i += 2 {
-				switch cmd[i] {
-				case cds.TimeoutSec, cds.TimeoutMilSec, cds.ExpireAtNano:
+			for i := cnt; i < len(cmd); i++ {
+				switch strings.ToUpper(cmd[i]) {
+				case cds.IfNotExist, cds.IfExist, cds.GetArg, cds.KeepTTL:
+					cmd[i] = formStr(cmd[i])
+				case cds.TimeoutSec, cds.TimeoutMilSec, cds.ExpireAtSec, cds.ExpireAtMilSec, cds.ExpireAtNano:
 					if i+1 >= len(cmd) {
 						fmt.Printf("missing argument of \"%s\"\n", cmd[i])
 						continue
 					}
 					cmd[i] = formStr(cmd[i])
-					cmd[i+1] = formNum(cmd[i+1])
+					i++
+					cmd[i] = formNum(cmd[i])
 				default:
 					fmt.Printf("unrecognized argument: \"%s\"\n", cmd[i])
 					continue
@@ -141,10 +528,18 @@ func main() {
 			fmt.Print(err.Error())
 			continue
 		}
-		rsp, _ := token.Deserialize(cli.Conn)
-		//fmt.Print("des\n")
-		for _, t := range rsp {
+		subscribing := cmd[0] == formStr(cds.Subscribe) || cmd[0] == formStr(cds.PSubscribe)
+		for {
+			t, err := cli.Reader.ReadToken()
+			if err != nil {
+				fmt.Println(err.Error())
+				break
+			}
 			fmt.Println(t.Format())
+			// keep printing messages received until interrupted
+			if !subscribing {
+				break
+			}
 		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/inhzus/go-redis-impl/internal/pkg/server"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
 
 func parseDuration(str string) time.Duration {
@@ -51,6 +52,11 @@ func getOption() *server.Option {
 	flag.BoolVar(&opt.Persist.SaveCopy, "es", false, "enable save persistence file with timestamp")
 	flag.StringVar(&flushInterval, "fi", "1s", "flushing to aof interval, format: 1Y2M3D4h5m6s")
 	flag.StringVar(&rewriteInterval, "ri", "1h", "rewriting rcl interval, format: 1Y2M3D4h5m6s")
+	flag.IntVar(&opt.Hz, "hz", 10, "frequency of background tasks like active expiration")
+	flag.StringVar(&opt.NotifyKeyspaceEvents, "ne", "", "classes of keyspace events notified, e.g. KEA")
+	flag.Int64Var(&opt.MaxBulkLen, "mb", token.DefaultMaxBulkLen, "max length of bulk strings of requests")
+	flag.Int64Var(&opt.MaxArrayLen, "ma", token.DefaultMaxArrayLen, "max number of elements of request arrays")
+	flag.IntVar(&opt.MaxDepth, "md", token.DefaultMaxDepth, "max nesting depth of request arrays")
 	flag.Parse()
 	opt.Addr = fmt.Sprintf("%s:%s", host, port)
 	opt.Persist.FlushInr = parseDuration(flushInterval)
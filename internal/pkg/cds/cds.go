@@ -8,22 +8,279 @@ package cds
 
 // command string
 const (
-	Desc    = "desc"
-	Discard = "discard"
-	Exec    = "exec"
-	Get     = "get"
-	Incr    = "incr"
-	Multi   = "multi"
-	Select  = "select"
-	Set     = "set"
-	Ping    = "ping"
-	Unwatch = "unwatch"
-	Watch   = "watch"
+	Append      = "append"
+	BitCount    = "bitcount"
+	BitField    = "bitfield"
+	BitFieldRO  = "bitfield_ro"
+	BitOp       = "bitop"
+	BitPos      = "bitpos"
+	BLMove      = "blmove"
+	BLPop       = "blpop"
+	BRPop       = "brpop"
+	BZPopMax    = "bzpopmax"
+	BZPopMin    = "bzpopmin"
+	Command     = "command"
+	Config      = "config"
+	Copy        = "copy"
+	DBSize      = "dbsize"
+	Decr        = "decr"
+	DecrBy      = "decrby"
+	Del         = "del"
+	Desc        = "desc"
+	Discard     = "discard"
+	Exec        = "exec"
+	Exists      = "exists"
+	Expire      = "expire"
+	ExpireAt    = "expireat"
+	ExpireTime  = "expiretime"
+	FlushAll    = "flushall"
+	FlushDB     = "flushdb"
+	GeoAdd      = "geoadd"
+	GeoDist     = "geodist"
+	GeoHash     = "geohash"
+	GeoPos      = "geopos"
+	GeoSearch   = "geosearch"
+	GeoSearchSt = "geosearchstore"
+	Get         = "get"
+	GetBit      = "getbit"
+	GetDel      = "getdel"
+	GetEx       = "getex"
+	GetRange    = "getrange"
+	GetSet      = "getset"
+	HDel        = "hdel"
+	Hello       = "hello"
+	HExists     = "hexists"
+	HGet        = "hget"
+	HGetAll     = "hgetall"
+	HIncrBy     = "hincrby"
+	HIncrByFlt  = "hincrbyfloat"
+	HKeys       = "hkeys"
+	HLen        = "hlen"
+	HMGet       = "hmget"
+	HMSet       = "hmset"
+	HRandField  = "hrandfield"
+	HScan       = "hscan"
+	HSet        = "hset"
+	HSetNX      = "hsetnx"
+	HStrLen     = "hstrlen"
+	HVals       = "hvals"
+	Incr        = "incr"
+	IncrBy      = "incrby"
+	IncrByFlt   = "incrbyfloat"
+	Info        = "info"
+	Keys        = "keys"
+	KeyType     = "type"
+	LIndex      = "lindex"
+	LInsert     = "linsert"
+	LLen        = "llen"
+	LMove       = "lmove"
+	LPop        = "lpop"
+	LPush       = "lpush"
+	LPushX      = "lpushx"
+	LRange      = "lrange"
+	LRem        = "lrem"
+	LSet        = "lset"
+	LTrim       = "ltrim"
+	MGet        = "mget"
+	Move        = "move"
+	MSet        = "mset"
+	MSetNX      = "msetnx"
+	Multi       = "multi"
+	Object      = "object"
+	PExpire     = "pexpire"
+	PExpireAt   = "pexpireat"
+	PExpireTime = "pexpiretime"
+	PFAdd       = "pfadd"
+	PFCount     = "pfcount"
+	PFMerge     = "pfmerge"
+	Persist     = "persist"
+	PSetEx      = "psetex"
+	PSubscribe  = "psubscribe"
+	PTTL        = "pttl"
+	PubSub      = "pubsub"
+	Publish     = "publish"
+	PUnsub      = "punsubscribe"
+	RandomKey   = "randomkey"
+	Rename      = "rename"
+	RenameNX    = "renamenx"
+	RPop        = "rpop"
+	RPush       = "rpush"
+	RPushX      = "rpushx"
+	SAdd        = "sadd"
+	SCard       = "scard"
+	SDiff       = "sdiff"
+	SDiffStore  = "sdiffstore"
+	Scan        = "scan"
+	Select      = "select"
+	Set         = "set"
+	SetBit      = "setbit"
+	SetEx       = "setex"
+	SetNX       = "setnx"
+	SetRange    = "setrange"
+	SInter      = "sinter"
+	SInterCard  = "sintercard"
+	SInterStore = "sinterstore"
+	SIsMember   = "sismember"
+	SMembers    = "smembers"
+	SMIsMember  = "smismember"
+	SMove       = "smove"
+	SPop        = "spop"
+	SRandMember = "srandmember"
+	SRem        = "srem"
+	SScan       = "sscan"
+	StrLen      = "strlen"
+	Subscribe   = "subscribe"
+	SUnion      = "sunion"
+	SUnionStore = "sunionstore"
+	SwapDB      = "swapdb"
+	Ping        = "ping"
+	Touch       = "touch"
+	TTL         = "ttl"
+	Unlink      = "unlink"
+	Unsubscribe = "unsubscribe"
+	Unwatch     = "unwatch"
+	Watch       = "watch"
+	XAck        = "xack"
+	XAdd        = "xadd"
+	XAutoClaim  = "xautoclaim"
+	XClaim      = "xclaim"
+	XDel        = "xdel"
+	XGroup      = "xgroup"
+	XInfo       = "xinfo"
+	XLen        = "xlen"
+	XPending    = "xpending"
+	XRange      = "xrange"
+	XRead       = "xread"
+	XReadGroup  = "xreadgroup"
+	XRevRange   = "xrevrange"
+	XSetID      = "xsetid"
+	XTrim       = "xtrim"
+	ZAdd        = "zadd"
+	ZCard       = "zcard"
+	ZCount      = "zcount"
+	ZIncrBy     = "zincrby"
+	ZInterStore = "zinterstore"
+	ZLexCount   = "zlexcount"
+	ZMScore     = "zmscore"
+	ZPopMax     = "zpopmax"
+	ZPopMin     = "zpopmin"
+	ZRange      = "zrange"
+	ZRangeStore = "zrangestore"
+	ZRank       = "zrank"
+	ZRem        = "zrem"
+	ZRemRngLex  = "zremrangebylex"
+	ZRemRngRank = "zremrangebyrank"
+	ZRemRngScr  = "zremrangebyscore"
+	ZRevRank    = "zrevrank"
+	ZScan       = "zscan"
+	ZScore      = "zscore"
+	ZUnionStore = "zunionstore"
 )
 
 // argument string
 const (
-	TimeoutSec    = "EX"
-	TimeoutMilSec = "PX"
-	ExpireAtNano  = "PT"
+	TimeoutSec     = "EX"
+	TimeoutMilSec  = "PX"
+	ExpireAtNano   = "PT"
+	ExpireAtSec    = "EXAT"
+	ExpireAtMilSec = "PXAT"
+	PersistArg     = "PERSIST"
+	KeepTTL        = "KEEPTTL"
+	GetArg         = "GET"
+	SetArg         = "SET"
+	IncrByArg      = "INCRBY"
+	Overflow       = "OVERFLOW"
+	Wrap           = "WRAP"
+	Sat            = "SAT"
+	Fail           = "FAIL"
+	ByteArg        = "BYTE"
+	BitArg         = "BIT"
+	And            = "AND"
+	Or             = "OR"
+	Xor            = "XOR"
+	Not            = "NOT"
+	IfNotExist     = "NX"
+	IfExist        = "XX"
+	IfGreater      = "GT"
+	IfLess         = "LT"
+	Before         = "BEFORE"
+	After          = "AFTER"
+	Left           = "LEFT"
+	Right          = "RIGHT"
+	Match          = "MATCH"
+	Count          = "COUNT"
+	Type           = "TYPE"
+	WithValues     = "WITHVALUES"
+	Limit          = "LIMIT"
+	Changed        = "CH"
+	Increment      = "INCR"
+	ByScore        = "BYSCORE"
+	ByLex          = "BYLEX"
+	Reverse        = "REV"
+	WithScores     = "WITHSCORES"
+	Weights        = "WEIGHTS"
+	Aggregate      = "AGGREGATE"
+	AggSum         = "SUM"
+	AggMin         = "MIN"
+	AggMax         = "MAX"
+	Channels       = "CHANNELS"
+	NumSub         = "NUMSUB"
+	NumPat         = "NUMPAT"
+	ConfigGet      = "GET"
+	ConfigSet      = "SET"
+	NoMkStream     = "NOMKSTREAM"
+	MkStream       = "MKSTREAM"
+	MaxLen         = "MAXLEN"
+	MinID          = "MINID"
+	Block          = "BLOCK"
+	Streams        = "STREAMS"
+	Group          = "GROUP"
+	NoAck          = "NOACK"
+	EntriesRead    = "ENTRIESREAD"
+	EntriesAdded   = "ENTRIESADDED"
+	MaxDeletedID   = "MAXDELETEDID"
+	Create         = "CREATE"
+	SetID          = "SETID"
+	Destroy        = "DESTROY"
+	CreateConsumer = "CREATECONSUMER"
+	DelConsumer    = "DELCONSUMER"
+	Idle           = "IDLE"
+	Time           = "TIME"
+	RetryCount     = "RETRYCOUNT"
+	Force          = "FORCE"
+	JustID         = "JUSTID"
+	LastID         = "LASTID"
+	StreamArg      = "STREAM"
+	Groups         = "GROUPS"
+	Consumers      = "CONSUMERS"
+	FromMember     = "FROMMEMBER"
+	FromLonLat     = "FROMLONLAT"
+	ByRadius       = "BYRADIUS"
+	ByBox          = "BYBOX"
+	Asc            = "ASC"
+	DescArg        = "DESC"
+	Any            = "ANY"
+	WithCoord      = "WITHCOORD"
+	WithDist       = "WITHDIST"
+	WithHash       = "WITHHASH"
+	StoreDist      = "STOREDIST"
+	Async          = "ASYNC"
+	Sync           = "SYNC"
+	DB             = "DB"
+	Replace        = "REPLACE"
+	Encoding       = "ENCODING"
+	RefCount       = "REFCOUNT"
+	IdleTime       = "IDLETIME"
+	Freq           = "FREQ"
+	Auth           = "AUTH"
+	SetName        = "SETNAME"
+	InfoArg        = "INFO"
+	Docs           = "DOCS"
+	GetKeys        = "GETKEYS"
+)
+
+// configuration parameter
+const (
+	Hz                   = "hz"
+	NotifyKeyspaceEvents = "notify-keyspace-events"
 )
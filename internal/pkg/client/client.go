@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
 
@@ -17,6 +18,16 @@ type Response struct {
 	Err  error
 }
 
+// ErrKind returns the kind of the error replied, e.g. token.ErrKindWrongType,
+// or empty if the reply is not an error.
+func (r *Response) ErrKind() token.ErrKind {
+	if r.Data == nil || r.Data.Label != label.Error {
+		return ""
+	}
+	msg, _ := r.Data.Data.(string)
+	return token.KindOf(msg)
+}
+
 // Task is the structure hold by the channel which connects front-end
 // interactive functions and consumer.
 type Task struct {
@@ -38,6 +49,7 @@ type Option struct {
 type Client struct {
 	option  *Option
 	Conn    net.Conn
+	Reader  *token.Reader
 	queue   chan *Task
 	stop    chan struct{}
 	request func(*token.Token) *Response
@@ -86,24 +98,21 @@ func (c *Client) consume(ts []*token.Token, rspCh chan *Response) {
 		rspCh <- &Response{Err: err}
 		return
 	}
-	endCh := make(chan struct{})
-	var responses []*Response
-	go func() {
-		rsp, _ := token.Deserialize(c.Conn)
-		for _, t := range rsp {
-			responses = append(responses, &Response{Data: t})
-		}
-		endCh <- struct{}{}
-	}()
 	if c.option.ReadTimeout > 0 {
-		select {
-		case <-endCh:
-		case <-time.After(c.option.ReadTimeout):
-			rspCh <- &Response{Err: fmt.Errorf("read data from connection timeout")}
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.option.ReadTimeout))
+	}
+	// every request is replied by exactly one token
+	responses := make([]*Response, 0, len(ts))
+	for range ts {
+		t, err := c.Reader.ReadToken()
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				err = fmt.Errorf("read data from connection timeout")
+			}
+			rspCh <- &Response{Err: err}
 			return
 		}
-	} else {
-		<-endCh
+		responses = append(responses, &Response{Data: t})
 	}
 	for _, rsp := range responses {
 		rspCh <- rsp
@@ -118,6 +127,7 @@ func (c *Client) Connect() (err error) {
 	if err != nil {
 		return
 	}
+	c.Reader = token.NewReader(c.Conn)
 	c.queue = make(chan *Task)
 	c.stop = make(chan struct{})
 
@@ -2,10 +2,15 @@ package client
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
 	"github.com/inhzus/go-redis-impl/internal/pkg/server"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,10 +19,16 @@ var (
 )
 
 func init() {
-	s := server.NewServer(&server.Option{})
+	// listen on another port in case of conflicting with server tests
+	opt := &server.Option{Addr: ":6390"}
+	opt.Persist.Enable = false
+	s := server.NewServer(opt)
 	go s.Serve()
-	c = NewClient(&Option{})
-	_ = c.Connect()
+	c = NewClient(&Option{Addr: opt.Addr})
+	// wait for the server to listen
+	for i := 0; i < 100 && c.Connect() != nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
 }
 
 func BenchmarkClient_Set(b *testing.B) {
@@ -42,3 +53,221 @@ func BenchmarkClient_Pipeline(b *testing.B) {
 		}
 	}
 }
+
+func TestClient_PubSub(t *testing.T) {
+	ps, err := c.PubSub()
+	assert.Nil(t, err)
+	defer ps.Close()
+	assert.Nil(t, ps.Subscribe("t_client_ch"))
+	assert.Equal(t, &Message{Kind: "subscribe", Channel: "t_client_ch", Count: 1}, <-ps.Channel())
+	assert.Nil(t, ps.PSubscribe("t_client_*"))
+	assert.Equal(t, &Message{Kind: "psubscribe", Pattern: "t_client_*", Count: 2}, <-ps.Channel())
+
+	rsp := c.Publish("t_client_ch", "hello")
+	assert.Nil(t, rsp.Err)
+	assert.Equal(t, int64(2), rsp.Data.Data)
+	assert.Equal(t, &Message{Kind: "message", Channel: "t_client_ch", Payload: []byte("hello")}, <-ps.Channel())
+	assert.Equal(t, &Message{Kind: "pmessage", Pattern: "t_client_*", Channel: "t_client_ch", Payload: []byte("hello")},
+		<-ps.Channel())
+	assert.Equal(t, int64(1), c.PubSubNumPat().Data.Data)
+
+	assert.Nil(t, ps.Unsubscribe())
+	assert.Equal(t, &Message{Kind: "unsubscribe", Channel: "t_client_ch", Count: 1}, <-ps.Channel())
+	assert.Equal(t, int64(1), c.Publish("t_client_ch", 1).Data.Data)
+	assert.Equal(t, &Message{Kind: "pmessage", Pattern: "t_client_*", Channel: "t_client_ch", Payload: []byte("1")},
+		<-ps.Channel())
+}
+
+func TestClient_Scan(t *testing.T) {
+	var expected []string
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("t_scan_%02d", i)
+		expected = append(expected, key)
+		assert.Nil(t, c.Set(key, i, 0).Err)
+	}
+	assert.Nil(t, c.SAdd("t_scan_set", 1).Err)
+	var keys []string
+	it := c.Scan("t_scan_*", 4, "string")
+	for it.Next() {
+		keys = append(keys, it.Val())
+	}
+	assert.Nil(t, it.Err())
+	sort.Strings(keys)
+	assert.Equal(t, expected, keys)
+
+	it = c.Scan("", 0, "foo")
+	assert.False(t, it.Next())
+	assert.NotNil(t, it.Err())
+}
+
+func TestClient_String(t *testing.T) {
+	assert.Equal(t, int64(5), c.Append("t_client_str", "hello").Data.Data)
+	assert.Equal(t, int64(7), c.SetRange("t_client_str", 5, "!!").Data.Data)
+	assert.Equal(t, []byte("lo!"), c.GetRange("t_client_str", 3, -2).Data.Data)
+	assert.Equal(t, int64(7), c.StrLen("t_client_str").Data.Data)
+	assert.Nil(t, c.MSet("t_client_a", 1, "t_client_b", "b").Err)
+	assert.NotNil(t, c.MSet(1, "t_client_a").Err)
+	assert.Equal(t, int64(0), c.MSetNX("t_client_a", 2, "t_client_c", 3).Data.Data)
+	rsp := c.MGet("t_client_a", "t_client_b", "t_client_c")
+	assert.Nil(t, rsp.Err)
+	assert.Equal(t, 3, len(rsp.Data.Data.([]*token.Token)))
+	assert.Equal(t, []byte("b"), c.GetSet("t_client_b", "c").Data.Data)
+	assert.Equal(t, []byte("1"), c.GetEx("t_client_a", "EX", 100).Data.Data)
+	assert.True(t, c.TTL("t_client_a").Data.Data.(int64) > 0)
+	assert.Equal(t, []byte("1"), c.GetDel("t_client_a").Data.Data)
+	assert.Equal(t, int64(0), c.Exists("t_client_a").Data.Data)
+}
+
+func TestClient_SetArgs(t *testing.T) {
+	assert.Equal(t, int64(1), c.SetNX("t_client_lock", "a").Data.Data)
+	assert.Nil(t, c.SetArgs("t_client_lock", "b", "NX", "PX", 30000).Data.Data)
+	assert.Equal(t, []byte("a"), c.SetArgs("t_client_lock", "b", "XX", "GET", "KEEPTTL").Data.Data)
+	assert.Equal(t, int64(-1), c.TTL("t_client_lock").Data.Data)
+	assert.Nil(t, c.SetEX("t_client_lock", 100, "c").Err)
+	assert.Equal(t, int64(100), c.TTL("t_client_lock").Data.Data)
+	assert.Nil(t, c.PSetEX("t_client_lock", 100000, "d").Err)
+	assert.Equal(t, []byte("d"), c.Get("t_client_lock").Data.Data)
+}
+
+func TestClient_IncrBy(t *testing.T) {
+	assert.Equal(t, int64(5), c.IncrBy("t_client_incr", 5).Data.Data)
+	assert.Equal(t, int64(3), c.DecrBy("t_client_incr", 2).Data.Data)
+	assert.Equal(t, int64(2), c.Decr("t_client_incr").Data.Data)
+	assert.Equal(t, []byte("3.5"), c.IncrByFloat("t_client_incr", 1.5).Data.Data)
+}
+
+func TestClient_Bitmap(t *testing.T) {
+	assert.Equal(t, int64(0), c.SetBit("t_client_bits", 9, 1).Data.Data)
+	assert.Equal(t, int64(1), c.GetBit("t_client_bits", 9).Data.Data)
+	assert.Equal(t, int64(1), c.BitCount("t_client_bits", 0, -1, "BIT").Data.Data)
+	assert.Equal(t, int64(9), c.BitPos("t_client_bits", 1).Data.Data)
+	assert.Equal(t, int64(2), c.BitOp("NOT", "t_client_bits_not", "t_client_bits").Data.Data)
+	assert.Equal(t, int64(15), c.BitCount("t_client_bits_not").Data.Data)
+	rsp := c.BitField("t_client_bits", "SET", "u8", "#1", 255, "GET", "u4", 8)
+	assert.Nil(t, rsp.Err)
+	assert.Equal(t, int64(64), rsp.Data.Data.([]*token.Token)[0].Data)
+	assert.Equal(t, int64(15), rsp.Data.Data.([]*token.Token)[1].Data)
+	assert.Equal(t, int64(255), c.BitFieldRO("t_client_bits", "GET", "u8", 8).Data.Data.([]*token.Token)[0].Data)
+}
+
+func TestClient_HyperLogLog(t *testing.T) {
+	assert.Equal(t, int64(1), c.PFAdd("t_client_hll", "a", "b", "c").Data.Data)
+	assert.Equal(t, int64(0), c.PFAdd("t_client_hll", "a").Data.Data)
+	assert.Equal(t, int64(1), c.PFAdd("t_client_hll2", "c", "d").Data.Data)
+	assert.Equal(t, int64(4), c.PFCount("t_client_hll", "t_client_hll2").Data.Data)
+	assert.Nil(t, c.PFMerge("t_client_hll", "t_client_hll2").Err)
+	assert.Equal(t, int64(4), c.PFCount("t_client_hll").Data.Data)
+}
+
+func TestClient_Stream(t *testing.T) {
+	key := "t_client_stream"
+	assert.Equal(t, []byte("1-0"), c.XAdd(key, "1", "f", "v").Data.Data)
+	assert.Equal(t, []byte("2-0"), c.XAddArgs(key, []interface{}{cds.MaxLen, 10}, "2", "f", "v").Data.Data)
+	assert.Equal(t, int64(2), c.XLen(key).Data.Data)
+	assert.Equal(t, 1, len(c.XRange(key, "-", "+", cds.Count, 1).Data.Data.([]*token.Token)))
+	assert.Equal(t, 2, len(c.XRevRange(key, "+", "-").Data.Data.([]*token.Token)))
+	assert.Equal(t, 1, len(c.XRead(cds.Count, 1, cds.Streams, key, "0").Data.Data.([]*token.Token)))
+	assert.Nil(t, c.XGroup(cds.Create, key, "g", "0").Err)
+	assert.Equal(t, 1, len(c.XReadGroup("g", "alice", cds.Streams, key, ">").Data.Data.([]*token.Token)))
+	assert.Equal(t, int64(2), c.XPending(key, "g").Data.Data.([]*token.Token)[0].Data)
+	assert.Equal(t, 1, len(c.XClaim(key, "g", "bob", 0, "1-0", cds.JustID).Data.Data.([]*token.Token)))
+	assert.Equal(t, 3, len(c.XAutoClaim(key, "g", "bob", 0, "-").Data.Data.([]*token.Token)))
+	assert.Equal(t, int64(2), c.XAck(key, "g", "1-0", "2-0").Data.Data)
+	assert.Equal(t, 1, len(c.XInfo(cds.Groups, key).Data.Data.([]*token.Token)))
+	assert.Equal(t, int64(1), c.XDel(key, "1-0").Data.Data)
+	assert.Equal(t, int64(1), c.XTrim(key, cds.MaxLen, 0).Data.Data)
+	assert.Nil(t, c.XSetID(key, "5").Err)
+}
+
+func TestClient_Block(t *testing.T) {
+	key := "t_client_block"
+	assert.Nil(t, c.BLPop(10*time.Millisecond, key).Data.Data)
+	c.RPush(key, "a", "b", "c")
+	assert.Equal(t, []byte("a"), c.BLPop(0, key).Data.Data.([]*token.Token)[1].Data)
+	assert.Equal(t, []byte("c"), c.BRPop(0, key+"_none", key).Data.Data.([]*token.Token)[1].Data)
+	assert.Equal(t, []byte("b"), c.BLMove(key, key+"_dst", true, false, 0).Data.Data)
+	assert.Equal(t, []byte("b"), c.LMove(key+"_dst", key, false, true).Data.Data)
+	c.ZAdd(key+"_z", Z{1, "a"}, Z{2, "b"})
+	assert.Equal(t, []byte("a"), c.BZPopMin(0, key+"_z").Data.Data.([]*token.Token)[1].Data)
+	assert.Equal(t, []byte("b"), c.BZPopMax(0, key+"_z").Data.Data.([]*token.Token)[1].Data)
+}
+
+func TestClient_DB(t *testing.T) {
+	key := "t_client_db"
+	c.Set(key, "v", 0)
+	assert.NotNil(t, c.RandomKey().Data.Data)
+	assert.Equal(t, int64(1), c.Copy(key, key, cds.DB, 1).Data.Data)
+	assert.Equal(t, int64(0), c.Move(key, 1).Data.Data)
+	n := c.DBSize().Data.Data.(int64)
+	assert.Equal(t, token.ReplyOk.Data, c.SwapDB(0, 1).Data.Data)
+	assert.Equal(t, int64(1), c.DBSize().Data.Data)
+	assert.Equal(t, token.ReplyOk.Data, c.FlushDB(true).Data.Data)
+	assert.Equal(t, token.ReplyOk.Data, c.SwapDB(1, 0).Data.Data)
+	assert.Equal(t, n, c.DBSize().Data.Data)
+	assert.Equal(t, int64(1), c.Del(key).Data.Data)
+}
+
+func TestClient_KeyMeta(t *testing.T) {
+	key := "t_client_key_meta"
+	c.Set(key, "100", 0)
+	assert.Equal(t, "string", c.Type(key).Data.Data)
+	assert.Equal(t, []byte("int"), c.Object(cds.Encoding, key).Data.Data)
+	assert.Equal(t, int64(1), c.Touch(key, key+"_none").Data.Data)
+	assert.Equal(t, token.ReplyOk.Data, c.Rename(key, key+"_new").Data.Data)
+	assert.Equal(t, int64(1), c.RenameNX(key+"_new", key).Data.Data)
+	assert.Equal(t, int64(1), c.Del(key).Data.Data)
+}
+
+func TestClient_Hello(t *testing.T) {
+	cli := NewClient(&Option{Addr: c.option.Addr})
+	assert.Nil(t, cli.Connect())
+	defer cli.Close()
+	key := "t_client_hello"
+	cli.HSet(key, "f", "v")
+	assert.Equal(t, label.Array, cli.HGetAll(key).Data.Label)
+	rsp := cli.Hello(3, cds.SetName, "hello")
+	assert.Nil(t, rsp.Err)
+	assert.Equal(t, label.Map, rsp.Data.Label)
+	assert.Equal(t, token.NewMap(token.NewBulked([]byte("f")), token.NewBulked([]byte("v"))), cli.HGetAll(key).Data)
+	assert.Equal(t, token.NewNull(), cli.Get(key+"_none").Data)
+	assert.Equal(t, token.ErrKindNoProto, cli.Hello(1).ErrKind())
+	assert.Equal(t, label.Array, cli.Hello(2).Data.Label)
+	assert.Equal(t, int64(1), cli.Del(key).Data.Data)
+}
+
+func TestClient_ErrKind(t *testing.T) {
+	key := "t_client_err_kind"
+	c.Set(key, "value", 0)
+	assert.Equal(t, token.ErrKind(""), c.Get(key).ErrKind())
+	assert.Equal(t, token.ErrKindWrongType, c.HGet(key, "f").ErrKind())
+	rsp := c.Incr(key)
+	assert.Equal(t, token.ErrKindErr, rsp.ErrKind())
+	assert.True(t, strings.HasPrefix(rsp.Data.Data.(string), "ERR "))
+	assert.Equal(t, token.NewError("ERR unknown command 'nocmd', with args beginning with: 'a' "),
+		c.req(token.NewArray(token.NewBulked([]byte("nocmd")), token.NewBulked([]byte("a")))).Data)
+	assert.Equal(t, int64(1), c.Del(key).Data.Data)
+}
+
+func TestClient_Command(t *testing.T) {
+	n := c.CommandCount().Data.Data.(int64)
+	assert.Equal(t, int(n), len(c.Command().Data.Data.([]*token.Token)))
+	info := c.CommandInfo(cds.Get, "none").Data.Data.([]*token.Token)
+	assert.Equal(t, []byte(cds.Get), info[0].Data.([]*token.Token)[0].Data)
+	assert.Equal(t, token.NewBulked(nil), info[1])
+	assert.Equal(t, 2, len(c.CommandDocs(cds.Get).Data.Data.([]*token.Token)))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("a")), token.NewBulked([]byte("c"))),
+		c.CommandGetKeys(cds.MSet, "a", 1, "c", 2).Data)
+}
+
+func TestClient_Geo(t *testing.T) {
+	key := "t_client_geo"
+	assert.Equal(t, int64(2), c.GeoAdd(key, nil, GeoLocation{13.361389, 38.115556, "Palermo"},
+		GeoLocation{15.087269, 37.502669, "Catania"}).Data.Data)
+	assert.Equal(t, int64(0), c.GeoAdd(key, []string{cds.IfNotExist}, GeoLocation{13, 38, "Palermo"}).Data.Data)
+	assert.Equal(t, []byte("166.2742"), c.GeoDist(key, "Palermo", "Catania", "KM").Data.Data)
+	assert.Equal(t, []byte("sqc8b49rny0"), c.GeoHash(key, "Palermo").Data.Data.([]*token.Token)[0].Data)
+	assert.Equal(t, 2, len(c.GeoPos(key, "Palermo", "Rome").Data.Data.([]*token.Token)))
+	assert.Equal(t, 1, len(c.GeoSearch(key, cds.FromLonLat, 15.0, 37.0, cds.ByRadius, 100.0, "KM").Data.Data.([]*token.Token)))
+	assert.Equal(t, int64(2), c.GeoSearchStore(key+"_dst", key, cds.FromMember, "Palermo",
+		cds.ByBox, 400, 400, "KM", cds.StoreDist).Data.Data)
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
@@ -8,6 +10,74 @@ import (
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
 
+func newRow(cmd string, keys ...string) *token.Token {
+	row := []*token.Token{token.NewString(cmd)}
+	for _, key := range keys {
+		row = append(row, token.NewString(key))
+	}
+	return token.NewArray(row...)
+}
+
+// appendValues appends values converted to bulked string to the row
+func appendValues(row *token.Token, values ...interface{}) error {
+	for _, v := range values {
+		bulked, err := proc.ItfToBulked(v)
+		if err != nil {
+			return err
+		}
+		row.Data = append(row.Data.([]*token.Token), token.NewBulked(bulked))
+	}
+	return nil
+}
+
+// appendInts appends integers to the row
+func appendInts(row *token.Token, nums ...int64) {
+	for _, num := range nums {
+		row.Data = append(row.Data.([]*token.Token), token.NewInteger(num))
+	}
+}
+
+// appendArgs appends arguments to the row, strings are appended as
+// options and integers as numbers.
+func appendArgs(row *token.Token, args ...interface{}) error {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			row.Data = append(row.Data.([]*token.Token), token.NewString(v))
+		case int:
+			appendInts(row, int64(v))
+		case int64:
+			appendInts(row, v)
+		case float64:
+			row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(strconv.FormatFloat(v, 'f', -1, 64))))
+		default:
+			if err := appendValues(row, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newScanRow returns the row of scan family commands, match is ignored if
+// empty and count is ignored if not positive.
+func newScanRow(cmd, key string, cursor int64, match string, count int64) *token.Token {
+	row := newRow(cmd, key)
+	appendScanArgs(row, cursor, match, count)
+	return row
+}
+
+// appendScanArgs appends "cursor [MATCH match] [COUNT count]" to the row
+func appendScanArgs(row *token.Token, cursor int64, match string, count int64) {
+	appendInts(row, cursor)
+	if match != "" {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(cds.Match), token.NewBulked([]byte(match)))
+	}
+	if count > 0 {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(cds.Count), token.NewInteger(count))
+	}
+}
+
 // Redis `get` command.
 func (c *Client) Get(key string) *Response {
 	row := token.NewArray(token.NewString(cds.Get), token.NewString(key))
@@ -28,6 +98,43 @@ func (c *Client) Set(key string, value interface{}, timeout time.Duration) *Resp
 	return c.request(row)
 }
 
+// Redis `set` command with options like "NX", "GET", "KEEPTTL" or "EX", 10.
+func (c *Client) SetArgs(key string, value interface{}, args ...interface{}) *Response {
+	row := newRow(cds.Set, key)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `setnx` command.
+func (c *Client) SetNX(key string, value interface{}) *Response {
+	return c.requestValues(cds.SetNX, key, value)
+}
+
+// Redis `setex` command.
+func (c *Client) SetEX(key string, seconds int64, value interface{}) *Response {
+	row := newRow(cds.SetEx, key)
+	appendInts(row, seconds)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `psetex` command.
+func (c *Client) PSetEX(key string, milliseconds int64, value interface{}) *Response {
+	row := newRow(cds.PSetEx, key)
+	appendInts(row, milliseconds)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
 // Redis `ping` command.
 func (c *Client) Ping() *Response {
 	row := token.NewArray(token.NewString(cds.Ping))
@@ -41,7 +148,1062 @@ func (c *Client) Incr(key string) *Response {
 }
 
 // Redis `desc` command.
+//
+// Deprecated: use Decr instead.
 func (c *Client) Desc(key string) *Response {
 	row := token.NewArray(token.NewString(cds.Desc), token.NewString(key))
 	return c.request(row)
 }
+
+// Redis `decr` command.
+func (c *Client) Decr(key string) *Response {
+	return c.request(newRow(cds.Decr, key))
+}
+
+// Redis `incrby` command.
+func (c *Client) IncrBy(key string, incr int64) *Response {
+	row := newRow(cds.IncrBy, key)
+	appendInts(row, incr)
+	return c.request(row)
+}
+
+// Redis `decrby` command.
+func (c *Client) DecrBy(key string, decr int64) *Response {
+	row := newRow(cds.DecrBy, key)
+	appendInts(row, decr)
+	return c.request(row)
+}
+
+// Redis `incrbyfloat` command.
+func (c *Client) IncrByFloat(key string, incr float64) *Response {
+	row := newRow(cds.IncrByFlt, key)
+	if err := appendArgs(row, incr); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `append` command.
+func (c *Client) Append(key string, value interface{}) *Response {
+	return c.requestValues(cds.Append, key, value)
+}
+
+// Redis `strlen` command.
+func (c *Client) StrLen(key string) *Response {
+	return c.request(newRow(cds.StrLen, key))
+}
+
+// Redis `getrange` command.
+func (c *Client) GetRange(key string, start, end int64) *Response {
+	row := newRow(cds.GetRange, key)
+	appendInts(row, start, end)
+	return c.request(row)
+}
+
+// Redis `setrange` command.
+func (c *Client) SetRange(key string, offset int64, value interface{}) *Response {
+	row := newRow(cds.SetRange, key)
+	appendInts(row, offset)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `mget` command.
+func (c *Client) MGet(keys ...string) *Response {
+	return c.request(newRow(cds.MGet, keys...))
+}
+
+// newPairsRow returns the row of key-value pairs, keys must be strings
+func newPairsRow(cmd string, pairs ...interface{}) (*token.Token, error) {
+	row := newRow(cmd)
+	for i, v := range pairs {
+		if i%2 == 1 {
+			if err := appendValues(row, v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("key %v is not a string", v)
+		}
+		row.Data = append(row.Data.([]*token.Token), token.NewString(key))
+	}
+	return row, nil
+}
+
+// Redis `mset` command, pairs are key-value pairs.
+func (c *Client) MSet(pairs ...interface{}) *Response {
+	row, err := newPairsRow(cds.MSet, pairs...)
+	if err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `msetnx` command, pairs are key-value pairs.
+func (c *Client) MSetNX(pairs ...interface{}) *Response {
+	row, err := newPairsRow(cds.MSetNX, pairs...)
+	if err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `getset` command.
+func (c *Client) GetSet(key string, value interface{}) *Response {
+	return c.requestValues(cds.GetSet, key, value)
+}
+
+// Redis `getdel` command.
+func (c *Client) GetDel(key string) *Response {
+	return c.request(newRow(cds.GetDel, key))
+}
+
+// Redis `getex` command, args are options like "EX", 10 or "PERSIST".
+func (c *Client) GetEx(key string, args ...interface{}) *Response {
+	row := newRow(cds.GetEx, key)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `setbit` command.
+func (c *Client) SetBit(key string, offset int64, bit int64) *Response {
+	row := newRow(cds.SetBit, key)
+	appendInts(row, offset, bit)
+	return c.request(row)
+}
+
+// Redis `getbit` command.
+func (c *Client) GetBit(key string, offset int64) *Response {
+	row := newRow(cds.GetBit, key)
+	appendInts(row, offset)
+	return c.request(row)
+}
+
+// Redis `bitcount` command, args are like 0, -1, "BIT".
+func (c *Client) BitCount(key string, args ...interface{}) *Response {
+	row := newRow(cds.BitCount, key)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `bitpos` command, args are like 0, -1, "BIT".
+func (c *Client) BitPos(key string, bit int64, args ...interface{}) *Response {
+	row := newRow(cds.BitPos, key)
+	appendInts(row, bit)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `bitop` command.
+func (c *Client) BitOp(op, dst string, keys ...string) *Response {
+	return c.request(newRow(cds.BitOp, append([]string{op, dst}, keys...)...))
+}
+
+// Redis `bitfield` command, args are subcommands like "INCRBY", "u8", "#1", 1.
+func (c *Client) BitField(key string, args ...interface{}) *Response {
+	row := newRow(cds.BitField, key)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `bitfield_ro` command, args are subcommands like "GET", "u8", 0.
+func (c *Client) BitFieldRO(key string, args ...interface{}) *Response {
+	row := newRow(cds.BitFieldRO, key)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `pfadd` command.
+func (c *Client) PFAdd(key string, elements ...interface{}) *Response {
+	return c.requestValues(cds.PFAdd, key, elements...)
+}
+
+// Redis `pfcount` command.
+func (c *Client) PFCount(keys ...string) *Response {
+	return c.request(newRow(cds.PFCount, keys...))
+}
+
+// Redis `pfmerge` command.
+func (c *Client) PFMerge(dst string, keys ...string) *Response {
+	return c.request(newRow(cds.PFMerge, append([]string{dst}, keys...)...))
+}
+
+// Redis `del` command.
+func (c *Client) Del(keys ...string) *Response {
+	return c.request(newRow(cds.Del, keys...))
+}
+
+// Redis `unlink` command.
+func (c *Client) Unlink(keys ...string) *Response {
+	return c.request(newRow(cds.Unlink, keys...))
+}
+
+// Redis `exists` command.
+func (c *Client) Exists(keys ...string) *Response {
+	return c.request(newRow(cds.Exists, keys...))
+}
+
+func newExpireRow(cmd, key string, num int64, args ...string) *token.Token {
+	row := newRow(cmd, key)
+	row.Data = append(row.Data.([]*token.Token), token.NewInteger(num))
+	for _, arg := range args {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(arg))
+	}
+	return row
+}
+
+// Redis `pexpire` command, args are options like NX, XX, GT, LT.
+func (c *Client) Expire(key string, timeout time.Duration, args ...string) *Response {
+	return c.request(newExpireRow(cds.PExpire, key, int64(timeout/time.Millisecond), args...))
+}
+
+// Redis `pexpireat` command, args are options like NX, XX, GT, LT.
+func (c *Client) ExpireAt(key string, tm time.Time, args ...string) *Response {
+	return c.request(newExpireRow(cds.PExpireAt, key, tm.UnixNano()/int64(time.Millisecond), args...))
+}
+
+// Redis `ttl` command.
+func (c *Client) TTL(key string) *Response {
+	return c.request(newRow(cds.TTL, key))
+}
+
+// Redis `pttl` command.
+func (c *Client) PTTL(key string) *Response {
+	return c.request(newRow(cds.PTTL, key))
+}
+
+// Redis `expiretime` command.
+func (c *Client) ExpireTime(key string) *Response {
+	return c.request(newRow(cds.ExpireTime, key))
+}
+
+// Redis `persist` command.
+func (c *Client) Persist(key string) *Response {
+	return c.request(newRow(cds.Persist, key))
+}
+
+// requestValues requests the command on key with values converted to bulked
+func (c *Client) requestValues(cmd, key string, values ...interface{}) *Response {
+	row := newRow(cmd, key)
+	if err := appendValues(row, values...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `lpush` command.
+func (c *Client) LPush(key string, values ...interface{}) *Response {
+	return c.requestValues(cds.LPush, key, values...)
+}
+
+// Redis `rpush` command.
+func (c *Client) RPush(key string, values ...interface{}) *Response {
+	return c.requestValues(cds.RPush, key, values...)
+}
+
+// Redis `lpushx` command.
+func (c *Client) LPushX(key string, values ...interface{}) *Response {
+	return c.requestValues(cds.LPushX, key, values...)
+}
+
+// Redis `rpushx` command.
+func (c *Client) RPushX(key string, values ...interface{}) *Response {
+	return c.requestValues(cds.RPushX, key, values...)
+}
+
+// Redis `lpop` command.
+func (c *Client) LPop(key string) *Response {
+	return c.request(newRow(cds.LPop, key))
+}
+
+// Redis `rpop` command.
+func (c *Client) RPop(key string) *Response {
+	return c.request(newRow(cds.RPop, key))
+}
+
+// Redis `lpop` command with count.
+func (c *Client) LPopCount(key string, count int64) *Response {
+	row := newRow(cds.LPop, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `rpop` command with count.
+func (c *Client) RPopCount(key string, count int64) *Response {
+	row := newRow(cds.RPop, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `llen` command.
+func (c *Client) LLen(key string) *Response {
+	return c.request(newRow(cds.LLen, key))
+}
+
+// Redis `lrange` command.
+func (c *Client) LRange(key string, start, stop int64) *Response {
+	row := newRow(cds.LRange, key)
+	appendInts(row, start, stop)
+	return c.request(row)
+}
+
+// Redis `lindex` command.
+func (c *Client) LIndex(key string, index int64) *Response {
+	row := newRow(cds.LIndex, key)
+	appendInts(row, index)
+	return c.request(row)
+}
+
+// Redis `lset` command.
+func (c *Client) LSet(key string, index int64, value interface{}) *Response {
+	row := newRow(cds.LSet, key)
+	appendInts(row, index)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `ltrim` command.
+func (c *Client) LTrim(key string, start, stop int64) *Response {
+	row := newRow(cds.LTrim, key)
+	appendInts(row, start, stop)
+	return c.request(row)
+}
+
+// Redis `lrem` command.
+func (c *Client) LRem(key string, count int64, value interface{}) *Response {
+	row := newRow(cds.LRem, key)
+	appendInts(row, count)
+	if err := appendValues(row, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `linsert` command.
+func (c *Client) LInsert(key string, before bool, pivot, value interface{}) *Response {
+	where := cds.After
+	if before {
+		where = cds.Before
+	}
+	row := newRow(cds.LInsert, key, where)
+	if err := appendValues(row, pivot, value); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `lmove` command, the value is moved from the head of the source if
+// srcLeft, and to the head of the destination if dstLeft.
+func (c *Client) LMove(src, dst string, srcLeft, dstLeft bool) *Response {
+	return c.request(newRow(cds.LMove, src, dst, listSide(srcLeft), listSide(dstLeft)))
+}
+
+// Redis `blmove` command, timeout 0 blocks forever.
+func (c *Client) BLMove(src, dst string, srcLeft, dstLeft bool, timeout time.Duration) *Response {
+	return c.requestArgs(cds.BLMove, []string{src, dst}, listSide(srcLeft), listSide(dstLeft), timeout.Seconds())
+}
+
+// Redis `blpop` command, timeout 0 blocks forever.
+func (c *Client) BLPop(timeout time.Duration, keys ...string) *Response {
+	return c.requestArgs(cds.BLPop, keys, timeout.Seconds())
+}
+
+// Redis `brpop` command, timeout 0 blocks forever.
+func (c *Client) BRPop(timeout time.Duration, keys ...string) *Response {
+	return c.requestArgs(cds.BRPop, keys, timeout.Seconds())
+}
+
+// listSide returns the argument of the side of list
+func listSide(left bool) string {
+	if left {
+		return cds.Left
+	}
+	return cds.Right
+}
+
+// Redis `hset` command, pairs are field-value pairs.
+func (c *Client) HSet(key string, pairs ...interface{}) *Response {
+	return c.requestValues(cds.HSet, key, pairs...)
+}
+
+// Redis `hsetnx` command.
+func (c *Client) HSetNX(key string, field, value interface{}) *Response {
+	return c.requestValues(cds.HSetNX, key, field, value)
+}
+
+// Redis `hget` command.
+func (c *Client) HGet(key string, field interface{}) *Response {
+	return c.requestValues(cds.HGet, key, field)
+}
+
+// Redis `hmget` command.
+func (c *Client) HMGet(key string, fields ...interface{}) *Response {
+	return c.requestValues(cds.HMGet, key, fields...)
+}
+
+// Redis `hdel` command.
+func (c *Client) HDel(key string, fields ...interface{}) *Response {
+	return c.requestValues(cds.HDel, key, fields...)
+}
+
+// Redis `hexists` command.
+func (c *Client) HExists(key string, field interface{}) *Response {
+	return c.requestValues(cds.HExists, key, field)
+}
+
+// Redis `hlen` command.
+func (c *Client) HLen(key string) *Response {
+	return c.request(newRow(cds.HLen, key))
+}
+
+// Redis `hstrlen` command.
+func (c *Client) HStrLen(key string, field interface{}) *Response {
+	return c.requestValues(cds.HStrLen, key, field)
+}
+
+// Redis `hkeys` command.
+func (c *Client) HKeys(key string) *Response {
+	return c.request(newRow(cds.HKeys, key))
+}
+
+// Redis `hvals` command.
+func (c *Client) HVals(key string) *Response {
+	return c.request(newRow(cds.HVals, key))
+}
+
+// Redis `hgetall` command.
+func (c *Client) HGetAll(key string) *Response {
+	return c.request(newRow(cds.HGetAll, key))
+}
+
+// Redis `hincrby` command.
+func (c *Client) HIncrBy(key string, field interface{}, incr int64) *Response {
+	row := newRow(cds.HIncrBy, key)
+	if err := appendValues(row, field); err != nil {
+		return &Response{Err: err}
+	}
+	appendInts(row, incr)
+	return c.request(row)
+}
+
+// Redis `hincrbyfloat` command.
+func (c *Client) HIncrByFloat(key string, field interface{}, incr float64) *Response {
+	return c.requestValues(cds.HIncrByFlt, key, field, strconv.FormatFloat(incr, 'f', -1, 64))
+}
+
+// Redis `hrandfield` command with count, values are returned if withValues.
+func (c *Client) HRandField(key string, count int64, withValues bool) *Response {
+	row := newRow(cds.HRandField, key)
+	appendInts(row, count)
+	if withValues {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(cds.WithValues))
+	}
+	return c.request(row)
+}
+
+// Redis `hscan` command, match is ignored if empty.
+func (c *Client) HScan(key string, cursor int64, match string, count int64) *Response {
+	return c.request(newScanRow(cds.HScan, key, cursor, match, count))
+}
+
+// Redis `sadd` command.
+func (c *Client) SAdd(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.SAdd, key, members...)
+}
+
+// Redis `srem` command.
+func (c *Client) SRem(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.SRem, key, members...)
+}
+
+// Redis `scard` command.
+func (c *Client) SCard(key string) *Response {
+	return c.request(newRow(cds.SCard, key))
+}
+
+// Redis `sismember` command.
+func (c *Client) SIsMember(key string, member interface{}) *Response {
+	return c.requestValues(cds.SIsMember, key, member)
+}
+
+// Redis `smismember` command.
+func (c *Client) SMIsMember(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.SMIsMember, key, members...)
+}
+
+// Redis `smembers` command.
+func (c *Client) SMembers(key string) *Response {
+	return c.request(newRow(cds.SMembers, key))
+}
+
+// Redis `spop` command with count.
+func (c *Client) SPop(key string, count int64) *Response {
+	row := newRow(cds.SPop, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `srandmember` command with count.
+func (c *Client) SRandMember(key string, count int64) *Response {
+	row := newRow(cds.SRandMember, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `smove` command.
+func (c *Client) SMove(src, dst string, member interface{}) *Response {
+	row := newRow(cds.SMove, src, dst)
+	if err := appendValues(row, member); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `sinter` command.
+func (c *Client) SInter(keys ...string) *Response {
+	return c.request(newRow(cds.SInter, keys...))
+}
+
+// Redis `sunion` command.
+func (c *Client) SUnion(keys ...string) *Response {
+	return c.request(newRow(cds.SUnion, keys...))
+}
+
+// Redis `sdiff` command.
+func (c *Client) SDiff(keys ...string) *Response {
+	return c.request(newRow(cds.SDiff, keys...))
+}
+
+// Redis `sinterstore` command.
+func (c *Client) SInterStore(dst string, keys ...string) *Response {
+	return c.request(newRow(cds.SInterStore, append([]string{dst}, keys...)...))
+}
+
+// Redis `sunionstore` command.
+func (c *Client) SUnionStore(dst string, keys ...string) *Response {
+	return c.request(newRow(cds.SUnionStore, append([]string{dst}, keys...)...))
+}
+
+// Redis `sdiffstore` command.
+func (c *Client) SDiffStore(dst string, keys ...string) *Response {
+	return c.request(newRow(cds.SDiffStore, append([]string{dst}, keys...)...))
+}
+
+// Redis `sintercard` command, limit 0 means unlimited.
+func (c *Client) SInterCard(limit int64, keys ...string) *Response {
+	row := token.NewArray(token.NewString(cds.SInterCard), token.NewInteger(int64(len(keys))))
+	for _, key := range keys {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(key))
+	}
+	if limit > 0 {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(cds.Limit), token.NewInteger(limit))
+	}
+	return c.request(row)
+}
+
+// Redis `sscan` command, match is ignored if empty.
+func (c *Client) SScan(key string, cursor int64, match string, count int64) *Response {
+	return c.request(newScanRow(cds.SScan, key, cursor, match, count))
+}
+
+// Z is the member with score of the sorted set
+type Z struct {
+	Score  float64
+	Member interface{}
+}
+
+// Redis `zadd` command.
+func (c *Client) ZAdd(key string, members ...Z) *Response {
+	return c.ZAddArgs(key, nil, members...)
+}
+
+// Redis `zadd` command, args are options like NX, XX, GT, LT, CH and INCR.
+func (c *Client) ZAddArgs(key string, args []string, members ...Z) *Response {
+	row := newRow(cds.ZAdd, key)
+	for _, arg := range args {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(arg))
+	}
+	for _, z := range members {
+		if err := appendArgs(row, z.Score); err != nil {
+			return &Response{Err: err}
+		}
+		if err := appendValues(row, z.Member); err != nil {
+			return &Response{Err: err}
+		}
+	}
+	return c.request(row)
+}
+
+// Redis `zincrby` command.
+func (c *Client) ZIncrBy(key string, incr float64, member interface{}) *Response {
+	row := newRow(cds.ZIncrBy, key)
+	if err := appendArgs(row, incr); err != nil {
+		return &Response{Err: err}
+	}
+	if err := appendValues(row, member); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `zrem` command.
+func (c *Client) ZRem(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.ZRem, key, members...)
+}
+
+// Redis `zscore` command.
+func (c *Client) ZScore(key string, member interface{}) *Response {
+	return c.requestValues(cds.ZScore, key, member)
+}
+
+// Redis `zmscore` command.
+func (c *Client) ZMScore(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.ZMScore, key, members...)
+}
+
+// Redis `zcard` command.
+func (c *Client) ZCard(key string) *Response {
+	return c.request(newRow(cds.ZCard, key))
+}
+
+// Redis `zcount` command, bounds are like "1", "(1" or "+inf".
+func (c *Client) ZCount(key, min, max string) *Response {
+	return c.request(newRow(cds.ZCount, key, min, max))
+}
+
+// Redis `zlexcount` command, bounds are like "[a", "(a", "-" or "+".
+func (c *Client) ZLexCount(key, min, max string) *Response {
+	return c.request(newRow(cds.ZLexCount, key, min, max))
+}
+
+// Redis `zrank` command.
+func (c *Client) ZRank(key string, member interface{}) *Response {
+	return c.requestValues(cds.ZRank, key, member)
+}
+
+// Redis `zrevrank` command.
+func (c *Client) ZRevRank(key string, member interface{}) *Response {
+	return c.requestValues(cds.ZRevRank, key, member)
+}
+
+// Redis `zrange` command, start and stop are ranks or bounds, args are
+// options like BYSCORE, BYLEX, REV, LIMIT offset count and WITHSCORES.
+func (c *Client) ZRange(key string, start, stop interface{}, args ...interface{}) *Response {
+	row := newRow(cds.ZRange, key)
+	if err := appendArgs(row, append([]interface{}{start, stop}, args...)...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `zrangestore` command.
+func (c *Client) ZRangeStore(dst, src string, start, stop interface{}, args ...interface{}) *Response {
+	row := newRow(cds.ZRangeStore, dst, src)
+	if err := appendArgs(row, append([]interface{}{start, stop}, args...)...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `zpopmin` command.
+func (c *Client) ZPopMin(key string, count int64) *Response {
+	row := newRow(cds.ZPopMin, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `zpopmax` command.
+func (c *Client) ZPopMax(key string, count int64) *Response {
+	row := newRow(cds.ZPopMax, key)
+	appendInts(row, count)
+	return c.request(row)
+}
+
+// Redis `bzpopmin` command, timeout 0 blocks forever.
+func (c *Client) BZPopMin(timeout time.Duration, keys ...string) *Response {
+	return c.requestArgs(cds.BZPopMin, keys, timeout.Seconds())
+}
+
+// Redis `bzpopmax` command, timeout 0 blocks forever.
+func (c *Client) BZPopMax(timeout time.Duration, keys ...string) *Response {
+	return c.requestArgs(cds.BZPopMax, keys, timeout.Seconds())
+}
+
+// Redis `zremrangebyrank` command.
+func (c *Client) ZRemRangeByRank(key string, start, stop int64) *Response {
+	row := newRow(cds.ZRemRngRank, key)
+	appendInts(row, start, stop)
+	return c.request(row)
+}
+
+// Redis `zremrangebyscore` command.
+func (c *Client) ZRemRangeByScore(key, min, max string) *Response {
+	return c.request(newRow(cds.ZRemRngScr, key, min, max))
+}
+
+// Redis `zremrangebylex` command.
+func (c *Client) ZRemRangeByLex(key, min, max string) *Response {
+	return c.request(newRow(cds.ZRemRngLex, key, min, max))
+}
+
+func (c *Client) zStore(cmd, dst string, keys []string, args ...interface{}) *Response {
+	row := newRow(cmd, dst)
+	appendInts(row, int64(len(keys)))
+	for _, key := range keys {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(key))
+	}
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `zunionstore` command, args are options like WEIGHTS and AGGREGATE.
+func (c *Client) ZUnionStore(dst string, keys []string, args ...interface{}) *Response {
+	return c.zStore(cds.ZUnionStore, dst, keys, args...)
+}
+
+// Redis `zinterstore` command, args are options like WEIGHTS and AGGREGATE.
+func (c *Client) ZInterStore(dst string, keys []string, args ...interface{}) *Response {
+	return c.zStore(cds.ZInterStore, dst, keys, args...)
+}
+
+// Redis `zscan` command, match is ignored if empty.
+func (c *Client) ZScan(key string, cursor int64, match string, count int64) *Response {
+	return c.request(newScanRow(cds.ZScan, key, cursor, match, count))
+}
+
+// requestArgs requests the command with the keys and arguments appended by appendArgs
+func (c *Client) requestArgs(cmd string, keys []string, args ...interface{}) *Response {
+	row := newRow(cmd, keys...)
+	if err := appendArgs(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `xadd` command, id is "*" to be generated.
+func (c *Client) XAdd(key, id string, pairs ...interface{}) *Response {
+	return c.XAddArgs(key, nil, id, pairs...)
+}
+
+// Redis `xadd` command with options like "NOMKSTREAM" or "MAXLEN", "~", 1000.
+func (c *Client) XAddArgs(key string, args []interface{}, id string, pairs ...interface{}) *Response {
+	row := newRow(cds.XAdd, key)
+	if err := appendArgs(row, append(args, id)...); err != nil {
+		return &Response{Err: err}
+	}
+	if err := appendValues(row, pairs...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `xlen` command.
+func (c *Client) XLen(key string) *Response {
+	return c.request(newRow(cds.XLen, key))
+}
+
+// Redis `xrange` command, args are like "COUNT", 10.
+func (c *Client) XRange(key, start, end string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XRange, []string{key, start, end}, args...)
+}
+
+// Redis `xrevrange` command, args are like "COUNT", 10.
+func (c *Client) XRevRange(key, end, start string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XRevRange, []string{key, end, start}, args...)
+}
+
+// Redis `xdel` command.
+func (c *Client) XDel(key string, ids ...string) *Response {
+	return c.request(newRow(cds.XDel, append([]string{key}, ids...)...))
+}
+
+// Redis `xtrim` command, args are like "MAXLEN", "~", 1000.
+func (c *Client) XTrim(key string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XTrim, []string{key}, args...)
+}
+
+// Redis `xsetid` command, args are like "ENTRIESADDED", 10.
+func (c *Client) XSetID(key, id string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XSetID, []string{key, id}, args...)
+}
+
+// Redis `xread` command, args are like "BLOCK", 0, "STREAMS", "key", "$".
+func (c *Client) XRead(args ...interface{}) *Response {
+	return c.requestArgs(cds.XRead, nil, args...)
+}
+
+// Redis `xgroup` command, sub is the subcommand like "CREATE".
+func (c *Client) XGroup(sub, key, group string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XGroup, []string{sub, key, group}, args...)
+}
+
+// Redis `xreadgroup` command, args are like "COUNT", 10, "STREAMS", "key", ">".
+func (c *Client) XReadGroup(group, consumer string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XReadGroup, []string{cds.Group, group, consumer}, args...)
+}
+
+// Redis `xack` command.
+func (c *Client) XAck(key, group string, ids ...string) *Response {
+	return c.request(newRow(cds.XAck, append([]string{key, group}, ids...)...))
+}
+
+// Redis `xpending` command, args are like "-", "+", 10 for the extended form.
+func (c *Client) XPending(key, group string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XPending, []string{key, group}, args...)
+}
+
+// Redis `xclaim` command, args are IDs followed by options like "JUSTID".
+func (c *Client) XClaim(key, group, consumer string, minIdle int64, args ...interface{}) *Response {
+	return c.requestArgs(cds.XClaim, []string{key, group, consumer}, append([]interface{}{minIdle}, args...)...)
+}
+
+// Redis `xautoclaim` command, args are like "COUNT", 10, "JUSTID".
+func (c *Client) XAutoClaim(key, group, consumer string, minIdle int64, start string, args ...interface{}) *Response {
+	return c.requestArgs(cds.XAutoClaim, []string{key, group, consumer},
+		append([]interface{}{minIdle, start}, args...)...)
+}
+
+// Redis `xinfo` command, sub is the subcommand like "STREAM".
+func (c *Client) XInfo(sub, key string, args ...string) *Response {
+	return c.request(newRow(cds.XInfo, append([]string{sub, key}, args...)...))
+}
+
+// GeoLocation is the member with longitude and latitude of the geospatial index
+type GeoLocation struct {
+	Longitude, Latitude float64
+	Member              interface{}
+}
+
+// Redis `geoadd` command, args are options like NX, XX and CH.
+func (c *Client) GeoAdd(key string, args []string, locations ...GeoLocation) *Response {
+	row := newRow(cds.GeoAdd, key)
+	for _, arg := range args {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(arg))
+	}
+	for _, l := range locations {
+		if err := appendArgs(row, l.Longitude, l.Latitude); err != nil {
+			return &Response{Err: err}
+		}
+		if err := appendValues(row, l.Member); err != nil {
+			return &Response{Err: err}
+		}
+	}
+	return c.request(row)
+}
+
+// Redis `geopos` command.
+func (c *Client) GeoPos(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.GeoPos, key, members...)
+}
+
+// Redis `geohash` command.
+func (c *Client) GeoHash(key string, members ...interface{}) *Response {
+	return c.requestValues(cds.GeoHash, key, members...)
+}
+
+// Redis `geodist` command, unit is one of "M", "KM", "FT" and "MI", or empty
+// for meters.
+func (c *Client) GeoDist(key string, member1, member2 interface{}, unit string) *Response {
+	row := newRow(cds.GeoDist, key)
+	if err := appendValues(row, member1, member2); err != nil {
+		return &Response{Err: err}
+	}
+	if unit != "" {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(unit))
+	}
+	return c.request(row)
+}
+
+// Redis `geosearch` command with args like "FROMLONLAT", 15.0, 37.0,
+// "BYRADIUS", 200.0, "KM", "WITHDIST".
+func (c *Client) GeoSearch(key string, args ...interface{}) *Response {
+	return c.requestArgs(cds.GeoSearch, []string{key}, args...)
+}
+
+// Redis `geosearchstore` command, args are the same as GeoSearch except WITH
+// options, and "STOREDIST" is allowed.
+func (c *Client) GeoSearchStore(dst, src string, args ...interface{}) *Response {
+	return c.requestArgs(cds.GeoSearchSt, []string{dst, src}, args...)
+}
+
+// Redis `publish` command.
+func (c *Client) Publish(channel string, msg interface{}) *Response {
+	return c.requestValues(cds.Publish, channel, msg)
+}
+
+// Redis `pubsub channels` command, all the channels are returned if pattern is empty.
+func (c *Client) PubSubChannels(pattern string) *Response {
+	row := newRow(cds.PubSub, cds.Channels)
+	if pattern != "" {
+		row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(pattern)))
+	}
+	return c.request(row)
+}
+
+// Redis `pubsub numsub` command.
+func (c *Client) PubSubNumSub(channels ...string) *Response {
+	row := newRow(cds.PubSub, cds.NumSub)
+	for _, channel := range channels {
+		row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(channel)))
+	}
+	return c.request(row)
+}
+
+// Redis `pubsub numpat` command.
+func (c *Client) PubSubNumPat() *Response {
+	return c.request(newRow(cds.PubSub, cds.NumPat))
+}
+
+// Redis `config get` command.
+func (c *Client) ConfigGet(pattern string) *Response {
+	return c.request(newRow(cds.Config, cds.ConfigGet, pattern))
+}
+
+// Redis `config set` command.
+func (c *Client) ConfigSet(name, value string) *Response {
+	row := newRow(cds.Config, cds.ConfigSet, name)
+	row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(value)))
+	return c.request(row)
+}
+
+// Redis `info` command, all the sections are returned if none is given.
+func (c *Client) Info(sections ...string) *Response {
+	return c.request(newRow(cds.Info, sections...))
+}
+
+// Redis `command` command, details of all the commands are returned.
+func (c *Client) Command() *Response {
+	return c.request(newRow(cds.Command))
+}
+
+// Redis `command count` command.
+func (c *Client) CommandCount() *Response {
+	return c.request(newRow(cds.Command, cds.Count))
+}
+
+// Redis `command info` command.
+func (c *Client) CommandInfo(names ...string) *Response {
+	return c.request(newRow(cds.Command, append([]string{cds.InfoArg}, names...)...))
+}
+
+// Redis `command docs` command.
+func (c *Client) CommandDocs(names ...string) *Response {
+	return c.request(newRow(cds.Command, append([]string{cds.Docs}, names...)...))
+}
+
+// Redis `command getkeys` command, args are the command and its arguments.
+func (c *Client) CommandGetKeys(name string, args ...interface{}) *Response {
+	row := newRow(cds.Command, cds.GetKeys, name)
+	if err := appendValues(row, args...); err != nil {
+		return &Response{Err: err}
+	}
+	return c.request(row)
+}
+
+// Redis `keys` command.
+func (c *Client) Keys(pattern string) *Response {
+	row := newRow(cds.Keys)
+	row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(pattern)))
+	return c.request(row)
+}
+
+// Redis `randomkey` command.
+func (c *Client) RandomKey() *Response {
+	return c.request(newRow(cds.RandomKey))
+}
+
+// Redis `dbsize` command.
+func (c *Client) DBSize() *Response {
+	return c.request(newRow(cds.DBSize))
+}
+
+// flushArgs returns the arguments of flushdb and flushall
+func flushArgs(async bool) []string {
+	if async {
+		return []string{cds.Async}
+	}
+	return nil
+}
+
+// Redis `flushdb` command, the keys are freed in the background if async.
+func (c *Client) FlushDB(async bool) *Response {
+	return c.request(newRow(cds.FlushDB, flushArgs(async)...))
+}
+
+// Redis `flushall` command, the keys are freed in the background if async.
+func (c *Client) FlushAll(async bool) *Response {
+	return c.request(newRow(cds.FlushAll, flushArgs(async)...))
+}
+
+// Redis `swapdb` command.
+func (c *Client) SwapDB(index1, index2 int64) *Response {
+	row := newRow(cds.SwapDB)
+	appendInts(row, index1, index2)
+	return c.request(row)
+}
+
+// Redis `move` command.
+func (c *Client) Move(key string, db int64) *Response {
+	row := newRow(cds.Move, key)
+	appendInts(row, db)
+	return c.request(row)
+}
+
+// Redis `copy` command, args are options like "DB", 1 and "REPLACE".
+func (c *Client) Copy(src, dst string, args ...interface{}) *Response {
+	return c.requestArgs(cds.Copy, []string{src, dst}, args...)
+}
+
+// Redis `type` command.
+func (c *Client) Type(key string) *Response {
+	return c.request(newRow(cds.KeyType, key))
+}
+
+// Redis `rename` command.
+func (c *Client) Rename(key, newKey string) *Response {
+	return c.request(newRow(cds.Rename, key, newKey))
+}
+
+// Redis `renamenx` command.
+func (c *Client) RenameNX(key, newKey string) *Response {
+	return c.request(newRow(cds.RenameNX, key, newKey))
+}
+
+// Redis `touch` command.
+func (c *Client) Touch(keys ...string) *Response {
+	return c.request(newRow(cds.Touch, keys...))
+}
+
+// Redis `object` command, sub is one of "ENCODING", "REFCOUNT", "IDLETIME" and "FREQ".
+func (c *Client) Object(sub, key string) *Response {
+	return c.request(newRow(cds.Object, sub, key))
+}
+
+// Redis `hello` command, args are options like "AUTH", "default", "pass" and "SETNAME", "name".
+// The replies are in RESP3 after the protocol is switched to 3.
+func (c *Client) Hello(proto int64, args ...string) *Response {
+	row := newRow(cds.Hello)
+	appendInts(row, proto)
+	for _, arg := range args {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(arg))
+	}
+	return c.request(row)
+}
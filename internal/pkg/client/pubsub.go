@@ -0,0 +1,141 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// Message is the message or the subscription reply received by the
+// subscriber. Kind is one of "subscribe", "unsubscribe", "psubscribe",
+// "punsubscribe", "message" and "pmessage". Count is the number of
+// subscriptions for subscription replies.
+type Message struct {
+	Kind    string
+	Pattern string
+	Channel string
+	Payload []byte
+	Count   int64
+}
+
+// PubSub subscribes channels and patterns on a dedicated connection,
+// messages received are sent to the channel returned by Channel.
+type PubSub struct {
+	conn net.Conn
+	mu   sync.Mutex
+	ch   chan *Message
+	done chan struct{}
+}
+
+// PubSub dials a new connection for subscription.
+func (c *Client) PubSub() (*PubSub, error) {
+	conn, err := net.DialTimeout(c.option.Proto, c.option.Addr, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	ps := &PubSub{conn: conn, ch: make(chan *Message, 100), done: make(chan struct{})}
+	go ps.receive()
+	return ps, nil
+}
+
+// receive parses messages from the connection until it is closed
+func (ps *PubSub) receive() {
+	defer close(ps.ch)
+	reader := token.NewReader(ps.conn)
+	for {
+		t, err := reader.ReadToken()
+		if err != nil {
+			return
+		}
+		msg, err := parseMessage(t)
+		if err != nil {
+			continue
+		}
+		select {
+		case ps.ch <- msg:
+		case <-ps.done:
+			return
+		}
+	}
+}
+
+// parseMessage converts the array pushed by the server to message
+func parseMessage(t *token.Token) (*Message, error) {
+	ts, ok := t.Data.([]*token.Token)
+	if (t.Label != label.Array && t.Label != label.Push) || !ok || len(ts) < 3 {
+		return nil, fmt.Errorf("unexpected message: %v", t.Format())
+	}
+	str := func(t *token.Token) string {
+		b, _ := t.Data.([]byte)
+		return string(b)
+	}
+	msg := &Message{Kind: str(ts[0])}
+	switch msg.Kind {
+	case "message":
+		msg.Channel, msg.Payload = str(ts[1]), ts[2].Data.([]byte)
+	case "pmessage":
+		if len(ts) < 4 {
+			return nil, fmt.Errorf("unexpected message: %v", t.Format())
+		}
+		msg.Pattern, msg.Channel, msg.Payload = str(ts[1]), str(ts[2]), ts[3].Data.([]byte)
+	case "subscribe", "unsubscribe":
+		msg.Channel, msg.Count = str(ts[1]), ts[2].Data.(int64)
+	case "psubscribe", "punsubscribe":
+		msg.Pattern, msg.Count = str(ts[1]), ts[2].Data.(int64)
+	default:
+		return nil, fmt.Errorf("unexpected message: %v", t.Format())
+	}
+	return msg, nil
+}
+
+func (ps *PubSub) write(cmd string, names ...string) error {
+	row := newRow(cmd)
+	for _, name := range names {
+		row.Data = append(row.Data.([]*token.Token), token.NewBulked([]byte(name)))
+	}
+	data, err := row.Serialize()
+	if err != nil {
+		return err
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	_, err = ps.conn.Write(data)
+	return err
+}
+
+// Subscribe subscribes the channels.
+func (ps *PubSub) Subscribe(channels ...string) error {
+	return ps.write(cds.Subscribe, channels...)
+}
+
+// PSubscribe subscribes the patterns.
+func (ps *PubSub) PSubscribe(patterns ...string) error {
+	return ps.write(cds.PSubscribe, patterns...)
+}
+
+// Unsubscribe unsubscribes the channels, all of them if none is given.
+func (ps *PubSub) Unsubscribe(channels ...string) error {
+	return ps.write(cds.Unsubscribe, channels...)
+}
+
+// PUnsubscribe unsubscribes the patterns, all of them if none is given.
+func (ps *PubSub) PUnsubscribe(patterns ...string) error {
+	return ps.write(cds.PUnsub, patterns...)
+}
+
+// Channel returns the channel of messages which is closed after the
+// connection is closed.
+func (ps *PubSub) Channel() <-chan *Message {
+	return ps.ch
+}
+
+// Close closes the connection of subscription.
+func (ps *PubSub) Close() error {
+	close(ps.done)
+	return ps.conn.Close()
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// ScanIterator iterates keys by following the cursors of `scan` command.
+//
+//	it := c.Scan("user:*", 100, "hash")
+//	for it.Next() {
+//		fmt.Println(it.Val())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ScanIterator struct {
+	c      *Client
+	match  string
+	count  int64
+	typ    string
+	cursor int64
+	keys   []string
+	key    string
+	done   bool
+	err    error
+}
+
+// Scan returns an iterator of keys matching the pattern whose values are of
+// the type. Match, count and typ are ignored if empty or zero.
+func (c *Client) Scan(match string, count int64, typ string) *ScanIterator {
+	return &ScanIterator{c: c, match: match, count: count, typ: typ}
+}
+
+// Redis `scan` command, match and typ are ignored if empty.
+func (c *Client) ScanCursor(cursor int64, match string, count int64, typ string) *Response {
+	row := newRow(cds.Scan)
+	appendScanArgs(row, cursor, match, count)
+	if typ != "" {
+		row.Data = append(row.Data.([]*token.Token), token.NewString(cds.Type), token.NewBulked([]byte(typ)))
+	}
+	return c.request(row)
+}
+
+// Next advances the iterator to the next key, reports false if iteration
+// is finished or an error occurred.
+func (it *ScanIterator) Next() bool {
+	for len(it.keys) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.err = it.fetch()
+	}
+	it.key, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+// fetch requests the next page of keys
+func (it *ScanIterator) fetch() error {
+	rsp := it.c.ScanCursor(it.cursor, it.match, it.count, it.typ)
+	if rsp.Err != nil {
+		return rsp.Err
+	}
+	if err := rsp.Data.Error(); err != nil {
+		return err
+	}
+	data, ok := rsp.Data.Data.([]*token.Token)
+	if rsp.Data.Label != label.Array || !ok || len(data) != 2 {
+		return fmt.Errorf("unexpected reply of scan: %v", rsp.Data.Format())
+	}
+	cursor, _ := data[0].Data.([]byte)
+	next, err := strconv.ParseInt(string(cursor), 10, 64)
+	if err != nil {
+		return fmt.Errorf("unexpected cursor of scan: %v", data[0].Format())
+	}
+	keys, _ := data[1].Data.([]*token.Token)
+	for _, key := range keys {
+		k, _ := key.Data.([]byte)
+		it.keys = append(it.keys, string(k))
+	}
+	it.cursor, it.done = next, next == 0
+	return nil
+}
+
+// Val returns the current key
+func (it *ScanIterator) Val() string {
+	return it.key
+}
+
+// Err returns the error occurred during iteration
+func (it *ScanIterator) Err() error {
+	return it.err
+}
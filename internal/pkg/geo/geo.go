@@ -0,0 +1,289 @@
+/*
+Package geo implements the geohash of redis, which encodes the longitude and
+latitude to the 52 bits integer stored as the score of sorted set members.
+
+The longitude and latitude are divided into 2^26 steps of the range of
+web mercator, and the steps are interleaved bit by bit with the latitude at
+even bits. Cells of lower precision are prefixes of the bits, so members
+in a cell are found by the score range of its prefix.
+*/
+package geo
+
+import (
+	"math"
+)
+
+const (
+	// StepMax is the number of steps of each of longitude and latitude
+	StepMax = 26
+	// LonMin, LonMax, LatMin and LatMax are the limits of web mercator
+	LonMin = -180.0
+	LonMax = 180.0
+	LatMin = -85.05112878
+	LatMax = 85.05112878
+
+	// EarthRadius is the radius of the earth in meters used by redis
+	EarthRadius = 6372797.560856
+	mercatorMax = 20037726.37
+
+	alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// Hash is the geohash of bits in step precision
+type Hash struct {
+	Bits uint64
+	Step uint
+}
+
+// Area is the cell of geohash, bounded by the min and max longitude and
+// latitude.
+type Area struct {
+	LonMin, LonMax, LatMin, LatMax float64
+}
+
+// Valid reports whether the longitude and latitude are encodable
+func Valid(lon, lat float64) bool {
+	return lon >= LonMin && lon <= LonMax && lat >= LatMin && lat <= LatMax
+}
+
+// interleave returns the bits of x at even positions and of y at odd ones
+func interleave(x, y uint32) uint64 {
+	spread := func(v uint32) uint64 {
+		b := uint64(v)
+		b = (b | b<<16) & 0x0000FFFF0000FFFF
+		b = (b | b<<8) & 0x00FF00FF00FF00FF
+		b = (b | b<<4) & 0x0F0F0F0F0F0F0F0F
+		b = (b | b<<2) & 0x3333333333333333
+		b = (b | b<<1) & 0x5555555555555555
+		return b
+	}
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave returns the bits at even positions and at odd ones
+func deinterleave(b uint64) (uint32, uint32) {
+	squash := func(b uint64) uint32 {
+		b &= 0x5555555555555555
+		b = (b | b>>1) & 0x3333333333333333
+		b = (b | b>>2) & 0x0F0F0F0F0F0F0F0F
+		b = (b | b>>4) & 0x00FF00FF00FF00FF
+		b = (b | b>>8) & 0x0000FFFF0000FFFF
+		b = (b | b>>16) & 0x00000000FFFFFFFF
+		return uint32(b)
+	}
+	return squash(b), squash(b >> 1)
+}
+
+// encode returns the geohash of step precision in the latitude range
+func encode(lon, lat float64, step uint, latMin, latMax float64) Hash {
+	lonOffset := (lon - LonMin) / (LonMax - LonMin)
+	latOffset := (lat - latMin) / (latMax - latMin)
+	n := float64(uint64(1) << step)
+	return Hash{Bits: interleave(uint32(latOffset*n), uint32(lonOffset*n)), Step: step}
+}
+
+// Encode returns the geohash of step precision. The longitude and latitude
+// should be checked by Valid.
+func Encode(lon, lat float64, step uint) Hash {
+	return encode(lon, lat, step, LatMin, LatMax)
+}
+
+// Score returns the score of the longitude and latitude in full precision
+func Score(lon, lat float64) float64 {
+	return float64(Encode(lon, lat, StepMax).Bits)
+}
+
+// Decode returns the cell of the geohash
+func (h Hash) Decode() Area {
+	latStep, lonStep := deinterleave(h.Bits)
+	n := float64(uint64(1) << h.Step)
+	return Area{
+		LonMin: LonMin + float64(lonStep)/n*(LonMax-LonMin),
+		LonMax: LonMin + float64(lonStep+1)/n*(LonMax-LonMin),
+		LatMin: LatMin + float64(latStep)/n*(LatMax-LatMin),
+		LatMax: LatMin + float64(latStep+1)/n*(LatMax-LatMin),
+	}
+}
+
+// Center returns the longitude and latitude of the center of the area
+func (a Area) Center() (float64, float64) {
+	lon := math.Max(LonMin, math.Min(LonMax, (a.LonMin+a.LonMax)/2))
+	lat := math.Max(LatMin, math.Min(LatMax, (a.LatMin+a.LatMax)/2))
+	return lon, lat
+}
+
+// Position returns the longitude and latitude of the score
+func Position(score float64) (float64, float64) {
+	return Hash{Bits: uint64(score), Step: StepMax}.Decode().Center()
+}
+
+// String returns the standard geohash string of 11 characters of the score,
+// which is encoded with the latitude range [-90, 90].
+func String(score float64) string {
+	lon, lat := Position(score)
+	h := encode(lon, lat, StepMax, -90, 90)
+	buf := make([]byte, 11)
+	for i := range buf {
+		idx := 0
+		// the last character has only 2 bits, which is padded by zeros
+		if i < 10 {
+			idx = int(h.Bits>>(52-uint(i+1)*5)) & 0x1f
+		}
+		buf[i] = alphabet[idx]
+	}
+	return string(buf)
+}
+
+// move returns the geohash moved by the steps of longitude and latitude,
+// which wraps around at limits.
+func (h Hash) move(dLon, dLat int) Hash {
+	lat, lon := deinterleave(h.Bits)
+	mask := uint32(uint64(1)<<h.Step - 1)
+	lon = uint32(int64(lon)+int64(dLon)) & mask
+	lat = uint32(int64(lat)+int64(dLat)) & mask
+	return Hash{Bits: interleave(lat, lon), Step: h.Step}
+}
+
+// ScoreRange returns the range [min, max) of scores of members in the cell
+func (h Hash) ScoreRange() (float64, float64) {
+	shift := 2 * (StepMax - h.Step)
+	return float64(h.Bits << shift), float64((h.Bits + 1) << shift)
+}
+
+// degree to radian
+func rad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// radian to degree
+func deg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// latDistance returns the distance in meters between latitudes
+func latDistance(lat1, lat2 float64) float64 {
+	return EarthRadius * math.Abs(rad(lat2)-rad(lat1))
+}
+
+// Distance returns the distance in meters between the positions by the
+// haversine formula.
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((rad(lon2) - rad(lon1)) / 2)
+	if v == 0 {
+		return latDistance(lat1, lat2)
+	}
+	u := math.Sin((rad(lat2) - rad(lat1)) / 2)
+	a := u*u + math.Cos(rad(lat1))*math.Cos(rad(lat2))*v*v
+	return 2 * EarthRadius * math.Asin(math.Sqrt(a))
+}
+
+// Shape is the area searched around the center, which is the circle of
+// Radius if it is positive, otherwise the box of Width and Height. Sizes
+// are in meters.
+type Shape struct {
+	Lon, Lat      float64
+	Radius        float64
+	Width, Height float64
+}
+
+// Contains returns the distance in meters from the center to the position,
+// and whether the position is inside the shape.
+func (s *Shape) Contains(lon, lat float64) (float64, bool) {
+	if s.Radius > 0 {
+		d := Distance(s.Lon, s.Lat, lon, lat)
+		return d, d <= s.Radius
+	}
+	// the latitude distance is cheaper, which is checked first
+	if latDistance(s.Lat, lat) > s.Height/2 {
+		return 0, false
+	}
+	if Distance(s.Lon, lat, lon, lat) > s.Width/2 {
+		return 0, false
+	}
+	return Distance(s.Lon, s.Lat, lon, lat), true
+}
+
+// bounds returns the bounding box of the shape
+func (s *Shape) bounds() Area {
+	width, height := s.Width/2, s.Height/2
+	if s.Radius > 0 {
+		width, height = s.Radius, s.Radius
+	}
+	latDelta := deg(height / EarthRadius)
+	// the longitude delta is wider on the side towards the pole
+	lat := s.Lat + latDelta
+	if s.Lat < 0 {
+		lat = s.Lat - latDelta
+	}
+	lonDelta := deg(width / EarthRadius / math.Cos(rad(lat)))
+	return Area{LonMin: s.Lon - lonDelta, LonMax: s.Lon + lonDelta,
+		LatMin: s.Lat - latDelta, LatMax: s.Lat + latDelta}
+}
+
+// estimateStep returns the step whose cell is large enough to cover the
+// range in meters at the latitude.
+func estimateStep(meters, lat float64) uint {
+	if meters == 0 {
+		return StepMax
+	}
+	step := 1
+	for ; meters < mercatorMax; meters *= 2 {
+		step++
+	}
+	// make sure the range is included in most of the base cases
+	step -= 2
+	// cells are narrower towards the poles
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > StepMax {
+		step = StepMax
+	}
+	return uint(step)
+}
+
+// Cells returns the geohash cells covering the shape, which are the cell of
+// the center and its neighbors not useless.
+func (s *Shape) Cells() []Hash {
+	bounds := s.bounds()
+	meters := s.Radius
+	if meters <= 0 {
+		meters = math.Sqrt(s.Width*s.Width+s.Height*s.Height) / 2
+	}
+	step := estimateStep(meters, s.Lat)
+	h := Encode(s.Lon, s.Lat, step)
+	// the step is decreased if the neighbors do not cover the bounds
+	if step > 1 {
+		north, south := h.move(0, 1).Decode(), h.move(0, -1).Decode()
+		east, west := h.move(1, 0).Decode(), h.move(-1, 0).Decode()
+		if north.LatMax < bounds.LatMax || south.LatMin > bounds.LatMin ||
+			east.LonMax < bounds.LonMax || west.LonMin > bounds.LonMin {
+			step--
+			h = Encode(s.Lon, s.Lat, step)
+		}
+	}
+	area := h.Decode()
+	cells := make([]Hash, 0, 9)
+	seen := make(map[uint64]bool, 9)
+	for dLat := -1; dLat <= 1; dLat++ {
+		for dLon := -1; dLon <= 1; dLon++ {
+			// neighbors out of the bounds are useless
+			if step >= 2 && (dLat < 0 && area.LatMin < bounds.LatMin || dLat > 0 && area.LatMax > bounds.LatMax ||
+				dLon < 0 && area.LonMin < bounds.LonMin || dLon > 0 && area.LonMax > bounds.LonMax) {
+				continue
+			}
+			n := h.move(dLon, dLat)
+			if !seen[n.Bits] {
+				seen[n.Bits] = true
+				cells = append(cells, n)
+			}
+		}
+	}
+	return cells
+}
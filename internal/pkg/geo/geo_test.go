@@ -0,0 +1,71 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// positions of the examples of redis
+const (
+	palermoLon, palermoLat = 13.361389, 38.115556
+	cataniaLon, cataniaLat = 15.087269, 37.502669
+)
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, 3479099956230698.0, Score(palermoLon, palermoLat))
+	assert.Equal(t, 3479447370796909.0, Score(cataniaLon, cataniaLat))
+	lon, lat := Position(Score(palermoLon, palermoLat))
+	assert.InDelta(t, 13.36138933897018433, lon, 1e-12)
+	assert.InDelta(t, 38.11555639549629859, lat, 1e-12)
+	assert.Equal(t, "sqc8b49rny0", String(Score(palermoLon, palermoLat)))
+	assert.Equal(t, "sqdtr74hyu0", String(Score(cataniaLon, cataniaLat)))
+
+	assert.True(t, Valid(LonMax, LatMin))
+	assert.False(t, Valid(180.1, 0))
+	assert.False(t, Valid(0, 85.06))
+	for _, v := range []uint32{0, 1, 0x2aaaaaa, 0x3ffffff} {
+		x, y := deinterleave(interleave(v, ^v&0x3ffffff))
+		assert.Equal(t, v, x)
+		assert.Equal(t, ^v&0x3ffffff, y)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// distances are of the positions decoded like redis
+	lon1, lat1 := Position(Score(palermoLon, palermoLat))
+	lon2, lat2 := Position(Score(cataniaLon, cataniaLat))
+	assert.InDelta(t, 166274.1516, Distance(lon1, lat1, lon2, lat2), 1e-4)
+	assert.Equal(t, 0.0, Distance(lon1, lat1, lon1, lat1))
+	assert.InDelta(t, 56441.3, Distance(15, 37, lon2, lat2), 0.05)
+}
+
+func TestShape_Cells(t *testing.T) {
+	for _, s := range []*Shape{
+		{Lon: 15, Lat: 37, Radius: 200000},
+		{Lon: 15, Lat: 37, Width: 400000, Height: 400000},
+		{Lon: 15, Lat: 37, Radius: 1},
+		{Lon: 179.9, Lat: -80, Radius: 50000},
+	} {
+		cells := s.Cells()
+		assert.LessOrEqual(t, len(cells), 9)
+		// every position inside the shape is covered by the cells
+		for _, p := range [][2]float64{{palermoLon, palermoLat}, {cataniaLon, cataniaLat}, {s.Lon, s.Lat}, {-179.9, -80.2}} {
+			if _, ok := s.Contains(p[0], p[1]); !ok {
+				continue
+			}
+			score := Score(p[0], p[1])
+			covered := false
+			for _, c := range cells {
+				min, max := c.ScoreRange()
+				covered = covered || score >= min && score < max
+			}
+			assert.True(t, covered, p)
+		}
+	}
+	_, ok := (&Shape{Lon: 15, Lat: 37, Width: 200000, Height: 400000}).Contains(palermoLon, palermoLat)
+	assert.False(t, ok)
+	d, ok := (&Shape{Lon: 15, Lat: 37, Width: 400000, Height: 400000}).Contains(palermoLon, palermoLat)
+	assert.True(t, ok)
+	assert.InDelta(t, 190442.4, d, 0.1)
+}
@@ -0,0 +1,94 @@
+/*
+Package glob implements the glob-style pattern matching of redis, which is
+used by commands like keys, scan and psubscribe.
+*/
+package glob
+
+// Match reports whether str matches the pattern. Supported patterns:
+//   - "*" matches any sequence of characters
+//   - "?" matches any single character
+//   - "[abc]" matches one character given in the bracket
+//   - "[^abc]" matches one character not given in the bracket
+//   - "[a-z]" matches one character in the range
+//   - "\x" escapes the special character x
+func Match(pattern, str string) bool {
+	p, s := 0, 0
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for i := s; i <= len(str); i++ {
+				if Match(pattern[p+1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s >= len(str) {
+				return false
+			}
+			s++
+		case '[':
+			if s >= len(str) {
+				return false
+			}
+			var ok bool
+			if p, ok = matchClass(pattern, p+1, str[s]); !ok {
+				return false
+			}
+			s++
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+		default:
+			if s >= len(str) || pattern[p] != str[s] {
+				return false
+			}
+			s++
+		}
+		p++
+	}
+	return s == len(str)
+}
+
+// matchClass matches c against the bracket class starting at p, and returns
+// the position of the closing bracket.
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	not := p < len(pattern) && pattern[p] == '^'
+	if not {
+		p++
+	}
+	matched := false
+	for ; p < len(pattern) && pattern[p] != ']'; p++ {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			if pattern[p] == c {
+				matched = true
+			}
+		case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+			start, end := pattern[p], pattern[p+2]
+			if start > end {
+				start, end = end, start
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			p += 2
+		case pattern[p] == c:
+			matched = true
+		}
+	}
+	if p >= len(pattern) {
+		// unclosed bracket, treat as the end of pattern
+		p = len(pattern) - 1
+	}
+	return p, matched != not
+}
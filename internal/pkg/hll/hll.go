@@ -0,0 +1,374 @@
+/*
+Package hll implements the HyperLogLog of redis, which estimates the
+cardinality of a set in fixed memory. HyperLogLogs are stored as strings in
+the same layout as redis:
+
+	+------+---+-----+----------+
+	| HYLL | E | N/U | Cardin.  |
+	+------+---+-----+----------+
+
+The 16 bytes header is followed by 16384 registers in either the sparse or
+the dense encoding. The cardinality cached is invalid if the most significant
+bit of its last byte is set.
+*/
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+const (
+	// P is the number of bits of the hash used to index registers
+	P = 14
+	// Registers is the number of registers
+	Registers = 1 << P
+	// SparseMaxBytes is the max size of the sparse encoding, header included,
+	// beyond which it is promoted to the dense encoding.
+	SparseMaxBytes = 3000
+
+	magic       = "HYLL"
+	headerSize  = 16
+	regBits     = 6
+	regMax      = 1<<regBits - 1
+	denseSize   = headerSize + (Registers*regBits+7)/8
+	encDense    = 0
+	encSparse   = 1
+	cacheOffset = 8
+	// q is the number of bits of the hash used to count the run of zeros
+	q = 64 - P
+	// alphaInf is the constant of the estimator when m approaches infinity
+	alphaInf = 0.721347520444481703680
+	seed     = 0xadc83b19
+
+	// opcodes of the sparse encoding
+	sparseZeroMaxLen  = 64
+	sparseXZeroMaxLen = 16384
+	sparseValMaxValue = 32
+	sparseValMaxLen   = 4
+	sparseXZeroBit    = 0x40
+	sparseValBit      = 0x80
+)
+
+// ErrInvalid is returned if the string is not a valid HyperLogLog
+var ErrInvalid = errors.New("key is not a valid HyperLogLog string value")
+
+// New returns an empty HyperLogLog of sparse encoding
+func New() []byte {
+	regs := make([]uint8, Registers)
+	return encode(regs, true)
+}
+
+// Check checks the header of the HyperLogLog, the registers are checked
+// when decoded.
+func Check(data []byte) error {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return ErrInvalid
+	}
+	switch data[4] {
+	case encDense:
+		if len(data) != denseSize {
+			return ErrInvalid
+		}
+	case encSparse:
+	default:
+		return ErrInvalid
+	}
+	return nil
+}
+
+// IsSparse reports whether the HyperLogLog is of sparse encoding
+func IsSparse(data []byte) bool {
+	return len(data) >= headerSize && data[4] == encSparse
+}
+
+// murmurHash64A is the hash function used by redis
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ (uint64(len(key)) * m)
+	n := len(key) / 8
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint64(key[i*8:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	tail := key[n*8:]
+	if len(tail) > 0 {
+		for i := len(tail) - 1; i >= 0; i-- {
+			h ^= uint64(tail[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// patLen returns the index of register of the element and the length of the
+// pattern 000..1 of its hash.
+func patLen(element []byte) (int, uint8) {
+	hash := murmurHash64A(element, seed)
+	index := int(hash & (Registers - 1))
+	hash >>= P
+	// make sure the loop terminates
+	hash |= 1 << q
+	return index, uint8(bits.TrailingZeros64(hash) + 1)
+}
+
+// denseGet returns the register of the dense encoding
+func denseGet(regs []byte, i int) uint8 {
+	b := i * regBits / 8
+	fb := uint(i * regBits & 7)
+	v := regs[b] >> fb
+	if b+1 < len(regs) {
+		v |= regs[b+1] << (8 - fb)
+	}
+	return v & regMax
+}
+
+// denseSet sets the register of the dense encoding
+func denseSet(regs []byte, i int, v uint8) {
+	b := i * regBits / 8
+	fb := uint(i * regBits & 7)
+	regs[b] &^= regMax << fb
+	regs[b] |= v << fb
+	if b+1 < len(regs) {
+		regs[b+1] &^= regMax >> (8 - fb)
+		regs[b+1] |= v >> (8 - fb)
+	}
+}
+
+// decode returns the registers of the HyperLogLog
+func decode(data []byte) ([]uint8, error) {
+	if err := Check(data); err != nil {
+		return nil, err
+	}
+	regs := make([]uint8, Registers)
+	switch data[4] {
+	case encDense:
+		for i := range regs {
+			regs[i] = denseGet(data[headerSize:], i)
+		}
+	default:
+		idx := 0
+		for i := headerSize; i < len(data); {
+			b := data[i]
+			var run int
+			switch {
+			case b&0xc0 == 0:
+				run = int(b&0x3f) + 1
+				i++
+			case b&0xc0 == sparseXZeroBit:
+				if i+1 >= len(data) {
+					return nil, ErrInvalid
+				}
+				run = (int(b&0x3f)<<8 | int(data[i+1])) + 1
+				i += 2
+			default:
+				run = int(b&0x3) + 1
+				if idx+run > Registers {
+					return nil, ErrInvalid
+				}
+				for j := idx; j < idx+run; j++ {
+					regs[j] = (b>>2)&0x1f + 1
+				}
+				i++
+			}
+			if idx += run; idx > Registers {
+				return nil, ErrInvalid
+			}
+		}
+		if idx != Registers {
+			return nil, ErrInvalid
+		}
+	}
+	return regs, nil
+}
+
+// encodeSparse returns the sparse encoding of registers, nil if some value
+// is too large or the size exceeds SparseMaxBytes.
+func encodeSparse(regs []uint8) []byte {
+	data := make([]byte, headerSize, 64)
+	for i := 0; i < len(regs); {
+		v, run := regs[i], 1
+		for i+run < len(regs) && regs[i+run] == v {
+			run++
+		}
+		i += run
+		switch {
+		case v == 0:
+			for ; run > 0; run -= sparseXZeroMaxLen {
+				n := run
+				if n > sparseXZeroMaxLen {
+					n = sparseXZeroMaxLen
+				}
+				if n <= sparseZeroMaxLen {
+					data = append(data, byte(n-1))
+				} else {
+					data = append(data, sparseXZeroBit|byte((n-1)>>8), byte(n-1))
+				}
+			}
+		case v > sparseValMaxValue:
+			return nil
+		default:
+			for ; run > 0; run -= sparseValMaxLen {
+				n := run
+				if n > sparseValMaxLen {
+					n = sparseValMaxLen
+				}
+				data = append(data, sparseValBit|(v-1)<<2|byte(n-1))
+			}
+		}
+		if len(data) > SparseMaxBytes {
+			return nil
+		}
+	}
+	return data
+}
+
+// encode returns the HyperLogLog of registers, in sparse encoding if
+// possible and preferred. The cardinality cached is valid.
+func encode(regs []uint8, sparse bool) []byte {
+	var data []byte
+	if sparse {
+		data = encodeSparse(regs)
+	}
+	if data != nil {
+		data[4] = encSparse
+	} else {
+		data = make([]byte, denseSize)
+		data[4] = encDense
+		for i, v := range regs {
+			denseSet(data[headerSize:], i, v)
+		}
+	}
+	copy(data, magic)
+	binary.LittleEndian.PutUint64(data[cacheOffset:], estimate(regs))
+	return data
+}
+
+// tau and sigma are used by the estimator of Otmar Ertl
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+// estimate returns the approximated cardinality of registers
+func estimate(regs []uint8) uint64 {
+	var histo [q + 2]int
+	for _, v := range regs {
+		histo[v]++
+	}
+	m := float64(Registers)
+	z := m * tau((m-float64(histo[q+1]))/m)
+	for j := q; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * sigma(float64(histo[0])/m)
+	return uint64(math.Round(alphaInf * m * m / z))
+}
+
+// Add returns the HyperLogLog with elements added and reports whether any
+// register is changed. The HyperLogLog given is not modified, and a new
+// one is returned if nil is given.
+func Add(data []byte, elements ...[]byte) ([]byte, bool, error) {
+	if data == nil {
+		data = New()
+	}
+	regs, err := decode(data)
+	if err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for _, e := range elements {
+		i, n := patLen(e)
+		if n > regs[i] {
+			regs[i] = n
+			changed = true
+		}
+	}
+	if !changed {
+		return data, false, nil
+	}
+	return encode(regs, IsSparse(data)), true, nil
+}
+
+// union returns the max of registers of the HyperLogLogs, nil is skipped
+func union(hs ...[]byte) ([]uint8, error) {
+	max := make([]uint8, Registers)
+	for _, h := range hs {
+		if h == nil {
+			continue
+		}
+		regs, err := decode(h)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range regs {
+			if v > max[i] {
+				max[i] = v
+			}
+		}
+	}
+	return max, nil
+}
+
+// Count returns the approximated cardinality of the union of HyperLogLogs,
+// nil is regarded as empty. The cardinality cached is used if valid.
+func Count(hs ...[]byte) (uint64, error) {
+	if len(hs) == 1 && hs[0] != nil {
+		h := hs[0]
+		if Check(h) == nil && h[headerSize-1]&0x80 == 0 {
+			return binary.LittleEndian.Uint64(h[cacheOffset:]), nil
+		}
+	}
+	regs, err := union(hs...)
+	if err != nil {
+		return 0, err
+	}
+	return estimate(regs), nil
+}
+
+// Merge returns the union of HyperLogLogs in dense encoding, nil is skipped
+func Merge(hs ...[]byte) ([]byte, error) {
+	regs, err := union(hs...)
+	if err != nil {
+		return nil, err
+	}
+	return encode(regs, false), nil
+}
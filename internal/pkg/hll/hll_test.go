@@ -0,0 +1,118 @@
+package hll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func elementsOf(prefix string, n int) [][]byte {
+	es := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		es = append(es, []byte(fmt.Sprintf("%s%d", prefix, i)))
+	}
+	return es
+}
+
+func TestMurmurHash64A(t *testing.T) {
+	// the hash should be the same as the one of redis
+	assert.Equal(t, uint64(0x53d2470a9b43b1a7), murmurHash64A([]byte("a"), seed))
+	assert.Equal(t, uint64(0x0f656f01eecfe400), murmurHash64A([]byte("hello"), seed))
+	assert.Equal(t, uint64(0x0f85070a21c57729), murmurHash64A([]byte("abcdefghijk"), seed))
+}
+
+func TestRegisters(t *testing.T) {
+	regs := make([]byte, (Registers*regBits+7)/8)
+	for i := 0; i < Registers; i++ {
+		denseSet(regs, i, uint8(i%64))
+	}
+	for i := 0; i < Registers; i++ {
+		assert.Equal(t, uint8(i%64), denseGet(regs, i))
+	}
+	values := make([]uint8, Registers)
+	values[0], values[100], values[101], values[Registers-1] = 1, 32, 32, 5
+	data := encodeSparse(values)
+	assert.NotNil(t, data)
+	decoded, err := decode(append(append([]byte(magic), encSparse), data[5:]...))
+	assert.Nil(t, err)
+	assert.Equal(t, values, decoded)
+	values[1] = 33
+	assert.Nil(t, encodeSparse(values))
+}
+
+func TestAdd(t *testing.T) {
+	h := New()
+	assert.True(t, IsSparse(h))
+	n, err := Count(h)
+	assert.Nil(t, err)
+	assert.Zero(t, n)
+
+	h2, changed, err := Add(h, elementsOf("a", 7)...)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, New(), h)
+	n, _ = Count(h2)
+	assert.Equal(t, uint64(7), n)
+	h3, changed, _ := Add(h2, []byte("a1"), []byte("a2"))
+	assert.False(t, changed)
+	assert.Equal(t, h2, h3)
+
+	// promoted to dense encoding if large
+	h, _, _ = Add(nil, elementsOf("b", 10000)...)
+	assert.False(t, IsSparse(h))
+	assert.Equal(t, denseSize, len(h))
+	n, _ = Count(h)
+	assert.InDelta(t, 10000, n, 10000*0.02)
+}
+
+func TestCount(t *testing.T) {
+	for _, size := range []int{100, 1000, 50000} {
+		h, _, err := Add(nil, elementsOf("c", size)...)
+		assert.Nil(t, err)
+		n, _ := Count(h)
+		assert.InDelta(t, size, n, math.Max(float64(size)*0.02, 2), fmt.Sprintf("size: %d", size))
+	}
+	a, _, _ := Add(nil, elementsOf("x", 3000)...)
+	b, _, _ := Add(nil, elementsOf("x", 5000)...)
+	n, err := Count(a, nil, b)
+	assert.Nil(t, err)
+	assert.InDelta(t, 5000, n, 5000*0.02)
+
+	// the cardinality cached is used if valid
+	c := append([]byte{}, a...)
+	binary.LittleEndian.PutUint64(c[cacheOffset:], 42)
+	n, _ = Count(c)
+	assert.Equal(t, uint64(42), n)
+	c[headerSize-1] |= 0x80
+	n, _ = Count(c)
+	assert.InDelta(t, 3000, n, 3000*0.02)
+}
+
+func TestMerge(t *testing.T) {
+	a, _, _ := Add(nil, elementsOf("m", 100)...)
+	b, _, _ := Add(nil, elementsOf("n", 100)...)
+	m, err := Merge(a, nil, b)
+	assert.Nil(t, err)
+	assert.False(t, IsSparse(m))
+	n, _ := Count(m)
+	assert.InDelta(t, 200, n, 4)
+	merged, _ := Count(a, b)
+	assert.Equal(t, merged, n)
+}
+
+func TestInvalid(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("string"),
+		[]byte("HYLL\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80"),
+		[]byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x01"),
+		[]byte("HYLL\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80"),
+	} {
+		_, _, err := Add(data, []byte("a"))
+		assert.Equal(t, ErrInvalid, err)
+		_, err = Merge(data)
+		assert.Equal(t, ErrInvalid, err)
+	}
+}
@@ -11,6 +11,19 @@ const (
 	Array   Label = '*'
 )
 
+// protocol type label introduced by RESP3
+const (
+	Null      Label = '_'
+	Boolean   Label = '#'
+	Double    Label = ','
+	BigNumber Label = '('
+	Verbatim  Label = '='
+	Map       Label = '%'
+	Set       Label = '~'
+	Attribute Label = '|'
+	Push      Label = '>'
+)
+
 // Label alias byte
 type Label = byte
 
@@ -40,6 +53,24 @@ func ToStr(ls ...Label) string {
 			builder.WriteString("bulked")
 		case Array:
 			builder.WriteString("array")
+		case Null:
+			builder.WriteString("null")
+		case Boolean:
+			builder.WriteString("boolean")
+		case Double:
+			builder.WriteString("double")
+		case BigNumber:
+			builder.WriteString("big number")
+		case Verbatim:
+			builder.WriteString("verbatim")
+		case Map:
+			builder.WriteString("map")
+		case Set:
+			builder.WriteString("set")
+		case Attribute:
+			builder.WriteString("attribute")
+		case Push:
+			builder.WriteString("push")
 		default:
 			builder.WriteString("<unknown type>")
 		}
@@ -10,4 +10,6 @@ func TestToStr(t *testing.T) {
 	assert.Equal(t, "", ToStr())
 	assert.Equal(t, "string/bulked/array/integer/error/<unknown type>",
 		ToStr(String, Bulked, Array, Integer, Error, '0'))
+	assert.Equal(t, "null/boolean/double/big number/verbatim/map/set/attribute/push",
+		ToStr(Null, Boolean, Double, BigNumber, Verbatim, Map, Set, Attribute, Push))
 }
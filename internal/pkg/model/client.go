@@ -2,6 +2,7 @@ package model
 
 import (
 	"net"
+	"time"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
@@ -16,6 +17,26 @@ type MultiInfo struct {
 	Queue []*token.Token
 	// watch keys
 	Watched []*watchKey
+	// true while the transaction queue is executed
+	Executing bool
+	// true if any command failed to be queued
+	Aborted bool
+}
+
+// BlockInfo stores the state of the client blocked by commands like XREAD
+// with BLOCK, until any key waited for is ready or the deadline is reached.
+type BlockInfo struct {
+	// keys waited for in the database selected
+	Keys []string
+	// zero if blocked until served
+	Deadline time.Time
+	// Retry executes the command again after a key is ready, it returns nil
+	// if the client is still blocked.
+	Retry func() *token.Token
+	// reply when the deadline is reached
+	Timeout *token.Token
+	// requests received while blocked, executed in order after unblocked
+	Pending []*token.Token
 }
 
 // Client entity: client information stored
@@ -27,11 +48,31 @@ type Client struct {
 	Multi *MultiInfo
 	// collect stat
 	Stat bool
+	// subscription info
+	Sub *SubInfo
+	// replies and messages waiting to be written, nil if no connection
+	Out *Outbox
+	// blocking info, nil if not blocked
+	Block *BlockInfo
+	// protocol version negotiated by HELLO
+	Proto int
+	// name set by HELLO SETNAME
+	Name string
 }
 
 // NewClient returns a client selecting database 0, transaction state false
 func NewClient(conn net.Conn, dataStorage *DataStorage) *Client {
-	return &Client{Conn: conn, Data: dataStorage, Multi: &MultiInfo{}}
+	return &Client{Conn: conn, Data: dataStorage, Multi: &MultiInfo{}, Sub: NewSubInfo(), Proto: token.Resp2}
+}
+
+// Push converts the tokens to the protocol of the client and pushes them to
+// the outbox
+func (c *Client) Push(ts ...*token.Token) {
+	converted := make([]*token.Token, 0, len(ts))
+	for _, t := range ts {
+		converted = append(converted, t.ConvertTo(c.Proto))
+	}
+	c.Out.Push(converted...)
 }
 
 // Watch append key to self watch list and append self to global watch map
@@ -58,13 +99,65 @@ func (c *Client) Get(key string) interface{} {
 	return c.Data.Get(key)
 }
 
+// GetMutable returns correspond value which is allowed to be modified in place,
+// Touch should be called after the value is modified.
+func (c *Client) GetMutable(key string) interface{} {
+	return c.Data.GetMutable(key)
+}
+
+// Keys returns all the keys not expired in data indexed
+func (c *Client) Keys() []string {
+	return c.Data.Keys()
+}
+
+// Touch marks the key modified for clients watching it
+func (c *Client) Touch(key string) {
+	c.Data.watch.Touch(key)
+}
+
+// Notify marks the key modified for clients watching it and publishes the
+// keyspace event of the command modifying it
+func (c *Client) Notify(class int, event, key string) {
+	c.Data.watch.Touch(key)
+	c.Data.notify(class, event, key)
+}
+
 // Set puts key-value pair and its ttl in data
 func (c *Client) Set(key string, value interface{}, expire int64) interface{} {
 	c.Data.watch.Touch(key)
 	return c.Data.Set(key, value, expire)
 }
 
-// Del deletes the value of correspond key
-func (c *Client) Del(key string) {
-	c.Data.Del(key)
+// GetExpire returns the expiration of correspond key and whether the key exists
+func (c *Client) GetExpire(key string) (int64, bool) {
+	return c.Data.GetExpire(key)
+}
+
+// SetExpire replaces the expiration of correspond key, 0 removes the expiration
+func (c *Client) SetExpire(key string, expire int64) bool {
+	if c.Data.SetExpire(key, expire) {
+		c.Data.watch.Touch(key)
+		return true
+	}
+	return false
+}
+
+// Del deletes the value of correspond key and reports whether the key existed
+func (c *Client) Del(key string) bool {
+	if c.Data.Del(key) {
+		c.Data.watch.Touch(key)
+		return true
+	}
+	return false
+}
+
+// Remove deletes the value of correspond key like Del but publishes no
+// keyspace event, which is left to commands moving the value like rename
+func (c *Client) Remove(key string) bool {
+	if c.Data.Peek(key) == nil {
+		return false
+	}
+	c.Data.remove(key)
+	c.Data.watch.Touch(key)
+	return true
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,7 +26,7 @@ func TestNewClient(t *testing.T) {
 		args args
 		want *Client
 	}{
-		{"new client", args{conn: nil, data: d}, &Client{Conn: nil, Data: d, Multi: &MultiInfo{false, false, nil, nil}}},
+		{"new client", args{conn: nil, data: d}, &Client{Conn: nil, Data: d, Multi: &MultiInfo{false, false, nil, nil, false, false}, Sub: NewSubInfo(), Proto: token.Resp2}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -174,16 +175,17 @@ func TestClient_Del(t *testing.T) {
 	key2 := "k_del2"
 	key3 := "k_del3"
 	cli.Set(key1, 1, 0)
-	cli.Del(key1)
+	assert.True(t, cli.Del(key1))
+	assert.False(t, cli.Del(key1))
 	assert.Equal(t, nil, cli.Get(key1))
 	cli.Set(key1, 1, 0)
 	cli.Set(key2, 2, 0)
 	cli.Set(key3, 4, 0)
 	_ = data.Freeze()
 	cli.Set(key1, 3, 0)
-	cli.Del(key1)
+	assert.True(t, cli.Del(key1))
 	assert.Equal(t, nil, cli.Get(key1))
-	cli.Del(key2)
+	assert.True(t, cli.Del(key2))
 	assert.Equal(t, nil, cli.Get(key2))
 	_ = data.ToMove()
 	cli.Del(key1)
@@ -192,3 +194,149 @@ func TestClient_Del(t *testing.T) {
 	cli.Del(key3)
 	assert.Equal(t, nil, cli.Get(key3))
 }
+
+func TestClient_Expire(t *testing.T) {
+	d := NewDataStorage()
+	c := NewClient(nil, d)
+	key := "k_expire"
+	_, ok := c.GetExpire(key)
+	assert.False(t, ok)
+	assert.False(t, c.SetExpire(key, 1))
+	c.Set(key, 1, 0)
+	expire, ok := c.GetExpire(key)
+	assert.True(t, ok)
+	assert.Zero(t, expire)
+	at := time.Now().Add(time.Hour).UnixNano()
+	assert.True(t, c.SetExpire(key, at))
+	expire, _ = c.GetExpire(key)
+	assert.Equal(t, at, expire)
+	_ = d.Freeze()
+	assert.True(t, c.SetExpire(key, 0))
+	expire, _ = c.GetExpire(key)
+	assert.Zero(t, expire)
+	assert.Equal(t, at, d.oldData[key].Expire)
+	_ = d.ToMove()
+	assert.True(t, c.SetExpire(key, time.Now().Add(time.Millisecond).UnixNano()))
+	<-time.After(time.Millisecond)
+	_, ok = c.GetExpire(key)
+	assert.False(t, ok)
+
+	// set without expiration clears the one existing
+	c.Set(key, 1, at)
+	c.Set(key, 2, 0)
+	expire, ok = c.GetExpire(key)
+	assert.True(t, ok)
+	assert.Zero(t, expire)
+}
+
+func TestDataStorage_ActiveExpire(t *testing.T) {
+	d := NewDataStorage()
+	expire := time.Now().Add(time.Millisecond).UnixNano()
+	for i := 0; i < 50; i++ {
+		d.Set(fmt.Sprintf("a%d", i), []byte("1"), expire)
+	}
+	d.Set("b", []byte("1"), 0)
+	<-time.After(2 * time.Millisecond)
+	// no expired item is popped once the deadline is reached
+	assert.False(t, d.ActiveExpire(time.Now()))
+	assert.Equal(t, int64(activeExpireNum), d.ExpiredKeys())
+	assert.True(t, d.ActiveExpire(time.Now().Add(time.Second)))
+	assert.Equal(t, int64(50), d.ExpiredKeys())
+	assert.Equal(t, 1, len(d.data))
+	assert.Equal(t, 1, d.queue.Len())
+}
+
+func TestDataStorage_Flush(t *testing.T) {
+	d := NewDataStorage()
+	c := NewClient(nil, d)
+	for i := 0; i < 10; i++ {
+		d.Set(strconv.Itoa(i), []byte("1"), 0)
+	}
+	c.Watch("1")
+	c.Multi.State = true
+	assert.Equal(t, 10, d.Size())
+	key, ok := d.RandomKey()
+	assert.True(t, ok)
+	assert.NotNil(t, d.Get(key))
+	d.Flush()
+	assert.True(t, c.Multi.Dirty)
+	assert.Equal(t, 0, d.Size())
+	_, ok = d.RandomKey()
+	assert.False(t, ok)
+
+	// the origin data is kept when blocked
+	d.Set("a", []byte("1"), 0)
+	d.Set("b", []byte("1"), 0)
+	_ = d.Freeze()
+	d.Set("c", []byte("1"), 0)
+	assert.Equal(t, 3, d.Size())
+	d.Flush()
+	assert.Equal(t, 0, d.Size())
+	assert.Equal(t, 2, len(d.GetOrigin()))
+	_ = d.ToMove()
+	assert.Nil(t, d.Get("a"))
+	assert.Equal(t, 0, d.Size())
+}
+
+func TestDataStorage_Swap(t *testing.T) {
+	d, o := NewDataStorage(), NewDataStorage()
+	c := NewClient(nil, o)
+	c.Watch("b")
+	c.Multi.State = true
+	d.Set("a", []byte("1"), 0)
+	d.Set("b", NewList(), 0)
+	o.Set("c", []byte("2"), 0)
+	d.Swap(o)
+	assert.Equal(t, []byte("2"), d.Get("c"))
+	assert.Equal(t, 2, o.Size())
+	assert.True(t, c.Multi.Dirty)
+
+	// values of the origin data are cloned when blocked
+	_ = o.Freeze()
+	o.Set("a", []byte("3"), 0)
+	at := time.Now().Add(time.Hour).UnixNano()
+	d.Set("c", []byte("2"), at)
+	d.Swap(o)
+	assert.Equal(t, []byte("3"), d.Get("a"))
+	assert.NotSame(t, o.GetOrigin()["b"].Row, d.Get("b"))
+	assert.Equal(t, 1, o.Size())
+	expire, _ := o.GetExpire("c")
+	assert.Equal(t, at, expire)
+	assert.Equal(t, 2, len(o.GetOrigin()))
+	_ = o.ToMove()
+	assert.Nil(t, o.Get("a"))
+	assert.Equal(t, []byte("2"), o.Get("c"))
+}
+
+func TestDataStorage_Access(t *testing.T) {
+	d := NewDataStorage()
+	d.Set("a", []byte("1"), 0)
+	item := d.Peek("a")
+	assert.Equal(t, uint8(lfuInitVal), item.Freq())
+	for i := 0; i < 100; i++ {
+		d.Get("a")
+	}
+	assert.Greater(t, item.Freq(), uint8(lfuInitVal))
+	assert.Less(t, item.Idle(), time.Second)
+	assert.Nil(t, d.Peek("b"))
+
+	// the counter decays by the idle minutes
+	item.access -= int64(3 * time.Minute)
+	item.freq = lfuInitVal
+	assert.Equal(t, uint8(lfuInitVal-3), item.Freq())
+	assert.GreaterOrEqual(t, item.Idle(), 3*time.Minute)
+	item.access -= int64(time.Hour)
+	assert.Equal(t, uint8(0), item.Freq())
+	assert.Equal(t, uint8(255), lfuIncr(255))
+
+	// internal lookups leave the access untouched
+	access := item.access
+	for i := 0; i < 100; i++ {
+		d.GetExpire("a")
+		d.SetExpire("a", 0)
+	}
+	assert.Equal(t, access, item.access)
+	assert.Equal(t, uint8(lfuInitVal), item.freq)
+	d.Del("a")
+	assert.Equal(t, access, item.access)
+}
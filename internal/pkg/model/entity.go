@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/inhzus/go-redis-impl/internal/pkg/task"
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
@@ -21,6 +23,22 @@ type CmdTask struct {
 	Rsp chan *token.Token
 }
 
+// CloseTask is the structure hold by the channel which notifies the processor
+// to release resources of the client whose connection is closed.
+type CloseTask struct {
+	Cli *Client
+	Rsp chan struct{}
+}
+
+// ExpireTask is the structure hold by the channel which triggers the active
+// expiration cycle periodically, the processor replies the interval until
+// the next cycle.
+type ExpireTask struct {
+	Rsp chan time.Duration
+}
+
 // Task prevents type cast from interface to Task.
-func (t *ModTask) Task() task.Task { return t }
-func (t *CmdTask) Task() task.Task { return t }
+func (t *ModTask) Task() task.Task    { return t }
+func (t *CmdTask) Task() task.Task    { return t }
+func (t *CloseTask) Task() task.Task  { return t }
+func (t *ExpireTask) Task() task.Task { return t }
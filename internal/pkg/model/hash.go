@@ -0,0 +1,76 @@
+package model
+
+// Hash is the field-value map stored, fields are indexed by the scan table
+// to be scanned incrementally.
+type Hash struct {
+	m     map[string][]byte
+	table *scanTable
+}
+
+// NewHash returns an empty hash
+func NewHash() *Hash {
+	return &Hash{m: make(map[string][]byte), table: newScanTable()}
+}
+
+// Len returns the number of fields in the hash
+func (h *Hash) Len() int {
+	return len(h.m)
+}
+
+// Clone returns a copy of the hash, values are shared since they are never
+// modified in place.
+func (h *Hash) Clone() interface{} {
+	c := &Hash{m: make(map[string][]byte, len(h.m)), table: h.table.clone()}
+	for k, v := range h.m {
+		c.m[k] = v
+	}
+	return c
+}
+
+// Get returns the value of field and whether the field exists
+func (h *Hash) Get(field string) ([]byte, bool) {
+	v, ok := h.m[field]
+	return v, ok
+}
+
+// Set puts the value of field, reports whether the field is newly created
+func (h *Hash) Set(field string, v []byte) bool {
+	_, ok := h.m[field]
+	h.m[field] = v
+	if !ok {
+		h.table.add(field)
+	}
+	return !ok
+}
+
+// Del deletes the field, reports whether the field existed
+func (h *Hash) Del(field string) bool {
+	_, ok := h.m[field]
+	if ok {
+		delete(h.m, field)
+		h.table.remove(field)
+	}
+	return ok
+}
+
+// Fields returns all the fields in random order
+func (h *Hash) Fields() []string {
+	fields := make([]string, 0, len(h.m))
+	for k := range h.m {
+		fields = append(fields, k)
+	}
+	return fields
+}
+
+// Random returns a random field, false if the hash is empty
+func (h *Hash) Random() (string, bool) {
+	return h.table.random()
+}
+
+// Scan calls fn with the fields and values of about count fields from cursor,
+// and returns the cursor to continue, 0 if the scan is finished
+func (h *Hash) Scan(cursor uint64, count int, fn func(field string, v []byte)) uint64 {
+	return h.table.scan(cursor, count, func(field string) {
+		fn(field, h.m[field])
+	})
+}
@@ -0,0 +1,309 @@
+package model
+
+import "bytes"
+
+// listNodeSize is the max number of entries a list node holds
+const listNodeSize = 128
+
+type listNode struct {
+	entries    [][]byte
+	prev, next *listNode
+}
+
+// List is a quicklist: a doubly linked list whose nodes hold a bounded array
+// of entries each, so pushing does not copy the whole list and walking the
+// list skips a node at a time.
+type List struct {
+	head, tail *listNode
+	length     int
+}
+
+// NewList returns an empty list
+func NewList() *List {
+	return &List{}
+}
+
+// Len returns the number of entries in the list
+func (l *List) Len() int {
+	return l.length
+}
+
+// Clone returns a copy of the list, entries are shared since they are never
+// modified in place.
+func (l *List) Clone() interface{} {
+	c := NewList()
+	for n := l.head; n != nil; n = n.next {
+		node := &listNode{entries: append([][]byte(nil), n.entries...), prev: c.tail}
+		if c.tail == nil {
+			c.head = node
+		} else {
+			c.tail.next = node
+		}
+		c.tail = node
+	}
+	c.length = l.length
+	return c
+}
+
+// insertNode links node after prev, or as head if prev is nil
+func (l *List) insertNode(prev, node *listNode) {
+	node.prev = prev
+	if prev == nil {
+		node.next = l.head
+		l.head = node
+	} else {
+		node.next = prev.next
+		prev.next = node
+	}
+	if node.next == nil {
+		l.tail = node
+	} else {
+		node.next.prev = node
+	}
+}
+
+// removeNode unlinks node from the list
+func (l *List) removeNode(node *listNode) {
+	if node.prev == nil {
+		l.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		l.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+}
+
+// PushFront inserts v at the head of the list
+func (l *List) PushFront(v []byte) {
+	if l.head == nil || len(l.head.entries) >= listNodeSize {
+		l.insertNode(nil, &listNode{})
+	}
+	n := l.head
+	n.entries = append(n.entries, nil)
+	copy(n.entries[1:], n.entries)
+	n.entries[0] = v
+	l.length++
+}
+
+// PushBack inserts v at the tail of the list
+func (l *List) PushBack(v []byte) {
+	if l.tail == nil || len(l.tail.entries) >= listNodeSize {
+		l.insertNode(l.tail, &listNode{})
+	}
+	l.tail.entries = append(l.tail.entries, v)
+	l.length++
+}
+
+// PopFront removes and returns the head entry, nil if the list is empty
+func (l *List) PopFront() []byte {
+	if l.length == 0 {
+		return nil
+	}
+	return l.removeAt(l.head, 0)
+}
+
+// PopBack removes and returns the tail entry, nil if the list is empty
+func (l *List) PopBack() []byte {
+	if l.length == 0 {
+		return nil
+	}
+	return l.removeAt(l.tail, len(l.tail.entries)-1)
+}
+
+// removeAt removes the i-th entry of node, unlinking the node if it is empty
+func (l *List) removeAt(node *listNode, i int) []byte {
+	v := node.entries[i]
+	copy(node.entries[i:], node.entries[i+1:])
+	node.entries[len(node.entries)-1] = nil
+	node.entries = node.entries[:len(node.entries)-1]
+	if len(node.entries) == 0 {
+		l.removeNode(node)
+	}
+	l.length--
+	return v
+}
+
+// locate returns the node holding the i-th entry and the offset in the node,
+// walking from the nearer end. i should be in range [0, length).
+func (l *List) locate(i int) (*listNode, int) {
+	if i < l.length/2 {
+		for n := l.head; n != nil; n = n.next {
+			if i < len(n.entries) {
+				return n, i
+			}
+			i -= len(n.entries)
+		}
+		return nil, 0
+	}
+	i = l.length - 1 - i
+	for n := l.tail; n != nil; n = n.prev {
+		if i < len(n.entries) {
+			return n, len(n.entries) - 1 - i
+		}
+		i -= len(n.entries)
+	}
+	return nil, 0
+}
+
+// normIndex converts i which may be negative counting from the tail to the
+// index from the head, reporting false if out of range.
+func (l *List) normIndex(i int) (int, bool) {
+	if i < 0 {
+		i += l.length
+	}
+	return i, i >= 0 && i < l.length
+}
+
+// Index returns the i-th entry, negative index counts from the tail.
+func (l *List) Index(i int) ([]byte, bool) {
+	i, ok := l.normIndex(i)
+	if !ok {
+		return nil, false
+	}
+	n, off := l.locate(i)
+	return n.entries[off], true
+}
+
+// Set replaces the i-th entry, negative index counts from the tail.
+func (l *List) Set(i int, v []byte) bool {
+	i, ok := l.normIndex(i)
+	if !ok {
+		return false
+	}
+	n, off := l.locate(i)
+	n.entries[off] = v
+	return true
+}
+
+// Range returns entries from start to stop inclusively,
+// both should be in range [0, length).
+func (l *List) Range(start, stop int) [][]byte {
+	if start > stop {
+		return nil
+	}
+	ret := make([][]byte, 0, stop-start+1)
+	n, off := l.locate(start)
+	for ; n != nil && len(ret) < cap(ret); n, off = n.next, 0 {
+		end := off + cap(ret) - len(ret)
+		if end > len(n.entries) {
+			end = len(n.entries)
+		}
+		ret = append(ret, n.entries[off:end]...)
+	}
+	return ret
+}
+
+// Trim keeps entries from start to stop inclusively,
+// both should be in range [0, length).
+func (l *List) Trim(start, stop int) {
+	l.trimFront(start)
+	l.trimBack(l.length - (stop - start + 1))
+}
+
+// trimFront removes n entries from the head
+func (l *List) trimFront(n int) {
+	for n > 0 && l.head != nil {
+		node := l.head
+		if len(node.entries) <= n {
+			n -= len(node.entries)
+			l.length -= len(node.entries)
+			l.removeNode(node)
+			continue
+		}
+		node.entries = append([][]byte(nil), node.entries[n:]...)
+		l.length -= n
+		return
+	}
+}
+
+// trimBack removes n entries from the tail
+func (l *List) trimBack(n int) {
+	for n > 0 && l.tail != nil {
+		node := l.tail
+		if len(node.entries) <= n {
+			n -= len(node.entries)
+			l.length -= len(node.entries)
+			l.removeNode(node)
+			continue
+		}
+		for i := len(node.entries) - n; i < len(node.entries); i++ {
+			node.entries[i] = nil
+		}
+		node.entries = node.entries[:len(node.entries)-n]
+		l.length -= n
+		return
+	}
+}
+
+// Remove removes entries equal to v and returns the number of entries removed.
+// count > 0 removes at most count entries from head to tail, count < 0 removes
+// at most -count entries from tail to head, count = 0 removes all.
+func (l *List) Remove(v []byte, count int) int {
+	removed := 0
+	if count >= 0 {
+		for n := l.head; n != nil; {
+			next := n.next
+			for i := 0; i < len(n.entries); {
+				if count > 0 && removed == count {
+					return removed
+				}
+				if bytes.Equal(n.entries[i], v) {
+					l.removeAt(n, i)
+					removed++
+				} else {
+					i++
+				}
+			}
+			n = next
+		}
+		return removed
+	}
+	count = -count
+	for n := l.tail; n != nil; {
+		prev := n.prev
+		for i := len(n.entries) - 1; i >= 0; i-- {
+			if removed == count {
+				return removed
+			}
+			if bytes.Equal(n.entries[i], v) {
+				l.removeAt(n, i)
+				removed++
+			}
+		}
+		n = prev
+	}
+	return removed
+}
+
+// Insert inserts v before or after the first entry equal to pivot from the
+// head, reporting false if pivot is not found.
+func (l *List) Insert(pivot, v []byte, before bool) bool {
+	for n := l.head; n != nil; n = n.next {
+		for i, e := range n.entries {
+			if !bytes.Equal(e, pivot) {
+				continue
+			}
+			if !before {
+				i++
+			}
+			n.entries = append(n.entries, nil)
+			copy(n.entries[i+1:], n.entries[i:])
+			n.entries[i] = v
+			l.length++
+			if len(n.entries) > listNodeSize {
+				// split the node in half
+				half := len(n.entries) / 2
+				l.insertNode(n, &listNode{entries: append([][]byte(nil), n.entries[half:]...)})
+				for j := half; j < len(n.entries); j++ {
+					n.entries[j] = nil
+				}
+				n.entries = n.entries[:half]
+			}
+			return true
+		}
+	}
+	return false
+}
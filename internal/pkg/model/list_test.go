@@ -0,0 +1,139 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listOf(n int) *List {
+	l := NewList()
+	for i := 0; i < n; i++ {
+		l.PushBack([]byte(strconv.Itoa(i)))
+	}
+	return l
+}
+
+func TestList_Push(t *testing.T) {
+	l := NewList()
+	const n = listNodeSize*3 + 1
+	for i := 0; i < n; i++ {
+		l.PushBack([]byte(strconv.Itoa(i)))
+		l.PushFront([]byte(strconv.Itoa(-i - 1)))
+	}
+	assert.Equal(t, 2*n, l.Len())
+	for i := 0; i < 2*n; i++ {
+		v, ok := l.Index(i)
+		assert.True(t, ok)
+		assert.Equal(t, strconv.Itoa(i-n), string(v))
+	}
+	v, ok := l.Index(-1)
+	assert.True(t, ok)
+	assert.Equal(t, strconv.Itoa(n-1), string(v))
+	_, ok = l.Index(2 * n)
+	assert.False(t, ok)
+	_, ok = l.Index(-2*n - 1)
+	assert.False(t, ok)
+}
+
+func TestList_Pop(t *testing.T) {
+	l := listOf(listNodeSize + 2)
+	assert.Equal(t, "0", string(l.PopFront()))
+	assert.Equal(t, strconv.Itoa(listNodeSize+1), string(l.PopBack()))
+	for l.Len() > 0 {
+		l.PopBack()
+	}
+	assert.Nil(t, l.PopFront())
+	assert.Nil(t, l.PopBack())
+	assert.Nil(t, l.head)
+	assert.Nil(t, l.tail)
+}
+
+func TestList_Range(t *testing.T) {
+	l := listOf(listNodeSize * 2)
+	r := l.Range(listNodeSize-1, listNodeSize+1)
+	assert.Equal(t, [][]byte{[]byte(strconv.Itoa(listNodeSize - 1)),
+		[]byte(strconv.Itoa(listNodeSize)), []byte(strconv.Itoa(listNodeSize + 1))}, r)
+	assert.Equal(t, listNodeSize*2, len(l.Range(0, l.Len()-1)))
+	assert.Nil(t, l.Range(2, 1))
+}
+
+func TestList_Trim(t *testing.T) {
+	l := listOf(listNodeSize * 3)
+	l.Trim(listNodeSize+1, listNodeSize*2+1)
+	assert.Equal(t, listNodeSize+1, l.Len())
+	v, _ := l.Index(0)
+	assert.Equal(t, strconv.Itoa(listNodeSize+1), string(v))
+	v, _ = l.Index(-1)
+	assert.Equal(t, strconv.Itoa(listNodeSize*2+1), string(v))
+	assert.Equal(t, listNodeSize+1, len(l.Range(0, l.Len()-1)))
+}
+
+func TestList_Remove(t *testing.T) {
+	l := NewList()
+	for i := 0; i < listNodeSize*2; i++ {
+		l.PushBack([]byte(strconv.Itoa(i % 3)))
+	}
+	assert.Equal(t, 2, l.Remove([]byte("0"), 2))
+	v, _ := l.Index(0)
+	assert.Equal(t, "1", string(v))
+	assert.Equal(t, 1, l.Remove([]byte("2"), -1))
+	v, _ = l.Index(-2)
+	assert.Equal(t, "1", string(v))
+	n := l.Len()
+	removed := l.Remove([]byte("1"), 0)
+	assert.Equal(t, n-removed, l.Len())
+	assert.Equal(t, 0, l.Remove([]byte("1"), 0))
+	for i := 0; i < l.Len(); i++ {
+		v, _ = l.Index(i)
+		assert.NotEqual(t, "1", string(v))
+	}
+}
+
+func TestList_Insert(t *testing.T) {
+	l := listOf(listNodeSize)
+	assert.False(t, l.Insert([]byte("none"), []byte("x"), true))
+	assert.True(t, l.Insert([]byte("0"), []byte("a"), true))
+	assert.True(t, l.Insert([]byte("0"), []byte("b"), false))
+	assert.Equal(t, listNodeSize+2, l.Len())
+	assert.NotNil(t, l.head.next)
+	r := l.Range(0, 3)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("0"), []byte("b"), []byte("1")}, r)
+	assert.Equal(t, listNodeSize+2, len(l.Range(0, l.Len()-1)))
+}
+
+func TestList_Clone(t *testing.T) {
+	l := listOf(listNodeSize + 1)
+	c := l.Clone().(*List)
+	c.PopFront()
+	assert.True(t, c.Set(0, []byte("x")))
+	assert.Equal(t, listNodeSize+1, l.Len())
+	v, _ := l.Index(1)
+	assert.Equal(t, "1", string(v))
+}
+
+func TestClient_GetMutable(t *testing.T) {
+	d := NewDataStorage()
+	c := NewClient(nil, d)
+	c.Set("list", listOf(3), 0)
+	_ = d.Freeze()
+	l := c.GetMutable("list").(*List)
+	l.PopFront()
+	assert.Equal(t, 3, d.oldData["list"].Row.(*List).Len())
+	assert.Equal(t, 2, c.Get("list").(*List).Len())
+	assert.Equal(t, l, c.GetMutable("list"))
+	_ = d.ToMove()
+	assert.Equal(t, 2, c.Get("list").(*List).Len())
+
+	// the value put by SetExpire when blocked is not shared with origin data
+	d = NewDataStorage()
+	c = NewClient(nil, d)
+	c.Set("list", listOf(3), 0)
+	_ = d.Freeze()
+	assert.True(t, c.SetExpire("list", time.Now().Add(time.Hour).UnixNano()))
+	c.GetMutable("list").(*List).PushFront([]byte("x"))
+	assert.Equal(t, 3, d.oldData["list"].Row.(*List).Len())
+	assert.Equal(t, 4, c.Get("list").(*List).Len())
+}
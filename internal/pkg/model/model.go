@@ -3,12 +3,22 @@ package model
 import (
 	"container/heap"
 	"fmt"
+	"math/rand"
 	"time"
 )
 
 const (
-	checkExpireNum = 10
-	moveBackNum    = 10
+	checkExpireNum  = 10
+	moveBackNum     = 10
+	activeExpireNum = 20
+)
+
+// parameters of the logarithmic access frequency counter, same as defaults of redis
+const (
+	lfuInitVal   = 5
+	lfuLogFactor = 10
+	// minutes of idle time to decrease the counter by one
+	lfuDecayTime = 1
 )
 
 // Item is key-value pair stored in model
@@ -17,10 +27,14 @@ type Item struct {
 	Row    interface{}
 	Expire int64
 	index  int
+	// unix nano of the last access
+	access int64
+	// logarithmic counter of access frequency
+	freq uint8
 }
 
 func newItem(key string, row interface{}, expire int64) *Item {
-	return &Item{key: key, Row: row, Expire: expire}
+	return &Item{key: key, Row: row, Expire: expire, access: time.Now().UnixNano(), freq: lfuInitVal}
 }
 
 // newExpiredItem returns a new item that is expired.
@@ -30,17 +44,60 @@ func newExpiredItem(key string) *Item {
 	return &Item{key: key, Expire: time.Now().UnixNano() - 1}
 }
 
+// fix replaces the value and the expiration of the item, the expiration is
+// cleared if expire is 0.
 func (i *Item) fix(row interface{}, expire int64) {
-	if expire == 0 && i.Expire < time.Now().UnixNano() {
-		i.Expire = 0
-	} else if expire > 0 {
-		i.Expire = expire
-	}
+	i.Expire = expire
 	i.Row = row
+	i.access = time.Now().UnixNano()
+}
+
+// touch updates the access time and the access frequency counter of the item
+func (i *Item) touch() {
+	now := time.Now().UnixNano()
+	i.freq = lfuIncr(i.decayedFreq(now))
+	i.access = now
+}
+
+// decayedFreq returns the access frequency counter decreased by the idle periods
+func (i *Item) decayedFreq(now int64) uint8 {
+	periods := (now - i.access) / int64(time.Minute) / lfuDecayTime
+	if periods >= int64(i.freq) {
+		return 0
+	}
+	return i.freq - uint8(periods)
+}
+
+// lfuIncr increments the counter logarithmically, the more the counter is,
+// the less likely it is incremented
+func lfuIncr(counter uint8) uint8 {
+	if counter == 255 {
+		return counter
+	}
+	base := float64(counter) - lfuInitVal
+	if base < 0 {
+		base = 0
+	}
+	if rand.Float64() < 1/(base*lfuLogFactor+1) {
+		counter++
+	}
+	return counter
+}
+
+// Idle returns the duration since the last access of the item
+func (i *Item) Idle() time.Duration {
+	return time.Duration(time.Now().UnixNano() - i.access)
+}
+
+// Freq returns the access frequency counter of the item
+func (i *Item) Freq() uint8 {
+	return i.decayedFreq(time.Now().UnixNano())
 }
 
+// makeExpired makes the item a tombstone of the deleted key
 func (i *Item) makeExpired() {
 	i.Expire = time.Now().UnixNano() - 1
+	i.Row = nil
 }
 
 // DataStorage stores key-value data, expiration control heap, watched key-client map
@@ -50,16 +107,21 @@ type DataStorage struct {
 	queue    *priorityQueue
 	oldQueue *priorityQueue
 	watch    watchMap
+	// keys of both data indexed to be scanned
+	keys *scanTable
 	// blocked data has two status, status block means origin data should not change,
 	// status moving means moving new data back to origin data
 	isMoving bool
 	isBlock  bool
 	idx      int
+	notifier *Notifier
+	// number of keys expired
+	expired int64
 }
 
 // NewDataStorage returns data storage entity with default constructor
 func NewDataStorage() *DataStorage {
-	return &DataStorage{data: make(map[string]*Item), queue: &priorityQueue{}, watch: newWatchMap()}
+	return &DataStorage{data: make(map[string]*Item), queue: &priorityQueue{}, watch: newWatchMap(), keys: newScanTable()}
 }
 
 func NewDataArray(n int) []*DataStorage {
@@ -81,6 +143,16 @@ func (d *DataStorage) Idx() int {
 	return d.idx
 }
 
+// SetNotifier sets the notifier which publishes keyspace events of the data
+func (d *DataStorage) SetNotifier(n *Notifier) {
+	d.notifier = n
+}
+
+// notify publishes the keyspace event of the key
+func (d *DataStorage) notify(class int, event, key string) {
+	d.notifier.Notify(class, event, key, d.idx)
+}
+
 // Freeze and following logic ensure the origin data won't change until "ToMove"
 func (d *DataStorage) Freeze() error {
 	if !d.resetIfMoved() {
@@ -104,11 +176,12 @@ func (d *DataStorage) ToMove() error {
 	return nil
 }
 
-// scanPop checks and pops n * expired items when called.
-func (d *DataStorage) scanPop(n int) {
+// scanPop checks and pops at most n expired items when called, returns
+// the number of items popped.
+func (d *DataStorage) scanPop(n int) int {
 	// When origin data blocked, expired item should be kept to set origin item expired when moving.
 	if d.isBlock {
-		return
+		return 0
 	}
 	queue := &d.queue
 	data := &d.data
@@ -118,18 +191,41 @@ func (d *DataStorage) scanPop(n int) {
 		data = &d.oldData
 	}
 	now := time.Now().UnixNano()
-	for i := 0; i < checkExpireNum; i++ {
+	for i := 0; i < n; i++ {
 		top := (*queue).Top()
 		if top == nil {
-			return
+			return i
 		}
 		if top.Expire > 0 && top.Expire < now {
 			heap.Pop(*queue)
 			delete(*data, top.key)
+			d.forget(top.key)
+			// tombstones of deleted keys are not expired ones
+			if top.Row != nil {
+				d.expired++
+				d.notify(NotifyExpired, EventExpired, top.key)
+			}
 		} else {
-			return
+			return i
+		}
+	}
+	return n
+}
+
+// ActiveExpire pops expired items until none is left or the deadline is
+// reached, reports whether all the expired items are popped.
+func (d *DataStorage) ActiveExpire(deadline time.Time) bool {
+	for d.scanPop(activeExpireNum) == activeExpireNum {
+		if time.Now().After(deadline) {
+			return false
 		}
 	}
+	return true
+}
+
+// ExpiredKeys returns the number of keys expired
+func (d *DataStorage) ExpiredKeys() int64 {
+	return d.expired
 }
 
 // moveBack moves n item from new data to origin data when called
@@ -157,12 +253,8 @@ func (d *DataStorage) resetIfMoved() bool {
 	return true
 }
 
-// Get returns the value of correspond key
-func (d *DataStorage) Get(key string) interface{} {
-	d.scanPop(checkExpireNum)
-	if !d.resetIfMoved() {
-		d.moveBack(moveBackNum)
-	}
+// lookup returns the item of correspond key, nil if not found or expired
+func (d *DataStorage) lookup(key string) *Item {
 	r, ok := d.data[key]
 	// when blocked or moving, both data should be checked
 	if !ok && (d.isBlock || d.isMoving) {
@@ -175,11 +267,136 @@ func (d *DataStorage) Get(key string) interface{} {
 	if r.Expire > 0 && r.Expire < now {
 		return nil
 	}
+	return r
+}
+
+// Keys returns all the keys not expired. When blocked or moving, keys of
+// new data take the place of the ones of origin data.
+func (d *DataStorage) Keys() []string {
+	now := time.Now().UnixNano()
+	alive := func(item *Item) bool { return item.Expire <= 0 || item.Expire >= now }
+	keys := make([]string, 0, len(d.data)+len(d.oldData))
+	for key, item := range d.data {
+		if alive(item) {
+			keys = append(keys, key)
+		}
+	}
+	if !d.isBlock && !d.isMoving {
+		return keys
+	}
+	for key, item := range d.oldData {
+		if _, ok := d.data[key]; !ok && alive(item) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fetch returns the item of correspond key without updating its access, some
+// expired items are popped and moved items are moved back before lookup
+func (d *DataStorage) fetch(key string) *Item {
+	d.scanPop(checkExpireNum)
+	if !d.resetIfMoved() {
+		d.moveBack(moveBackNum)
+	}
+	return d.lookup(key)
+}
+
+// Get returns the value of correspond key and updates its access, which is
+// called once by the command looking up the key
+func (d *DataStorage) Get(key string) interface{} {
+	r := d.fetch(key)
+	if r == nil {
+		return nil
+	}
+	r.touch()
 	return r.Row
 }
 
-// Set puts the new value of key
+// Peek returns the item of correspond key without updating its access, nil
+// if not found or expired
+func (d *DataStorage) Peek(key string) *Item {
+	return d.lookup(key)
+}
+
+// Cloner is implemented by values which are modified in place
+type Cloner interface {
+	Clone() interface{}
+}
+
+// GetMutable returns the value of correspond key which is allowed to be
+// modified in place. When blocked, origin item shouldn't be changed, so
+// a clone of the origin value is put in new data and returned instead.
+func (d *DataStorage) GetMutable(key string) interface{} {
+	v := d.Get(key)
+	if v == nil || !d.isBlock {
+		return v
+	}
+	if _, ok := d.data[key]; ok {
+		return v
+	}
+	c, ok := v.(Cloner)
+	if !ok {
+		return v
+	}
+	item := newItem(key, c.Clone(), d.lookup(key).Expire)
+	d.data[key] = item
+	heap.Push(d.queue, item)
+	return item.Row
+}
+
+// GetExpire returns the expiration of correspond key, 0 if the key has no
+// expiration. The key not found is reported by false.
+func (d *DataStorage) GetExpire(key string) (int64, bool) {
+	r := d.fetch(key)
+	if r == nil {
+		return 0, false
+	}
+	return r.Expire, true
+}
+
+// SetExpire replaces the expiration of correspond key, 0 removes the expiration.
+// It reports whether the key exists.
+func (d *DataStorage) SetExpire(key string, expire int64) bool {
+	if d.fetch(key) == nil {
+		return false
+	}
+	if expire > 0 {
+		d.notify(NotifyGeneric, EventExpire, key)
+	} else {
+		d.notify(NotifyGeneric, EventPersist, key)
+	}
+	item := d.lookup(key)
+	if d.data[key] != item {
+		// When blocked, origin item shouldn't be changed, put a copy in new data
+		// instead, whose value is cloned since GetMutable doesn't clone it again.
+		if d.isBlock {
+			row := item.Row
+			if c, ok := row.(Cloner); ok {
+				row = c.Clone()
+			}
+			item = newItem(key, row, expire)
+			d.data[key] = item
+			heap.Push(d.queue, item)
+			return true
+		}
+		item.Expire = expire
+		heap.Fix(d.oldQueue, item.index)
+		return true
+	}
+	item.Expire = expire
+	heap.Fix(d.queue, item.index)
+	return true
+}
+
+// Set puts the new value of key, the keyspace events are published by the
+// commands calling it since the event depends on the command
 func (d *DataStorage) Set(key string, value interface{}, expire int64) interface{} {
+	return d.put(key, value, expire).Row
+}
+
+// put puts the new value of key without keyspace events
+func (d *DataStorage) put(key string, value interface{}, expire int64) *Item {
 	d.scanPop(checkExpireNum)
 	data := &d.data
 	queue := &d.queue
@@ -203,12 +420,23 @@ func (d *DataStorage) Set(key string, value interface{}, expire int64) interface
 		item = newItem(key, value, expire)
 		(*data)[key] = item
 		heap.Push(*queue, item)
+		d.keys.add(key)
 	}
-	return item.Row
+	return item
+}
+
+// Del deletes the value of correspond key and reports whether the key existed
+func (d *DataStorage) Del(key string) bool {
+	existed := d.fetch(key) != nil
+	if existed {
+		d.notify(NotifyGeneric, EventDel, key)
+	}
+	d.remove(key)
+	return existed
 }
 
-// Del deletes the value of correspond key
-func (d *DataStorage) Del(key string) {
+// remove deletes the value of correspond key without keyspace events
+func (d *DataStorage) remove(key string) {
 	item, ok := d.data[key]
 	// When blocked, origin data shouldn't be changed, just set item of correspond key in new data expired
 	if d.isBlock {
@@ -219,6 +447,7 @@ func (d *DataStorage) Del(key string) {
 			item = newExpiredItem(key)
 			d.data[key] = item
 			heap.Push(d.queue, item)
+			d.keys.add(key)
 		}
 		return
 	}
@@ -234,4 +463,124 @@ func (d *DataStorage) Del(key string) {
 			delete(d.oldData, key)
 		}
 	}
+	d.forget(key)
+}
+
+// forget removes the key from the scan table if neither data holds it
+func (d *DataStorage) forget(key string) {
+	if _, ok := d.data[key]; ok {
+		return
+	}
+	if _, ok := d.oldData[key]; ok {
+		return
+	}
+	d.keys.remove(key)
+}
+
+// Scan calls fn with the keys not expired and their values of about count
+// keys from cursor, and returns the cursor to continue, 0 if the scan is
+// finished. Keys are indexed no matter which data holds them, so the cursor
+// stays valid when the storage is frozen or moving.
+func (d *DataStorage) Scan(cursor uint64, count int, fn func(key string, value interface{})) uint64 {
+	return d.keys.scan(cursor, count, func(key string) {
+		if item := d.lookup(key); item != nil {
+			fn(key, item.Row)
+		}
+	})
+}
+
+// Size returns the number of keys. Expired keys not deleted yet are counted
+// unless blocked or moving.
+func (d *DataStorage) Size() int {
+	if d.isBlock || d.isMoving {
+		return len(d.Keys())
+	}
+	return len(d.data)
+}
+
+// RandomKey returns a random key not expired, false if there is no key
+func (d *DataStorage) RandomKey() (string, bool) {
+	if d.isBlock || d.isMoving {
+		keys := d.Keys()
+		if len(keys) == 0 {
+			return "", false
+		}
+		return keys[rand.Intn(len(keys))], true
+	}
+	now := time.Now().UnixNano()
+	// the iteration of map starts at random
+	for key, item := range d.data {
+		if item.Expire <= 0 || item.Expire >= now {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// touchWatched marks the existing keys modified for clients watching them
+func (d *DataStorage) touchWatched() {
+	for key := range d.watch {
+		if d.lookup(key) != nil {
+			d.watch.Touch(key)
+		}
+	}
+}
+
+// Flush deletes all the keys, clients watching existing keys are touched.
+// When blocked, origin data shouldn't be changed, so tombstones of its keys
+// are put in new data instead. Otherwise the data is just dropped and left
+// to the garbage collector.
+func (d *DataStorage) Flush() {
+	d.touchWatched()
+	if d.isBlock {
+		for _, key := range d.Keys() {
+			d.remove(key)
+		}
+		return
+	}
+	d.data, d.queue, d.keys = make(map[string]*Item), &priorityQueue{}, newScanTable()
+	d.oldData, d.oldQueue, d.isMoving = nil, nil, false
+}
+
+// items returns the items not expired. Values of origin data are cloned
+// when blocked, so that they are allowed to be modified in place.
+func (d *DataStorage) items() []*Item {
+	keys := d.Keys()
+	items := make([]*Item, 0, len(keys))
+	for _, key := range keys {
+		item := d.lookup(key)
+		row := item.Row
+		if c, ok := row.(Cloner); ok && d.isBlock && d.data[key] != item {
+			row = c.Clone()
+		}
+		it := newItem(key, row, item.Expire)
+		it.access, it.freq = item.access, item.freq
+		items = append(items, it)
+	}
+	return items
+}
+
+// Swap swaps all the keys with the other data storage, clients watching
+// existing keys of both are touched. Keys are moved one by one if either is
+// blocked or moving.
+func (d *DataStorage) Swap(o *DataStorage) {
+	d.touchWatched()
+	o.touchWatched()
+	if !d.isBlock && !d.isMoving && !o.isBlock && !o.isMoving {
+		d.data, o.data = o.data, d.data
+		d.queue, o.queue = o.queue, d.queue
+		d.keys, o.keys = o.keys, d.keys
+	} else {
+		a, b := d.items(), o.items()
+		d.Flush()
+		o.Flush()
+		for _, item := range b {
+			d.put(item.key, item.Row, item.Expire)
+		}
+		for _, item := range a {
+			o.put(item.key, item.Row, item.Expire)
+		}
+	}
+	d.touchWatched()
+	o.touchWatched()
 }
@@ -0,0 +1,176 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// classes of keyspace events, which are configured by the flags string like
+// "notify-keyspace-events" of redis
+const (
+	NotifyKeyspace = 1 << iota // K, published to __keyspace@<db>__:<key>
+	NotifyKeyevent             // E, published to __keyevent@<db>__:<event>
+	NotifyGeneric              // g, del, expire, persist
+	NotifyString               // $
+	NotifyList                 // l
+	NotifySet                  // s
+	NotifyHash                 // h
+	NotifyZSet                 // z
+	NotifyExpired              // x
+	NotifyEvicted              // e, accepted as redis does, nothing is evicted without maxmemory
+	NotifyStream               // t
+	// A, alias for "g$lshzxet"
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash | NotifyZSet |
+		NotifyExpired | NotifyEvicted | NotifyStream
+)
+
+// keyspace events published, named after the commands like redis
+const (
+	EventSet     = "set"
+	EventDel     = "del"
+	EventExpire  = "expire"
+	EventPersist = "persist"
+	EventExpired = "expired"
+
+	EventAppend      = "append"
+	EventIncrBy      = "incrby"
+	EventIncrByFloat = "incrbyfloat"
+	EventSetRange    = "setrange"
+	EventSetBit      = "setbit"
+	EventPFAdd       = "pfadd"
+
+	EventRenameFrom = "rename_from"
+	EventRenameTo   = "rename_to"
+	EventMoveFrom   = "move_from"
+	EventMoveTo     = "move_to"
+	EventCopyTo     = "copy_to"
+
+	EventLPush   = "lpush"
+	EventRPush   = "rpush"
+	EventLPop    = "lpop"
+	EventRPop    = "rpop"
+	EventLSet    = "lset"
+	EventLTrim   = "ltrim"
+	EventLRem    = "lrem"
+	EventLInsert = "linsert"
+
+	EventHSet         = "hset"
+	EventHDel         = "hdel"
+	EventHIncrBy      = "hincrby"
+	EventHIncrByFloat = "hincrbyfloat"
+
+	EventSAdd        = "sadd"
+	EventSRem        = "srem"
+	EventSPop        = "spop"
+	EventSInterStore = "sinterstore"
+	EventSUnionStore = "sunionstore"
+	EventSDiffStore  = "sdiffstore"
+
+	EventZAdd             = "zadd"
+	EventZIncr            = "zincr"
+	EventZRem             = "zrem"
+	EventZPopMin          = "zpopmin"
+	EventZPopMax          = "zpopmax"
+	EventZRemRangeByScore = "zremrangebyscore"
+	EventZRemRangeByRank  = "zremrangebyrank"
+	EventZRemRangeByLex   = "zremrangebylex"
+	EventZUnionStore      = "zunionstore"
+	EventZInterStore      = "zinterstore"
+	EventZRangeStore      = "zrangestore"
+	EventGeoSearchStore   = "geosearchstore"
+
+	EventXAdd                 = "xadd"
+	EventXDel                 = "xdel"
+	EventXTrim                = "xtrim"
+	EventXSetID               = "xsetid"
+	EventXGroupCreate         = "xgroup-create"
+	EventXGroupSetID          = "xgroup-setid"
+	EventXGroupDestroy        = "xgroup-destroy"
+	EventXGroupCreateConsumer = "xgroup-createconsumer"
+	EventXGroupDelConsumer    = "xgroup-delconsumer"
+)
+
+var notifyFlags = []struct {
+	c     byte
+	class int
+}{
+	{'g', NotifyGeneric}, {'$', NotifyString}, {'l', NotifyList}, {'s', NotifySet},
+	{'h', NotifyHash}, {'z', NotifyZSet}, {'x', NotifyExpired}, {'e', NotifyEvicted}, {'t', NotifyStream},
+}
+
+// ParseNotifyFlags parses the flags string to classes of keyspace events
+func ParseNotifyFlags(s string) (int, error) {
+	flags := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 'A':
+			flags |= NotifyAll
+		case 'K':
+			flags |= NotifyKeyspace
+		case 'E':
+			flags |= NotifyKeyevent
+		default:
+			found := false
+			for _, f := range notifyFlags {
+				if f.c == c {
+					flags |= f.class
+					found = true
+					break
+				}
+			}
+			if !found {
+				return 0, fmt.Errorf("invalid keyspace event flag '%c'", c)
+			}
+		}
+	}
+	// nothing is published if neither keyspace nor keyevent is enabled
+	if flags&(NotifyKeyspace|NotifyKeyevent) == 0 {
+		return 0, nil
+	}
+	return flags, nil
+}
+
+// FormatNotifyFlags returns the flags string of classes of keyspace events
+func FormatNotifyFlags(flags int) string {
+	var b strings.Builder
+	if flags&NotifyAll == NotifyAll {
+		b.WriteByte('A')
+	} else {
+		for _, f := range notifyFlags {
+			if flags&f.class != 0 {
+				b.WriteByte(f.c)
+			}
+		}
+	}
+	if flags&NotifyKeyspace != 0 {
+		b.WriteByte('K')
+	}
+	if flags&NotifyKeyevent != 0 {
+		b.WriteByte('E')
+	}
+	return b.String()
+}
+
+// Notifier publishes keyspace events of the data storages to subscribers
+type Notifier struct {
+	Flags  int
+	pubsub *PubSub
+}
+
+// NewNotifier returns a notifier publishing nothing until flags are set
+func NewNotifier(pubsub *PubSub) *Notifier {
+	return &Notifier{pubsub: pubsub}
+}
+
+// Notify publishes the event of the key in database db if the class is enabled
+func (n *Notifier) Notify(class int, event, key string, db int) {
+	if n == nil || n.Flags&class == 0 {
+		return
+	}
+	if n.Flags&NotifyKeyspace != 0 {
+		n.pubsub.Publish(fmt.Sprintf("__keyspace@%d__:%s", db, key), []byte(event))
+	}
+	if n.Flags&NotifyKeyevent != 0 {
+		n.pubsub.Publish(fmt.Sprintf("__keyevent@%d__:%s", db, event), []byte(key))
+	}
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotifyFlags(t *testing.T) {
+	flags, err := ParseNotifyFlags("KEA")
+	assert.Nil(t, err)
+	assert.Equal(t, NotifyKeyspace|NotifyKeyevent|NotifyAll, flags)
+	assert.Equal(t, "AKE", FormatNotifyFlags(flags))
+	flags, err = ParseNotifyFlags("Elg$")
+	assert.Nil(t, err)
+	assert.Equal(t, "g$lE", FormatNotifyFlags(flags))
+	flags, err = ParseNotifyFlags("A")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, flags)
+	assert.Equal(t, "", FormatNotifyFlags(flags))
+	_, err = ParseNotifyFlags("KEy")
+	assert.NotNil(t, err)
+}
+
+func keyspaceMsg(pattern, channel, msg string) *token.Token {
+	return token.NewArray(token.NewBulked([]byte(MsgPMessage)), token.NewBulked([]byte(pattern)),
+		token.NewBulked([]byte(channel)), token.NewBulked([]byte(msg)))
+}
+
+func TestDataStorage_Notify(t *testing.T) {
+	ps := NewPubSub()
+	n := NewNotifier(ps)
+	d := NewDataArray(2)[1]
+	d.SetNotifier(n)
+	sub := NewClient(nil, d)
+	sub.Out = NewOutbox()
+	ps.PSubscribe(sub, "__key*")
+	c := NewClient(nil, d)
+
+	c.Set("a", []byte("1"), 0)
+	c.Notify(NotifyString, EventSet, "a")
+	assert.Nil(t, sub.Out.Pop())
+
+	n.Flags, _ = ParseNotifyFlags("KEA")
+	c.Set("a", []byte("1"), 0)
+	c.Notify(NotifyString, EventSet, "a")
+	assert.Equal(t, []*token.Token{
+		keyspaceMsg("__key*", "__keyspace@1__:a", EventSet),
+		keyspaceMsg("__key*", "__keyevent@1__:set", "a"),
+	}, sub.Out.Pop())
+
+	n.Flags, _ = ParseNotifyFlags("Kgl")
+	c.Set("l", NewList(), 0)
+	c.Notify(NotifyList, EventLPush, "l")
+	c.Set("a", []byte("2"), 0)
+	c.Notify(NotifyString, EventSet, "a")
+	d.SetExpire("a", time.Now().Add(time.Hour).UnixNano())
+	d.SetExpire("a", 0)
+	d.Del("a")
+	d.Del("a")
+	c.Set("b", []byte("1"), 0)
+	assert.True(t, c.Remove("b"))
+	assert.False(t, c.Remove("b"))
+	assert.Equal(t, []*token.Token{
+		keyspaceMsg("__key*", "__keyspace@1__:l", EventLPush),
+		keyspaceMsg("__key*", "__keyspace@1__:a", EventExpire),
+		keyspaceMsg("__key*", "__keyspace@1__:a", EventPersist),
+		keyspaceMsg("__key*", "__keyspace@1__:a", EventDel),
+	}, sub.Out.Pop())
+
+	n.Flags, _ = ParseNotifyFlags("Ex")
+	d.Set("b", []byte("1"), time.Now().Add(time.Millisecond).UnixNano())
+	<-time.After(2 * time.Millisecond)
+	d.Get("b")
+	assert.Equal(t, []*token.Token{keyspaceMsg("__key*", "__keyevent@1__:expired", "b")}, sub.Out.Pop())
+
+	// tombstones of keys deleted when blocked are not notified as expired
+	assert.Nil(t, d.Freeze())
+	d.Del("l")
+	assert.Nil(t, d.ToMove())
+	for i := 0; i < 10; i++ {
+		d.Get("l")
+	}
+	assert.Nil(t, sub.Out.Pop())
+}
@@ -0,0 +1,229 @@
+package model
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/glob"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// kinds of messages pushed to subscribers
+const (
+	MsgSubscribe    = "subscribe"
+	MsgUnsubscribe  = "unsubscribe"
+	MsgPSubscribe   = "psubscribe"
+	MsgPUnsubscribe = "punsubscribe"
+	MsgMessage      = "message"
+	MsgPMessage     = "pmessage"
+)
+
+// Outbox queues the tokens to be written to the connection of client.
+// Tokens are pushed by the processor and popped by the connection writer,
+// so that replies and messages published are written in order without
+// blocking the processor.
+type Outbox struct {
+	mu     sync.Mutex
+	ts     []*token.Token
+	notify chan struct{}
+	closed bool
+	corked bool
+}
+
+// NewOutbox returns an empty outbox
+func NewOutbox() *Outbox {
+	return &Outbox{notify: make(chan struct{}, 1)}
+}
+
+// Push appends tokens to the outbox and notifies the writer
+func (o *Outbox) Push(ts ...*token.Token) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.ts = append(o.ts, ts...)
+	if !o.corked {
+		o.signal()
+	}
+}
+
+// signal notifies the writer without blocking
+func (o *Outbox) signal() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Cork holds the tokens pushed until Uncork is called, so that replies of
+// pipelined requests are written at once.
+func (o *Outbox) Cork() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.corked = true
+}
+
+// Uncork notifies the writer of the tokens held
+func (o *Outbox) Uncork() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.corked = false
+	if !o.closed && len(o.ts) > 0 {
+		o.signal()
+	}
+}
+
+// Notify returns the channel notified when tokens are pushed, which is
+// closed after the outbox is closed.
+func (o *Outbox) Notify() <-chan struct{} {
+	return o.notify
+}
+
+// Pop returns and removes all the tokens in the outbox
+func (o *Outbox) Pop() []*token.Token {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ts := o.ts
+	o.ts = nil
+	return ts
+}
+
+// Close closes the outbox, tokens pushed later are discarded
+func (o *Outbox) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.closed {
+		o.closed = true
+		close(o.notify)
+	}
+}
+
+// SubInfo stores channels and patterns subscribed by the client
+type SubInfo struct {
+	Channels map[string]struct{}
+	Patterns map[string]struct{}
+}
+
+// NewSubInfo returns the info without subscriptions
+func NewSubInfo() *SubInfo {
+	return &SubInfo{Channels: make(map[string]struct{}), Patterns: make(map[string]struct{})}
+}
+
+// Count returns the number of channels and patterns subscribed
+func (s *SubInfo) Count() int {
+	return len(s.Channels) + len(s.Patterns)
+}
+
+// PubSub is the registry of subscriptions of all the clients
+type PubSub struct {
+	channels map[string]map[*Client]struct{}
+	patterns map[string]map[*Client]struct{}
+}
+
+// NewPubSub returns an empty registry
+func NewPubSub() *PubSub {
+	return &PubSub{
+		channels: make(map[string]map[*Client]struct{}),
+		patterns: make(map[string]map[*Client]struct{}),
+	}
+}
+
+func subscribe(m map[string]map[*Client]struct{}, own map[string]struct{}, cli *Client, key string) bool {
+	if _, ok := own[key]; ok {
+		return false
+	}
+	own[key] = struct{}{}
+	if m[key] == nil {
+		m[key] = make(map[*Client]struct{})
+	}
+	m[key][cli] = struct{}{}
+	return true
+}
+
+func unsubscribe(m map[string]map[*Client]struct{}, own map[string]struct{}, cli *Client, key string) bool {
+	if _, ok := own[key]; !ok {
+		return false
+	}
+	delete(own, key)
+	delete(m[key], cli)
+	if len(m[key]) == 0 {
+		delete(m, key)
+	}
+	return true
+}
+
+// Subscribe subscribes the channel, reports whether it is newly subscribed
+func (ps *PubSub) Subscribe(cli *Client, channel string) bool {
+	return subscribe(ps.channels, cli.Sub.Channels, cli, channel)
+}
+
+// Unsubscribe unsubscribes the channel, reports whether it was subscribed
+func (ps *PubSub) Unsubscribe(cli *Client, channel string) bool {
+	return unsubscribe(ps.channels, cli.Sub.Channels, cli, channel)
+}
+
+// PSubscribe subscribes the pattern, reports whether it is newly subscribed
+func (ps *PubSub) PSubscribe(cli *Client, pattern string) bool {
+	return subscribe(ps.patterns, cli.Sub.Patterns, cli, pattern)
+}
+
+// PUnsubscribe unsubscribes the pattern, reports whether it was subscribed
+func (ps *PubSub) PUnsubscribe(cli *Client, pattern string) bool {
+	return unsubscribe(ps.patterns, cli.Sub.Patterns, cli, pattern)
+}
+
+// UnsubscribeAll removes all the subscriptions of the client
+func (ps *PubSub) UnsubscribeAll(cli *Client) {
+	for channel := range cli.Sub.Channels {
+		ps.Unsubscribe(cli, channel)
+	}
+	for pattern := range cli.Sub.Patterns {
+		ps.PUnsubscribe(cli, pattern)
+	}
+}
+
+// Publish pushes the message to clients subscribing the channel or patterns
+// matching it, and returns the number of clients received.
+func (ps *PubSub) Publish(channel string, msg []byte) int {
+	n := 0
+	for cli := range ps.channels[channel] {
+		cli.Push(token.NewPush(token.NewBulked([]byte(MsgMessage)),
+			token.NewBulked([]byte(channel)), token.NewBulked(msg)))
+		n++
+	}
+	for pattern, clients := range ps.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for cli := range clients {
+			cli.Push(token.NewPush(token.NewBulked([]byte(MsgPMessage)), token.NewBulked([]byte(pattern)),
+				token.NewBulked([]byte(channel)), token.NewBulked(msg)))
+			n++
+		}
+	}
+	return n
+}
+
+// Channels returns the sorted active channels matching the pattern,
+// all the channels are returned if the pattern is empty.
+func (ps *PubSub) Channels(pattern string) []string {
+	channels := make([]string, 0)
+	for channel := range ps.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NumSub returns the number of subscribers of the channel
+func (ps *PubSub) NumSub(channel string) int {
+	return len(ps.channels[channel])
+}
+
+// NumPat returns the number of patterns subscribed
+func (ps *PubSub) NumPat() int {
+	return len(ps.patterns)
+}
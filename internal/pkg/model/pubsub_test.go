@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	o := NewOutbox()
+	o.Cork()
+	o.Push(token.ReplyOk)
+	o.Push(token.ReplyQueued)
+	select {
+	case <-o.Notify():
+		t.Fatal("notified when corked")
+	default:
+	}
+	o.Uncork()
+	<-o.Notify()
+	assert.Equal(t, []*token.Token{token.ReplyOk, token.ReplyQueued}, o.Pop())
+	o.Close()
+	o.Push(token.ReplyOk)
+	_, ok := <-o.Notify()
+	assert.False(t, ok)
+	assert.Nil(t, o.Pop())
+}
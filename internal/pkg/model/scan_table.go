@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+// minScanBuckets is the least number of buckets of the scan table
+const minScanBuckets = 4
+
+// scanTable indexes strings in buckets by their hash, so that they are
+// iterated incrementally by a cursor like the dictScan of redis. The number of
+// buckets is a power of two, which doubles when the load factor exceeds 1 and
+// halves when it falls below 1/8.
+type scanTable struct {
+	buckets [][]string
+	size    int
+}
+
+func newScanTable() *scanTable {
+	return &scanTable{buckets: make([][]string, minScanBuckets)}
+}
+
+// scanHash returns the FNV-1a hash of the string
+func scanHash(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// bucket returns the index of bucket of the string
+func (t *scanTable) bucket(s string) int {
+	return int(scanHash(s) & uint64(len(t.buckets)-1))
+}
+
+// clone returns a copy of the table
+func (t *scanTable) clone() *scanTable {
+	c := &scanTable{buckets: make([][]string, len(t.buckets)), size: t.size}
+	for i, b := range t.buckets {
+		c.buckets[i] = append([]string(nil), b...)
+	}
+	return c
+}
+
+// add puts the string in the table if it is not found
+func (t *scanTable) add(s string) {
+	i := t.bucket(s)
+	for _, e := range t.buckets[i] {
+		if e == s {
+			return
+		}
+	}
+	t.buckets[i] = append(t.buckets[i], s)
+	t.size++
+	if t.size > len(t.buckets) {
+		t.resize(len(t.buckets) * 2)
+	}
+}
+
+// remove deletes the string from the table if it is found
+func (t *scanTable) remove(s string) {
+	i := t.bucket(s)
+	b := t.buckets[i]
+	for j, e := range b {
+		if e != s {
+			continue
+		}
+		b[j] = b[len(b)-1]
+		b[len(b)-1] = ""
+		t.buckets[i] = b[:len(b)-1]
+		t.size--
+		if len(t.buckets) > minScanBuckets && t.size < len(t.buckets)/8 {
+			t.resize(len(t.buckets) / 2)
+		}
+		return
+	}
+}
+
+// random returns a random string of a random bucket not empty like the
+// dictGetRandomKey of redis, false if the table is empty. The load factor is
+// kept above 1/8, so that a few buckets are tried on average.
+func (t *scanTable) random() (string, bool) {
+	if t.size == 0 {
+		return "", false
+	}
+	for {
+		if b := t.buckets[rand.Intn(len(t.buckets))]; len(b) > 0 {
+			return b[rand.Intn(len(b))], true
+		}
+	}
+}
+
+// resize rehashes all the strings into n buckets
+func (t *scanTable) resize(n int) {
+	old := t.buckets
+	t.buckets = make([][]string, n)
+	for _, b := range old {
+		for _, s := range b {
+			i := t.bucket(s)
+			t.buckets[i] = append(t.buckets[i], s)
+		}
+	}
+}
+
+// scan calls fn with the strings of buckets starting from cursor until count
+// strings are visited or count*10 buckets are visited, and returns the cursor
+// of the next call, 0 if the iteration is finished. The cursor increments its
+// reversed bits, so that the buckets visited before are not visited again
+// after the table is resized. A string existing during the whole iteration is
+// visited at least once, and may be visited more than once if the table
+// shrinks. fn must not modify the table.
+func (t *scanTable) scan(cursor uint64, count int, fn func(s string)) uint64 {
+	if t.size == 0 {
+		return 0
+	}
+	mask := uint64(len(t.buckets) - 1)
+	for n, visits := 0, 0; ; visits++ {
+		for _, s := range t.buckets[cursor&mask] {
+			fn(s)
+			n++
+		}
+		cursor |= ^mask
+		cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+		if cursor == 0 || n >= count || (visits+1)/10 >= count {
+			return cursor
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanTable(t *testing.T) {
+	table := newScanTable()
+	assert.Equal(t, uint64(0), table.scan(0, 10, func(string) {}))
+	for i := 0; i < 100; i++ {
+		table.add(strconv.Itoa(i))
+		table.add(strconv.Itoa(i))
+	}
+	assert.Equal(t, 100, table.size)
+	assert.Equal(t, 128, len(table.buckets))
+
+	// strings existing during the whole scan are visited although the table
+	// grows and shrinks between calls
+	seen := make(map[string]bool)
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		visited := 0
+		cursor = table.scan(cursor, 10, func(s string) {
+			seen[s] = true
+			visited++
+		})
+		assert.Less(t, visited, 20)
+		switch calls {
+		case 2:
+			for i := 100; i < 1000; i++ {
+				table.add(strconv.Itoa(i))
+			}
+		case 5:
+			for i := 100; i < 1000; i++ {
+				table.remove(strconv.Itoa(i))
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := 0; i < 100; i++ {
+		assert.True(t, seen[strconv.Itoa(i)], i)
+	}
+
+	for i := 0; i < 100; i++ {
+		table.remove(strconv.Itoa(i))
+	}
+	table.remove("0")
+	assert.Equal(t, 0, table.size)
+	assert.Equal(t, minScanBuckets, len(table.buckets))
+}
+
+func TestDataStorage_Scan(t *testing.T) {
+	d := NewDataStorage()
+	d.Set("a", []byte("1"), 0)
+	d.Set("b", []byte("2"), 0)
+	assert.Nil(t, d.Freeze())
+	d.Del("a")
+	d.Set("c", []byte("3"), 0)
+	scanned := func() map[string]interface{} {
+		m := make(map[string]interface{})
+		var cursor uint64
+		for {
+			cursor = d.Scan(cursor, 1, func(key string, v interface{}) { m[key] = v })
+			if cursor == 0 {
+				return m
+			}
+		}
+	}
+	assert.Equal(t, map[string]interface{}{"b": []byte("2"), "c": []byte("3")}, scanned())
+	assert.Nil(t, d.ToMove())
+	for i := 0; i < 10; i++ {
+		d.Get("b")
+	}
+	assert.Equal(t, map[string]interface{}{"b": []byte("2"), "c": []byte("3")}, scanned())
+	assert.Equal(t, 2, d.keys.size)
+	d.Flush()
+	assert.Equal(t, map[string]interface{}{}, scanned())
+}
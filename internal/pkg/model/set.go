@@ -0,0 +1,189 @@
+package model
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// encodings of the set
+const (
+	EncIntSet   = "intset"
+	EncHashSet  = "hashtable"
+	intSetLimit = 512
+)
+
+// Set is the unordered collection of distinct members stored. Members are
+// kept in a sorted integer slice if all of them are integers and the number
+// of them does not exceed intSetLimit, otherwise in a hash set indexed by the
+// scan table.
+type Set struct {
+	ints  []int64
+	m     map[string]struct{}
+	table *scanTable
+}
+
+// NewSet returns an empty set of the integer set encoding
+func NewSet() *Set {
+	return &Set{}
+}
+
+// Encoding returns the encoding of the set
+func (s *Set) Encoding() string {
+	if s.m != nil {
+		return EncHashSet
+	}
+	return EncIntSet
+}
+
+// Len returns the number of members in the set
+func (s *Set) Len() int {
+	if s.m != nil {
+		return len(s.m)
+	}
+	return len(s.ints)
+}
+
+// Clone returns a copy of the set
+func (s *Set) Clone() interface{} {
+	c := &Set{}
+	if s.m != nil {
+		c.m = make(map[string]struct{}, len(s.m))
+		for k := range s.m {
+			c.m[k] = struct{}{}
+		}
+		c.table = s.table.clone()
+	} else {
+		c.ints = append([]int64(nil), s.ints...)
+	}
+	return c
+}
+
+// toInt returns the integer of member if the member is the canonical form
+// of it, e.g. "1" but not "01" or "+1".
+func toInt(member string) (int64, bool) {
+	n, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != member {
+		return 0, false
+	}
+	return n, true
+}
+
+// search returns the position of n in the integer set, and whether it exists
+func (s *Set) search(n int64) (int, bool) {
+	i := sort.Search(len(s.ints), func(i int) bool { return s.ints[i] >= n })
+	return i, i < len(s.ints) && s.ints[i] == n
+}
+
+// convert converts the set to the hash set encoding
+func (s *Set) convert() {
+	s.m = make(map[string]struct{}, len(s.ints)+1)
+	s.table = newScanTable()
+	for _, n := range s.ints {
+		member := strconv.FormatInt(n, 10)
+		s.m[member] = struct{}{}
+		s.table.add(member)
+	}
+	s.ints = nil
+}
+
+// Add adds the member, reports whether the member is newly added
+func (s *Set) Add(member string) bool {
+	if s.m == nil {
+		n, ok := toInt(member)
+		if ok {
+			i, found := s.search(n)
+			if found {
+				return false
+			}
+			if len(s.ints) < intSetLimit {
+				s.ints = append(s.ints, 0)
+				copy(s.ints[i+1:], s.ints[i:])
+				s.ints[i] = n
+				return true
+			}
+		}
+		s.convert()
+	}
+	if _, ok := s.m[member]; ok {
+		return false
+	}
+	s.m[member] = struct{}{}
+	s.table.add(member)
+	return true
+}
+
+// Remove removes the member, reports whether the member existed
+func (s *Set) Remove(member string) bool {
+	if s.m != nil {
+		_, ok := s.m[member]
+		if ok {
+			delete(s.m, member)
+			s.table.remove(member)
+		}
+		return ok
+	}
+	n, ok := toInt(member)
+	if !ok {
+		return false
+	}
+	i, found := s.search(n)
+	if found {
+		s.ints = append(s.ints[:i], s.ints[i+1:]...)
+	}
+	return found
+}
+
+// Has reports whether the member exists
+func (s *Set) Has(member string) bool {
+	if s.m != nil {
+		_, ok := s.m[member]
+		return ok
+	}
+	n, ok := toInt(member)
+	if !ok {
+		return false
+	}
+	_, found := s.search(n)
+	return found
+}
+
+// Members returns all the members, in ascending order for the integer set
+// and in random order for the hash set.
+func (s *Set) Members() []string {
+	members := make([]string, 0, s.Len())
+	if s.m != nil {
+		for k := range s.m {
+			members = append(members, k)
+		}
+		return members
+	}
+	for _, n := range s.ints {
+		members = append(members, strconv.FormatInt(n, 10))
+	}
+	return members
+}
+
+// Random returns a random member, false if the set is empty
+func (s *Set) Random() (string, bool) {
+	if s.m != nil {
+		return s.table.random()
+	}
+	if len(s.ints) == 0 {
+		return "", false
+	}
+	return strconv.FormatInt(s.ints[rand.Intn(len(s.ints))], 10), true
+}
+
+// Scan calls fn with about count members from cursor, and returns the cursor
+// to continue, 0 if the scan is finished. The integer set is small enough to
+// be scanned at once like redis does.
+func (s *Set) Scan(cursor uint64, count int, fn func(member string)) uint64 {
+	if s.m != nil {
+		return s.table.scan(cursor, count, fn)
+	}
+	for _, n := range s.ints {
+		fn(strconv.FormatInt(n, 10))
+	}
+	return 0
+}
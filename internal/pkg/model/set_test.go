@@ -0,0 +1,77 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet_IntSet(t *testing.T) {
+	s := NewSet()
+	assert.True(t, s.Add("3"))
+	assert.True(t, s.Add("-1"))
+	assert.True(t, s.Add("2"))
+	assert.False(t, s.Add("2"))
+	assert.Equal(t, EncIntSet, s.Encoding())
+	assert.Equal(t, []string{"-1", "2", "3"}, s.Members())
+	assert.True(t, s.Has("2"))
+	assert.False(t, s.Has("02"))
+	assert.False(t, s.Remove("a"))
+	assert.True(t, s.Remove("2"))
+	assert.False(t, s.Has("2"))
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestSet_Convert(t *testing.T) {
+	s := NewSet()
+	s.Add("1")
+	assert.True(t, s.Add("01"))
+	assert.Equal(t, EncHashSet, s.Encoding())
+	assert.True(t, s.Has("1"))
+	assert.True(t, s.Has("01"))
+	members := s.Members()
+	sort.Strings(members)
+	assert.Equal(t, []string{"01", "1"}, members)
+
+	s = NewSet()
+	for i := 0; i < intSetLimit; i++ {
+		s.Add(strconv.Itoa(i))
+	}
+	assert.Equal(t, EncIntSet, s.Encoding())
+	s.Add(strconv.Itoa(intSetLimit))
+	assert.Equal(t, EncHashSet, s.Encoding())
+	assert.Equal(t, intSetLimit+1, s.Len())
+}
+
+func TestSet_Random(t *testing.T) {
+	s := NewSet()
+	_, ok := s.Random()
+	assert.False(t, ok)
+	s.Add("1")
+	m, ok := s.Random()
+	assert.True(t, ok)
+	assert.Equal(t, "1", m)
+	for i := 0; i < 1000; i++ {
+		s.Add("m" + strconv.Itoa(i))
+	}
+	for i := 0; i < 1000; i++ {
+		s.Remove("m" + strconv.Itoa(i))
+	}
+	// buckets left empty after removals are skipped
+	m, ok = s.Random()
+	assert.True(t, ok)
+	assert.Equal(t, "1", m)
+}
+
+func TestSet_Clone(t *testing.T) {
+	s := NewSet()
+	s.Add("1")
+	c := s.Clone().(*Set)
+	c.Add("2")
+	c.Add("a")
+	assert.Equal(t, 1, s.Len())
+	assert.Equal(t, EncIntSet, s.Encoding())
+	assert.Equal(t, 3, c.Len())
+}
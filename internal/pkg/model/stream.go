@@ -0,0 +1,553 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// streamNodeSize is the max number of entries a stream node holds
+const streamNodeSize = 100
+
+// StreamID is the ID of stream entries, which are ordered by the millisecond
+// time and then the sequence number.
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// MaxStreamID is the greatest ID of streams
+var MaxStreamID = StreamID{math.MaxUint64, math.MaxUint64}
+
+// Compare returns -1, 0 or 1 if id is less than, equal to or greater than o
+func (id StreamID) Compare(o StreamID) int {
+	switch {
+	case id.Ms < o.Ms || (id.Ms == o.Ms && id.Seq < o.Seq):
+		return -1
+	case id == o:
+		return 0
+	}
+	return 1
+}
+
+// Less reports whether id is less than o
+func (id StreamID) Less(o StreamID) bool {
+	return id.Compare(o) < 0
+}
+
+// IsZero reports whether id is 0-0
+func (id StreamID) IsZero() bool {
+	return id.Ms == 0 && id.Seq == 0
+}
+
+// Next returns the ID following id, false if id is the greatest one
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{id.Ms, id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{id.Ms + 1, 0}, true
+	}
+	return id, false
+}
+
+// Prev returns the ID preceding id, false if id is 0-0
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{id.Ms, id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{id.Ms - 1, math.MaxUint64}, true
+	}
+	return id, false
+}
+
+func (id StreamID) String() string {
+	return fmt.Sprintf("%d-%d", id.Ms, id.Seq)
+}
+
+// StreamEntry is the entry of stream, fields and values are stored in turn
+type StreamEntry struct {
+	ID     StreamID
+	Fields [][]byte
+}
+
+type streamNode struct {
+	entries []StreamEntry
+}
+
+func (n *streamNode) last() StreamID {
+	return n.entries[len(n.entries)-1].ID
+}
+
+// Stream is the append-only log of entries ordered by ID. Like the radix tree
+// of listpacks in redis, entries are kept in nodes holding streamNodeSize
+// entries at most, which are ordered by ID, so that an entry is found by the
+// binary search of nodes and then of entries in the node.
+type Stream struct {
+	nodes  []*streamNode
+	length int
+	// LastID is the ID of the last entry ever added
+	LastID StreamID
+	// MaxDeletedID is the greatest ID of entries deleted by XDEL
+	MaxDeletedID StreamID
+	// EntriesAdded is the number of entries ever added
+	EntriesAdded int64
+	groups       map[string]*ConsumerGroup
+}
+
+// NewStream returns an empty stream
+func NewStream() *Stream {
+	return &Stream{groups: make(map[string]*ConsumerGroup)}
+}
+
+// Len returns the number of entries in the stream
+func (s *Stream) Len() int {
+	return s.length
+}
+
+// Nodes returns the number of nodes holding entries
+func (s *Stream) Nodes() int {
+	return len(s.nodes)
+}
+
+// Clone returns a copy of the stream and its consumer groups, fields of
+// entries are shared since they are never modified in place.
+func (s *Stream) Clone() interface{} {
+	c := *s
+	c.nodes = make([]*streamNode, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		c.nodes = append(c.nodes, &streamNode{entries: append([]StreamEntry(nil), n.entries...)})
+	}
+	c.groups = make(map[string]*ConsumerGroup, len(s.groups))
+	for name, g := range s.groups {
+		c.groups[name] = g.clone()
+	}
+	return &c
+}
+
+// Append adds the entry at the tail, the ID must be greater than LastID
+func (s *Stream) Append(id StreamID, fields [][]byte) {
+	if len(s.nodes) == 0 || len(s.nodes[len(s.nodes)-1].entries) >= streamNodeSize {
+		s.nodes = append(s.nodes, &streamNode{entries: make([]StreamEntry, 0, streamNodeSize)})
+	}
+	n := s.nodes[len(s.nodes)-1]
+	n.entries = append(n.entries, StreamEntry{ID: id, Fields: fields})
+	s.length++
+	s.LastID = id
+	s.EntriesAdded++
+}
+
+// seek returns the position of the first entry whose ID is not less than id
+func (s *Stream) seek(id StreamID) (int, int) {
+	i := sort.Search(len(s.nodes), func(i int) bool { return !s.nodes[i].last().Less(id) })
+	if i == len(s.nodes) {
+		return i, 0
+	}
+	entries := s.nodes[i].entries
+	return i, sort.Search(len(entries), func(j int) bool { return !entries[j].ID.Less(id) })
+}
+
+// Get returns the entry of id and whether it exists
+func (s *Stream) Get(id StreamID) (StreamEntry, bool) {
+	i, j := s.seek(id)
+	if i < len(s.nodes) && s.nodes[i].entries[j].ID == id {
+		return s.nodes[i].entries[j], true
+	}
+	return StreamEntry{}, false
+}
+
+// First returns the first entry, false if the stream is empty
+func (s *Stream) First() (StreamEntry, bool) {
+	if s.length == 0 {
+		return StreamEntry{}, false
+	}
+	return s.nodes[0].entries[0], true
+}
+
+// Last returns the last entry, false if the stream is empty
+func (s *Stream) Last() (StreamEntry, bool) {
+	if s.length == 0 {
+		return StreamEntry{}, false
+	}
+	n := s.nodes[len(s.nodes)-1]
+	return n.entries[len(n.entries)-1], true
+}
+
+// Range returns the entries whose ID is between start and end inclusively,
+// in reversed order if rev. At most count entries are returned if count is
+// positive.
+func (s *Stream) Range(start, end StreamID, count int, rev bool) []StreamEntry {
+	var entries []StreamEntry
+	if end.Less(start) {
+		return entries
+	}
+	full := func() bool { return count > 0 && len(entries) >= count }
+	if !rev {
+		for i, j := s.seek(start); i < len(s.nodes) && !full(); {
+			e := s.nodes[i].entries[j]
+			if end.Less(e.ID) {
+				break
+			}
+			entries = append(entries, e)
+			if j++; j == len(s.nodes[i].entries) {
+				i, j = i+1, 0
+			}
+		}
+		return entries
+	}
+	// the position after the last entry not greater than end
+	i, j := len(s.nodes), 0
+	if next, ok := end.Next(); ok {
+		i, j = s.seek(next)
+	}
+	for !full() {
+		if j == 0 {
+			if i--; i < 0 {
+				break
+			}
+			j = len(s.nodes[i].entries)
+		}
+		j--
+		e := s.nodes[i].entries[j]
+		if e.ID.Less(start) {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// removeNode removes the node at index i
+func (s *Stream) removeNode(i int) {
+	s.length -= len(s.nodes[i].entries)
+	s.nodes = append(s.nodes[:i], s.nodes[i+1:]...)
+}
+
+// Delete removes the entry of id, reports whether the entry existed
+func (s *Stream) Delete(id StreamID) bool {
+	i, j := s.seek(id)
+	if i == len(s.nodes) || s.nodes[i].entries[j].ID != id {
+		return false
+	}
+	n := s.nodes[i]
+	if len(n.entries) == 1 {
+		s.removeNode(i)
+	} else {
+		n.entries = append(n.entries[:j], n.entries[j+1:]...)
+		s.length--
+	}
+	if s.MaxDeletedID.Less(id) {
+		s.MaxDeletedID = id
+	}
+	return true
+}
+
+// trim removes the entries from the head, count returns the number of
+// entries of the node to remove. Only whole nodes are removed if approx,
+// and at most limit entries are removed if limit is positive. It returns
+// the number of entries removed.
+func (s *Stream) trim(count func(n *streamNode) int, approx bool, limit int64) int64 {
+	var removed int64
+	for len(s.nodes) > 0 {
+		n := s.nodes[0]
+		c := count(n)
+		if c <= 0 {
+			break
+		}
+		if c >= len(n.entries) {
+			if limit > 0 && removed+int64(len(n.entries)) > limit {
+				break
+			}
+			removed += int64(len(n.entries))
+			s.removeNode(0)
+			continue
+		}
+		if approx {
+			break
+		}
+		n.entries = append(n.entries[:0:0], n.entries[c:]...)
+		s.length -= c
+		removed += int64(c)
+		break
+	}
+	return removed
+}
+
+// TrimMaxLen removes the entries from the head until the length is not
+// greater than maxLen, returns the number of entries removed.
+func (s *Stream) TrimMaxLen(maxLen int64, approx bool, limit int64) int64 {
+	return s.trim(func(n *streamNode) int {
+		c := int64(s.length) - maxLen
+		if c > int64(len(n.entries)) {
+			c = int64(len(n.entries))
+		}
+		return int(c)
+	}, approx, limit)
+}
+
+// TrimMinID removes the entries whose ID is less than minID, returns the
+// number of entries removed.
+func (s *Stream) TrimMinID(minID StreamID, approx bool, limit int64) int64 {
+	return s.trim(func(n *streamNode) int {
+		return sort.Search(len(n.entries), func(i int) bool { return !n.entries[i].ID.Less(minID) })
+	}, approx, limit)
+}
+
+// HasTombstones reports whether entries after start were deleted by XDEL
+func (s *Stream) HasTombstones(start StreamID) bool {
+	if s.length == 0 || s.MaxDeletedID.IsZero() {
+		return false
+	}
+	return !s.MaxDeletedID.Less(start)
+}
+
+// EstimateEntriesRead returns the logical counter of the entry of id, that
+// is the number of entries added before it and itself, -1 if unknown.
+func (s *Stream) EstimateEntriesRead(id StreamID) int64 {
+	if s.EntriesAdded == 0 {
+		return 0
+	}
+	cmpLast := id.Compare(s.LastID)
+	if s.length == 0 && cmpLast <= 0 {
+		return s.EntriesAdded
+	}
+	if cmpLast == 0 {
+		return s.EntriesAdded
+	} else if cmpLast > 0 {
+		return -1
+	}
+	first, _ := s.First()
+	if s.MaxDeletedID.IsZero() || s.MaxDeletedID.Less(first.ID) {
+		// no entry deleted after the first one
+		switch id.Compare(first.ID) {
+		case -1:
+			return s.EntriesAdded - int64(s.length)
+		case 0:
+			return s.EntriesAdded - int64(s.length) + 1
+		}
+	}
+	return -1
+}
+
+// Lag returns the number of entries not yet delivered to the group, false
+// if it is unknown.
+func (s *Stream) Lag(g *ConsumerGroup) (int64, bool) {
+	if s.EntriesAdded == 0 {
+		return 0, true
+	}
+	if g.EntriesRead >= 0 && !s.HasTombstones(g.LastID) {
+		return s.EntriesAdded - g.EntriesRead, true
+	}
+	if read := s.EstimateEntriesRead(g.LastID); read >= 0 {
+		return s.EntriesAdded - read, true
+	}
+	return 0, false
+}
+
+// Advance moves the last delivered ID of the group to the entry of id
+// delivered, and updates the counter of entries read.
+func (s *Stream) Advance(g *ConsumerGroup, id StreamID) {
+	if g.EntriesRead >= 0 && !s.HasTombstones(id) {
+		g.EntriesRead++
+	} else if s.EntriesAdded > 0 {
+		g.EntriesRead = s.EstimateEntriesRead(id)
+	}
+	g.LastID = id
+}
+
+// Group returns the consumer group of name, nil if not found
+func (s *Stream) Group(name string) *ConsumerGroup {
+	return s.groups[name]
+}
+
+// CreateGroup creates the consumer group whose last delivered ID is id,
+// nil is returned if the group exists.
+func (s *Stream) CreateGroup(name string, id StreamID, entriesRead int64) *ConsumerGroup {
+	if _, ok := s.groups[name]; ok {
+		return nil
+	}
+	g := &ConsumerGroup{Name: name, LastID: id, EntriesRead: entriesRead, consumers: make(map[string]*Consumer)}
+	s.groups[name] = g
+	return g
+}
+
+// DestroyGroup removes the consumer group, reports whether it existed
+func (s *Stream) DestroyGroup(name string) bool {
+	_, ok := s.groups[name]
+	delete(s.groups, name)
+	return ok
+}
+
+// Groups returns the consumer groups ordered by name
+func (s *Stream) Groups() []*ConsumerGroup {
+	groups := make([]*ConsumerGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// PendingEntry is the entry delivered to the consumer but not acknowledged
+type PendingEntry struct {
+	ID       StreamID
+	Consumer *Consumer
+	// unix time in milliseconds of the last delivery
+	DeliveryTime  int64
+	DeliveryCount int64
+}
+
+// Consumer is the member of consumer group. SeenTime is the unix time in
+// milliseconds of the last interaction, and ActiveTime is the one of the
+// last successful interaction, -1 if never.
+type Consumer struct {
+	Name       string
+	SeenTime   int64
+	ActiveTime int64
+	pending    int
+}
+
+// Pending returns the number of entries pending of the consumer
+func (c *Consumer) Pending() int {
+	return c.pending
+}
+
+// ConsumerGroup tracks the entries delivered to its consumers. EntriesRead
+// is the logical counter of the last entry delivered, -1 if unknown.
+type ConsumerGroup struct {
+	Name        string
+	LastID      StreamID
+	EntriesRead int64
+	// pending entries ordered by ID
+	pel       []*PendingEntry
+	consumers map[string]*Consumer
+}
+
+func (g *ConsumerGroup) clone() *ConsumerGroup {
+	c := &ConsumerGroup{Name: g.Name, LastID: g.LastID, EntriesRead: g.EntriesRead,
+		pel: make([]*PendingEntry, 0, len(g.pel)), consumers: make(map[string]*Consumer, len(g.consumers))}
+	for name, consumer := range g.consumers {
+		cc := *consumer
+		c.consumers[name] = &cc
+	}
+	for _, pe := range g.pel {
+		pc := *pe
+		pc.Consumer = c.consumers[pe.Consumer.Name]
+		c.pel = append(c.pel, &pc)
+	}
+	return c
+}
+
+// Consumer returns the consumer of name, nil if not found
+func (g *ConsumerGroup) Consumer(name string) *Consumer {
+	return g.consumers[name]
+}
+
+// CreateConsumer creates the consumer, nil is returned if it exists
+func (g *ConsumerGroup) CreateConsumer(name string, now int64) *Consumer {
+	if _, ok := g.consumers[name]; ok {
+		return nil
+	}
+	c := &Consumer{Name: name, SeenTime: now, ActiveTime: -1}
+	g.consumers[name] = c
+	return c
+}
+
+// DelConsumer removes the consumer and its pending entries, returns the
+// number of entries pending, -1 if the consumer does not exist.
+func (g *ConsumerGroup) DelConsumer(name string) int {
+	c, ok := g.consumers[name]
+	if !ok {
+		return -1
+	}
+	pel := g.pel[:0]
+	for _, pe := range g.pel {
+		if pe.Consumer != c {
+			pel = append(pel, pe)
+		}
+	}
+	g.pel = pel
+	delete(g.consumers, name)
+	return c.pending
+}
+
+// Consumers returns the consumers ordered by name
+func (g *ConsumerGroup) Consumers() []*Consumer {
+	consumers := make([]*Consumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		consumers = append(consumers, c)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+	return consumers
+}
+
+// PendingLen returns the number of entries pending of the group
+func (g *ConsumerGroup) PendingLen() int {
+	return len(g.pel)
+}
+
+// seekPending returns the index of the first pending entry whose ID is
+// not less than id
+func (g *ConsumerGroup) seekPending(id StreamID) int {
+	return sort.Search(len(g.pel), func(i int) bool { return !g.pel[i].ID.Less(id) })
+}
+
+// GetPending returns the pending entry of id, nil if not found
+func (g *ConsumerGroup) GetPending(id StreamID) *PendingEntry {
+	if i := g.seekPending(id); i < len(g.pel) && g.pel[i].ID == id {
+		return g.pel[i]
+	}
+	return nil
+}
+
+// PendingRange returns the pending entries whose ID is between start and end
+// inclusively, of the consumer if it is not nil. At most count entries are
+// returned if count is positive.
+func (g *ConsumerGroup) PendingRange(start, end StreamID, c *Consumer, count int) []*PendingEntry {
+	var pes []*PendingEntry
+	for _, pe := range g.pel[g.seekPending(start):] {
+		if end.Less(pe.ID) || (count > 0 && len(pes) >= count) {
+			break
+		}
+		if c == nil || pe.Consumer == c {
+			pes = append(pes, pe)
+		}
+	}
+	return pes
+}
+
+// Deliver assigns the entry of id to the consumer, the delivery count is
+// reset if the entry is pending of another consumer.
+func (g *ConsumerGroup) Deliver(id StreamID, c *Consumer, now int64) *PendingEntry {
+	i := g.seekPending(id)
+	if i < len(g.pel) && g.pel[i].ID == id {
+		pe := g.pel[i]
+		g.Claim(pe, c)
+		pe.DeliveryTime, pe.DeliveryCount = now, 1
+		return pe
+	}
+	pe := &PendingEntry{ID: id, Consumer: c, DeliveryTime: now, DeliveryCount: 1}
+	g.pel = append(g.pel, nil)
+	copy(g.pel[i+1:], g.pel[i:])
+	g.pel[i] = pe
+	c.pending++
+	return pe
+}
+
+// Claim transfers the pending entry to the consumer
+func (g *ConsumerGroup) Claim(pe *PendingEntry, c *Consumer) {
+	pe.Consumer.pending--
+	pe.Consumer = c
+	c.pending++
+}
+
+// Ack removes the pending entry of id, reports whether it was pending
+func (g *ConsumerGroup) Ack(id StreamID) bool {
+	i := g.seekPending(id)
+	if i == len(g.pel) || g.pel[i].ID != id {
+		return false
+	}
+	g.pel[i].Consumer.pending--
+	g.pel = append(g.pel[:i], g.pel[i+1:]...)
+	return true
+}
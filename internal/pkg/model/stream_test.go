@@ -0,0 +1,175 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func streamOf(n int) *Stream {
+	s := NewStream()
+	for i := 1; i <= n; i++ {
+		s.Append(StreamID{uint64(i), 0}, [][]byte{[]byte("f"), []byte("v")})
+	}
+	return s
+}
+
+func idsOf(entries []StreamEntry) []uint64 {
+	ids := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID.Ms)
+	}
+	return ids
+}
+
+func TestStreamID(t *testing.T) {
+	assert.True(t, StreamID{1, 2}.Less(StreamID{2, 0}))
+	assert.True(t, StreamID{1, 2}.Less(StreamID{1, 3}))
+	assert.Equal(t, 0, StreamID{1, 2}.Compare(StreamID{1, 2}))
+	assert.Equal(t, "1-2", StreamID{1, 2}.String())
+	next, ok := StreamID{1, math.MaxUint64}.Next()
+	assert.True(t, ok)
+	assert.Equal(t, StreamID{2, 0}, next)
+	_, ok = MaxStreamID.Next()
+	assert.False(t, ok)
+	prev, ok := StreamID{2, 0}.Prev()
+	assert.True(t, ok)
+	assert.Equal(t, StreamID{1, math.MaxUint64}, prev)
+	_, ok = StreamID{}.Prev()
+	assert.False(t, ok)
+}
+
+func TestStream_Range(t *testing.T) {
+	s := streamOf(250)
+	assert.Equal(t, 250, s.Len())
+	assert.Equal(t, 3, s.Nodes())
+	assert.Equal(t, StreamID{250, 0}, s.LastID)
+	assert.Equal(t, 250, len(s.Range(StreamID{}, MaxStreamID, 0, false)))
+	assert.Equal(t, []uint64{99, 100, 101, 102}, idsOf(s.Range(StreamID{99, 0}, StreamID{102, 0}, 0, false)))
+	assert.Equal(t, []uint64{102, 101, 100}, idsOf(s.Range(StreamID{99, 0}, StreamID{102, 0}, 3, true)))
+	assert.Equal(t, []uint64{250, 249}, idsOf(s.Range(StreamID{}, MaxStreamID, 2, true)))
+	assert.Equal(t, []uint64{1}, idsOf(s.Range(StreamID{}, StreamID{1, 0}, 0, true)))
+	assert.Empty(t, s.Range(StreamID{3, 0}, StreamID{2, 0}, 0, false))
+	assert.Empty(t, s.Range(StreamID{251, 0}, MaxStreamID, 0, true))
+
+	e, ok := s.Get(StreamID{100, 0})
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("f"), []byte("v")}, e.Fields)
+	_, ok = s.Get(StreamID{100, 1})
+	assert.False(t, ok)
+}
+
+func TestStream_Delete(t *testing.T) {
+	s := streamOf(150)
+	assert.True(t, s.Delete(StreamID{50, 0}))
+	assert.False(t, s.Delete(StreamID{50, 0}))
+	assert.Equal(t, StreamID{50, 0}, s.MaxDeletedID)
+	for i := 101; i <= 150; i++ {
+		assert.True(t, s.Delete(StreamID{uint64(i), 0}))
+	}
+	assert.Equal(t, 99, s.Len())
+	assert.Equal(t, 1, s.Nodes())
+	last, _ := s.Last()
+	assert.Equal(t, StreamID{100, 0}, last.ID)
+	assert.Equal(t, StreamID{150, 0}, s.LastID)
+	assert.Equal(t, int64(150), s.EntriesAdded)
+	assert.True(t, s.HasTombstones(StreamID{}))
+}
+
+func TestStream_Trim(t *testing.T) {
+	s := streamOf(250)
+	// whole nodes are removed only if approx
+	assert.Equal(t, int64(100), s.TrimMaxLen(120, true, 0))
+	assert.Equal(t, 150, s.Len())
+	assert.Equal(t, int64(30), s.TrimMaxLen(120, false, 0))
+	first, _ := s.First()
+	assert.Equal(t, StreamID{131, 0}, first.ID)
+	assert.Equal(t, int64(0), s.TrimMinID(StreamID{201, 0}, true, 50))
+	assert.Equal(t, int64(70), s.TrimMinID(StreamID{201, 0}, true, 0))
+	assert.Equal(t, int64(10), s.TrimMinID(StreamID{211, 0}, false, 0))
+	assert.Equal(t, 40, s.Len())
+	assert.True(t, s.MaxDeletedID.IsZero())
+	assert.Equal(t, int64(40), s.TrimMaxLen(0, false, 0))
+	assert.Equal(t, 0, s.Nodes())
+}
+
+func TestStream_Clone(t *testing.T) {
+	s := streamOf(10)
+	g := s.CreateGroup("g", StreamID{}, 0)
+	g.Deliver(StreamID{1, 0}, g.CreateConsumer("c", 0), 0)
+	c := s.Clone().(*Stream)
+	s.Delete(StreamID{1, 0})
+	s.Append(StreamID{11, 0}, nil)
+	g.Ack(StreamID{1, 0})
+	assert.Equal(t, 10, c.Len())
+	assert.Equal(t, StreamID{10, 0}, c.LastID)
+	assert.Equal(t, 1, c.Group("g").PendingLen())
+	assert.Equal(t, 1, c.Group("g").Consumer("c").Pending())
+	assert.Equal(t, 0, g.Consumer("c").Pending())
+}
+
+func TestStream_EntriesRead(t *testing.T) {
+	s := streamOf(10)
+	g := s.CreateGroup("g", StreamID{}, -1)
+	lag, ok := s.Lag(g)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), lag)
+	s.Advance(g, StreamID{1, 0})
+	assert.Equal(t, int64(1), g.EntriesRead)
+	s.Advance(g, StreamID{2, 0})
+	assert.Equal(t, int64(2), g.EntriesRead)
+	lag, _ = s.Lag(g)
+	assert.Equal(t, int64(8), lag)
+	// the counter is unknown after entries deleted in the middle
+	s.Delete(StreamID{5, 0})
+	assert.Equal(t, int64(-1), s.EstimateEntriesRead(StreamID{3, 0}))
+	_, ok = s.Lag(s.CreateGroup("other", StreamID{3, 0}, -1))
+	assert.False(t, ok)
+	assert.Equal(t, int64(10), s.EstimateEntriesRead(StreamID{10, 0}))
+	assert.Equal(t, int64(-1), s.EstimateEntriesRead(StreamID{11, 0}))
+	assert.Equal(t, []*ConsumerGroup{g, s.Group("other")}, s.Groups())
+	assert.Nil(t, s.CreateGroup("g", StreamID{}, 0))
+	assert.True(t, s.DestroyGroup("other"))
+	assert.False(t, s.DestroyGroup("other"))
+}
+
+func TestConsumerGroup_Pending(t *testing.T) {
+	g := NewStream().CreateGroup("g", StreamID{}, 0)
+	alice := g.CreateConsumer("alice", 1)
+	bob := g.CreateConsumer("bob", 1)
+	assert.Nil(t, g.CreateConsumer("bob", 2))
+	assert.Equal(t, int64(-1), alice.ActiveTime)
+	for i := 5; i > 0; i-- {
+		c := alice
+		if i%2 == 0 {
+			c = bob
+		}
+		g.Deliver(StreamID{uint64(i), 0}, c, int64(i))
+	}
+	assert.Equal(t, 5, g.PendingLen())
+	assert.Equal(t, 3, alice.Pending())
+	pes := g.PendingRange(StreamID{}, MaxStreamID, bob, 0)
+	assert.Equal(t, 2, len(pes))
+	assert.Equal(t, StreamID{2, 0}, pes[0].ID)
+	assert.Equal(t, 2, len(g.PendingRange(StreamID{2, 0}, StreamID{3, 0}, nil, 0)))
+	assert.Equal(t, 1, len(g.PendingRange(StreamID{}, MaxStreamID, nil, 1)))
+
+	// the entry delivered again is reassigned
+	pe := g.GetPending(StreamID{1, 0})
+	pe.DeliveryCount = 3
+	g.Deliver(StreamID{1, 0}, bob, 10)
+	assert.Equal(t, bob, pe.Consumer)
+	assert.Equal(t, int64(1), pe.DeliveryCount)
+	assert.Equal(t, 3, bob.Pending())
+	g.Claim(pe, alice)
+	assert.Equal(t, 3, alice.Pending())
+
+	assert.True(t, g.Ack(StreamID{1, 0}))
+	assert.False(t, g.Ack(StreamID{1, 0}))
+	assert.Nil(t, g.GetPending(StreamID{1, 0}))
+	assert.Equal(t, 2, g.DelConsumer("bob"))
+	assert.Equal(t, -1, g.DelConsumer("bob"))
+	assert.Equal(t, 2, g.PendingLen())
+	assert.Equal(t, []*Consumer{alice}, g.Consumers())
+}
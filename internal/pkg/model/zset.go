@@ -0,0 +1,458 @@
+package model
+
+import (
+	"math/rand"
+)
+
+const (
+	zslMaxLevel = 32
+	zslP        = 0.25
+)
+
+// ZItem is the member-score pair of the sorted set
+type ZItem struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange is the range of scores, bounds are excluded if MinEx or MaxEx
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r *ScoreRange) gteMin(score float64) bool {
+	if r.MinEx {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r *ScoreRange) lteMax(score float64) bool {
+	if r.MaxEx {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+func (r *ScoreRange) empty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx))
+}
+
+// LexBound is the bound of lexicographical range. Inf is -1 for the
+// negative infinity "-", 1 for the positive infinity "+", or 0 for Value.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// LexRange is the lexicographical range of members
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r *LexRange) gteMin(member string) bool {
+	switch {
+	case r.Min.Inf < 0:
+		return true
+	case r.Min.Inf > 0:
+		return false
+	case r.Min.Exclusive:
+		return member > r.Min.Value
+	}
+	return member >= r.Min.Value
+}
+
+func (r *LexRange) lteMax(member string) bool {
+	switch {
+	case r.Max.Inf > 0:
+		return true
+	case r.Max.Inf < 0:
+		return false
+	case r.Max.Exclusive:
+		return member < r.Max.Value
+	}
+	return member <= r.Max.Value
+}
+
+func (r *LexRange) empty() bool {
+	if r.Min.Inf > 0 || r.Max.Inf < 0 {
+		return true
+	}
+	if r.Min.Inf < 0 || r.Max.Inf > 0 {
+		return false
+	}
+	return r.Min.Value > r.Max.Value ||
+		(r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+type zslLevel struct {
+	forward *zslNode
+	span    int
+}
+
+type zslNode struct {
+	ZItem
+	backward *zslNode
+	level    []zslLevel
+}
+
+// less reports whether the node is ordered before the score-member pair
+func (n *zslNode) less(score float64, member string) bool {
+	return n.Score < score || (n.Score == score && n.Member < member)
+}
+
+// skipList orders the items by score and then member, the span of each level
+// records the number of nodes skipped to calculate the rank.
+type skipList struct {
+	header, tail *zslNode
+	length       int
+	level        int
+}
+
+func newSkipList() *skipList {
+	return &skipList{header: &zslNode{level: make([]zslLevel, zslMaxLevel)}, level: 1}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < zslMaxLevel && rand.Float64() < zslP {
+		level++
+	}
+	return level
+}
+
+func (l *skipList) insert(score float64, member string) {
+	var update [zslMaxLevel]*zslNode
+	var rank [zslMaxLevel]int
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	level := randomLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = l.header
+			update[i].level[i].span = l.length
+		}
+		l.level = level
+	}
+	x = &zslNode{ZItem: ZItem{member, score}, level: make([]zslLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < l.level; i++ {
+		update[i].level[i].span++
+	}
+	if update[0] != l.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		l.tail = x
+	}
+	l.length++
+}
+
+func (l *skipList) delete(score float64, member string) bool {
+	var update [zslMaxLevel]*zslNode
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.Score != score || x.Member != member {
+		return false
+	}
+	for i := 0; i < l.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		l.tail = x.backward
+	}
+	for l.level > 1 && l.header.level[l.level-1].forward == nil {
+		l.level--
+	}
+	l.length--
+	return true
+}
+
+// rank returns the 1-based rank of the item, 0 if not found
+func (l *skipList) rank(score float64, member string) int {
+	rank := 0
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.less(score, member) ||
+				(x.level[i].forward.Score == score && x.level[i].forward.Member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != l.header && x.Member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node of the 1-based rank
+func (l *skipList) byRank(rank int) *zslNode {
+	traversed := 0
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// seek returns the last node for which before reports true, or the header
+func (l *skipList) seek(before func(*zslNode) bool) *zslNode {
+	x := l.header
+	for i := l.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && before(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	return x
+}
+
+// firstIn returns the first node in the range, gteMin and lteMax of which
+// check the lower and the upper bound.
+func (l *skipList) firstIn(gteMin, lteMax func(*zslNode) bool) *zslNode {
+	x := l.seek(func(n *zslNode) bool { return !gteMin(n) }).level[0].forward
+	if x == nil || !lteMax(x) {
+		return nil
+	}
+	return x
+}
+
+// lastIn returns the last node in the range
+func (l *skipList) lastIn(gteMin, lteMax func(*zslNode) bool) *zslNode {
+	x := l.seek(lteMax)
+	if x == l.header || !gteMin(x) {
+		return nil
+	}
+	return x
+}
+
+// ZSet is the sorted set stored, the dict maps members to scores, the skip
+// list orders them and the scan table indexes them to be scanned.
+type ZSet struct {
+	dict  map[string]float64
+	zsl   *skipList
+	table *scanTable
+}
+
+// NewZSet returns an empty sorted set
+func NewZSet() *ZSet {
+	return &ZSet{dict: make(map[string]float64), zsl: newSkipList(), table: newScanTable()}
+}
+
+// Len returns the number of members in the sorted set
+func (z *ZSet) Len() int {
+	return len(z.dict)
+}
+
+// Clone returns a copy of the sorted set
+func (z *ZSet) Clone() interface{} {
+	c := NewZSet()
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		c.Add(x.Member, x.Score)
+	}
+	return c
+}
+
+// Score returns the score of member and whether the member exists
+func (z *ZSet) Score(member string) (float64, bool) {
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// Add puts the member with score, reports whether the member is newly added
+func (z *ZSet) Add(member string, score float64) bool {
+	old, ok := z.dict[member]
+	if ok {
+		if old != score {
+			z.zsl.delete(old, member)
+			z.zsl.insert(score, member)
+			z.dict[member] = score
+		}
+		return false
+	}
+	z.zsl.insert(score, member)
+	z.dict[member] = score
+	z.table.add(member)
+	return true
+}
+
+// Remove removes the member, reports whether the member existed
+func (z *ZSet) Remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	z.table.remove(member)
+	return true
+}
+
+// Rank returns the 0-based rank of member, in descending order if rev
+func (z *ZSet) Rank(member string, rev bool) (int, bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false
+	}
+	rank := z.zsl.rank(score, member)
+	if rev {
+		return z.Len() - rank, true
+	}
+	return rank - 1, true
+}
+
+// collect returns at most limit items walking from node x, limit is
+// ignored if negative, and in returns whether the node is in range.
+func collect(x *zslNode, rev bool, limit int, in func(*zslNode) bool) []ZItem {
+	items := make([]ZItem, 0)
+	for ; x != nil && limit != 0 && in(x); limit-- {
+		items = append(items, x.ZItem)
+		if rev {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return items
+}
+
+// skip skips offset nodes from x
+func skip(x *zslNode, rev bool, offset int) *zslNode {
+	for ; x != nil && offset > 0; offset-- {
+		if rev {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return x
+}
+
+// Range returns the items of ranks from start to stop which are both
+// valid indices, the ranks are counted in descending order if rev.
+func (z *ZSet) Range(start, stop int, rev bool) []ZItem {
+	rank := start + 1
+	if rev {
+		rank = z.Len() - start
+	}
+	x := z.zsl.byRank(rank)
+	return collect(x, rev, stop-start+1, func(*zslNode) bool { return true })
+}
+
+// RangeByScore returns the items in the score range skipping offset ones,
+// at most limit items are returned if limit is not negative.
+func (z *ZSet) RangeByScore(r ScoreRange, rev bool, offset, limit int) []ZItem {
+	if r.empty() {
+		return make([]ZItem, 0)
+	}
+	gteMin := func(n *zslNode) bool { return r.gteMin(n.Score) }
+	lteMax := func(n *zslNode) bool { return r.lteMax(n.Score) }
+	return z.rangeGeneric(gteMin, lteMax, rev, offset, limit)
+}
+
+// RangeByLex returns the items in the lexicographical range, which assumes
+// all the members have the same score.
+func (z *ZSet) RangeByLex(r LexRange, rev bool, offset, limit int) []ZItem {
+	if r.empty() {
+		return make([]ZItem, 0)
+	}
+	gteMin := func(n *zslNode) bool { return r.gteMin(n.Member) }
+	lteMax := func(n *zslNode) bool { return r.lteMax(n.Member) }
+	return z.rangeGeneric(gteMin, lteMax, rev, offset, limit)
+}
+
+func (z *ZSet) rangeGeneric(gteMin, lteMax func(*zslNode) bool, rev bool, offset, limit int) []ZItem {
+	var x *zslNode
+	in := lteMax
+	if rev {
+		x = z.zsl.lastIn(gteMin, lteMax)
+		in = gteMin
+	} else {
+		x = z.zsl.firstIn(gteMin, lteMax)
+	}
+	return collect(skip(x, rev, offset), rev, limit, in)
+}
+
+// CountByScore returns the number of items in the score range
+func (z *ZSet) CountByScore(r ScoreRange) int {
+	if r.empty() {
+		return 0
+	}
+	gteMin := func(n *zslNode) bool { return r.gteMin(n.Score) }
+	lteMax := func(n *zslNode) bool { return r.lteMax(n.Score) }
+	return z.countGeneric(gteMin, lteMax)
+}
+
+// CountByLex returns the number of items in the lexicographical range
+func (z *ZSet) CountByLex(r LexRange) int {
+	if r.empty() {
+		return 0
+	}
+	gteMin := func(n *zslNode) bool { return r.gteMin(n.Member) }
+	lteMax := func(n *zslNode) bool { return r.lteMax(n.Member) }
+	return z.countGeneric(gteMin, lteMax)
+}
+
+func (z *ZSet) countGeneric(gteMin, lteMax func(*zslNode) bool) int {
+	first := z.zsl.firstIn(gteMin, lteMax)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastIn(gteMin, lteMax)
+	return z.zsl.rank(last.Score, last.Member) - z.zsl.rank(first.Score, first.Member) + 1
+}
+
+// Members returns all the members in random order
+func (z *ZSet) Members() []string {
+	members := make([]string, 0, len(z.dict))
+	for k := range z.dict {
+		members = append(members, k)
+	}
+	return members
+}
+
+// Scan calls fn with the members and scores of about count members from
+// cursor, and returns the cursor to continue, 0 if the scan is finished
+func (z *ZSet) Scan(cursor uint64, count int, fn func(member string, score float64)) uint64 {
+	return z.table.scan(cursor, count, func(member string) {
+		fn(member, z.dict[member])
+	})
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func zsetOf(n int) *ZSet {
+	z := NewZSet()
+	for i := n - 1; i >= 0; i-- {
+		z.Add(strconv.Itoa(i), float64(i))
+	}
+	return z
+}
+
+func TestZSet_Add(t *testing.T) {
+	z := NewZSet()
+	assert.True(t, z.Add("b", 1))
+	assert.True(t, z.Add("a", 1))
+	assert.True(t, z.Add("c", 0))
+	assert.False(t, z.Add("c", 2))
+	assert.Equal(t, []ZItem{{"a", 1}, {"b", 1}, {"c", 2}}, z.Range(0, 2, false))
+	assert.Equal(t, []ZItem{{"c", 2}, {"b", 1}}, z.Range(0, 1, true))
+	score, ok := z.Score("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, score)
+	assert.True(t, z.Remove("a"))
+	assert.False(t, z.Remove("a"))
+	assert.Equal(t, 2, z.Len())
+	_, ok = z.Score("a")
+	assert.False(t, ok)
+}
+
+func TestZSet_Rank(t *testing.T) {
+	const n = 1000
+	z := zsetOf(n)
+	for i := 0; i < n; i++ {
+		rank, ok := z.Rank(strconv.Itoa(i), false)
+		assert.True(t, ok)
+		assert.Equal(t, i, rank)
+		rank, _ = z.Rank(strconv.Itoa(i), true)
+		assert.Equal(t, n-1-i, rank)
+	}
+	for i := 0; i < n; i += 2 {
+		z.Remove(strconv.Itoa(i))
+	}
+	rank, _ := z.Rank("501", false)
+	assert.Equal(t, 250, rank)
+	_, ok := z.Rank("500", false)
+	assert.False(t, ok)
+	assert.Equal(t, []ZItem{{"501", 501}, {"503", 503}}, z.Range(250, 251, false))
+}
+
+func TestZSet_RangeByScore(t *testing.T) {
+	z := zsetOf(10)
+	r := ScoreRange{Min: 2, Max: 5, MinEx: true}
+	assert.Equal(t, []ZItem{{"3", 3}, {"4", 4}, {"5", 5}}, z.RangeByScore(r, false, 0, -1))
+	assert.Equal(t, []ZItem{{"5", 5}, {"4", 4}}, z.RangeByScore(r, true, 0, 2))
+	assert.Equal(t, []ZItem{{"4", 4}}, z.RangeByScore(r, false, 1, 1))
+	assert.Equal(t, []ZItem{}, z.RangeByScore(r, false, 5, -1))
+	assert.Equal(t, 3, z.CountByScore(r))
+	inf := ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)}
+	assert.Equal(t, 10, z.CountByScore(inf))
+	assert.Equal(t, 0, z.CountByScore(ScoreRange{Min: 5, Max: 5, MaxEx: true}))
+	assert.Equal(t, 0, z.CountByScore(ScoreRange{Min: 20, Max: 30}))
+}
+
+func TestZSet_RangeByLex(t *testing.T) {
+	z := NewZSet()
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		z.Add(m, 0)
+	}
+	r := LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Value: "d", Exclusive: true}}
+	assert.Equal(t, []ZItem{{"b", 0}, {"c", 0}}, z.RangeByLex(r, false, 0, -1))
+	assert.Equal(t, []ZItem{{"c", 0}, {"b", 0}}, z.RangeByLex(r, true, 0, -1))
+	all := LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Inf: 1}}
+	assert.Equal(t, 5, z.CountByLex(all))
+	assert.Equal(t, []ZItem{{"e", 0}}, z.RangeByLex(all, true, 0, 1))
+	assert.Equal(t, 0, z.CountByLex(LexRange{Min: LexBound{Inf: 1}, Max: LexBound{Inf: 1}}))
+}
+
+func TestZSet_Clone(t *testing.T) {
+	z := zsetOf(3)
+	c := z.Clone().(*ZSet)
+	c.Add("0", 10)
+	c.Remove("1")
+	assert.Equal(t, []ZItem{{"0", 0}, {"1", 1}, {"2", 2}}, z.Range(0, 2, false))
+	assert.Equal(t, []ZItem{{"2", 2}, {"0", 10}}, c.Range(0, 1, false))
+}
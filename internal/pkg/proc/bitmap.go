@@ -0,0 +1,567 @@
+package proc
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// maxBitOffset is the max offset of bits in the string of max size
+const maxBitOffset = maxStringSize*8 - 1
+
+var (
+	errBitOffset     = errors.New("bit offset is not an integer or out of range")
+	errBitValue      = errors.New("bit is not an integer or out of range")
+	errBitfieldType  = errors.New("invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is")
+	errOverflowType  = errors.New("invalid OVERFLOW type specified")
+	errBitfieldRO    = errors.New("BITFIELD_RO only supports the GET subcommand")
+	errBitOpNotKeys  = errors.New("BITOP NOT must be called with a single source key")
+	errBitOpUnknown  = errors.New("unknown BITOP operation")
+	errBitPosBitArgs = errors.New("the bit argument must be 1 or 0")
+)
+
+// growString returns a copy of data which is at least n bytes, the bytes
+// stored may be shared, so that they are never modified in place.
+func growString(data []byte, n int64) []byte {
+	if int64(len(data)) > n {
+		n = int64(len(data))
+	}
+	buf := make([]byte, n)
+	copy(buf, data)
+	return buf
+}
+
+// bitAt returns the bit at offset, bit 0 is the most significant bit of
+// the first byte, and bits out of the string are 0.
+func bitAt(data []byte, offset int64) byte {
+	i := offset >> 3
+	if i >= int64(len(data)) {
+		return 0
+	}
+	return data[i] >> (7 - uint(offset&7)) & 1
+}
+
+// setBitAt sets the bit at offset, data must be long enough
+func setBitAt(data []byte, offset int64, bit byte) {
+	mask := byte(1) << (7 - uint(offset&7))
+	if bit == 0 {
+		data[offset>>3] &^= mask
+	} else {
+		data[offset>>3] |= mask
+	}
+}
+
+// parseBitOffset parses the offset of bit in the range of the max string
+func parseBitOffset(t *token.Token) (int64, error) {
+	if t.Label != label.Integer && !coerce(t, label.Integer) {
+		return 0, errBitOffset
+	}
+	offset := t.Data.(int64)
+	if offset < 0 || offset > maxBitOffset {
+		return 0, errBitOffset
+	}
+	return offset, nil
+}
+
+// parseBit parses the bit which is 0 or 1
+func parseBit(t *token.Token, err error) (byte, error) {
+	if t.Label != label.Integer && !coerce(t, label.Integer) {
+		return 0, err
+	}
+	switch t.Data.(int64) {
+	case 0:
+		return 0, nil
+	case 1:
+		return 1, nil
+	}
+	return 0, err
+}
+
+// setBit sets the bit at offset and returns the original one, the string
+// is grown with zero bytes if needed.
+func (p *Processor) setBit(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	offset, err := parseBitOffset(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	bit, err := parseBit(tokens[2], errBitValue)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	data, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	old := bitAt(data, offset)
+	buf := growString(data, offset>>3+1)
+	setBitAt(buf, offset, bit)
+	setKeepTTL(cli, key, buf, model.EventSetBit)
+	return token.NewInteger(int64(old))
+}
+
+func (p *Processor) getBit(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	offset, err := parseBitOffset(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	data, err := getString(cli, tokens[0].Data.(string))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewInteger(int64(bitAt(data, offset)))
+}
+
+// parseBitRange parses "[start [end [BYTE|BIT]]]" of the string of n bytes
+// into the inclusive range of bits, false is returned if the range is empty.
+func parseBitRange(tokens []*token.Token, n int64) (int64, int64, bool, error) {
+	if len(tokens) > 3 {
+		return 0, 0, false, errSyntax
+	}
+	start, end := int64(0), int64(-1)
+	if len(tokens) > 0 {
+		if err := checkType(tokens[0], "start", label.Integer); err != nil {
+			return 0, 0, false, err
+		}
+		start = tokens[0].Data.(int64)
+	}
+	if len(tokens) > 1 {
+		if err := checkType(tokens[1], "end", label.Integer); err != nil {
+			return 0, 0, false, err
+		}
+		end = tokens[1].Data.(int64)
+	}
+	bitMode := false
+	if len(tokens) > 2 {
+		switch arg, _ := optionOf(tokens[2]); arg {
+		case cds.ByteArg:
+		case cds.BitArg:
+			bitMode = true
+		default:
+			return 0, 0, false, errSyntax
+		}
+	}
+	if bitMode {
+		start, end, ok := normRange(start, end, n*8)
+		return start, end, ok, nil
+	}
+	start, end, ok := normRange(start, end, n)
+	return start * 8, end*8 + 7, ok, nil
+}
+
+// bitCount counts the bits set in the range "[start end [BYTE|BIT]]"
+func (p *Processor) bitCount(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	data, err := getString(cli, tokens[0].Data.(string))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	// start and end are given together
+	if len(tokens) == 2 {
+		return token.NewError(errSyntax.Error())
+	}
+	start, end, ok, err := parseBitRange(tokens[1:], int64(len(data)))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if !ok {
+		return token.NewInteger(0)
+	}
+	var count int64
+	for i := start; i <= end; {
+		// count the whole byte if possible
+		if i&7 == 0 && i+7 <= end {
+			count += int64(bits.OnesCount8(data[i>>3]))
+			i += 8
+			continue
+		}
+		count += int64(bitAt(data, i))
+		i++
+	}
+	return token.NewInteger(count)
+}
+
+// bitPos returns the position of the first bit set to 0 or 1 in the range
+// "[start [end [BYTE|BIT]]]". The string is regarded as padded with zeros
+// on the right when looking for 0 and the end is not given.
+func (p *Processor) bitPos(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	bit, err := parseBit(tokens[1], errBitPosBitArgs)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	data, err := getString(cli, tokens[0].Data.(string))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	start, end, ok, err := parseBitRange(tokens[2:], int64(len(data)))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if len(data) == 0 {
+		if bit == 0 {
+			return token.NewInteger(0)
+		}
+		return token.NewInteger(-1)
+	}
+	if !ok {
+		return token.NewInteger(-1)
+	}
+	// bytes of all the bits not looked for are skipped
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for i := start; i <= end; {
+		if i&7 == 0 && i+7 <= end && data[i>>3] == skip {
+			i += 8
+			continue
+		}
+		if bitAt(data, i) == bit {
+			return token.NewInteger(i)
+		}
+		i++
+	}
+	if hasEnd := len(tokens) > 3; bit == 0 && !hasEnd {
+		return token.NewInteger(end + 1)
+	}
+	return token.NewInteger(-1)
+}
+
+// bitOp performs the bitwise operation between strings and stores the
+// result in the destination key by "AND|OR|XOR|NOT destkey key [key ...]".
+// Shorter strings are regarded as padded with zeros.
+func (p *Processor) bitOp(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[0], "operation", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkKeysType(tokens[1:]); err != nil {
+		return token.NewError(err.Error())
+	}
+	op, _ := optionOf(tokens[0])
+	switch op {
+	case cds.And, cds.Or, cds.Xor:
+	case cds.Not:
+		if len(tokens) != 3 {
+			return token.NewError(errBitOpNotKeys.Error())
+		}
+	default:
+		return token.NewError(errBitOpUnknown.Error())
+	}
+	srcs := make([][]byte, 0, len(tokens)-2)
+	var n int
+	for _, t := range tokens[2:] {
+		data, err := getString(cli, t.Data.(string))
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		if len(data) > n {
+			n = len(data)
+		}
+		srcs = append(srcs, data)
+	}
+	dst := tokens[1].Data.(string)
+	if n == 0 {
+		cli.Del(dst)
+		return token.NewInteger(0)
+	}
+	res := growString(srcs[0], int64(n))
+	if op == cds.Not {
+		for i := range res {
+			res[i] = ^res[i]
+		}
+	}
+	for _, src := range srcs[1:] {
+		for i := range res {
+			var b byte
+			if i < len(src) {
+				b = src[i]
+			}
+			switch op {
+			case cds.And:
+				res[i] &= b
+			case cds.Or:
+				res[i] |= b
+			case cds.Xor:
+				res[i] ^= b
+			}
+		}
+	}
+	cli.Set(dst, res, 0)
+	cli.Notify(model.NotifyString, model.EventSet, dst)
+	return token.NewInteger(int64(n))
+}
+
+// bitfieldType is the type of integer like "i16" or "u8"
+type bitfieldType struct {
+	signed bool
+	bits   uint
+}
+
+// bitfieldOp is the subcommand of bitfield
+type bitfieldOp struct {
+	cmd    string
+	typ    bitfieldType
+	offset int64
+	value  int64
+	// overflow mode of SET and INCRBY
+	overflow string
+}
+
+// parseBitfieldType parses the type, i1 to i64 and u1 to u63 are supported
+func parseBitfieldType(t *token.Token) (bitfieldType, error) {
+	data, err := tokenToBytes(t)
+	if err != nil || len(data) < 2 {
+		return bitfieldType{}, errBitfieldType
+	}
+	typ := bitfieldType{}
+	switch data[0] {
+	case 'i', 'I':
+		typ.signed = true
+	case 'u', 'U':
+	default:
+		return typ, errBitfieldType
+	}
+	n, err := strconv.Atoi(string(data[1:]))
+	if err != nil || n < 1 || (typ.signed && n > 64) || (!typ.signed && n > 63) {
+		return typ, errBitfieldType
+	}
+	typ.bits = uint(n)
+	return typ, nil
+}
+
+// parseBitfieldOffset parses the offset in bits, or in multiples of the type
+// width if prefixed by '#' like "#2".
+func parseBitfieldOffset(t *token.Token, typ bitfieldType) (int64, error) {
+	var offset int64
+	if t.Label == label.Integer {
+		offset = t.Data.(int64)
+	} else {
+		data, err := tokenToBytes(t)
+		if err != nil {
+			return 0, errBitOffset
+		}
+		s := string(data)
+		mul := strings.HasPrefix(s, "#")
+		if offset, err = strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64); err != nil {
+			return 0, errBitOffset
+		}
+		if mul {
+			if offset > math.MaxInt64/int64(typ.bits) {
+				return 0, errBitOffset
+			}
+			offset *= int64(typ.bits)
+		}
+	}
+	if offset < 0 || offset+int64(typ.bits)-1 > maxBitOffset {
+		return 0, errBitOffset
+	}
+	return offset, nil
+}
+
+// parseBitfield parses subcommands "GET type offset", "SET type offset
+// value", "INCRBY type offset increment" and "OVERFLOW WRAP|SAT|FAIL".
+func parseBitfield(tokens []*token.Token, readOnly bool) ([]bitfieldOp, error) {
+	var ops []bitfieldOp
+	overflow := cds.Wrap
+	for i := 0; i < len(tokens); i++ {
+		cmd, ok := optionOf(tokens[i])
+		if !ok {
+			return nil, errSyntax
+		}
+		if readOnly && cmd != cds.GetArg {
+			return nil, errBitfieldRO
+		}
+		switch cmd {
+		case cds.Overflow:
+			if i+1 >= len(tokens) {
+				return nil, errArgMissing(cmd)
+			}
+			i++
+			mode, _ := optionOf(tokens[i])
+			switch mode {
+			case cds.Wrap, cds.Sat, cds.Fail:
+				overflow = mode
+			default:
+				return nil, errOverflowType
+			}
+			continue
+		case cds.GetArg, cds.SetArg, cds.IncrByArg:
+		default:
+			return nil, errSyntax
+		}
+		n := 2
+		if cmd != cds.GetArg {
+			n = 3
+		}
+		if i+n >= len(tokens) {
+			return nil, errArgMissing(cmd)
+		}
+		typ, err := parseBitfieldType(tokens[i+1])
+		if err != nil {
+			return nil, err
+		}
+		offset, err := parseBitfieldOffset(tokens[i+2], typ)
+		if err != nil {
+			return nil, err
+		}
+		op := bitfieldOp{cmd: cmd, typ: typ, offset: offset, overflow: overflow}
+		if cmd != cds.GetArg {
+			if err := checkType(tokens[i+3], "value", label.Integer); err != nil {
+				return nil, err
+			}
+			op.value = tokens[i+3].Data.(int64)
+		}
+		ops = append(ops, op)
+		i += n
+	}
+	return ops, nil
+}
+
+// get returns the integer of the type at offset
+func (typ bitfieldType) get(data []byte, offset int64) int64 {
+	var v uint64
+	for i := int64(0); i < int64(typ.bits); i++ {
+		v = v<<1 | uint64(bitAt(data, offset+i))
+	}
+	// extend the sign bit
+	if typ.signed && typ.bits < 64 && v&(1<<(typ.bits-1)) != 0 {
+		v |= ^uint64(0) << typ.bits
+	}
+	return int64(v)
+}
+
+// set stores the integer of the type at offset, data must be long enough
+func (typ bitfieldType) set(data []byte, offset, value int64) {
+	v := uint64(value)
+	for i := int64(0); i < int64(typ.bits); i++ {
+		setBitAt(data, offset+i, byte(v>>(int64(typ.bits)-1-i)&1))
+	}
+}
+
+// add returns value plus incr handled by the overflow mode, false is
+// returned if it overflows in mode FAIL.
+func (typ bitfieldType) add(value, incr int64, overflow string) (int64, bool) {
+	var lo, hi int64
+	if typ.signed {
+		hi = math.MaxInt64
+		if typ.bits < 64 {
+			hi = 1<<(typ.bits-1) - 1
+		}
+		lo = -hi - 1
+	} else {
+		hi = 1<<typ.bits - 1
+	}
+	high := value > hi || (incr > 0 && value > hi-incr)
+	// lo-incr overflows only if unsigned and incr is min int64
+	low := !high && (value < lo || (incr < 0 && (incr == math.MinInt64 && !typ.signed || value < lo-incr)))
+	if !high && !low {
+		return value + incr, true
+	}
+	switch overflow {
+	case cds.Fail:
+		return 0, false
+	case cds.Sat:
+		if high {
+			return hi, true
+		}
+		return lo, true
+	}
+	// wrap around by truncating to the width of the type
+	v := uint64(value) + uint64(incr)
+	if typ.bits < 64 {
+		mask := ^uint64(0) << typ.bits
+		if typ.signed && v&(1<<(typ.bits-1)) != 0 {
+			v |= mask
+		} else {
+			v &^= mask
+		}
+	}
+	return int64(v), true
+}
+
+// bitfieldGeneric treats the string as an array of integers of arbitrary
+// width and offset. Nil is replied for the subcommand failed in mode FAIL.
+func (p *Processor) bitfieldGeneric(cli *model.Client, tokens []*token.Token, readOnly bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	ops, err := parseBitfield(tokens[1:], readOnly)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	data, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	// the string is grown to hold the highest bit written
+	var size int64 = -1
+	for _, op := range ops {
+		if end := (op.offset+int64(op.typ.bits)-1)>>3 + 1; op.cmd != cds.GetArg && end > size {
+			size = end
+		}
+	}
+	if size >= 0 {
+		data = growString(data, size)
+	}
+	ts := make([]*token.Token, 0, len(ops))
+	for _, op := range ops {
+		old := op.typ.get(data, op.offset)
+		if op.cmd == cds.GetArg {
+			ts = append(ts, token.NewInteger(old))
+			continue
+		}
+		var v int64
+		var ok bool
+		if op.cmd == cds.SetArg {
+			v, ok = op.typ.add(op.value, 0, op.overflow)
+		} else {
+			v, ok = op.typ.add(old, op.value, op.overflow)
+		}
+		if !ok {
+			ts = append(ts, token.NewBulked(nil))
+			continue
+		}
+		op.typ.set(data, op.offset, v)
+		if op.cmd == cds.SetArg {
+			ts = append(ts, token.NewInteger(old))
+		} else {
+			ts = append(ts, token.NewInteger(v))
+		}
+	}
+	if size >= 0 {
+		setKeepTTL(cli, key, data, model.EventSetBit)
+	} else {
+		p.propagateAs()
+	}
+	return token.NewArray(ts...)
+}
+
+func (p *Processor) bitfield(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.bitfieldGeneric(cli, tokens, false)
+}
+
+func (p *Processor) bitfieldRO(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.bitfieldGeneric(cli, tokens, true)
+}
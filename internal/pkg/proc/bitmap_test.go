@@ -0,0 +1,198 @@
+package proc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_setBit(t *testing.T) {
+	key := token.NewString("t_setbit")
+	assert.Equal(t, token.NewInteger(0), proc.setBit(cli, key, token.NewInteger(7), token.NewInteger(1)))
+	assert.Equal(t, token.NewInteger(1), proc.setBit(cli, key, token.NewInteger(7), token.NewInteger(1)))
+	assert.Equal(t, token.NewBulked([]byte{1}), proc.get(cli, key))
+	assert.Equal(t, token.NewInteger(1), proc.getBit(cli, key, token.NewInteger(7)))
+	assert.Equal(t, token.NewInteger(0), proc.getBit(cli, key, token.NewInteger(0)))
+	assert.Equal(t, token.NewInteger(0), proc.getBit(cli, key, token.NewInteger(100)))
+	assert.Equal(t, token.NewInteger(0), proc.getBit(cli, token.NewString("t_setbit_none"), token.NewInteger(1)))
+
+	// grown with zero bytes and the expiration is kept
+	proc.expire(cli, key, token.NewInteger(100))
+	assert.Equal(t, token.NewInteger(0), proc.setBit(cli, key, token.NewInteger(17), token.NewInteger(1)))
+	assert.Equal(t, token.NewBulked([]byte{1, 0, 0x40}), proc.get(cli, key))
+	assert.Equal(t, token.NewInteger(1), proc.setBit(cli, key, token.NewInteger(7), token.NewInteger(0)))
+	assert.Equal(t, token.NewBulked([]byte{0, 0, 0x40}), proc.get(cli, key))
+	assert.Equal(t, token.NewInteger(100), proc.ttl(cli, key))
+
+	assert.Equal(t, token.NewError(errBitOffset.Error()), proc.setBit(cli, key, token.NewInteger(-1), token.NewInteger(1)))
+	assert.Equal(t, token.NewError(errBitOffset.Error()),
+		proc.getBit(cli, key, token.NewInteger(maxBitOffset+1)))
+	assert.Equal(t, token.NewError(errBitValue.Error()), proc.setBit(cli, key, token.NewInteger(1), token.NewInteger(2)))
+	proc.rPush(cli, token.NewString("t_setbit_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType),
+		proc.setBit(cli, token.NewString("t_setbit_list"), token.NewInteger(1), token.NewInteger(1)))
+}
+
+func TestProcessor_bitCount(t *testing.T) {
+	key := token.NewString("t_bitcount")
+	proc.set(cli, key, token.NewString("foobar"))
+	count := func(ts ...*token.Token) *token.Token {
+		return proc.bitCount(cli, append([]*token.Token{key}, ts...)...)
+	}
+	assert.Equal(t, token.NewInteger(26), count())
+	assert.Equal(t, token.NewInteger(4), count(token.NewInteger(0), token.NewInteger(0)))
+	assert.Equal(t, token.NewInteger(6), count(token.NewInteger(1), token.NewInteger(1)))
+	assert.Equal(t, token.NewInteger(6), count(token.NewInteger(1), token.NewInteger(1), token.NewString("byte")))
+	assert.Equal(t, token.NewInteger(17), count(token.NewInteger(5), token.NewInteger(30), token.NewString(cds.BitArg)))
+	assert.Equal(t, token.NewInteger(26), count(token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(0), count(token.NewInteger(4), token.NewInteger(2)))
+	assert.Equal(t, token.NewInteger(0), proc.bitCount(cli, token.NewString("t_bitcount_none")))
+	assert.Equal(t, token.NewError(errSyntax.Error()), count(token.NewInteger(1)))
+	assert.Equal(t, token.NewError(errSyntax.Error()), count(token.NewInteger(1), token.NewInteger(1),
+		token.NewString("bits")))
+}
+
+func TestProcessor_bitPos(t *testing.T) {
+	pos := func(value []byte, ts ...*token.Token) *token.Token {
+		key := token.NewString("t_bitpos")
+		proc.set(cli, key, token.NewBulked(value))
+		return proc.bitPos(cli, append([]*token.Token{key}, ts...)...)
+	}
+	assert.Equal(t, token.NewInteger(12), pos([]byte{0xff, 0xf0, 0}, token.NewInteger(0)))
+	assert.Equal(t, token.NewInteger(8), pos([]byte{0, 0xff, 0xf0}, token.NewInteger(1), token.NewInteger(0)))
+	assert.Equal(t, token.NewInteger(16), pos([]byte{0, 0xff, 0xf0}, token.NewInteger(1), token.NewInteger(2)))
+	assert.Equal(t, token.NewInteger(16), pos([]byte{0, 0xff, 0xf0}, token.NewInteger(1), token.NewInteger(2),
+		token.NewInteger(-1), token.NewString(cds.ByteArg)))
+	assert.Equal(t, token.NewInteger(8), pos([]byte{0, 0xff, 0xf0}, token.NewInteger(1), token.NewInteger(7),
+		token.NewInteger(15), token.NewString(cds.BitArg)))
+	assert.Equal(t, token.NewInteger(-1), pos([]byte{0, 0, 0}, token.NewInteger(1)))
+	// zeros are padded on the right unless the end is given
+	assert.Equal(t, token.NewInteger(24), pos([]byte{0xff, 0xff, 0xff}, token.NewInteger(0)))
+	assert.Equal(t, token.NewInteger(-1), pos([]byte{0xff, 0xff, 0xff}, token.NewInteger(0), token.NewInteger(0),
+		token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(-1), pos([]byte{0xff}, token.NewInteger(1), token.NewInteger(2)))
+
+	none := token.NewString("t_bitpos_none")
+	assert.Equal(t, token.NewInteger(-1), proc.bitPos(cli, none, token.NewInteger(1)))
+	assert.Equal(t, token.NewInteger(0), proc.bitPos(cli, none, token.NewInteger(0)))
+	assert.Equal(t, token.NewError(errBitPosBitArgs.Error()), proc.bitPos(cli, none, token.NewInteger(2)))
+}
+
+func TestProcessor_bitOp(t *testing.T) {
+	a, b, dst := token.NewString("t_bitop_a"), token.NewString("t_bitop_b"), token.NewString("t_bitop_dst")
+	proc.set(cli, a, token.NewString("foobar"))
+	proc.set(cli, b, token.NewString("abcdef"))
+	op := func(name string, keys ...*token.Token) *token.Token {
+		return proc.bitOp(cli, append([]*token.Token{token.NewString(name), dst}, keys...)...)
+	}
+	assert.Equal(t, token.NewInteger(6), op("and", a, b))
+	assert.Equal(t, token.NewBulked([]byte("`bc`ab")), proc.get(cli, dst))
+	assert.Equal(t, token.NewInteger(6), op(cds.Or, a, b))
+	assert.Equal(t, token.NewBulked([]byte("goofev")), proc.get(cli, dst))
+	assert.Equal(t, token.NewInteger(6), op(cds.Xor, a, b))
+	assert.Equal(t, token.NewBulked([]byte{7, 13, 12, 6, 4, 20}), proc.get(cli, dst))
+
+	// shorter strings are padded with zeros
+	proc.set(cli, b, token.NewBulked([]byte{0xff}))
+	assert.Equal(t, token.NewInteger(6), op(cds.And, b, a))
+	assert.Equal(t, token.NewBulked([]byte{'f', 0, 0, 0, 0, 0}), proc.get(cli, dst))
+	assert.Equal(t, token.NewInteger(1), op(cds.Not, b))
+	assert.Equal(t, token.NewBulked([]byte{0}), proc.get(cli, dst))
+
+	// destination is deleted if all the keys are empty
+	assert.Equal(t, token.NewInteger(0), op(cds.Or, token.NewString("t_bitop_none")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, dst))
+
+	assert.Equal(t, token.NewError(errBitOpNotKeys.Error()), op(cds.Not, a, b))
+	assert.Equal(t, token.NewError(errBitOpUnknown.Error()), op("nand", a, b))
+	proc.rPush(cli, token.NewString("t_bitop_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), op(cds.And, a, token.NewString("t_bitop_list")))
+}
+
+func TestProcessor_bitfield(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	key := token.NewString("t_bitfield")
+	field := func(args ...interface{}) *token.Token {
+		ts := []*token.Token{key}
+		for _, arg := range args {
+			switch v := arg.(type) {
+			case string:
+				ts = append(ts, token.NewString(v))
+			case int:
+				ts = append(ts, token.NewInteger(int64(v)))
+			case int64:
+				ts = append(ts, token.NewInteger(v))
+			}
+		}
+		return p.bitfield(c, ts...)
+	}
+	ints := func(nums ...int64) *token.Token {
+		ts := make([]*token.Token, 0, len(nums))
+		for _, n := range nums {
+			ts = append(ts, token.NewInteger(n))
+		}
+		return token.NewArray(ts...)
+	}
+	assert.Equal(t, ints(1, 0), field(cds.IncrByArg, "i5", 100, 1, cds.GetArg, "u4", 0))
+	assert.Equal(t, token.NewInteger(14), p.strLen(c, key))
+
+	// overflow of unsigned integers
+	for _, expected := range [][]int64{{1, 1}, {2, 2}, {3, 3}, {0, 3}} {
+		assert.Equal(t, ints(expected...), field("incrby", "u2", 100, 1, "overflow", "sat", "incrby", "u2", 102, 1))
+	}
+	assert.Equal(t, token.NewArray(token.NewBulked(nil)), field(cds.Overflow, cds.Fail, cds.IncrByArg, "u2", 102, 1))
+
+	// overflow of signed integers
+	assert.Equal(t, ints(0, -1), field(cds.SetArg, "i8", "#1", -1, cds.GetArg, "i8", 8))
+	assert.Equal(t, ints(-1, 127), field(cds.SetArg, "i8", "#1", 127, cds.GetArg, "u8", 8))
+	assert.Equal(t, ints(-128), field(cds.IncrByArg, "i8", "#1", 1))
+	assert.Equal(t, ints(-128), field(cds.Overflow, cds.Sat, cds.IncrByArg, "i8", "#1", -1))
+	assert.Equal(t, ints(127), field(cds.Overflow, cds.Sat, cds.IncrByArg, "i8", "#1", 1000))
+	assert.Equal(t, token.NewArray(token.NewBulked(nil)), field(cds.Overflow, cds.Fail, cds.SetArg, "i8", "#1", 128))
+	assert.Equal(t, ints(127, 0), field(cds.SetArg, "i8", "#1", 256, cds.GetArg, "i8", "#1"))
+	assert.Equal(t, ints(0, 0), field(cds.Overflow, cds.Sat, cds.SetArg, "u8", "#1", -1, cds.GetArg, "i8", "#1"))
+
+	// 64-bit signed integers
+	assert.Equal(t, ints(0, math.MinInt64), field(cds.SetArg, "i64", 128, math.MaxInt64, cds.IncrByArg, "i64", 128, 1))
+	assert.Equal(t, ints(math.MinInt64), field(cds.Overflow, cds.Sat, cds.IncrByArg, "i64", 128, -1))
+	assert.Equal(t, ints(-1), field(cds.IncrByArg, "i64", 128, math.MaxInt64))
+	assert.Equal(t, ints(math.MaxInt64, math.MaxInt64), field(cds.GetArg, "u63", 128, cds.IncrByArg, "u63", 128,
+		math.MinInt64))
+}
+
+func TestProcessor_bitfield_options(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("t_bitfield")
+	exec := func(cmd string, ts ...*token.Token) *token.Token {
+		return p.execCmd(c, token.NewArray(append([]*token.Token{token.NewString(cmd), key}, ts...)...))
+	}
+	get := []*token.Token{token.NewString(cds.GetArg), token.NewString("u8"), token.NewInteger(0)}
+	assert.Equal(t, []*token.Token{token.NewInteger(0)}, exec(cds.BitField, get...).Data)
+	assert.Equal(t, token.NewInteger(0), p.exists(c, key))
+	assert.Equal(t, 0, len(p.aof))
+	assert.Equal(t, []*token.Token{token.NewInteger(0)}, exec(cds.BitFieldRO, get...).Data)
+	set := []*token.Token{token.NewString(cds.SetArg), token.NewString("u8"), token.NewInteger(0), token.NewInteger(1)}
+	assert.Equal(t, []*token.Token{token.NewInteger(0)}, exec(cds.BitField, set...).Data)
+	assert.Equal(t, 1, len(p.aof))
+	assert.Equal(t, token.NewError("ERR "+errBitfieldRO.Error()), exec(cds.BitFieldRO, set...))
+
+	for arg, err := range map[string]error{
+		"u64": errBitfieldType, "i65": errBitfieldType, "x8": errBitfieldType, "i": errBitfieldType,
+	} {
+		assert.Equal(t, token.NewError("ERR "+err.Error()),
+			exec(cds.BitField, token.NewString(cds.GetArg), token.NewString(arg), token.NewInteger(0)))
+	}
+	assert.Equal(t, token.NewError("ERR "+errBitOffset.Error()),
+		exec(cds.BitField, token.NewString(cds.GetArg), token.NewString("u8"), token.NewString("#-1")))
+	assert.Equal(t, token.NewError("ERR "+errOverflowType.Error()),
+		exec(cds.BitField, token.NewString(cds.Overflow), token.NewString("none")))
+	assert.Equal(t, token.NewError("ERR "+errArgMissing(cds.SetArg).Error()),
+		exec(cds.BitField, token.NewString(cds.SetArg), token.NewString("u8"), token.NewInteger(0)))
+	assert.Equal(t, token.NewError("ERR "+errSyntax.Error()), exec(cds.BitField, token.NewString("del")))
+}
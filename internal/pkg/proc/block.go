@@ -0,0 +1,182 @@
+package proc
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+var (
+	errTimeoutNegative = errors.New("timeout is negative")
+	errTimeoutFloat    = errors.New("timeout is not a float or out of range")
+)
+
+// blockKey is the key of the database waited for by blocked clients
+type blockKey struct {
+	db  int
+	key string
+}
+
+// parseTimeout parses the timeout in seconds of blocking commands like blpop
+func parseTimeout(t *token.Token) (time.Duration, error) {
+	f, err := tokenToFloat(t)
+	if err != nil || math.IsInf(f, 0) || f > math.MaxInt64/float64(time.Second) {
+		return 0, errTimeoutFloat
+	}
+	if f < 0 {
+		return 0, errTimeoutNegative
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// block blocks the client on keys until retry returns the reply after any
+// key is signaled ready, or the timeout reply is returned after the timeout,
+// 0 blocks forever. Clients without connection or executing a transaction
+// are not blocked, the timeout reply is returned at once instead.
+//
+// Deadlines are checked by the active expiration cycle, so the timeout is
+// precise to the interval between cycles.
+func (p *Processor) block(cli *model.Client, keys []string, timeout time.Duration,
+	timeoutReply *token.Token, retry func() *token.Token) *token.Token {
+	if cli.Out == nil || cli.Multi.Executing {
+		return timeoutReply
+	}
+	// changes made before blocked are propagated at once
+	if p.rewrite.done && len(p.rewrite.ts) > 0 && cli.Stat {
+		p.propagate(cli, nil)
+	}
+	p.rewrite.done, p.rewrite.ts = false, nil
+	b := &model.BlockInfo{Retry: retry, Timeout: timeoutReply}
+	if timeout > 0 {
+		b.Deadline = time.Now().Add(timeout)
+	}
+	db := cli.Data.Idx()
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		b.Keys = append(b.Keys, key)
+		k := blockKey{db, key}
+		p.blocking.clients[k] = append(p.blocking.clients[k], cli)
+	}
+	cli.Block = b
+	return nil
+}
+
+// signalKey marks the key ready for the clients blocked by it, which are
+// retried after the command is executed.
+func (p *Processor) signalKey(cli *model.Client, key string) {
+	p.signalReady(blockKey{cli.Data.Idx(), key})
+}
+
+// signalDB marks the keys existing in the database ready, which is called
+// after all the keys of the database are replaced.
+func (p *Processor) signalDB(db int) {
+	for k := range p.blocking.clients {
+		if k.db == db && p.data[db].Get(k.key) != nil {
+			p.signalReady(k)
+		}
+	}
+}
+
+// signalReady marks the key ready if any client is blocked by it
+func (p *Processor) signalReady(k blockKey) {
+	if _, ok := p.blocking.clients[k]; !ok {
+		return
+	}
+	for _, r := range p.blocking.ready {
+		if r == k {
+			return
+		}
+	}
+	p.blocking.ready = append(p.blocking.ready, k)
+}
+
+// unblock removes the client from the keys it is blocked by, and returns
+// its blocking info.
+func (p *Processor) unblock(cli *model.Client) *model.BlockInfo {
+	b := cli.Block
+	cli.Block = nil
+	db := cli.Data.Idx()
+	for _, key := range b.Keys {
+		k := blockKey{db, key}
+		clients := p.blocking.clients[k]
+		for i, c := range clients {
+			if c == cli {
+				clients = append(clients[:i:i], clients[i+1:]...)
+				break
+			}
+		}
+		if len(clients) == 0 {
+			delete(p.blocking.clients, k)
+		} else {
+			p.blocking.clients[k] = clients
+		}
+	}
+	return b
+}
+
+// replyBlocked unblocks the client with the reply, then executes requests
+// received while blocked until it is blocked again.
+func (p *Processor) replyBlocked(cli *model.Client, reply *token.Token) {
+	b := p.unblock(cli)
+	cli.Push(WithKind(reply))
+	for i, req := range b.Pending {
+		if r := p.execCmd(cli, req); r != nil {
+			cli.Push(r)
+		}
+		if cli.Block != nil {
+			cli.Block.Pending = append(cli.Block.Pending, b.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// serveBlocked retries the clients blocked by the keys signaled ready in
+// order of blocking, until no key is ready. Requests rewritten by the retry
+// are propagated.
+func (p *Processor) serveBlocked() {
+	for len(p.blocking.ready) > 0 {
+		k := p.blocking.ready[0]
+		p.blocking.ready = p.blocking.ready[1:]
+		for _, cli := range append([]*model.Client(nil), p.blocking.clients[k]...) {
+			// the client may be served by another key
+			if cli.Block == nil {
+				continue
+			}
+			p.rewrite.done, p.rewrite.ts = false, nil
+			reply := cli.Block.Retry()
+			if reply == nil {
+				continue
+			}
+			if p.rewrite.done {
+				p.propagate(cli, nil)
+			}
+			p.replyBlocked(cli, reply)
+		}
+	}
+}
+
+// expireBlocked replies the timeout to the clients whose deadline is reached
+func (p *Processor) expireBlocked() {
+	now := time.Now()
+	var expired []*model.Client
+	seen := make(map[*model.Client]bool)
+	for _, clients := range p.blocking.clients {
+		for _, cli := range clients {
+			b := cli.Block
+			if !seen[cli] && !b.Deadline.IsZero() && b.Deadline.Before(now) {
+				seen[cli] = true
+				expired = append(expired, cli)
+			}
+		}
+	}
+	for _, cli := range expired {
+		p.replyBlocked(cli, cli.Block.Timeout)
+	}
+}
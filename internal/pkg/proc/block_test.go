@@ -0,0 +1,87 @@
+package proc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_block(t *testing.T) {
+	p := NewProcessor(1)
+	p.Msgs.Set = make(chan *SetMsg, 16)
+	do := func(c *model.Client, cmd string, args ...string) *token.Token {
+		rsp := make(chan *token.Token, 1)
+		p.Do(&model.CmdTask{Cli: c, Req: streamCmd(cmd, args...), Rsp: rsp})
+		return <-rsp
+	}
+	propagated := func() [][]string {
+		var reqs [][]string
+		for len(p.Msgs.Set) > 0 {
+			reqs = append(reqs, argsOf((<-p.Msgs.Set).T))
+		}
+		return reqs
+	}
+	alice, bob, writer := p.NewClient(nil), p.NewClient(nil), p.NewClient(nil)
+
+	// clients are served in order of blocking
+	assert.Nil(t, do(alice, cds.BLPop, "a", "b", "0"))
+	assert.Nil(t, do(bob, cds.BRPop, "b", "0"))
+	assert.Equal(t, int64(2), do(writer, cds.RPush, "b", "x", "y").Data)
+	assert.Equal(t, []*token.Token{bulkedArray("b", "x")}, alice.Out.Pop())
+	assert.Equal(t, []*token.Token{bulkedArray("b", "y")}, bob.Out.Pop())
+	assert.Equal(t, [][]string{{cds.RPush, "b", "x", "y"}, {cds.LPop, "b"}, {cds.RPop, "b"}}, propagated())
+	assert.Equal(t, 0, len(p.blocking.clients))
+
+	// the value moved by blmove serves the client blocked by the destination
+	assert.Nil(t, do(alice, cds.BLMove, "src", "dst", cds.Left, cds.Right, "0"))
+	assert.Nil(t, do(bob, cds.BLPop, "dst", "0"))
+	do(writer, cds.LPush, "src", "v")
+	assert.Equal(t, []*token.Token{token.NewBulked([]byte("v"))}, alice.Out.Pop())
+	assert.Equal(t, []*token.Token{bulkedArray("dst", "v")}, bob.Out.Pop())
+	assert.Equal(t, [][]string{{cds.LPush, "src", "v"}, {cds.LMove, "src", "dst", cds.Left, cds.Right},
+		{cds.LPop, "dst"}}, propagated())
+	assert.Equal(t, int64(0), do(writer, cds.Exists, "src", "dst").Data)
+
+	// sorted sets are popped by bzpopmin and bzpopmax
+	assert.Nil(t, do(alice, cds.BZPopMin, "z", "0"))
+	assert.Nil(t, do(bob, cds.BZPopMax, "z", "0"))
+	do(writer, cds.ZAdd, "z", "1", "a", "2", "b")
+	assert.Equal(t, []*token.Token{bulkedArray("z", "a", "1")}, alice.Out.Pop())
+	assert.Equal(t, []*token.Token{bulkedArray("z", "b", "2")}, bob.Out.Pop())
+	assert.Equal(t, [][]string{{cds.ZAdd, "z", "1", "a", "2", "b"}, {cds.ZPopMin, "z"}, {cds.ZPopMax, "z"}},
+		propagated())
+
+	// keys of other types keep the client blocked
+	assert.Nil(t, do(alice, cds.BLPop, "k", "0"))
+	do(writer, cds.ZAdd, "k", "1", "a")
+	assert.NotNil(t, alice.Block)
+	assert.Equal(t, token.NewError(eStrWrongType), do(bob, cds.BLPop, "k", "0"))
+	do(writer, cds.Del, "k")
+	do(writer, cds.RPush, "k", "v")
+	assert.Equal(t, []*token.Token{bulkedArray("k", "v")}, alice.Out.Pop())
+
+	// the timeout reply is returned after the deadline
+	assert.Nil(t, do(alice, cds.BLPop, "a", "0.001"))
+	<-time.After(2 * time.Millisecond)
+	rsp := make(chan time.Duration, 1)
+	p.Do(&model.ExpireTask{Rsp: rsp})
+	<-rsp
+	timeout := alice.Out.Pop()
+	assert.Equal(t, []*token.Token{token.NewNilArray()}, timeout)
+	assert.Equal(t, "*-1 ", timeout[0].Format())
+
+	// blocking commands return at once in the transaction
+	propagated()
+	do(alice, cds.Multi)
+	do(alice, cds.BLPop, "a", "0")
+	do(alice, cds.BZPopMin, "a", "0")
+	for _, r := range do(alice, cds.Exec).Data.([]*token.Token) {
+		assert.Equal(t, "*-1 ", r.Format())
+	}
+	assert.Nil(t, alice.Block)
+	assert.Empty(t, propagated())
+}
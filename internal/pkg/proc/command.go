@@ -1,8 +1,12 @@
 package proc
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
@@ -22,14 +26,46 @@ const (
 	strPong      = "pong"
 	eStrMismatch = "type of %v is %v instead of %v"
 	eStrArgMore  = "not enough arguments"
+	// WRONGTYPE is the prefix of error which clients recognize
+	eStrWrongType = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	// properties of the server replied by HELLO
+	serverName    = "redis"
+	serverVersion = "7.0.0"
+	// the only user, which accepts any password
+	defaultUser = "default"
 )
 
 // Processor handles all the tasks sent from the connection handlers
 // to the consumer.
 type Processor struct {
-	ctrlMap map[string]func(*model.Client, ...*token.Token) *token.Token
-	data    []*model.DataStorage
-	Msgs    struct {
+	// commands supported in lower case
+	commands map[string]*command
+	data     []*model.DataStorage
+	pubsub   *model.PubSub
+	// publishes keyspace events of all the databases
+	notifier *model.Notifier
+	// frequency of background tasks like active expiration
+	hz          int
+	expireCycle struct {
+		// database to scan first in the next cycle
+		db int
+		// number of cycles which ran out of time
+		timeCapReached int64
+		cycleTime      time.Duration
+	}
+	// messages waiting to be sent to the aof after the task is done
+	aof []*SetMsg
+	// requests propagated instead of the one being executed
+	rewrite struct {
+		done bool
+		ts   []*token.Token
+	}
+	// clients blocked by keys in order of blocking, and keys signaled ready
+	blocking struct {
+		clients map[blockKey][]*model.Client
+		ready   []blockKey
+	}
+	Msgs struct {
 		Set chan *SetMsg
 	}
 }
@@ -38,20 +74,18 @@ type Processor struct {
 // table-driven methods and data storage.
 func NewProcessor(n int) *Processor {
 	p := &Processor{}
-	p.ctrlMap = map[string]func(*model.Client, ...*token.Token) *token.Token{
-		cds.Desc:    p.desc,
-		cds.Discard: p.discard,
-		cds.Exec:    p.exec,
-		cds.Get:     p.get,
-		cds.Incr:    p.incr,
-		cds.Multi:   p.multi,
-		cds.Select:  p.sel,
-		cds.Set:     p.set,
-		cds.Ping:    p.ping,
-		cds.Unwatch: p.unwatch,
-		cds.Watch:   p.watch,
+	p.commands = make(map[string]*command)
+	for _, c := range p.commandTable() {
+		p.commands[c.name] = c
 	}
 	p.data = model.NewDataArray(n)
+	p.pubsub = model.NewPubSub()
+	p.hz = defaultHz
+	p.notifier = model.NewNotifier(p.pubsub)
+	for _, d := range p.data {
+		d.SetNotifier(p.notifier)
+	}
+	p.blocking.clients = make(map[blockKey][]*model.Client)
 	p.Msgs.Set = make(chan *SetMsg)
 	return p
 }
@@ -61,9 +95,11 @@ func (p *Processor) NewMockClient() *model.Client {
 	return model.NewClient(nil, p.data[0])
 }
 
-// NewClient returns a new client collecting stat.
+// NewClient returns a new client collecting stat, whose replies are pushed
+// to the outbox.
 func (p *Processor) NewClient(conn net.Conn) *model.Client {
-	return &model.Client{Conn: conn, Data: p.data[0], Multi: &model.MultiInfo{}, Stat: true}
+	return &model.Client{Conn: conn, Data: p.data[0], Multi: &model.MultiInfo{},
+		Stat: true, Sub: model.NewSubInfo(), Out: model.NewOutbox(), Proto: token.Resp2}
 }
 
 // GenBin is a generator which yields every key-value pair of the original data.
@@ -74,18 +110,63 @@ func (p *Processor) GenBin(idx int, ch chan<- []byte) {
 		return
 	}
 	for k, v := range data {
-		val, _ := ItfToBulked(v.Row)
-		t := token.NewArray(token.NewString(cds.Set), token.NewString(k), token.NewBulked(val))
-		if v.Expire > 0 {
-			t.Data = append(t.Data.([]*token.Token), token.NewString(cds.ExpireAtNano), token.NewInteger(v.Expire))
+		for _, t := range dumpItem(k, v) {
+			d, _ := t.Serialize()
+			ch <- d
+		}
+	}
+}
+
+// dumpItem returns the requests which restore the item when replayed.
+func dumpItem(key string, item *model.Item) []*token.Token {
+	var ts []*token.Token
+	switch v := item.Row.(type) {
+	case *model.List:
+		row := []*token.Token{token.NewString(cds.RPush), token.NewString(key)}
+		for _, e := range v.Range(0, v.Len()-1) {
+			row = append(row, token.NewBulked(e))
 		}
-		d, _ := t.Serialize()
-		ch <- d
+		ts = append(ts, token.NewArray(row...))
+	case *model.Hash:
+		row := []*token.Token{token.NewString(cds.HSet), token.NewString(key)}
+		for _, field := range v.Fields() {
+			val, _ := v.Get(field)
+			row = append(row, token.NewBulked([]byte(field)), token.NewBulked(val))
+		}
+		ts = append(ts, token.NewArray(row...))
+	case *model.Set:
+		row := []*token.Token{token.NewString(cds.SAdd), token.NewString(key)}
+		for _, m := range v.Members() {
+			row = append(row, token.NewBulked([]byte(m)))
+		}
+		ts = append(ts, token.NewArray(row...))
+	case *model.ZSet:
+		row := []*token.Token{token.NewString(cds.ZAdd), token.NewString(key)}
+		for _, item := range v.Range(0, v.Len()-1, false) {
+			row = append(row, token.NewBulked([]byte(token.FormatFloat(item.Score))), token.NewBulked([]byte(item.Member)))
+		}
+		ts = append(ts, token.NewArray(row...))
+	case *model.Stream:
+		ts = dumpStream(key, v)
+	default:
+		val, _ := ItfToBulked(v)
+		return []*token.Token{setAtToken(key, token.NewBulked(val), item.Expire)}
 	}
+	if item.Expire > 0 {
+		ts = append(ts, expireAtToken(key, item.Expire))
+	}
+	return ts
 }
 
 // execCmd returns result of parsing request command and arguments
 func (p *Processor) execCmd(cli *model.Client, req *token.Token) (ret *token.Token) {
+	var name string
+	defer func() {
+		if ret != nil && ret.Label == label.Error && (ret.Data == eStrArgMore || ret.Data == errArgNumber.Error()) {
+			ret = token.NewError(errArity(name).Error())
+		}
+		ret = WithKind(ret)
+	}()
 	if req == nil {
 		return token.NewError("empty request")
 	}
@@ -97,29 +178,70 @@ func (p *Processor) execCmd(cli *model.Client, req *token.Token) (ret *token.Tok
 	if err := checkType(cmd, "command", label.String); err != nil {
 		return token.NewError(err.Error())
 	}
-	if cli.Multi.State {
-		switch cmd.Data.(string) {
-		case cds.Discard, cds.Exec, cds.Multi, cds.Watch:
-		default:
-			cli.Multi.Queue = append(cli.Multi.Queue, req)
-			return token.ReplyQueued
+	// command names are case-insensitive
+	original := cmd.Data.(string)
+	name = strings.ToLower(original)
+	cmd.Data = name
+	c, ok := p.commands[name]
+	// commands rejected inside MULTI discard the transaction
+	reject := func(err error) *token.Token {
+		if cli.Multi.State {
+			cli.Multi.Aborted = true
 		}
+		return token.NewError(err.Error())
+	}
+	if !ok {
+		return reject(errUnknownCommand(original, args))
+	}
+	if !c.arityMatched(len(data)) {
+		return reject(errArity(name))
 	}
-	if proc, ok := p.ctrlMap[cmd.Data.(string)]; ok {
-		ret = proc(cli, args...)
-	} else {
-		ret = token.NewError("unrecognized command")
+	// messages are pushed out of band in RESP3, so that any command is allowed
+	if cli.Sub.Count() > 0 && cli.Proto < token.Resp3 && !allowedInSubscriberMode(name) {
+		return reject(fmt.Errorf(eStrSubscriberMode, name))
 	}
-	if ret.Label == label.Error || !cli.Stat {
+	// replies pushed by subscriptions would not be replied by EXEC
+	if cli.Multi.State && isSubscription(name) {
+		return reject(errors.New(eStrSubscribeInMulti))
+	}
+	if cli.Multi.State && c.flags&flagNoMulti == 0 {
+		cli.Multi.Queue = append(cli.Multi.Queue, req)
+		return token.ReplyQueued
+	}
+	p.rewrite.done, p.rewrite.ts = false, nil
+	ret = c.proc(cli, args...)
+	// nil is returned if replies are pushed to the client by the command
+	if ret == nil || ret.Label == label.Error || !cli.Stat {
 		return
 	}
-	switch cmd.Data.(string) {
-	case cds.Incr, cds.Desc, cds.Set:
-		ret.Flag |= token.FlagSet
+	if c.flags&flagWrite != 0 {
+		// replies like ReplyOk are shared, flag a copy instead
+		ret = &token.Token{Data: ret.Data, Flag: ret.Flag | token.FlagSet, Label: ret.Label}
+		p.propagate(cli, req)
 	}
 	return
 }
 
+// propagate queues the request executed to be sent to the aof, or the
+// requests rewritten by the handler instead.
+func (p *Processor) propagate(cli *model.Client, req *token.Token) {
+	ts := []*token.Token{req}
+	if p.rewrite.done {
+		ts = p.rewrite.ts
+	}
+	for _, t := range ts {
+		p.aof = append(p.aof, &SetMsg{cli.Data.Idx(), t})
+	}
+}
+
+// propagateAs replaces the requests sent to the aof by the command being
+// executed. Commands whose replay is not deterministic, e.g. relative
+// expiration, should be rewritten. Nothing is propagated if ts is empty.
+func (p *Processor) propagateAs(ts ...*token.Token) {
+	p.rewrite.done = true
+	p.rewrite.ts = ts
+}
+
 func (p *Processor) execMod(cmd string, index int) error {
 	switch cmd {
 	case ModFreeze:
@@ -134,79 +256,327 @@ func (p *Processor) execMod(cmd string, index int) error {
 func (p *Processor) Do(tsk task.Task) {
 	switch t := tsk.(type) {
 	case *model.CmdTask:
-		rsp := p.execCmd(t.Cli, t.Req)
-		t.Rsp <- rsp
-		if rsp.Flag&token.FlagSet > 0 {
-			p.Msgs.Set <- &SetMsg{t.Cli.Data.Idx(), t.Req}
+		if t.Cli.Block != nil {
+			// requests of the blocked client are executed after it is unblocked
+			t.Cli.Block.Pending = append(t.Cli.Block.Pending, t.Req)
+			t.Rsp <- nil
+			return
 		}
+		reply := p.execCmd(t.Cli, t.Req)
+		if reply != nil && t.Cli.Out != nil {
+			t.Cli.Push(reply)
+		}
+		t.Rsp <- reply
+		p.serveBlocked()
+		p.flushAof()
 	case *model.ModTask:
 		t.Rsp <- p.execMod(t.Cmd, t.DataIdx)
+	case *model.CloseTask:
+		t.Cli.Unwatch()
+		p.pubsub.UnsubscribeAll(t.Cli)
+		if t.Cli.Block != nil {
+			p.unblock(t.Cli)
+		}
+		t.Rsp <- struct{}{}
+	case *model.ExpireTask:
+		p.expireBlocked()
+		p.serveBlocked()
+		p.flushAof()
+		t.Rsp <- p.activeExpire()
 	}
 }
 
-func (p *Processor) ping(_ *model.Client, _ ...*token.Token) *token.Token {
-	return token.NewString(strPong)
+// flushAof sends the messages queued to the aof
+func (p *Processor) flushAof() {
+	for _, m := range p.aof {
+		p.Msgs.Set <- m
+	}
+	p.aof = nil
 }
 
-func (p *Processor) set(cli *model.Client, tokens ...*token.Token) *token.Token {
-	if len(tokens) < 2 {
-		return token.NewError(eStrArgMore)
+func (p *Processor) ping(cli *model.Client, _ ...*token.Token) *token.Token {
+	if cli.Sub.Count() > 0 {
+		return token.NewArray(token.NewBulked([]byte(strPong)), token.NewBulked([]byte{}))
 	}
-	key, value := tokens[0], tokens[1]
-	if err := checkKeyType(key); err != nil {
-		return token.NewError(err.Error())
+	return token.NewString(strPong)
+}
+
+// hello switches the protocol of the client by "[protover [AUTH username
+// password] [SETNAME clientname]]", and replies the properties of the server.
+// There are no users but the default one, which accepts any password.
+func (p *Processor) hello(cli *model.Client, tokens ...*token.Token) *token.Token {
+	proto := cli.Proto
+	if len(tokens) > 0 {
+		if err := checkType(tokens[0], "protover", label.Integer); err != nil {
+			return token.NewError("Protocol version is not an integer or out of range")
+		}
+		v := tokens[0].Data.(int64)
+		if v != token.Resp2 && v != token.Resp3 {
+			return token.NewError(errNoProto.Error())
+		}
+		proto = int(v)
+	}
+	name, setName := "", false
+	for i := 1; i < len(tokens); i++ {
+		switch arg, _ := optionOf(tokens[i]); {
+		case arg == cds.Auth && i+2 < len(tokens):
+			user, err := tokenToBytes(tokens[i+1])
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			if string(user) != defaultUser {
+				return token.NewError(errWrongPass.Error())
+			}
+			i += 2
+		case arg == cds.SetName && i+1 < len(tokens):
+			data, err := tokenToBytes(tokens[i+1])
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			if !validClientName(data) {
+				return token.NewError("Client names cannot contain spaces, newlines or special characters.")
+			}
+			name, setName = string(data), true
+			i++
+		default:
+			return token.NewError("Syntax error in HELLO option '%s'", arg)
+		}
 	}
-	if err := checkType(value, "value", label.Bulked, label.Integer, label.String); err != nil {
-		return token.NewError(err.Error())
+	cli.Proto = proto
+	if setName {
+		cli.Name = name
+	}
+	return token.NewMap(
+		token.NewBulked([]byte("server")), token.NewBulked([]byte(serverName)),
+		token.NewBulked([]byte("version")), token.NewBulked([]byte(serverVersion)),
+		token.NewBulked([]byte("proto")), token.NewInteger(int64(proto)),
+		token.NewBulked([]byte("mode")), token.NewBulked([]byte("standalone")),
+		token.NewBulked([]byte("role")), token.NewBulked([]byte("master")),
+		token.NewBulked([]byte("modules")), token.NewArray(),
+	)
+}
+
+// validClientName reports whether the name contains only printable
+// characters except spaces
+func validClientName(name []byte) bool {
+	for _, c := range name {
+		if c <= ' ' || c > '~' {
+			return false
+		}
 	}
-	var expire int64
-	for i := 2; i < len(tokens); i++ {
+	return true
+}
+
+// setOption is the options of set
+type setOption struct {
+	expire  int64
+	nx, xx  bool
+	get     bool
+	keepTTL bool
+}
+
+// parseSet parses "[NX|XX] [GET] [EX seconds|PX milliseconds|EXAT timestamp|
+// PXAT timestamp-ms|KEEPTTL]" and the private "PT timestamp-ns".
+func parseSet(tokens []*token.Token) (*setOption, error) {
+	opt := &setOption{}
+	var expireSet bool
+	for i := 0; i < len(tokens); i++ {
 		if tokens[i] == nil {
-			return token.NewError("empty token")
+			return nil, fmt.Errorf("empty token")
 		}
 		if err := checkType(tokens[i], "argument", label.String); err != nil {
-			return token.NewError(err.Error())
+			return nil, err
 		}
-		arg := tokens[i].Data.(string)
+		arg, _ := optionOf(tokens[i])
 		switch arg {
-		case cds.TimeoutMilSec, cds.TimeoutSec, cds.ExpireAtNano:
+		case cds.IfNotExist:
+			opt.nx = true
+		case cds.IfExist:
+			opt.xx = true
+		case cds.GetArg:
+			opt.get = true
+		case cds.KeepTTL:
+			if expireSet {
+				return nil, errSyntax
+			}
+			opt.keepTTL, expireSet = true, true
+		case cds.TimeoutSec, cds.TimeoutMilSec, cds.ExpireAtSec, cds.ExpireAtMilSec, cds.ExpireAtNano:
+			if expireSet {
+				return nil, errSyntax
+			}
+			expireSet = true
 			i++
 			if len(tokens) < i+1 {
-				return token.NewError("argument missing of %s", arg)
+				return nil, errArgMissing(arg)
 			}
 			if err := checkType(tokens[i], "timeout", label.Integer); err != nil {
-				return token.NewError(err.Error())
+				return nil, err
 			}
+			// 0 means no expiration, so the time must be positive like setex
 			num := tokens[i].Data.(int64)
+			if num <= 0 {
+				return nil, errExpireInvalid
+			}
+			var err error
 			switch arg {
 			case cds.TimeoutSec:
-				expire = time.Now().Add(time.Duration(num) * time.Second).UnixNano()
+				opt.expire, err = toExpire(num, time.Second, false)
 			case cds.TimeoutMilSec:
-				expire = time.Now().Add(time.Duration(num) * time.Millisecond).UnixNano()
+				opt.expire, err = toExpire(num, time.Millisecond, false)
+			case cds.ExpireAtSec:
+				opt.expire, err = toExpire(num, time.Second, true)
+			case cds.ExpireAtMilSec:
+				opt.expire, err = toExpire(num, time.Millisecond, true)
 			case cds.ExpireAtNano:
-				expire = num
+				opt.expire = num
+			}
+			if err != nil {
+				return nil, err
 			}
 		default:
-			return token.NewError("argument not recognized")
+			return nil, errArgNotRecognized
 		}
 	}
-	cli.Set(key.Data.(string), value.Data, expire)
-	return token.ReplyOk
+	if opt.nx && opt.xx {
+		return nil, errSyntax
+	}
+	return opt, nil
 }
 
-func (p *Processor) get(cli *model.Client, tokens ...*token.Token) *token.Token {
-	if len(tokens) < 1 {
+// setAtToken returns the request setting the value of key along with the
+// absolute expiration, which is used to propagate set deterministically.
+func setAtToken(key string, value *token.Token, expire int64) *token.Token {
+	t := token.NewArray(token.NewString(cds.Set), token.NewString(key), value)
+	if expire > 0 {
+		t.Data = append(t.Data.([]*token.Token), token.NewString(cds.ExpireAtNano), token.NewInteger(expire))
+	}
+	return t
+}
+
+// set sets the value of key with options parsed by parseSet. Nil is returned
+// if the condition NX or XX is not met, and the old value is returned instead
+// of OK if GET is given.
+func (p *Processor) set(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, value := tokens[0], tokens[1]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(value, "value", label.Bulked, label.Integer, label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseSet(tokens[2:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	k := key.Data.(string)
+	var old []byte
+	if opt.get {
+		if old, err = getString(cli, k); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	reply := token.ReplyOk
+	if opt.get {
+		reply = stringReply(old)
+	}
+	if exists := cli.Get(k) != nil; (opt.nx && exists) || (opt.xx && !exists) {
+		p.propagateAs()
+		if opt.get {
+			return reply
+		}
+		return token.NewBulked(nil)
+	}
+	expire := opt.expire
+	if opt.keepTTL {
+		expire, _ = cli.GetExpire(k)
+	}
+	cli.Set(k, value.Data, expire)
+	cli.Notify(model.NotifyString, model.EventSet, k)
+	if opt.expire > 0 {
+		cli.Notify(model.NotifyGeneric, model.EventExpire, k)
+	}
+	p.propagateAs(setAtToken(k, value, expire))
+	return reply
+}
+
+// setNX sets the value of key if it does not exist, returns 1 if set
+func (p *Processor) setNX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	reply := p.set(cli, tokens[0], tokens[1], token.NewString(cds.IfNotExist))
+	if reply.Label == label.Error {
+		return reply
+	}
+	if reply.Data == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(1)
+}
+
+// setWithTimeout sets the value of key by "key timeout value", the timeout
+// is set by the argument arg of set and must be positive.
+func (p *Processor) setWithTimeout(cli *model.Client, tokens []*token.Token, arg string) *token.Token {
+	if len(tokens) < 3 {
 		return token.NewError(eStrArgMore)
 	}
+	if err := checkType(tokens[1], "timeout", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	if tokens[1].Data.(int64) <= 0 {
+		return token.NewError(eStrExpireInvalid)
+	}
+	return p.set(cli, tokens[0], tokens[2], token.NewString(arg), tokens[1])
+}
+
+func (p *Processor) setEx(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setWithTimeout(cli, tokens, cds.TimeoutSec)
+}
+
+func (p *Processor) pSetEx(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setWithTimeout(cli, tokens, cds.TimeoutMilSec)
+}
+
+func (p *Processor) get(cli *model.Client, tokens ...*token.Token) *token.Token {
 	key := tokens[0]
 	if err := checkKeyType(key); err != nil {
 		return token.NewError(err.Error())
 	}
 	val := cli.Get(key.Data.(string))
+	if val != nil && !isString(val) {
+		return token.NewError(eStrWrongType)
+	}
 	data, _ := ItfToBulked(val)
 	return token.NewBulked(data)
 }
 
+// del removes the keys given and returns the number of keys removed.
+// Command unlink shares the implementation since memory is reclaimed by gc.
+func (p *Processor) del(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	for _, key := range tokens {
+		if cli.Del(key.Data.(string)) {
+			n++
+		}
+	}
+	return token.NewInteger(n)
+}
+
+// exists returns the number of keys existing, a key mentioned multiple times
+// is counted multiple times.
+func (p *Processor) exists(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	for _, key := range tokens {
+		if cli.Get(key.Data.(string)) != nil {
+			n++
+		}
+	}
+	return token.NewInteger(n)
+}
+
 func (p *Processor) step(cli *model.Client, tokens []*token.Token, n int64) *token.Token {
 	if len(tokens) < 1 {
 		return token.NewError(eStrArgMore)
@@ -216,32 +586,90 @@ func (p *Processor) step(cli *model.Client, tokens []*token.Token, n int64) *tok
 		return token.NewError(err.Error())
 	}
 	oldVal := cli.Get(key.Data.(string))
+	if oldVal != nil && !isString(oldVal) {
+		return token.NewError(eStrWrongType)
+	}
 	num, err := ItfToInt(oldVal)
 	if err != nil {
 		return token.NewError(err.Error())
 	}
 	if num != nil {
-		n = num.(int64) + n
+		old := num.(int64)
+		if (n > 0 && old > math.MaxInt64-n) || (n < 0 && old < math.MinInt64-n) {
+			return token.NewError(errOverflow.Error())
+		}
+		n = old + n
 	}
-	t := token.NewInteger(n)
-	p.set(cli, tokens[0], t)
-	return t
+	setKeepTTL(cli, key.Data.(string), n, model.EventIncrBy)
+	return token.NewInteger(n)
 }
 
 func (p *Processor) incr(cli *model.Client, tokens ...*token.Token) *token.Token {
 	return p.step(cli, tokens, 1)
 }
 
-func (p *Processor) desc(cli *model.Client, tokens ...*token.Token) *token.Token {
+// decr is also registered as the legacy command desc
+func (p *Processor) decr(cli *model.Client, tokens ...*token.Token) *token.Token {
 	return p.step(cli, tokens, -1)
 }
 
+func (p *Processor) incrBy(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[1], "increment", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	return p.step(cli, tokens, tokens[1].Data.(int64))
+}
+
+func (p *Processor) decrBy(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[1], "decrement", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	n := tokens[1].Data.(int64)
+	// the negation of min int64 overflows
+	if n == math.MinInt64 {
+		return token.NewError(errOverflow.Error())
+	}
+	return p.step(cli, tokens, -n)
+}
+
+// incrByFloat propagates the result with set, since float arithmetic
+// may differ when replayed.
+func (p *Processor) incrByFloat(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	incr, err := tokenToFloat(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	data, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var num float64
+	if data != nil {
+		if num, err = strconv.ParseFloat(string(data), 64); err != nil || math.IsNaN(num) {
+			return token.NewError(errNotFloat.Error())
+		}
+	}
+	num += incr
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		return token.NewError(errNaNOrInf.Error())
+	}
+	v := []byte(token.FormatFloat(num))
+	setKeepTTL(cli, key, v, model.EventIncrByFloat)
+	p.propagateAs(token.NewArray(token.NewString(cds.Set), tokens[0], token.NewBulked(v), token.NewString(cds.KeepTTL)))
+	return token.NewBulked(v)
+}
+
 func (p *Processor) multi(cli *model.Client, _ ...*token.Token) *token.Token {
 	if cli.Multi.State {
 		return token.NewError("multi calls can not be nested")
 	}
 	cli.Multi.State = true
 	cli.Multi.Dirty = false
+	cli.Multi.Aborted = false
 	return token.ReplyOk
 }
 
@@ -249,13 +677,22 @@ func (p *Processor) exec(cli *model.Client, _ ...*token.Token) *token.Token {
 	if !cli.Multi.State {
 		return token.NewError("exec without multi")
 	}
-	var responses []*token.Token
 	cli.Multi.State = false
+	if cli.Multi.Aborted {
+		cli.Multi.Aborted = false
+		cli.Multi.Queue = nil
+		cli.Unwatch()
+		return token.NewError(errExecAbort.Error())
+	}
+	var responses []*token.Token
 	if !cli.Multi.Dirty {
+		// blocking commands return at once in the transaction
+		cli.Multi.Executing = true
 		for _, t := range cli.Multi.Queue {
 			rsp := p.execCmd(cli, t)
 			responses = append(responses, rsp)
 		}
+		cli.Multi.Executing = false
 	}
 	cli.Multi.Queue = nil
 	cli.Unwatch()
@@ -267,15 +704,13 @@ func (p *Processor) discard(cli *model.Client, _ ...*token.Token) *token.Token {
 		return token.NewError("discard calls without multi")
 	}
 	cli.Multi.State = false
+	cli.Multi.Aborted = false
 	cli.Multi.Queue = nil
 	cli.Unwatch()
 	return token.ReplyOk
 }
 
 func (p *Processor) watch(cli *model.Client, tokens ...*token.Token) *token.Token {
-	if len(tokens) < 1 {
-		return token.NewError(eStrArgMore)
-	}
 	if cli.Multi.State {
 		return token.NewError("watch inside multi is not allowed")
 	}
@@ -293,13 +728,10 @@ func (p *Processor) unwatch(cli *model.Client, _ ...*token.Token) *token.Token {
 
 // select
 func (p *Processor) sel(cli *model.Client, tokens ...*token.Token) *token.Token {
-	if len(tokens) < 1 {
-		return token.NewError(eStrArgMore)
-	}
-	if err := checkType(tokens[0], "index", label.Integer); err != nil {
+	idx, err := p.parseDB(tokens[0])
+	if err != nil {
 		return token.NewError(err.Error())
 	}
-	idx := int(tokens[0].Data.(int64))
 	cli.Data = p.data[idx]
 	return token.ReplyOk
 }
@@ -1,11 +1,14 @@
 package proc
 
 import (
+	"math"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
 	"github.com/inhzus/go-redis-impl/internal/pkg/model"
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 	"github.com/stretchr/testify/assert"
@@ -50,9 +53,6 @@ func TestProcessor_set(t *testing.T) {
 		args args
 		want *token.Token
 	}{
-		{"missing arguments",
-			args{cli, []*token.Token{token.NewString("a")}},
-			token.NewError(eStrArgMore)},
 		{"key type error",
 			args{cli, []*token.Token{token.NewInteger(1), token.NewInteger(2)}},
 			token.NewError("type of key is integer instead of string")},
@@ -98,6 +98,99 @@ func TestProcessor_set(t *testing.T) {
 	assert.Equal(t, nil, proc.get(cli, token.NewString("a")).Data)
 }
 
+func TestProcessor_set_options(t *testing.T) {
+	key := token.NewString("t_set_opt")
+	set := func(ts ...*token.Token) *token.Token {
+		return proc.set(cli, append([]*token.Token{key}, ts...)...)
+	}
+	assert.Equal(t, token.NewBulked(nil), set(token.NewString("1"), token.NewString(cds.IfExist)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+	assert.Equal(t, token.ReplyOk, set(token.NewString("1"), token.NewString("nx"), token.NewString(cds.TimeoutSec),
+		token.NewInteger(100)))
+	assert.Equal(t, token.NewBulked(nil), set(token.NewString("2"), token.NewString(cds.IfNotExist)))
+	assert.Equal(t, token.NewBulked([]byte("1")), set(token.NewString("2"), token.NewString(cds.IfNotExist),
+		token.NewString(cds.GetArg)))
+	assert.Equal(t, token.NewBulked([]byte("1")), proc.get(cli, key))
+
+	// KEEPTTL preserves the expiration, which is cleared otherwise
+	assert.Equal(t, token.NewBulked([]byte("1")), set(token.NewString("2"), token.NewString(cds.IfExist),
+		token.NewString(cds.GetArg), token.NewString(cds.KeepTTL)))
+	assert.Equal(t, token.NewBulked([]byte("2")), proc.get(cli, key))
+	assert.True(t, proc.ttl(cli, key).Data.(int64) > 0)
+	assert.Equal(t, token.ReplyOk, set(token.NewString("3")))
+	assert.Equal(t, token.NewInteger(-1), proc.ttl(cli, key))
+
+	at := time.Now().Add(time.Hour).Unix()
+	assert.Equal(t, token.ReplyOk, set(token.NewString("4"), token.NewString(cds.ExpireAtSec), token.NewInteger(at)))
+	expire, _ := cli.GetExpire("t_set_opt")
+	assert.Equal(t, at*int64(time.Second), expire)
+	assert.Equal(t, token.ReplyOk, set(token.NewString("4"), token.NewString(cds.ExpireAtMilSec), token.NewInteger(at*1000)))
+	expire, _ = cli.GetExpire("t_set_opt")
+	assert.Equal(t, at*int64(time.Second), expire)
+
+	proc.rPush(cli, token.NewString("t_set_opt_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.set(cli, token.NewString("t_set_opt_list"), token.NewString("1"),
+		token.NewString(cds.GetArg)))
+	assert.Equal(t, token.ReplyOk, proc.set(cli, token.NewString("t_set_opt_list"), token.NewString("1")))
+
+	for _, args := range [][]*token.Token{
+		{token.NewString(cds.IfNotExist), token.NewString(cds.IfExist)},
+		{token.NewString(cds.KeepTTL), token.NewString(cds.TimeoutSec), token.NewInteger(1)},
+		{token.NewString(cds.TimeoutSec), token.NewInteger(1), token.NewString(cds.TimeoutMilSec), token.NewInteger(1)},
+	} {
+		assert.Equal(t, token.NewError(errSyntax.Error()), set(append([]*token.Token{token.NewString("5")}, args...)...))
+	}
+	for _, args := range [][]*token.Token{
+		{token.NewString(cds.ExpireAtSec), token.NewInteger(0)},
+		{token.NewString(cds.TimeoutSec), token.NewInteger(0)},
+		{token.NewString(cds.TimeoutMilSec), token.NewInteger(-5)},
+		{token.NewString(cds.ExpireAtMilSec), token.NewInteger(-1)},
+	} {
+		assert.Equal(t, token.NewError(eStrExpireInvalid), set(append([]*token.Token{token.NewString("5")}, args...)...))
+	}
+	assert.Equal(t, token.NewBulked([]byte("4")), proc.get(cli, key))
+}
+
+func TestProcessor_setNX(t *testing.T) {
+	key := token.NewString("t_setnx")
+	assert.Equal(t, token.NewInteger(1), proc.setNX(cli, key, token.NewString("1")))
+	assert.Equal(t, token.NewInteger(0), proc.setNX(cli, key, token.NewString("2")))
+	assert.Equal(t, token.NewBulked([]byte("1")), proc.get(cli, key))
+
+	assert.Equal(t, token.ReplyOk, proc.setEx(cli, key, token.NewInteger(100), token.NewString("3")))
+	assert.Equal(t, token.NewInteger(100), proc.ttl(cli, key))
+	assert.Equal(t, token.ReplyOk, proc.pSetEx(cli, key, token.NewInteger(100000), token.NewString("4")))
+	assert.InDelta(t, 100000, proc.pTTL(cli, key).Data.(int64), 1000)
+	assert.Equal(t, token.NewBulked([]byte("4")), proc.get(cli, key))
+	assert.Equal(t, token.NewError(eStrExpireInvalid), proc.setEx(cli, key, token.NewInteger(0), token.NewString("5")))
+	assert.Equal(t, token.NewError(eStrArgMore), proc.pSetEx(cli, key, token.NewInteger(1)))
+
+	// incr keeps the expiration while getset clears it
+	proc.setEx(cli, key, token.NewInteger(100), token.NewInteger(1))
+	assert.Equal(t, token.NewInteger(2), proc.incr(cli, key))
+	assert.Equal(t, token.NewInteger(100), proc.ttl(cli, key))
+	proc.getSet(cli, key, token.NewInteger(1))
+	assert.Equal(t, token.NewInteger(-1), proc.ttl(cli, key))
+}
+
+func TestProcessor_set_propagate(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("k")
+	p.execCmd(c, token.NewArray(token.NewString(cds.Set), key, token.NewString("1"), token.NewString(cds.TimeoutSec),
+		token.NewInteger(100)))
+	expire, _ := c.GetExpire("k")
+	assert.Equal(t, setAtToken("k", token.NewString("1"), expire), p.aof[0].T)
+	p.execCmd(c, token.NewArray(token.NewString(cds.SetNX), key, token.NewString("2")))
+	assert.Equal(t, 1, len(p.aof))
+	p.execCmd(c, token.NewArray(token.NewString(cds.Set), key, token.NewString("2"), token.NewString(cds.KeepTTL)))
+	assert.Equal(t, setAtToken("k", token.NewString("2"), expire), p.aof[1].T)
+	p.execCmd(c, token.NewArray(token.NewString(cds.Set), key, token.NewString("3")))
+	assert.Equal(t, setAtToken("k", token.NewString("3"), 0), p.aof[2].T)
+	p.execCmd(c, token.NewArray(token.NewString(cds.PSetEx), key, token.NewInteger(100), token.NewString("4")))
+	assert.Equal(t, 4, len(p.aof))
+}
+
 func TestProcessor_get(t *testing.T) {
 	proc.set(cli, []*token.Token{token.NewString("a"), token.NewInteger(4)}...)
 	type args struct {
@@ -109,12 +202,9 @@ func TestProcessor_get(t *testing.T) {
 		args args
 		want *token.Token
 	}{
-		{"missing argument",
-			args{cli, []*token.Token{}},
-			token.NewError("not enough arguments")},
 		{"key type error",
-			args{cli, []*token.Token{token.NewBulked([]byte("a"))}},
-			token.NewError("type of key is bulked instead of string")},
+			args{cli, []*token.Token{token.NewInteger(1)}},
+			token.NewError("type of key is integer instead of string")},
 		{"success",
 			args{cli, []*token.Token{token.NewString("a")}},
 			token.NewBulked([]byte("4"))},
@@ -144,8 +234,8 @@ func TestProcessor_step(t *testing.T) {
 			args{cli, []*token.Token{}, 1},
 			token.NewError("not enough arguments")},
 		{"key type error",
-			args{cli, []*token.Token{token.NewBulked([]byte("a"))}, 1},
-			token.NewError("type of key is bulked instead of string")},
+			args{cli, []*token.Token{token.NewInteger(1)}, 1},
+			token.NewError("type of key is integer instead of string")},
 		{"value type error",
 			args{cli, []*token.Token{token.NewString("b")}, 1},
 			token.NewError("value (test) cannot cast to int")},
@@ -188,7 +278,7 @@ func TestProcessor_incr(t *testing.T) {
 	}
 }
 
-func TestProcessor_desc(t *testing.T) {
+func TestProcessor_decr(t *testing.T) {
 	type args struct {
 		cli    *model.Client
 		tokens []*token.Token
@@ -204,13 +294,91 @@ func TestProcessor_desc(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := proc.desc(tt.args.cli, tt.args.tokens...); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("desc() = %v, want %v", got, tt.want)
+			if got := proc.decr(tt.args.cli, tt.args.tokens...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decr() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestProcessor_incrBy(t *testing.T) {
+	key := token.NewString("t_incrby")
+	assert.Equal(t, token.NewInteger(10), proc.incrBy(cli, key, token.NewInteger(10)))
+	assert.Equal(t, token.NewInteger(7), proc.decrBy(cli, key, token.NewInteger(3)))
+	assert.Equal(t, token.NewInteger(6), proc.execCmd(cli, token.NewArray(token.NewString(cds.Desc), key)))
+	assert.Equal(t, token.NewInteger(5), proc.execCmd(cli, token.NewArray(token.NewString(cds.Decr), key)))
+	assert.Equal(t, token.NewError("type of increment is string instead of integer"),
+		proc.incrBy(cli, key, token.NewString("a")))
+
+	proc.set(cli, key, token.NewInteger(math.MaxInt64-1))
+	assert.Equal(t, token.NewInteger(math.MaxInt64), proc.incr(cli, key))
+	assert.Equal(t, token.NewError(errOverflow.Error()), proc.incr(cli, key))
+	assert.Equal(t, token.NewError(errOverflow.Error()), proc.decrBy(cli, key, token.NewInteger(math.MinInt64)))
+	proc.set(cli, key, token.NewInteger(math.MinInt64+1))
+	assert.Equal(t, token.NewError(errOverflow.Error()), proc.decrBy(cli, key, token.NewInteger(2)))
+	assert.Equal(t, token.NewError(errOverflow.Error()), proc.incrBy(cli, key, token.NewInteger(-2)))
+	assert.Equal(t, token.NewBulked([]byte(strconv.FormatInt(math.MinInt64+1, 10))), proc.get(cli, key))
+}
+
+func TestProcessor_incrByFloat(t *testing.T) {
+	key := token.NewString("t_incrbyfloat")
+	proc.set(cli, key, token.NewBulked([]byte("10.50")), token.NewString(cds.TimeoutSec), token.NewInteger(100))
+	assert.Equal(t, token.NewBulked([]byte("10.6")), proc.incrByFloat(cli, key, token.NewBulked([]byte("0.1"))))
+	assert.Equal(t, token.NewBulked([]byte("5.6")), proc.incrByFloat(cli, key, token.NewInteger(-5)))
+	assert.Equal(t, token.NewBulked([]byte("5200")), proc.incrByFloat(cli, key, token.NewBulked([]byte("5.1944e3"))))
+	assert.Equal(t, token.NewInteger(100), proc.ttl(cli, key))
+	assert.Equal(t, token.NewBulked([]byte("1.5")), proc.incrByFloat(cli, token.NewString("t_incrbyfloat_none"),
+		token.NewBulked([]byte("1.5"))))
+
+	assert.Equal(t, token.NewError(errNotFloat.Error()), proc.incrByFloat(cli, key, token.NewBulked([]byte("a"))))
+	assert.Equal(t, token.NewError(errNaNOrInf.Error()), proc.incrByFloat(cli, key, token.NewBulked([]byte("+inf"))))
+	proc.set(cli, key, token.NewBulked([]byte("a")))
+	assert.Equal(t, token.NewError(errNotFloat.Error()), proc.incrByFloat(cli, key, token.NewInteger(1)))
+	proc.rPush(cli, token.NewString("t_incrbyfloat_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.incrByFloat(cli, token.NewString("t_incrbyfloat_list"),
+		token.NewInteger(1)))
+
+	// the result is propagated by set
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	p.execCmd(c, token.NewArray(token.NewString(cds.IncrByFlt), key, token.NewBulked([]byte("0.3"))))
+	assert.Equal(t, token.NewArray(token.NewString(cds.Set), key, token.NewBulked([]byte("0.3")),
+		token.NewString(cds.KeepTTL)), p.aof[0].T)
+}
+
+func TestProcessor_del(t *testing.T) {
+	assert.Equal(t, token.NewError("type of key is integer instead of string"),
+		proc.del(cli, token.NewString("t_del"), token.NewInteger(1)))
+	proc.set(cli, token.NewString("t_del1"), token.NewInteger(1))
+	proc.set(cli, token.NewString("t_del2"), token.NewInteger(2))
+	assert.Equal(t, token.NewInteger(2), proc.exists(cli,
+		token.NewString("t_del1"), token.NewString("t_del2"), token.NewString("t_del3")))
+	assert.Equal(t, token.NewInteger(2), proc.exists(cli,
+		token.NewString("t_del1"), token.NewString("t_del1")))
+	assert.Equal(t, token.NewInteger(1), proc.del(cli,
+		token.NewString("t_del1"), token.NewString("t_del3")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, token.NewString("t_del1")))
+	assert.Equal(t, token.NewInteger(1), proc.execCmd(cli,
+		token.NewArray(token.NewString(cds.Unlink), token.NewString("t_del2"))))
+	assert.Equal(t, token.NewBulked(nil), proc.get(cli, token.NewString("t_del2")))
+}
+
+func TestProcessor_del_watch(t *testing.T) {
+	c := model.NewClient(nil, proc.data[0])
+	key := "t_del_watch"
+	proc.set(c, token.NewString(key), token.NewInteger(1))
+	assert.Equal(t, token.ReplyOk,
+		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch), token.NewString(key))))
+	assert.Equal(t, token.ReplyOk,
+		proc.execCmd(cli, token.NewArray(token.NewString(cds.Multi))))
+	assert.Equal(t, token.NewInteger(1),
+		proc.execCmd(c, token.NewArray(token.NewString(cds.Del), token.NewString(key))))
+	assert.Equal(t, token.ReplyQueued,
+		proc.execCmd(cli, token.NewArray(token.NewString(cds.Get), token.NewString(key))))
+	assert.Equal(t, token.NewArray(),
+		proc.execCmd(cli, token.NewArray(token.NewString(cds.Exec))))
+}
+
 func TestProcessor_multi(t *testing.T) {
 	type args struct {
 		cli *model.Client
@@ -235,7 +403,7 @@ func TestProcessor_multi(t *testing.T) {
 			}
 		})
 	}
-	assert.Equal(t, token.NewError("multi calls can not be nested"),
+	assert.Equal(t, token.NewError("ERR multi calls can not be nested"),
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Multi))))
 	proc.exec(cli)
 }
@@ -267,12 +435,12 @@ func TestProcessor_exec(t *testing.T) {
 }
 
 func TestProcessor_discard(t *testing.T) {
-	assert.Equal(t, token.NewError("discard calls without multi"),
+	assert.Equal(t, token.NewError("ERR discard calls without multi"),
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Discard))))
 	assert.Equal(t, token.ReplyOk, proc.multi(cli))
 	assert.Equal(t, token.ReplyQueued, proc.execCmd(cli, token.NewArray(token.NewString(cds.Get), token.NewString("a"))))
 	assert.Equal(t, token.ReplyOk, proc.execCmd(cli, token.NewArray(token.NewString(cds.Discard))))
-	assert.Equal(t, token.NewError("exec without multi"), proc.execCmd(cli, token.NewArray(token.NewString(cds.Exec))))
+	assert.Equal(t, token.NewError("ERR exec without multi"), proc.execCmd(cli, token.NewArray(token.NewString(cds.Exec))))
 	assert.Equal(t, token.ReplyOk, proc.execCmd(cli, token.NewArray(token.NewString(cds.Multi))))
 	assert.Equal(t, token.NewArray(), proc.execCmd(cli, token.NewArray(token.NewString(cds.Exec))))
 }
@@ -280,17 +448,17 @@ func TestProcessor_discard(t *testing.T) {
 func TestProcessor_watch(t *testing.T) {
 	c := model.NewClient(nil, proc.data[0])
 	key := "t_watch"
-	assert.Equal(t, token.NewError(eStrArgMore),
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'watch' command"),
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch))))
-	assert.Equal(t, token.NewError("type of key is bulked instead of string"),
-		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch), token.NewBulked([]byte(key)))))
+	assert.Equal(t, token.NewError("ERR type of key is integer instead of string"),
+		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch), token.NewInteger(1))))
 	assert.Equal(t, token.ReplyOk,
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch), token.NewString(key))))
 	assert.Equal(t, token.ReplyOk,
 		proc.execCmd(c, token.NewArray(token.NewString(cds.Set), token.NewString(key), token.NewInteger(2))))
 	assert.Equal(t, token.ReplyOk,
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Multi))))
-	assert.Equal(t, token.NewError("watch inside multi is not allowed"),
+	assert.Equal(t, token.NewError("ERR watch inside multi is not allowed"),
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Watch), token.NewString(key))))
 	assert.Equal(t, token.ReplyQueued,
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Get), token.NewString(key))))
@@ -350,22 +518,31 @@ func TestProcessor_unwatch(t *testing.T) {
 func TestProcessor_sel(t *testing.T) {
 	key := "t_sel"
 	c := model.NewClient(nil, proc.data[0])
-	assert.Equal(t, token.NewError(eStrArgMore), proc.sel(cli))
 	assert.Equal(t, token.NewError("type of index is string instead of integer"), proc.sel(cli, token.NewString("1")))
 	assert.Equal(t, token.ReplyOk, proc.sel(cli, token.NewInteger(1)))
 	assert.Equal(t, token.ReplyOk, proc.set(cli, token.NewString(key), token.NewString("value")))
 	assert.Equal(t, token.NewBulked(nil), proc.get(c, token.NewString(key)))
 	assert.Equal(t, token.ReplyOk, proc.sel(c, token.NewInteger(1)))
 	assert.Equal(t, token.NewBulked([]byte("value")), proc.get(c, token.NewString(key)))
+	for _, idx := range []int64{-1, int64(len(proc.data)), 99} {
+		assert.Equal(t, token.NewError(errDBIndex.Error()), proc.sel(c, token.NewInteger(idx)))
+	}
+	assert.Equal(t, proc.data[1], c.Data)
 }
 
 func TestProcessor_Exec(t *testing.T) {
-	assert.Equal(t, token.NewError("empty request"),
+	assert.Equal(t, token.NewError("ERR empty request"),
 		proc.execCmd(cli, nil))
-	assert.Equal(t, token.NewError("empty token"),
+	assert.Equal(t, token.NewError("ERR empty token"),
 		proc.execCmd(cli, token.NewArray()))
-	assert.Equal(t, token.NewError("type of command is bulked instead of string"),
-		proc.execCmd(cli, token.NewArray(token.NewBulked([]byte(cds.Get)))))
+	assert.Equal(t, token.NewError("ERR type of command is integer instead of string"),
+		proc.execCmd(cli, token.NewArray(token.NewInteger(1))))
+	// arguments sent as bulked strings are converted, command names are case-insensitive
+	assert.Equal(t, token.ReplyOk.Data, proc.execCmd(cli, token.NewArray(token.NewBulked([]byte("SET")),
+		token.NewBulked([]byte("t_process_bulked")), token.NewBulked([]byte("1")))).Data)
+	assert.Equal(t, int64(3), proc.execCmd(cli, token.NewArray(token.NewBulked([]byte("IncrBy")),
+		token.NewBulked([]byte("t_process_bulked")), token.NewBulked([]byte("2")))).Data)
+	assert.Equal(t, token.NewString(strPong), proc.execCmd(cli, token.NewArray(token.NewString("PING"))))
 	assert.Equal(t, token.NewString(strPong),
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Ping))))
 	assert.Equal(t, token.NewInteger(-1),
@@ -375,6 +552,33 @@ func TestProcessor_Exec(t *testing.T) {
 	assert.Equal(t, token.ReplyOk,
 		proc.execCmd(cli, token.NewArray(token.NewString(cds.Select), token.NewInteger(2))))
 	assert.Equal(t, proc.data[2], cli.Data)
-	assert.Equal(t, token.NewError("unrecognized command"),
+	assert.Equal(t, token.NewError("ERR unknown command 'unknown command', with args beginning with: "),
 		proc.execCmd(cli, token.NewArray(token.NewString("unknown command"))))
 }
+
+func TestProcessor_hello(t *testing.T) {
+	c := proc.NewClient(nil)
+	reply := proc.hello(c)
+	assert.Equal(t, label.Map, reply.Label)
+	ts := reply.Data.([]*token.Token)
+	assert.Equal(t, []byte("proto"), ts[4].Data)
+	assert.Equal(t, token.NewInteger(token.Resp2), ts[5])
+
+	assert.Equal(t, token.NewInteger(token.Resp3), proc.hello(c, token.NewInteger(3)).Data.([]*token.Token)[5])
+	assert.Equal(t, token.Resp3, c.Proto)
+	assert.Equal(t, token.NewError("NOPROTO unsupported protocol version"), proc.hello(c, token.NewInteger(4)))
+	assert.Equal(t, token.NewError("Protocol version is not an integer or out of range"),
+		proc.hello(c, token.NewBulked([]byte("x"))))
+	assert.Equal(t, token.NewError("WRONGPASS invalid username-password pair or user is disabled."),
+		proc.hello(c, token.NewInteger(2), token.NewString(cds.Auth), token.NewString("foo"), token.NewString("bar")))
+	assert.Equal(t, token.NewError("Syntax error in HELLO option 'AUTH'"),
+		proc.hello(c, token.NewInteger(2), token.NewString("auth"), token.NewString("default")))
+	assert.Equal(t, token.NewError("Client names cannot contain spaces, newlines or special characters."),
+		proc.hello(c, token.NewInteger(2), token.NewString(cds.SetName), token.NewString("a b")))
+	assert.Equal(t, token.Resp3, c.Proto)
+
+	assert.Equal(t, label.Map, proc.hello(c, token.NewInteger(2), token.NewString(cds.Auth), token.NewString("default"),
+		token.NewString("any"), token.NewString(cds.SetName), token.NewString("conn")).Label)
+	assert.Equal(t, token.Resp2, c.Proto)
+	assert.Equal(t, "conn", c.Name)
+}
@@ -0,0 +1,101 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/glob"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// configParam gets and sets the parameter configurable at runtime
+type configParam struct {
+	get func() string
+	set func(string) error
+}
+
+// configParams returns the parameters configurable at runtime
+func (p *Processor) configParams() map[string]configParam {
+	return map[string]configParam{
+		cds.NotifyKeyspaceEvents: {
+			get: func() string { return model.FormatNotifyFlags(p.notifier.Flags) },
+			set: func(s string) error {
+				flags, err := model.ParseNotifyFlags(s)
+				if err != nil {
+					return err
+				}
+				p.notifier.Flags = flags
+				return nil
+			},
+		},
+		cds.Hz: {
+			get: func() string { return strconv.Itoa(p.hz) },
+			set: func(s string) error {
+				hz, err := strconv.Atoi(s)
+				if err != nil {
+					return fmt.Errorf("argument couldn't be parsed into an integer")
+				}
+				// clamp like redis does
+				if hz < 1 {
+					hz = 1
+				} else if hz > maxHz {
+					hz = maxHz
+				}
+				p.hz = hz
+				return nil
+			},
+		},
+	}
+}
+
+// ConfigSet sets the parameter, which is used to apply the server options
+func (p *Processor) ConfigSet(name, value string) error {
+	param, ok := p.configParams()[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unsupported CONFIG parameter: %s", name)
+	}
+	if err := param.set(value); err != nil {
+		return fmt.Errorf("invalid argument '%s' for CONFIG SET '%s': %s", value, name, err.Error())
+	}
+	return nil
+}
+
+// config gets parameters matching the pattern by "GET pattern", or sets the
+// parameter by "SET parameter value".
+func (p *Processor) config(_ *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[0], "subcommand", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	args, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	sub := strings.ToUpper(tokens[0].Data.(string))
+	switch {
+	case sub == cds.ConfigGet && len(args) == 1:
+		params := p.configParams()
+		names := make([]string, 0, len(params))
+		for name := range params {
+			if glob.Match(strings.ToLower(args[0]), name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		ts := make([]*token.Token, 0, len(names)*2)
+		for _, name := range names {
+			ts = append(ts, token.NewBulked([]byte(name)), token.NewBulked([]byte(params[name].get())))
+		}
+		return token.NewMap(ts...)
+	case sub == cds.ConfigSet && len(args) == 2:
+		if err := p.ConfigSet(args[0], args[1]); err != nil {
+			return token.NewError(err.Error())
+		}
+		return token.ReplyOk
+	}
+	return token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", sub))
+}
@@ -0,0 +1,98 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_config(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	name := token.NewString(cds.NotifyKeyspaceEvents)
+	assert.Equal(t, bulkedMap(cds.NotifyKeyspaceEvents, ""), p.config(c, token.NewString("get"), name))
+	assert.Equal(t, token.ReplyOk, p.config(c, token.NewString(cds.ConfigSet), name, token.NewString("Kgl")))
+	assert.Equal(t, bulkedMap(cds.NotifyKeyspaceEvents, "glK"), p.config(c, token.NewString(cds.ConfigGet), token.NewString("notify-*")))
+	assert.Equal(t, bulkedMap(), p.config(c, token.NewString(cds.ConfigGet), token.NewString("foo")))
+	assert.Equal(t, token.NewError("invalid argument 'Ky' for CONFIG SET 'notify-keyspace-events': invalid keyspace event flag 'y'"),
+		p.config(c, token.NewString(cds.ConfigSet), name, token.NewString("Ky")))
+	assert.Equal(t, token.NewError("unsupported CONFIG parameter: foo"),
+		p.config(c, token.NewString(cds.ConfigSet), token.NewString("foo"), token.NewString("1")))
+	assert.Equal(t, token.NewError("unknown subcommand or wrong number of arguments for 'SET'"),
+		p.config(c, token.NewString(cds.ConfigSet), name))
+
+	sub := p.NewClient(nil)
+	assert.Nil(t, p.pSubscribe(sub, token.NewString("__keyspace@0__:*")))
+	sub.Out.Pop()
+	p.lPush(c, token.NewString("t_config_l"), token.NewString("a"))
+	p.del(c, token.NewString("t_config_l"))
+	assert.Equal(t, []*token.Token{
+		msgArray(model.MsgPMessage, "__keyspace@0__:*", "__keyspace@0__:t_config_l", model.EventLPush),
+		msgArray(model.MsgPMessage, "__keyspace@0__:*", "__keyspace@0__:t_config_l", model.EventDel),
+	}, sub.Out.Pop())
+}
+
+func TestProcessor_keyspaceEvents(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	exec := func(args ...interface{}) *token.Token {
+		ts := make([]*token.Token, 0, len(args))
+		for _, arg := range args {
+			switch arg := arg.(type) {
+			case string:
+				ts = append(ts, token.NewString(arg))
+			case int:
+				ts = append(ts, token.NewInteger(int64(arg)))
+			}
+		}
+		return p.execCmd(c, token.NewArray(ts...))
+	}
+	exec(cds.Config, cds.ConfigSet, cds.NotifyKeyspaceEvents, "EA")
+	sub := p.NewClient(nil)
+	assert.Nil(t, p.pSubscribe(sub, token.NewString("__keyevent@0__:*")))
+	sub.Out.Pop()
+	events := func() []string {
+		var names []string
+		for _, m := range sub.Out.Pop() {
+			channel := string(m.Data.([]*token.Token)[2].Data.([]byte))
+			names = append(names, channel[len("__keyevent@0__:"):])
+		}
+		return names
+	}
+
+	exec(cds.Set, "s", "1", cds.TimeoutSec, 100)
+	exec(cds.Append, "s", "2")
+	exec(cds.Incr, "s")
+	exec(cds.Rename, "s", "s2")
+	assert.Equal(t, []string{"set", "expire", "append", "incrby", "rename_from", "rename_to"}, events())
+
+	exec(cds.HSet, "h", "f", "1")
+	exec(cds.HSet, "h", "f", "2")
+	exec(cds.HDel, "h", "f")
+	assert.Equal(t, []string{"hset", "hset", "hdel", "del"}, events())
+
+	exec(cds.LPush, "l", "a", "b")
+	exec(cds.RPush, "l", "c")
+	exec(cds.LPop, "l")
+	exec(cds.RPop, "l", 2)
+	assert.Equal(t, []string{"lpush", "rpush", "lpop", "rpop", "del"}, events())
+
+	exec(cds.SAdd, "t", "a")
+	exec(cds.SAdd, "t", "a")
+	exec(cds.SAdd, "t", "b")
+	exec(cds.SRem, "t", "a")
+	assert.Equal(t, []string{"sadd", "sadd", "srem"}, events())
+
+	exec(cds.ZAdd, "z", 1, "a")
+	exec(cds.ZAdd, "z", 2, "b")
+	exec(cds.ZIncrBy, "z", 1, "a")
+	exec(cds.ZPopMin, "z")
+	assert.Equal(t, []string{"zadd", "zadd", "zincr", "zpopmin"}, events())
+
+	exec(cds.XAdd, "x", "*", "f", "v")
+	exec(cds.XAdd, "x", "*", "f", "v")
+	assert.Equal(t, []string{"xadd", "xadd"}, events())
+}
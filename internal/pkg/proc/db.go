@@ -0,0 +1,173 @@
+package proc
+
+import (
+	"errors"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+var (
+	errDBIndex    = errors.New("DB index is out of range")
+	errSameObject = errors.New("source and destination objects are the same")
+)
+
+// parseDB returns the index of the database, which should be in range
+func (p *Processor) parseDB(t *token.Token) (int, error) {
+	if err := checkType(t, "index", label.Integer); err != nil {
+		return 0, err
+	}
+	idx := t.Data.(int64)
+	if idx < 0 || idx >= int64(len(p.data)) {
+		return 0, errDBIndex
+	}
+	return int(idx), nil
+}
+
+// clientOn returns the copy of the client selecting the database, so that
+// keys of other databases are accessed like the ones selected.
+func clientOn(cli *model.Client, d *model.DataStorage) *model.Client {
+	c := *cli
+	c.Data = d
+	return &c
+}
+
+// parseFlush parses "[ASYNC|SYNC]" of flushdb and flushall. Both modes are
+// the same, since the data flushed is dropped at once and freed by the
+// garbage collector in the background.
+func parseFlush(tokens []*token.Token) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	if len(tokens) == 1 {
+		switch mode, _ := optionOf(tokens[0]); mode {
+		case cds.Async, cds.Sync:
+			return nil
+		}
+	}
+	return errSyntax
+}
+
+func (p *Processor) dbSize(cli *model.Client, _ ...*token.Token) *token.Token {
+	return token.NewInteger(int64(cli.Data.Size()))
+}
+
+// flushDB deletes all the keys of the database selected by "[ASYNC|SYNC]"
+func (p *Processor) flushDB(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := parseFlush(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	cli.Data.Flush()
+	return token.ReplyOk
+}
+
+// flushAll deletes all the keys of all the databases by "[ASYNC|SYNC]"
+func (p *Processor) flushAll(_ *model.Client, tokens ...*token.Token) *token.Token {
+	if err := parseFlush(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	for _, d := range p.data {
+		d.Flush()
+	}
+	return token.ReplyOk
+}
+
+// swapDB swaps the keys of databases by "index1 index2", clients selecting
+// either see the keys of the other one at once.
+func (p *Processor) swapDB(_ *model.Client, tokens ...*token.Token) *token.Token {
+	var idx [2]int
+	for i, t := range tokens[:2] {
+		var err error
+		if idx[i], err = p.parseDB(t); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	if idx[0] != idx[1] {
+		p.data[idx[0]].Swap(p.data[idx[1]])
+		p.signalDB(idx[0])
+		p.signalDB(idx[1])
+	}
+	return token.ReplyOk
+}
+
+// move moves the key to the database by "key db", it returns 1 if moved,
+// 0 if the key does not exist or exists in the destination.
+func (p *Processor) move(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	idx, err := p.parseDB(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if p.data[idx] == cli.Data {
+		return token.NewError(errSameObject.Error())
+	}
+	key := tokens[0].Data.(string)
+	expire, ok := cli.GetExpire(key)
+	dst := clientOn(cli, p.data[idx])
+	if !ok || dst.Data.Peek(key) != nil {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	dst.Set(key, cli.GetMutable(key), expire)
+	cli.Remove(key)
+	cli.Notify(model.NotifyGeneric, model.EventMoveFrom, key)
+	dst.Notify(model.NotifyGeneric, model.EventMoveTo, key)
+	p.signalKey(dst, key)
+	return token.NewInteger(1)
+}
+
+// copy copies the value of the source to the destination by "source
+// destination [DB destination-db] [REPLACE]", it returns 1 if copied, 0 if
+// the source does not exist or the destination exists without REPLACE.
+func (p *Processor) copy(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	dst := cli
+	var replace bool
+	for i := 2; i < len(tokens); i++ {
+		switch arg, _ := optionOf(tokens[i]); {
+		case arg == cds.Replace:
+			replace = true
+		case arg == cds.DB && i+1 < len(tokens):
+			idx, err := p.parseDB(tokens[i+1])
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			dst = clientOn(cli, p.data[idx])
+			i++
+		default:
+			return token.NewError(errSyntax.Error())
+		}
+	}
+	src, key := tokens[0].Data.(string), tokens[1].Data.(string)
+	if dst.Data == cli.Data && src == key {
+		return token.NewError(errSameObject.Error())
+	}
+	v := cli.Get(src)
+	if v == nil || (!replace && dst.Data.Peek(key) != nil) {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	if c, ok := v.(model.Cloner); ok {
+		v = c.Clone()
+	}
+	expire, _ := cli.GetExpire(src)
+	dst.Set(key, v, expire)
+	dst.Notify(model.NotifyGeneric, model.EventCopyTo, key)
+	p.signalKey(dst, key)
+	return token.NewInteger(1)
+}
+
+// randomKey returns a random key, nil if the database is empty
+func (p *Processor) randomKey(cli *model.Client, _ ...*token.Token) *token.Token {
+	key, ok := cli.Data.RandomKey()
+	if !ok {
+		return token.NewBulked(nil)
+	}
+	return token.NewBulked([]byte(key))
+}
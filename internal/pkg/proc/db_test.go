@@ -0,0 +1,98 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_flushDB(t *testing.T) {
+	p := NewProcessor(2)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) *token.Token {
+		return p.execCmd(c, streamCmd(cmd, args...))
+	}
+	exec(cds.Set, "a", "1")
+	exec(cds.RPush, "b", "1")
+	assert.Equal(t, int64(2), exec(cds.DBSize).Data)
+	key := exec(cds.RandomKey).Data.([]byte)
+	assert.Contains(t, []string{"a", "b"}, string(key))
+	assert.Equal(t, token.NewError("ERR "+errSyntax.Error()), exec(cds.FlushDB, "LAZY"))
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.FlushDB, cds.Async).Data)
+	assert.Equal(t, int64(0), exec(cds.DBSize).Data)
+	assert.Equal(t, token.NewBulked(nil), exec(cds.RandomKey))
+
+	exec(cds.Set, "a", "1")
+	exec(cds.Select, "1")
+	exec(cds.Set, "a", "1")
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.FlushAll).Data)
+	assert.Equal(t, 0, p.data[0].Size()+p.data[1].Size())
+}
+
+func TestProcessor_swapDB(t *testing.T) {
+	p := NewProcessor(2)
+	p.Msgs.Set = make(chan *SetMsg, 16)
+	do := func(c *model.Client, cmd string, args ...string) *token.Token {
+		rsp := make(chan *token.Token, 1)
+		p.Do(&model.CmdTask{Cli: c, Req: streamCmd(cmd, args...), Rsp: rsp})
+		return <-rsp
+	}
+	c, watcher, blocked := p.NewClient(nil), p.NewClient(nil), p.NewClient(nil)
+	do(c, cds.Select, "1")
+	do(c, cds.RPush, "l", "v")
+	do(c, cds.Set, "s", "v")
+	do(watcher, cds.Set, "s", "old")
+	do(watcher, cds.Watch, "s")
+	do(watcher, cds.Multi)
+	assert.Nil(t, do(blocked, cds.BLPop, "l", "0"))
+
+	assert.Equal(t, token.NewError("ERR "+errDBIndex.Error()), do(c, cds.SwapDB, "0", "2"))
+	assert.Equal(t, token.ReplyOk.Data, do(c, cds.SwapDB, "0", "1").Data)
+	// the client blocked by the key swapped in is served
+	assert.Equal(t, []*token.Token{bulkedArray("l", "v")}, blocked.Out.Pop())
+	assert.Equal(t, []byte("old"), do(c, cds.Get, "s").Data)
+	assert.Equal(t, token.NewArray(), do(watcher, cds.Exec))
+
+	// the swap works with the frozen database
+	_ = p.data[0].Freeze()
+	assert.Equal(t, token.ReplyOk.Data, do(c, cds.SwapDB, "1", "0").Data)
+	assert.Equal(t, []byte("v"), do(c, cds.Get, "s").Data)
+	assert.Equal(t, 1, len(p.data[0].GetOrigin()))
+	_ = p.data[0].ToMove()
+	assert.Equal(t, []byte("old"), do(watcher, cds.Get, "s").Data)
+}
+
+func TestProcessor_move(t *testing.T) {
+	p := NewProcessor(2)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) *token.Token {
+		return p.execCmd(c, streamCmd(cmd, args...))
+	}
+	exec(cds.RPush, "l", "a", "b")
+	exec(cds.Expire, "l", "100")
+	assert.Equal(t, int64(1), exec(cds.Move, "l", "1").Data)
+	assert.Equal(t, int64(0), exec(cds.Move, "l", "1").Data)
+	assert.Equal(t, token.NewError("ERR "+errSameObject.Error()), exec(cds.Move, "l", "0"))
+	assert.Equal(t, token.NewError("ERR "+errDBIndex.Error()), exec(cds.Move, "l", "-1"))
+	exec(cds.Select, "1")
+	assert.Equal(t, int64(2), exec(cds.LLen, "l").Data)
+	assert.True(t, exec(cds.TTL, "l").Data.(int64) > 0)
+
+	// the copy is not affected by changes of the source
+	assert.Equal(t, int64(1), exec(cds.Copy, "l", "l2").Data)
+	assert.Equal(t, int64(0), exec(cds.Copy, "l", "l2").Data)
+	exec(cds.RPush, "l", "c")
+	assert.Equal(t, int64(2), exec(cds.LLen, "l2").Data)
+	assert.True(t, exec(cds.TTL, "l2").Data.(int64) > 0)
+	exec(cds.Set, "s", "v")
+	assert.Equal(t, int64(1), exec(cds.Copy, "s", "l2", cds.Replace).Data)
+	assert.Equal(t, []byte("v"), exec(cds.Get, "l2").Data)
+	assert.Equal(t, int64(1), exec(cds.Copy, "l", "l", cds.DB, "0").Data)
+	assert.Equal(t, token.NewError("ERR "+errSameObject.Error()), exec(cds.Copy, "l", "l", cds.DB, "1"))
+	assert.Equal(t, token.NewError("ERR "+errSyntax.Error()), exec(cds.Copy, "l", "l", cds.DB))
+	exec(cds.Select, "0")
+	assert.Equal(t, int64(3), exec(cds.LLen, "l").Data)
+}
@@ -0,0 +1,67 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// Error is an error replied with its kind
+type Error struct {
+	Kind token.ErrKind
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return string(e.Kind) + " " + e.Msg
+}
+
+var (
+	errExecAbort = &Error{token.ErrKindExecAbort, "Transaction discarded because of previous errors."}
+	errNoProto   = &Error{token.ErrKindNoProto, "unsupported protocol version"}
+	errWrongPass = &Error{token.ErrKindWrongPass, "invalid username-password pair or user is disabled."}
+)
+
+// maxUnknownArgsLen limits the length of arguments quoted by the error of
+// unknown command, the same as redis
+const maxUnknownArgsLen = 128
+
+// errUnknownCommand returns the error of the command not found like redis
+func errUnknownCommand(name string, args []*token.Token) error {
+	var b strings.Builder
+	for _, arg := range args {
+		if b.Len() >= maxUnknownArgsLen {
+			break
+		}
+		v, _ := ItfToBulked(arg.Data)
+		data, _ := v.([]byte)
+		if n := maxUnknownArgsLen - b.Len(); len(data) > n {
+			data = data[:n]
+		}
+		fmt.Fprintf(&b, "'%s' ", data)
+	}
+	if len(name) > maxUnknownArgsLen {
+		name = name[:maxUnknownArgsLen]
+	}
+	return fmt.Errorf("unknown command '%s', with args beginning with: %s", name, b.String())
+}
+
+// errArity returns the error of wrong number of arguments of the command
+func errArity(name string) error {
+	return fmt.Errorf("wrong number of arguments for '%s' command", name)
+}
+
+// WithKind prefixes the error reply with "ERR" unless it has a kind, other
+// replies are returned as is
+func WithKind(t *token.Token) *token.Token {
+	if t == nil || t.Label != label.Error {
+		return t
+	}
+	msg, _ := t.Data.(string)
+	if kind := token.KindOf(msg); kind != token.ErrKindErr || strings.HasPrefix(msg, string(token.ErrKindErr)+" ") {
+		return t
+	}
+	return token.NewError(string(token.ErrKindErr) + " " + msg)
+}
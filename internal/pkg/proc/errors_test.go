@@ -0,0 +1,52 @@
+package proc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithKind(t *testing.T) {
+	assert.Nil(t, WithKind(nil))
+	assert.Equal(t, token.ReplyOk, WithKind(token.ReplyOk))
+	assert.Equal(t, token.NewError("ERR syntax error"), WithKind(token.NewError("syntax error")))
+	assert.Equal(t, token.NewError("ERR syntax error"), WithKind(token.NewError("ERR syntax error")))
+	assert.Equal(t, token.NewError(eStrWrongType), WithKind(token.NewError(eStrWrongType)))
+}
+
+func TestProcessor_errors(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	exec := func(args ...string) *token.Token {
+		return p.execCmd(c, token.NewArray(stringTokens(args...)...))
+	}
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'get' command"), exec("GET"))
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'hset' command"), exec(cds.HSet, "h", "f"))
+	exec(cds.Set, "k", "v")
+	assert.Equal(t, token.NewError(eStrWrongType), exec(cds.LPush, "k", "v"))
+
+	// arguments quoted are limited to 128 bytes
+	long := strings.Repeat("a", 200)
+	assert.Equal(t, token.NewError("ERR unknown command 'NoCmd', with args beginning with: 'x' '"+long[:124]+"' "),
+		exec("NoCmd", "x", long, "y"))
+	// newlines quoted are not taken as the end of the reply
+	rsp := exec("NoCmd\r\n", "a\r\n+OK")
+	assert.Equal(t, token.NewError("ERR unknown command 'NoCmd  ', with args beginning with: 'a  +OK' "), rsp)
+	data, _ := rsp.Serialize()
+	assert.Equal(t, "-ERR unknown command 'NoCmd  ', with args beginning with: 'a  +OK' \r\n", string(data))
+	assert.Equal(t, token.NewError("ERR Syntax error in HELLO option 'X  Y'"), p.execCmd(c, token.NewArray(
+		token.NewBulked([]byte(cds.Hello)), token.NewInteger(token.Resp2), token.NewBulked([]byte("x\r\ny")))))
+
+	// the transaction is aborted by commands failed to be queued
+	assert.Equal(t, token.ReplyOk, exec(cds.Multi))
+	assert.Equal(t, token.ReplyQueued, exec(cds.Set, "k", "w"))
+	assert.Equal(t, token.ErrKindErr, token.KindOf(exec("nocmd").Data.(string)))
+	assert.Equal(t, token.NewError(errExecAbort.Error()), exec(cds.Exec))
+	assert.Equal(t, token.NewBulked([]byte("v")), exec(cds.Get, "k"))
+	assert.Equal(t, token.ReplyOk, exec(cds.Multi))
+	assert.Equal(t, token.NewArray(), exec(cds.Exec))
+}
@@ -0,0 +1,213 @@
+package proc
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+const (
+	eStrExpireInvalid = "invalid expire time"
+	eStrNXConflict    = "NX and XX, GT or LT options at the same time are not compatible"
+	eStrGTLTConflict  = "GT and LT options at the same time are not compatible"
+)
+
+const (
+	// percentage of the interval between cycles spent on active expiration at most
+	expireCycleTimePerc = 25
+	defaultHz           = 10
+	maxHz               = 500
+)
+
+// expireAtToken returns the request setting the absolute expiration of key,
+// which is used to propagate expiration deterministically.
+func expireAtToken(key string, expire int64) *token.Token {
+	return token.NewArray(token.NewString(cds.PExpireAt), token.NewString(key),
+		token.NewInteger(expire/int64(time.Millisecond)),
+		token.NewString(cds.ExpireAtNano), token.NewInteger(expire))
+}
+
+// toExpire converts the time counted in unit to the absolute nanosecond
+// timestamp, the time is relative to now unless abs is true.
+func toExpire(num int64, unit time.Duration, abs bool) (int64, error) {
+	if num > math.MaxInt64/int64(unit) || num < math.MinInt64/int64(unit) {
+		return 0, errExpireInvalid
+	}
+	expire := num * int64(unit)
+	if !abs {
+		now := time.Now().UnixNano()
+		if expire > math.MaxInt64-now {
+			return 0, errExpireInvalid
+		}
+		expire += now
+	}
+	return expire, nil
+}
+
+// expireGeneric sets the expiration of key. The time given is counted in unit,
+// and is relative to now unless abs is true. Private argument PT overrides the
+// time given with the absolute nanosecond timestamp.
+func (p *Processor) expireGeneric(cli *model.Client, tokens []*token.Token, unit time.Duration, abs bool) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	key, when := tokens[0], tokens[1]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(when, "timeout", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	expire, err := toExpire(when.Data.(int64), unit, abs)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var nx, xx, gt, lt bool
+	for i := 2; i < len(tokens); i++ {
+		if err := checkType(tokens[i], "argument", label.String); err != nil {
+			return token.NewError(err.Error())
+		}
+		arg := strings.ToUpper(tokens[i].Data.(string))
+		switch arg {
+		case cds.IfNotExist:
+			nx = true
+		case cds.IfExist:
+			xx = true
+		case cds.IfGreater:
+			gt = true
+		case cds.IfLess:
+			lt = true
+		case cds.ExpireAtNano:
+			i++
+			if len(tokens) < i+1 {
+				return token.NewError("argument missing of %s", arg)
+			}
+			if err := checkType(tokens[i], "timeout", label.Integer); err != nil {
+				return token.NewError(err.Error())
+			}
+			expire = tokens[i].Data.(int64)
+		default:
+			return token.NewError("argument not recognized")
+		}
+	}
+	if nx && (xx || gt || lt) {
+		return token.NewError(eStrNXConflict)
+	}
+	if gt && lt {
+		return token.NewError(eStrGTLTConflict)
+	}
+	k := key.Data.(string)
+	cur, ok := cli.GetExpire(k)
+	// key without expiration is regarded as infinite ttl when compared
+	if !ok || (nx && cur > 0) || (xx && cur == 0) ||
+		(gt && (cur == 0 || expire <= cur)) || (lt && cur > 0 && expire >= cur) {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	if expire <= time.Now().UnixNano() {
+		cli.Del(k)
+		p.propagateAs(token.NewArray(token.NewString(cds.Del), key))
+		return token.NewInteger(1)
+	}
+	cli.SetExpire(k, expire)
+	p.propagateAs(expireAtToken(k, expire))
+	return token.NewInteger(1)
+}
+
+func (p *Processor) expire(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.expireGeneric(cli, tokens, time.Second, false)
+}
+
+func (p *Processor) pExpire(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.expireGeneric(cli, tokens, time.Millisecond, false)
+}
+
+func (p *Processor) expireAt(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.expireGeneric(cli, tokens, time.Second, true)
+}
+
+func (p *Processor) pExpireAt(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.expireGeneric(cli, tokens, time.Millisecond, true)
+}
+
+// ttlGeneric returns the expiration of key counted in unit, -2 if the key
+// does not exist, -1 if the key has no expiration. The remaining time is
+// returned unless abs is true.
+func (p *Processor) ttlGeneric(cli *model.Client, tokens []*token.Token, unit time.Duration, abs bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	expire, ok := cli.GetExpire(key.Data.(string))
+	if !ok {
+		return token.NewInteger(-2)
+	}
+	if expire == 0 {
+		return token.NewInteger(-1)
+	}
+	if !abs {
+		expire -= time.Now().UnixNano()
+		if expire < 0 {
+			expire = 0
+		}
+	}
+	return token.NewInteger((expire + int64(unit)/2) / int64(unit))
+}
+
+func (p *Processor) ttl(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.ttlGeneric(cli, tokens, time.Second, false)
+}
+
+func (p *Processor) pTTL(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.ttlGeneric(cli, tokens, time.Millisecond, false)
+}
+
+func (p *Processor) expireTime(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.ttlGeneric(cli, tokens, time.Second, true)
+}
+
+func (p *Processor) pExpireTime(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.ttlGeneric(cli, tokens, time.Millisecond, true)
+}
+
+// persist removes the expiration of key, returns 1 if the expiration is removed.
+func (p *Processor) persist(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	expire, ok := cli.GetExpire(key.Data.(string))
+	if !ok || expire == 0 {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	cli.SetExpire(key.Data.(string), 0)
+	return token.NewInteger(1)
+}
+
+// activeExpire pops expired keys of every database within the time budget,
+// returns the interval until the next cycle. The cycle resumes from the
+// database where the last one ran out of time.
+func (p *Processor) activeExpire() time.Duration {
+	interval := time.Second / time.Duration(p.hz)
+	start := time.Now()
+	deadline := start.Add(interval * expireCycleTimePerc / 100)
+	for i := 0; i < len(p.data); i++ {
+		idx := (p.expireCycle.db + i) % len(p.data)
+		if !p.data[idx].ActiveExpire(deadline) {
+			p.expireCycle.timeCapReached++
+			p.expireCycle.db = idx
+			break
+		}
+	}
+	p.expireCycle.cycleTime += time.Since(start)
+	return interval
+}
@@ -0,0 +1,97 @@
+package proc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_expire(t *testing.T) {
+	key := token.NewString("t_expire")
+	assert.Equal(t, token.NewError(eStrArgMore), proc.expire(cli, key))
+	assert.Equal(t, token.NewError("type of timeout is string instead of integer"),
+		proc.expire(cli, key, token.NewString("1")))
+	assert.Equal(t, token.NewInteger(0), proc.expire(cli, key, token.NewInteger(10)))
+	proc.set(cli, key, token.NewInteger(1))
+	assert.Equal(t, token.NewInteger(-1), proc.ttl(cli, key))
+	assert.Equal(t, token.NewInteger(0), proc.expire(cli, key, token.NewInteger(10), token.NewString(cds.IfExist)))
+	assert.Equal(t, token.NewInteger(0), proc.expire(cli, key, token.NewInteger(10), token.NewString(cds.IfGreater)))
+	assert.Equal(t, token.NewInteger(1), proc.expire(cli, key, token.NewInteger(10), token.NewString(cds.IfNotExist)))
+	assert.Equal(t, token.NewInteger(10), proc.ttl(cli, key))
+	assert.Equal(t, token.NewInteger(0), proc.expire(cli, key, token.NewInteger(20), token.NewString(cds.IfNotExist)))
+	assert.Equal(t, token.NewInteger(0), proc.expire(cli, key, token.NewInteger(20), token.NewString(cds.IfLess)))
+	assert.Equal(t, token.NewInteger(1), proc.pExpire(cli, key, token.NewInteger(20000), token.NewString("gt")))
+	assert.Equal(t, token.NewInteger(20000), proc.pTTL(cli, key))
+	assert.Equal(t, token.NewError(eStrNXConflict),
+		proc.expire(cli, key, token.NewInteger(1), token.NewString(cds.IfNotExist), token.NewString(cds.IfExist)))
+	assert.Equal(t, token.NewError(eStrGTLTConflict),
+		proc.expire(cli, key, token.NewInteger(1), token.NewString(cds.IfGreater), token.NewString(cds.IfLess)))
+	assert.Equal(t, token.NewError("argument not recognized"),
+		proc.expire(cli, key, token.NewInteger(1), token.NewString("KEEP")))
+	assert.Equal(t, token.NewError(eStrExpireInvalid),
+		proc.expire(cli, key, token.NewInteger(1<<62)))
+
+	assert.Equal(t, token.NewInteger(1), proc.persist(cli, key))
+	assert.Equal(t, token.NewInteger(0), proc.persist(cli, key))
+	assert.Equal(t, token.NewInteger(-1), proc.pTTL(cli, key))
+
+	at := time.Now().Add(time.Hour)
+	assert.Equal(t, token.NewInteger(1), proc.expireAt(cli, key, token.NewInteger(at.Unix())))
+	assert.Equal(t, token.NewInteger(at.Unix()), proc.expireTime(cli, key))
+	assert.Equal(t, token.NewInteger(1), proc.pExpireAt(cli, key, token.NewInteger(at.UnixNano()/1e6)))
+	assert.Equal(t, token.NewInteger(at.UnixNano()/1e6), proc.pExpireTime(cli, key))
+
+	assert.Equal(t, token.NewInteger(1), proc.expire(cli, key, token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(-2), proc.ttl(cli, key))
+	assert.Equal(t, token.NewInteger(-2), proc.expireTime(cli, key))
+	assert.Equal(t, token.NewBulked(nil), proc.get(cli, key))
+}
+
+func TestProcessor_expire_propagate(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("t_expire_propagate")
+	p.execCmd(c, token.NewArray(token.NewString(cds.Set), key, token.NewInteger(1)))
+	assert.Equal(t, 1, len(p.aof))
+	p.execCmd(c, token.NewArray(token.NewString(cds.Expire), key, token.NewInteger(10), token.NewString(cds.IfExist)))
+	assert.Equal(t, 1, len(p.aof))
+	p.execCmd(c, token.NewArray(token.NewString(cds.Expire), key, token.NewInteger(10)))
+	assert.Equal(t, 2, len(p.aof))
+	expire, _ := c.GetExpire("t_expire_propagate")
+	assert.Equal(t, expireAtToken("t_expire_propagate", expire), p.aof[1].T)
+
+	// replay the rewritten request
+	mock := model.NewClient(nil, NewProcessor(1).data[0])
+	mock.Set("t_expire_propagate", 1, 0)
+	proc.execCmd(mock, p.aof[1].T)
+	replayed, _ := mock.GetExpire("t_expire_propagate")
+	assert.Equal(t, expire, replayed)
+
+	p.execCmd(c, token.NewArray(token.NewString(cds.PExpire), key, token.NewInteger(-1)))
+	assert.Equal(t, token.NewArray(token.NewString(cds.Del), key), p.aof[2].T)
+}
+
+func TestProcessor_activeExpire(t *testing.T) {
+	p := NewProcessor(2)
+	c := model.NewClient(nil, p.data[1])
+	p.set(c, token.NewString("a"), token.NewString("1"), token.NewString(cds.TimeoutMilSec), token.NewInteger(1))
+	p.set(c, token.NewString("b"), token.NewString("1"))
+	<-time.After(2 * time.Millisecond)
+	rsp := make(chan time.Duration, 1)
+	p.Do(&model.ExpireTask{Rsp: rsp})
+	assert.Equal(t, 100*time.Millisecond, <-rsp)
+	assert.Equal(t, int64(1), p.data[1].ExpiredKeys())
+	assert.Equal(t, token.NewBulked([]byte("# Stats\r\nexpired_keys:1\r\nexpired_time_cap_reached_count:0\r\n"+
+		"expire_cycle_cpu_milliseconds:0\r\n")), p.info(c, token.NewString("stats")))
+
+	assert.Equal(t, token.ReplyOk, p.config(c, token.NewString(cds.ConfigSet), token.NewString(cds.Hz), token.NewInteger(1000)))
+	p.Do(&model.ExpireTask{Rsp: rsp})
+	assert.Equal(t, 2*time.Millisecond, <-rsp)
+	assert.Equal(t, token.NewBulked([]byte("# Server\r\nhz:500\r\n")), p.info(c, token.NewString("server")))
+	assert.Equal(t, token.NewBulked([]byte("")), p.info(c, token.NewString("foo")))
+	assert.Contains(t, string(p.info(c).Data.([]byte)), "# Server\r\nhz:500\r\n\r\n# Stats\r\n")
+}
@@ -0,0 +1,501 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/geo"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+var (
+	errGeoAddSyntax  = errors.New("syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ")
+	errGeoUnit       = errors.New("unsupported unit provided. please use M, KM, FT, MI")
+	errGeoMember     = errors.New("could not decode requested zset member")
+	errGeoFrom       = errors.New("exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	errGeoBy         = errors.New("exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+	errGeoRadius     = errors.New("radius cannot be negative")
+	errGeoBox        = errors.New("height or width cannot be negative")
+	errGeoCount      = errors.New("COUNT must be > 0")
+	errGeoAny        = errors.New("the ANY argument requires COUNT argument")
+	errGeoStoreWith  = errors.New("GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options")
+	errGeoStoreDist  = errors.New("STOREDIST option is only supported by GEOSEARCHSTORE")
+	errGeoPairFormat = "invalid longitude,latitude pair %f,%f"
+)
+
+// geoUnits is the meters of units of distance
+var geoUnits = map[string]float64{"M": 1, "KM": 1000, "FT": 0.3048, "MI": 1609.34}
+
+// parseGeoUnit returns the meters of the unit token
+func parseGeoUnit(t *token.Token) (float64, error) {
+	unit, _ := optionOf(t)
+	if m, ok := geoUnits[unit]; ok {
+		return m, nil
+	}
+	return 0, errGeoUnit
+}
+
+// parseLonLat parses the longitude and latitude tokens
+func parseLonLat(lonToken, latToken *token.Token) (float64, float64, error) {
+	lon, err := tokenToFloat(lonToken)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, err := tokenToFloat(latToken)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !geo.Valid(lon, lat) {
+		return 0, 0, fmt.Errorf(errGeoPairFormat, lon, lat)
+	}
+	return lon, lat, nil
+}
+
+// formatDistance formats the distance in unit like redis
+func formatDistance(meters, unit float64) string {
+	return fmt.Sprintf("%.4f", meters/unit)
+}
+
+// geoPosToken returns the array of longitude and latitude of the score
+func geoPosToken(score float64) *token.Token {
+	lon, lat := geo.Position(score)
+	return token.NewArray(token.NewBulked([]byte(token.FormatFloat(lon))), token.NewBulked([]byte(token.FormatFloat(lat))))
+}
+
+// geoAdd adds members with positions by "key [NX|XX] [CH] longitude latitude
+// member [longitude latitude member ...]", the position is stored as the
+// geohash score of the sorted set.
+func (p *Processor) geoAdd(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	var nx, xx, ch bool
+	i := 1
+loop:
+	for ; i < len(tokens); i++ {
+		opt, _ := optionOf(tokens[i])
+		switch opt {
+		case cds.IfNotExist:
+			nx = true
+		case cds.IfExist:
+			xx = true
+		case cds.Changed:
+			ch = true
+		default:
+			break loop
+		}
+	}
+	triples := tokens[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 {
+		return token.NewError(errGeoAddSyntax.Error())
+	}
+	if nx && xx {
+		return token.NewError(errNXAndXX.Error())
+	}
+	items := make([]model.ZItem, 0, len(triples)/3)
+	for j := 0; j < len(triples); j += 3 {
+		lon, lat, err := parseLonLat(triples[j], triples[j+1])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		member, err := tokenToBytes(triples[j+2])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		items = append(items, model.ZItem{Member: string(member), Score: geo.Score(lon, lat)})
+	}
+	key := tokens[0].Data.(string)
+	z, err := getZSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	created := z == nil
+	if created {
+		z = model.NewZSet()
+	}
+	var added, changed int64
+	for _, item := range items {
+		_, a, c, _ := zAddGeneric(z, item.Member, item.Score, nx, xx, false, false, false)
+		if a {
+			added++
+		} else if c {
+			changed++
+		}
+	}
+	if added+changed == 0 {
+		p.propagateAs()
+	} else if created {
+		cli.Set(key, z, 0)
+		cli.Notify(model.NotifyZSet, model.EventZAdd, key)
+		p.signalKey(cli, key)
+	} else {
+		cli.Notify(model.NotifyZSet, model.EventZAdd, key)
+	}
+	if ch {
+		return token.NewInteger(added + changed)
+	}
+	return token.NewInteger(added)
+}
+
+// geoMembers returns the sorted set and members of "key member [member ...]"
+func geoMembers(cli *model.Client, tokens []*token.Token) (*model.ZSet, []string, error) {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return nil, nil, err
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return z, members, nil
+}
+
+// geoEach returns the array of the reply of each member, nil if the member
+// does not exist.
+func geoEach(cli *model.Client, tokens []*token.Token, reply func(score float64) *token.Token) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	z, members, err := geoMembers(cli, tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(members))
+	for _, member := range members {
+		var score float64
+		ok := false
+		if z != nil {
+			score, ok = z.Score(member)
+		}
+		if ok {
+			ts = append(ts, reply(score))
+		} else {
+			ts = append(ts, token.NewBulked(nil))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// geoPos returns the longitude and latitude of members
+func (p *Processor) geoPos(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return geoEach(cli, tokens, geoPosToken)
+}
+
+// geoHash returns the standard geohash strings of members
+func (p *Processor) geoHash(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return geoEach(cli, tokens, func(score float64) *token.Token {
+		return token.NewBulked([]byte(geo.String(score)))
+	})
+}
+
+// geoDist returns the distance between members by "key member1 member2
+// [M|KM|FT|MI]", nil if either does not exist.
+func (p *Processor) geoDist(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if len(tokens) > 4 {
+		return token.NewError(errSyntax.Error())
+	}
+	unit := 1.0
+	if len(tokens) == 4 {
+		var err error
+		if unit, err = parseGeoUnit(tokens[3]); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	z, members, err := geoMembers(cli, tokens[:3])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewBulked(nil)
+	}
+	score1, ok1 := z.Score(members[0])
+	score2, ok2 := z.Score(members[1])
+	if !ok1 || !ok2 {
+		return token.NewBulked(nil)
+	}
+	lon1, lat1 := geo.Position(score1)
+	lon2, lat2 := geo.Position(score2)
+	return token.NewBulked([]byte(formatDistance(geo.Distance(lon1, lat1, lon2, lat2), unit)))
+}
+
+// geoSearchOption is the options of geosearch and geosearchstore
+type geoSearchOption struct {
+	// member searched from if fromMember, otherwise the position of shape
+	member     string
+	fromMember bool
+	shape      geo.Shape
+	// meters of the unit of distances
+	unit float64
+	// 1 for ASC, -1 for DESC, 0 for unsorted
+	sort  int
+	count int
+	any   bool
+
+	withCoord, withDist, withHash bool
+	storeDist                     bool
+}
+
+// parseGeoSearch parses "FROMMEMBER member|FROMLONLAT longitude latitude
+// BYRADIUS radius unit|BYBOX width height unit [ASC|DESC] [COUNT count [ANY]]
+// [WITHCOORD] [WITHDIST] [WITHHASH]", or "[STOREDIST]" instead of WITH options
+// if store.
+func parseGeoSearch(tokens []*token.Token, store bool) (*geoSearchOption, error) {
+	opt := &geoSearchOption{}
+	var from, by bool
+	// checks the number of arguments after the option
+	need := func(i, n int, arg string) error {
+		if i+n >= len(tokens) {
+			return errArgMissing(arg)
+		}
+		return nil
+	}
+	for i := 0; i < len(tokens); i++ {
+		arg, _ := optionOf(tokens[i])
+		switch arg {
+		case cds.FromMember:
+			if from {
+				return nil, errGeoFrom
+			}
+			if err := need(i, 1, arg); err != nil {
+				return nil, err
+			}
+			member, err := tokenToBytes(tokens[i+1])
+			if err != nil {
+				return nil, err
+			}
+			from, opt.fromMember, opt.member = true, true, string(member)
+			i++
+		case cds.FromLonLat:
+			if from {
+				return nil, errGeoFrom
+			}
+			if err := need(i, 2, arg); err != nil {
+				return nil, err
+			}
+			lon, lat, err := parseLonLat(tokens[i+1], tokens[i+2])
+			if err != nil {
+				return nil, err
+			}
+			from, opt.shape.Lon, opt.shape.Lat = true, lon, lat
+			i += 2
+		case cds.ByRadius, cds.ByBox:
+			n := 2
+			if arg == cds.ByBox {
+				n = 3
+			}
+			if by {
+				return nil, errGeoBy
+			}
+			if err := need(i, n, arg); err != nil {
+				return nil, err
+			}
+			sizes := make([]float64, 0, 2)
+			for _, t := range tokens[i+1 : i+n] {
+				size, err := tokenToFloat(t)
+				if err != nil {
+					return nil, err
+				}
+				if size < 0 {
+					if arg == cds.ByBox {
+						return nil, errGeoBox
+					}
+					return nil, errGeoRadius
+				}
+				sizes = append(sizes, size)
+			}
+			unit, err := parseGeoUnit(tokens[i+n])
+			if err != nil {
+				return nil, err
+			}
+			by, opt.unit = true, unit
+			if arg == cds.ByRadius {
+				opt.shape.Radius = sizes[0] * unit
+			} else {
+				opt.shape.Width, opt.shape.Height = sizes[0]*unit, sizes[1]*unit
+			}
+			i += n
+		case cds.Asc:
+			opt.sort = 1
+		case cds.DescArg:
+			opt.sort = -1
+		case cds.Count:
+			if err := need(i, 1, arg); err != nil {
+				return nil, err
+			}
+			if err := checkType(tokens[i+1], "count", label.Integer); err != nil {
+				return nil, err
+			}
+			count := tokens[i+1].Data.(int64)
+			if count <= 0 {
+				return nil, errGeoCount
+			}
+			opt.count = int(count)
+			i++
+		case cds.Any:
+			opt.any = true
+		case cds.WithCoord:
+			opt.withCoord = true
+		case cds.WithDist:
+			opt.withDist = true
+		case cds.WithHash:
+			opt.withHash = true
+		case cds.StoreDist:
+			if !store {
+				return nil, errGeoStoreDist
+			}
+			opt.storeDist = true
+		default:
+			return nil, errSyntax
+		}
+	}
+	if !from {
+		return nil, errGeoFrom
+	}
+	if !by {
+		return nil, errGeoBy
+	}
+	if opt.any && opt.count == 0 {
+		return nil, errGeoAny
+	}
+	if store && (opt.withCoord || opt.withDist || opt.withHash) {
+		return nil, errGeoStoreWith
+	}
+	// the nearest ones are returned if the count is limited
+	if opt.count > 0 && opt.sort == 0 && !opt.any {
+		opt.sort = 1
+	}
+	return opt, nil
+}
+
+// geoPoint is the member found by the search
+type geoPoint struct {
+	model.ZItem
+	dist float64
+}
+
+// search returns the members in the shape of the sorted set
+func (opt *geoSearchOption) search(z *model.ZSet) ([]geoPoint, error) {
+	if opt.fromMember {
+		score, ok := z.Score(opt.member)
+		if !ok {
+			return nil, errGeoMember
+		}
+		opt.shape.Lon, opt.shape.Lat = geo.Position(score)
+	}
+	var points []geoPoint
+	for _, cell := range opt.shape.Cells() {
+		min, max := cell.ScoreRange()
+		for _, item := range z.RangeByScore(model.ScoreRange{Min: min, Max: max, MaxEx: true}, false, 0, -1) {
+			lon, lat := geo.Position(item.Score)
+			if d, ok := opt.shape.Contains(lon, lat); ok {
+				points = append(points, geoPoint{item, d})
+				// any ones are enough
+				if opt.any && len(points) >= opt.count {
+					break
+				}
+			}
+		}
+		if opt.any && len(points) >= opt.count {
+			break
+		}
+	}
+	if opt.sort != 0 {
+		sort.SliceStable(points, func(i, j int) bool {
+			if opt.sort > 0 {
+				return points[i].dist < points[j].dist
+			}
+			return points[i].dist > points[j].dist
+		})
+	}
+	if opt.count > 0 && len(points) > opt.count {
+		points = points[:opt.count]
+	}
+	return points, nil
+}
+
+// geoSearch returns the members in the area of circle or box by "key
+// FROMMEMBER member|FROMLONLAT longitude latitude BYRADIUS radius unit|BYBOX
+// width height unit [ASC|DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST]
+// [WITHHASH]". Each member is replied along with the distance, the geohash
+// score and the position in order if requested.
+func (p *Processor) geoSearch(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseGeoSearch(tokens[1:], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewArray()
+	}
+	points, err := opt.search(z)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(points))
+	for _, pt := range points {
+		name := token.NewBulked([]byte(pt.Member))
+		if !opt.withDist && !opt.withHash && !opt.withCoord {
+			ts = append(ts, name)
+			continue
+		}
+		row := []*token.Token{name}
+		if opt.withDist {
+			row = append(row, token.NewBulked([]byte(formatDistance(pt.dist, opt.unit))))
+		}
+		if opt.withHash {
+			row = append(row, token.NewInteger(int64(pt.Score)))
+		}
+		if opt.withCoord {
+			row = append(row, geoPosToken(pt.Score))
+		}
+		ts = append(ts, token.NewArray(row...))
+	}
+	return token.NewArray(ts...)
+}
+
+// geoSearchStore stores the members found by geosearch in the destination
+// by "destination source ... [STOREDIST]", the score is the distance in
+// unit if STOREDIST is given, otherwise the geohash. It returns the number
+// of members stored.
+func (p *Processor) geoSearchStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseGeoSearch(tokens[2:], true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[1].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	dst := model.NewZSet()
+	if z != nil {
+		points, err := opt.search(z)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		for _, pt := range points {
+			score := pt.Score
+			if opt.storeDist {
+				score = pt.dist / opt.unit
+			}
+			dst.Add(pt.Member, score)
+		}
+	}
+	p.storeZSet(cli, tokens[0].Data.(string), dst, model.EventGeoSearchStore)
+	return token.NewInteger(int64(dst.Len()))
+}
@@ -0,0 +1,111 @@
+package proc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// bulkedTokens returns the bulked tokens of args
+func bulkedTokens(args ...string) []*token.Token {
+	ts := make([]*token.Token, 0, len(args))
+	for _, arg := range args {
+		ts = append(ts, token.NewBulked([]byte(arg)))
+	}
+	return ts
+}
+
+func TestProcessor_geoAdd(t *testing.T) {
+	key := "t_geoadd"
+	assert.Equal(t, token.NewInteger(2), proc.geoAdd(cli, stringTokens(key,
+		"13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")...))
+	assert.Equal(t, token.NewBulked([]byte("3479099956230698")), proc.zScore(cli, token.NewString(key), token.NewString("Palermo")))
+	assert.Equal(t, token.NewInteger(0), proc.geoAdd(cli, stringTokens(key, cds.IfNotExist, "13", "38", "Palermo")...))
+	assert.Equal(t, token.NewInteger(1), proc.geoAdd(cli, stringTokens(key, cds.IfExist, cds.Changed,
+		"13", "38", "Palermo", "13", "38", "Rome")...))
+	assert.Equal(t, token.NewInteger(2), proc.zCard(cli, token.NewString(key)))
+
+	assert.Equal(t, token.NewError(errGeoAddSyntax.Error()), proc.geoAdd(cli, stringTokens(key, "13", "38", "a", "14")...))
+	assert.Equal(t, token.NewError("invalid longitude,latitude pair 13.000000,86.000000"),
+		proc.geoAdd(cli, stringTokens(key, "13", "86", "Palermo")...))
+	assert.Equal(t, token.NewError(errNXAndXX.Error()), proc.geoAdd(cli, stringTokens(key, "nx", "xx", "13", "38", "a")...))
+}
+
+func TestProcessor_geoQuery(t *testing.T) {
+	key := "t_geoquery"
+	proc.geoAdd(cli, stringTokens(key, "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")...)
+	assert.Equal(t, token.NewBulked([]byte("166274.1516")), proc.geoDist(cli, stringTokens(key, "Palermo", "Catania")...))
+	assert.Equal(t, token.NewBulked([]byte("166.2742")), proc.geoDist(cli, stringTokens(key, "Palermo", "Catania", "km")...))
+	assert.Equal(t, token.NewBulked([]byte("103.3182")), proc.geoDist(cli, stringTokens(key, "Palermo", "Catania", "MI")...))
+	assert.Equal(t, token.NewBulked(nil), proc.geoDist(cli, stringTokens(key, "Palermo", "Rome")...))
+	assert.Equal(t, token.NewError(errGeoUnit.Error()), proc.geoDist(cli, stringTokens(key, "Palermo", "Catania", "yd")...))
+
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("sqc8b49rny0")), token.NewBulked([]byte("sqdtr74hyu0")),
+		token.NewBulked(nil)), proc.geoHash(cli, stringTokens(key, "Palermo", "Catania", "Rome")...))
+	pos := proc.geoPos(cli, stringTokens(key, "Palermo", "Rome")...).Data.([]*token.Token)
+	assert.Equal(t, token.NewArray(bulkedTokens("13.361389338970184", "38.1155563954963")...), pos[0])
+	assert.Equal(t, token.NewBulked(nil), pos[1])
+	assert.Equal(t, token.NewArray(token.NewBulked(nil)), proc.geoPos(cli, stringTokens("t_geoquery_none", "a")...))
+}
+
+func TestProcessor_geoSearch(t *testing.T) {
+	key := "t_geosearch"
+	proc.geoAdd(cli, stringTokens(key, "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania",
+		"12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2")...)
+	assert.Equal(t, token.NewArray(
+		token.NewArray(bulkedTokens("Catania", "56.4413")...),
+		token.NewArray(bulkedTokens("Palermo", "190.4424")...),
+	), proc.geoSearch(cli, stringTokens(key, "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "ASC", "WITHDIST")...))
+	assert.Equal(t, token.NewArray(bulkedTokens("edge1", "edge2", "Palermo", "Catania")...),
+		proc.geoSearch(cli, stringTokens(key, "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "DESC")...))
+	ts := append(stringTokens(key, "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "COUNT"), token.NewInteger(1))
+	assert.Equal(t, token.NewArray(bulkedTokens("Catania")...), proc.geoSearch(cli, ts...))
+	assert.Equal(t, token.NewArray(token.NewArray(token.NewBulked([]byte("Palermo")), token.NewBulked([]byte("0.0000")),
+		token.NewInteger(3479099956230698))), proc.geoSearch(cli, stringTokens(key,
+		"FROMMEMBER", "Palermo", "BYRADIUS", "10", "km", "WITHDIST", "WITHHASH")...))
+	assert.Equal(t, token.NewArray([]*token.Token{}...),
+		proc.geoSearch(cli, stringTokens(key, "FROMLONLAT", "0", "0", "BYRADIUS", "100", "km")...))
+
+	for args, err := range map[string]error{
+		"FROMMEMBER Rome BYRADIUS 1 km":                     errGeoMember,
+		"FROMLONLAT 15 37":                                  errGeoBy,
+		"BYRADIUS 1 km":                                     errGeoFrom,
+		"FROMLONLAT 15 37 BYRADIUS 1 km BYBOX 1 1 km":       errGeoBy,
+		"FROMLONLAT 15 37 BYRADIUS 1 km ANY":                errGeoAny,
+		"FROMLONLAT 15 37 BYRADIUS 1 yd":                    errGeoUnit,
+		"FROMLONLAT 15 37 BYRADIUS 1 km STOREDIST":          errGeoStoreDist,
+		"FROMLONLAT 15 37 BYRADIUS 1 km WITHSCORES":         errSyntax,
+		"FROMLONLAT 15 37 BYRADIUS -1 km":                   errGeoRadius,
+		"FROMLONLAT 15 37 BYBOX 1 -1 km":                    errGeoBox,
+		"FROMMEMBER Palermo FROMLONLAT 15 37 BYRADIUS 1 km": errGeoFrom,
+	} {
+		ts := stringTokens(append([]string{key}, strings.Fields(args)...)...)
+		assert.Equal(t, token.NewError(err.Error()), proc.geoSearch(cli, ts...), args)
+	}
+	ts = append(stringTokens(key, "FROMLONLAT", "15", "37", "BYRADIUS", "1", "km", "COUNT"), token.NewInteger(0))
+	assert.Equal(t, token.NewError(errGeoCount.Error()), proc.geoSearch(cli, ts...))
+	ts = append(stringTokens(key, "FROMLONLAT", "15", "37", "BYRADIUS", "300", "km", "COUNT"),
+		token.NewInteger(1), token.NewString("ANY"))
+	assert.Equal(t, 1, len(proc.geoSearch(cli, ts...).Data.([]*token.Token)))
+}
+
+func TestProcessor_geoSearchStore(t *testing.T) {
+	src, dst := "t_geosearchstore_src", "t_geosearchstore_dst"
+	proc.geoAdd(cli, stringTokens(src, "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")...)
+	assert.Equal(t, token.NewInteger(2), proc.geoSearchStore(cli, stringTokens(dst, src,
+		"FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "STOREDIST")...))
+	score, err := tokenToFloat(proc.zScore(cli, token.NewString(dst), token.NewString("Catania")))
+	assert.Nil(t, err)
+	assert.InDelta(t, 56.4413, score, 1e-4)
+	assert.Equal(t, token.NewInteger(1), proc.geoSearchStore(cli, stringTokens(dst, src,
+		"FROMMEMBER", "Palermo", "BYRADIUS", "1", "km")...))
+	assert.Equal(t, token.NewBulked([]byte("3479099956230698")), proc.zScore(cli, token.NewString(dst), token.NewString("Palermo")))
+	assert.Equal(t, token.NewError(errGeoStoreWith.Error()), proc.geoSearchStore(cli, stringTokens(dst, src,
+		"FROMMEMBER", "Palermo", "BYRADIUS", "1", "km", "WITHDIST")...))
+	assert.Equal(t, token.NewInteger(0), proc.geoSearchStore(cli, stringTokens(dst, src,
+		"FROMLONLAT", "0", "0", "BYRADIUS", "1", "km")...))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, token.NewString(dst)))
+}
@@ -0,0 +1,491 @@
+package proc
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// getHash returns the hash stored at key, nil if the key does not exist.
+// The hash returned is allowed to be modified in place if mutable is true.
+func getHash(cli *model.Client, key string, mutable bool) (*model.Hash, error) {
+	var v interface{}
+	if mutable {
+		v = cli.GetMutable(key)
+	} else {
+		v = cli.Get(key)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	h, ok := v.(*model.Hash)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, nil
+}
+
+// getOrNewHash returns the hash stored at key for modification,
+// a new hash is stored if the key does not exist.
+func getOrNewHash(cli *model.Client, key string) (*model.Hash, error) {
+	h, err := getHash(cli, key, true)
+	if err != nil || h != nil {
+		return h, err
+	}
+	h = model.NewHash()
+	cli.Set(key, h, 0)
+	return h, nil
+}
+
+// checkKeyField checks types of key and field, and returns both in string
+func checkKeyField(tokens []*token.Token) (string, string, error) {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return "", "", err
+	}
+	field, err := tokenToBytes(tokens[1])
+	if err != nil {
+		return "", "", err
+	}
+	return tokens[0].Data.(string), string(field), nil
+}
+
+// hSetGeneric puts field-value pairs and returns the number of fields added
+func (p *Processor) hSetGeneric(cli *model.Client, tokens []*token.Token) (int64, error) {
+	if len(tokens) < 3 || len(tokens)%2 == 0 {
+		return 0, errArgNumber
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return 0, err
+	}
+	pairs := make([][]byte, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		v, err := tokenToBytes(t)
+		if err != nil {
+			return 0, err
+		}
+		pairs = append(pairs, v)
+	}
+	key := tokens[0].Data.(string)
+	h, err := getOrNewHash(cli, key)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for i := 0; i < len(pairs); i += 2 {
+		if h.Set(string(pairs[i]), pairs[i+1]) {
+			n++
+		}
+	}
+	cli.Notify(model.NotifyHash, model.EventHSet, key)
+	return n, nil
+}
+
+func (p *Processor) hSet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	n, err := p.hSetGeneric(cli, tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewInteger(n)
+}
+
+// hMSet is deprecated and same as hSet except for the reply
+func (p *Processor) hMSet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if _, err := p.hSetGeneric(cli, tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.ReplyOk
+}
+
+func (p *Processor) hSetNX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	v, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h, err := getHash(cli, key, false); err != nil {
+		return token.NewError(err.Error())
+	} else if h != nil {
+		if _, ok := h.Get(field); ok {
+			p.propagateAs()
+			return token.NewInteger(0)
+		}
+	}
+	h, _ := getOrNewHash(cli, key)
+	h.Set(field, v)
+	cli.Notify(model.NotifyHash, model.EventHSet, key)
+	return token.NewInteger(1)
+}
+
+func (p *Processor) hGet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, key, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h == nil {
+		return token.NewBulked(nil)
+	}
+	if v, ok := h.Get(field); ok {
+		return token.NewBulked(v)
+	}
+	return token.NewBulked(nil)
+}
+
+func (p *Processor) hMGet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	fields := make([][]byte, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		field, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		fields = append(fields, field)
+	}
+	h, err := getHash(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(fields))
+	for _, field := range fields {
+		if h == nil {
+			ts = append(ts, token.NewBulked(nil))
+		} else if v, ok := h.Get(string(field)); ok {
+			ts = append(ts, token.NewBulked(v))
+		} else {
+			ts = append(ts, token.NewBulked(nil))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+func (p *Processor) hDel(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	fields := make([][]byte, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		field, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		fields = append(fields, field)
+	}
+	key := tokens[0].Data.(string)
+	h, err := getHash(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if h != nil {
+		for _, field := range fields {
+			if h.Del(string(field)) {
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return token.NewInteger(0)
+	}
+	cli.Notify(model.NotifyHash, model.EventHDel, key)
+	if h.Len() == 0 {
+		cli.Del(key)
+	}
+	return token.NewInteger(n)
+}
+
+func (p *Processor) hExists(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, key, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h == nil {
+		return token.NewInteger(0)
+	}
+	if _, ok := h.Get(field); ok {
+		return token.NewInteger(1)
+	}
+	return token.NewInteger(0)
+}
+
+func (p *Processor) hLen(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(int64(h.Len()))
+}
+
+func (p *Processor) hStrLen(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, key, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h == nil {
+		return token.NewInteger(0)
+	}
+	v, _ := h.Get(field)
+	return token.NewInteger(int64(len(v)))
+}
+
+// hGetAllGeneric returns fields and/or values of the hash
+func (p *Processor) hGetAllGeneric(cli *model.Client, tokens []*token.Token, withField, withValue bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0)
+	// fields and values are replied as a map in RESP3
+	reply := token.NewArray
+	if withField && withValue {
+		reply = token.NewMap
+	}
+	if h == nil {
+		return reply(ts...)
+	}
+	for _, field := range h.Fields() {
+		if withField {
+			ts = append(ts, token.NewBulked([]byte(field)))
+		}
+		if withValue {
+			v, _ := h.Get(field)
+			ts = append(ts, token.NewBulked(v))
+		}
+	}
+	return reply(ts...)
+}
+
+func (p *Processor) hKeys(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.hGetAllGeneric(cli, tokens, true, false)
+}
+
+func (p *Processor) hVals(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.hGetAllGeneric(cli, tokens, false, true)
+}
+
+func (p *Processor) hGetAll(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.hGetAllGeneric(cli, tokens, true, true)
+}
+
+func (p *Processor) hIncrBy(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[2], "increment", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	incr := tokens[2].Data.(int64)
+	h, err := getOrNewHash(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var num int64
+	if v, ok := h.Get(field); ok {
+		if num, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return token.NewError("hash value is not an integer")
+		}
+	}
+	if (incr > 0 && num > math.MaxInt64-incr) || (incr < 0 && num < math.MinInt64-incr) {
+		return token.NewError(errOverflow.Error())
+	}
+	num += incr
+	h.Set(field, []byte(strconv.FormatInt(num, 10)))
+	cli.Notify(model.NotifyHash, model.EventHIncrBy, key)
+	return token.NewInteger(num)
+}
+
+// hIncrByFloat propagates the result with hset, since float arithmetic
+// may differ when replayed.
+func (p *Processor) hIncrByFloat(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, field, err := checkKeyField(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	incr, err := tokenToFloat(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getOrNewHash(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var num float64
+	if v, ok := h.Get(field); ok {
+		if num, err = strconv.ParseFloat(string(v), 64); err != nil || math.IsNaN(num) {
+			return token.NewError("hash value is not a float")
+		}
+	}
+	num += incr
+	if math.IsNaN(num) || math.IsInf(num, 0) {
+		return token.NewError(errNaNOrInf.Error())
+	}
+	v := []byte(token.FormatFloat(num))
+	h.Set(field, v)
+	cli.Notify(model.NotifyHash, model.EventHIncrByFloat, key)
+	p.propagateAs(token.NewArray(token.NewString(cds.HSet), tokens[0],
+		token.NewBulked([]byte(field)), token.NewBulked(v)))
+	return token.NewBulked(v)
+}
+
+// hRandField returns random fields. A positive count returns distinct fields,
+// a negative count allows the same field returned multiple times.
+func (p *Processor) hRandField(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	withCount := len(tokens) > 1
+	var count int64 = 1
+	var withValues bool
+	if withCount {
+		if err := checkType(tokens[1], "count", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		count = tokens[1].Data.(int64)
+		if err := checkRandCount(count); err != nil {
+			return token.NewError(err.Error())
+		}
+		if len(tokens) > 2 {
+			if err := checkType(tokens[2], "argument", label.String); err != nil {
+				return token.NewError(err.Error())
+			}
+			if strings.ToUpper(tokens[2].Data.(string)) != cds.WithValues || len(tokens) > 3 {
+				return token.NewError(errSyntax.Error())
+			}
+			withValues = true
+		}
+	}
+	h, err := getHash(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if h == nil {
+		if withCount {
+			return token.NewArray()
+		}
+		return token.NewBulked(nil)
+	}
+	if !withCount {
+		field, _ := h.Random()
+		return token.NewBulked([]byte(field))
+	}
+	fields := randElements(h, h.Fields, count)
+	ts := make([]*token.Token, 0, len(fields))
+	for _, field := range fields {
+		ts = append(ts, token.NewBulked([]byte(field)))
+		if withValues {
+			v, _ := h.Get(field)
+			ts = append(ts, token.NewBulked(v))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// maxRandCount limits the number of random elements with repetitions, which
+// are allocated at once regardless of the number of elements stored
+const maxRandCount = 1 << 20
+
+// checkRandCount returns an error if the negative count asks for too many
+// elements, whose negation may even overflow
+func checkRandCount(count int64) error {
+	if count < -maxRandCount {
+		return errOutOfRange
+	}
+	return nil
+}
+
+// randomPicker is the collection picking its elements at random
+type randomPicker interface {
+	Len() int
+	Random() (string, bool)
+}
+
+// randElements returns count random elements of c, distinct if count is
+// positive. Elements are picked one by one unless the count is close to the
+// number of elements, when all the elements listed by all are shuffled like
+// redis does. The count must be checked by checkRandCount.
+func randElements(c randomPicker, all func() []string, count int64) []string {
+	if count < 0 {
+		ret := make([]string, 0, -count)
+		for ; count < 0; count++ {
+			e, _ := c.Random()
+			ret = append(ret, e)
+		}
+		return ret
+	}
+	if count > int64(c.Len())/3 {
+		elems := all()
+		rand.Shuffle(len(elems), func(i, j int) { elems[i], elems[j] = elems[j], elems[i] })
+		if count < int64(len(elems)) {
+			elems = elems[:count]
+		}
+		return elems
+	}
+	ret := make([]string, 0, count)
+	picked := make(map[string]struct{}, count)
+	for int64(len(ret)) < count {
+		e, _ := c.Random()
+		if _, ok := picked[e]; !ok {
+			picked[e] = struct{}{}
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+func (p *Processor) hScan(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseScan(tokens[1:], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, err := getHash(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0)
+	if h == nil {
+		return scanReply(0, ts)
+	}
+	next := h.Scan(uint64(opt.cursor), int(opt.count), func(field string, v []byte) {
+		if opt.matched(field) {
+			ts = append(ts, token.NewBulked([]byte(field)), token.NewBulked(v))
+		}
+	})
+	return scanReply(next, ts)
+}
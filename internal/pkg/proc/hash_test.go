@@ -0,0 +1,166 @@
+package proc
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// sortedArray sorts the bulked elements of the array token
+func sortedArray(t *token.Token) *token.Token {
+	ts := t.Data.([]*token.Token)
+	sort.Slice(ts, func(i, j int) bool {
+		return string(ts[i].Data.([]byte)) < string(ts[j].Data.([]byte))
+	})
+	return t
+}
+
+func TestProcessor_hSet(t *testing.T) {
+	key := token.NewString("t_hset")
+	assert.Equal(t, token.NewError(errArgNumber.Error()), proc.hSet(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(2), proc.hSet(cli, key,
+		token.NewString("a"), token.NewInteger(1), token.NewString("b"), token.NewString("2")))
+	assert.Equal(t, token.NewInteger(1), proc.hSet(cli, key,
+		token.NewString("a"), token.NewInteger(3), token.NewString("c"), token.NewString("4")))
+	assert.Equal(t, token.NewBulked([]byte("3")), proc.hGet(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewBulked(nil), proc.hGet(cli, key, token.NewString("d")))
+	assert.Equal(t, token.NewInteger(0), proc.hSetNX(cli, key, token.NewString("a"), token.NewString("5")))
+	assert.Equal(t, token.NewInteger(1), proc.hSetNX(cli, key, token.NewString("d"), token.NewString("5")))
+	assert.Equal(t, token.ReplyOk, proc.hMSet(cli, key, token.NewString("e"), token.NewString("6")))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("3")), token.NewBulked(nil), token.NewBulked([]byte("6"))),
+		proc.hMGet(cli, key, token.NewString("a"), token.NewString("f"), token.NewString("e")))
+	assert.Equal(t, token.NewInteger(5), proc.hLen(cli, key))
+	assert.Equal(t, token.NewInteger(1), proc.hStrLen(cli, key, token.NewString("e")))
+	assert.Equal(t, token.NewInteger(0), proc.hStrLen(cli, key, token.NewString("f")))
+
+	proc.set(cli, token.NewString("t_hset_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType),
+		proc.hSet(cli, token.NewString("t_hset_str"), token.NewString("a"), token.NewString("b")))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.get(cli, key))
+}
+
+func TestProcessor_hDel(t *testing.T) {
+	key := token.NewString("t_hdel")
+	assert.Equal(t, token.NewInteger(0), proc.hDel(cli, key, token.NewString("a")))
+	proc.hSet(cli, key, token.NewString("a"), token.NewString("1"), token.NewString("b"), token.NewString("2"))
+	assert.Equal(t, token.NewInteger(1), proc.hExists(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(1), proc.hDel(cli, key, token.NewString("a"), token.NewString("c")))
+	assert.Equal(t, token.NewInteger(0), proc.hExists(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(1), proc.hDel(cli, key, token.NewString("b")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+}
+
+func TestProcessor_hGetAll(t *testing.T) {
+	key := token.NewString("t_hgetall")
+	assert.Equal(t, bulkedMap(), proc.hGetAll(cli, key))
+	proc.hSet(cli, key, token.NewString("a"), token.NewString("1"), token.NewString("b"), token.NewString("2"))
+	assert.Equal(t, bulkedArray("a", "b"), sortedArray(proc.hKeys(cli, key)))
+	assert.Equal(t, bulkedArray("1", "2"), sortedArray(proc.hVals(cli, key)))
+	all := proc.hGetAll(cli, key).Data.([]*token.Token)
+	assert.Equal(t, 4, len(all))
+	for i := 0; i < len(all); i += 2 {
+		assert.Equal(t, token.NewBulked(nil).Label, all[i].Label)
+		assert.Equal(t, proc.hGet(cli, key, token.NewBulked(all[i].Data.([]byte))), all[i+1])
+	}
+}
+
+func TestProcessor_hIncrBy(t *testing.T) {
+	key := token.NewString("t_hincrby")
+	field := token.NewString("f")
+	assert.Equal(t, token.NewInteger(5), proc.hIncrBy(cli, key, field, token.NewInteger(5)))
+	assert.Equal(t, token.NewInteger(2), proc.hIncrBy(cli, key, field, token.NewInteger(-3)))
+	proc.hSet(cli, key, field, token.NewInteger(9223372036854775807))
+	assert.Equal(t, token.NewError(errOverflow.Error()), proc.hIncrBy(cli, key, field, token.NewInteger(1)))
+	proc.hSet(cli, key, field, token.NewString("a"))
+	assert.Equal(t, token.NewError("hash value is not an integer"), proc.hIncrBy(cli, key, field, token.NewInteger(1)))
+
+	proc.hSet(cli, key, field, token.NewString("10.5"))
+	assert.Equal(t, token.NewBulked([]byte("10.6")), proc.hIncrByFloat(cli, key, field, token.NewString("0.1")))
+	assert.Equal(t, token.NewBulked([]byte("5.6")), proc.hIncrByFloat(cli, key, field, token.NewInteger(-5)))
+	assert.Equal(t, token.NewError(errNotFloat.Error()), proc.hIncrByFloat(cli, key, field, token.NewString("a")))
+	proc.hSet(cli, key, field, token.NewString("a"))
+	assert.Equal(t, token.NewError("hash value is not a float"), proc.hIncrByFloat(cli, key, field, token.NewInteger(1)))
+}
+
+func TestProcessor_hIncrByFloat_propagate(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("t_hincrbyfloat")
+	p.execCmd(c, token.NewArray(token.NewString(cds.HIncrByFlt), key, token.NewString("f"), token.NewString("1.5")))
+	assert.Equal(t, 1, len(p.aof))
+	assert.Equal(t, token.NewArray(token.NewString(cds.HSet), key,
+		token.NewBulked([]byte("f")), token.NewBulked([]byte("1.5"))), p.aof[0].T)
+	p.execCmd(c, token.NewArray(token.NewString(cds.HSetNX), key, token.NewString("f"), token.NewString("1")))
+	assert.Equal(t, 1, len(p.aof))
+}
+
+func TestProcessor_hRandField(t *testing.T) {
+	key := token.NewString("t_hrandfield")
+	assert.Equal(t, token.NewBulked(nil), proc.hRandField(cli, key))
+	assert.Equal(t, token.NewArray(), proc.hRandField(cli, key, token.NewInteger(1)))
+	proc.hSet(cli, key, token.NewString("a"), token.NewString("1"), token.NewString("b"), token.NewString("2"))
+	assert.Equal(t, bulkedArray("a", "b"), sortedArray(proc.hRandField(cli, key, token.NewInteger(5))))
+	assert.Equal(t, 1, len(proc.hRandField(cli, key, token.NewInteger(1)).Data.([]*token.Token)))
+	assert.Equal(t, 5, len(proc.hRandField(cli, key, token.NewInteger(-5)).Data.([]*token.Token)))
+	assert.Equal(t, 4, len(proc.hRandField(cli, key, token.NewInteger(2),
+		token.NewString("withvalues")).Data.([]*token.Token)))
+	assert.Equal(t, token.NewError(errSyntax.Error()),
+		proc.hRandField(cli, key, token.NewInteger(2), token.NewString("a")))
+	// the negation of the count overflows
+	assert.Equal(t, token.NewError(errOutOfRange.Error()), proc.hRandField(cli, key, token.NewInteger(math.MinInt64)))
+	assert.Equal(t, token.NewError(errOutOfRange.Error()), proc.hRandField(cli, key, token.NewInteger(-maxRandCount-1)))
+	assert.Equal(t, token.NewError(errOutOfRange.Error()),
+		proc.hRandField(cli, token.NewString("t_hrandfield_none"), token.NewInteger(math.MinInt64)))
+}
+
+func TestProcessor_hScan(t *testing.T) {
+	key := token.NewString("t_hscan")
+	assert.Equal(t, scanReply(0, []*token.Token{}), proc.hScan(cli, key, token.NewInteger(0)))
+	for i := 0; i < 25; i++ {
+		proc.hSet(cli, key, token.NewInteger(int64(i)), token.NewInteger(int64(i)))
+	}
+	fields := make(map[string]bool)
+	var cursor int64
+	for {
+		ret := proc.hScan(cli, key, token.NewInteger(cursor), token.NewString("count"), token.NewInteger(7))
+		data := ret.Data.([]*token.Token)
+		pairs := data[1].Data.([]*token.Token)
+		for i := 0; i < len(pairs); i += 2 {
+			assert.Equal(t, pairs[i], pairs[i+1])
+			fields[string(pairs[i].Data.([]byte))] = true
+		}
+		next, _ := ItfToInt(data[0].Data)
+		if cursor = next.(int64); cursor == 0 {
+			break
+		}
+	}
+	assert.Equal(t, 25, len(fields))
+
+	ret := proc.hScan(cli, key, token.NewInteger(0), token.NewString(cds.Match), token.NewString("1?"),
+		token.NewString(cds.Count), token.NewInteger(100))
+	assert.Equal(t, 20, len(ret.Data.([]*token.Token)[1].Data.([]*token.Token)))
+	assert.Equal(t, token.NewError(errInvalidCursor.Error()), proc.hScan(cli, key, token.NewInteger(-1)))
+}
+
+func TestProcessor_GenBin_hash(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewMockClient()
+	p.hSet(c, token.NewString("hash"), token.NewString("a"), token.NewString("1"))
+	_ = p.data[0].Freeze()
+	p.hSet(c, token.NewString("hash"), token.NewString("b"), token.NewString("2"))
+	ch := make(chan []byte)
+	go p.GenBin(0, ch)
+	var dump []byte
+	for d := range ch {
+		dump = append(dump, d...)
+	}
+	_ = p.data[0].ToMove()
+	set, _ := token.NewArray(token.NewString(cds.HSet), token.NewString("hash"),
+		token.NewBulked([]byte("a")), token.NewBulked([]byte("1"))).Serialize()
+	assert.Equal(t, string(set), string(dump))
+	assert.Equal(t, token.NewInteger(2), p.hLen(c, token.NewString("hash")))
+}
@@ -0,0 +1,92 @@
+package proc
+
+import (
+	"github.com/inhzus/go-redis-impl/internal/pkg/hll"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// getHLL returns the HyperLogLog stored at key, nil if the key does not exist
+func getHLL(cli *model.Client, key string) ([]byte, error) {
+	data, err := getString(cli, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	if err := hll.Check(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// pfAdd adds elements to the HyperLogLog, returns 1 if the approximated
+// cardinality is changed or the key is created.
+func (p *Processor) pfAdd(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	elements := make([][]byte, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		e, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		elements = append(elements, e)
+	}
+	key := tokens[0].Data.(string)
+	data, err := getHLL(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	h, changed, err := hll.Add(data, elements...)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if !changed && data != nil {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	setKeepTTL(cli, key, h, model.EventPFAdd)
+	return token.NewInteger(1)
+}
+
+// pfCount returns the approximated cardinality of the union of HyperLogLogs
+func (p *Processor) pfCount(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	hs := make([][]byte, 0, len(tokens))
+	for _, t := range tokens {
+		data, err := getHLL(cli, t.Data.(string))
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		hs = append(hs, data)
+	}
+	n, err := hll.Count(hs...)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewInteger(int64(n))
+}
+
+// pfMerge stores the union of the destination and the source HyperLogLogs
+// in the destination.
+func (p *Processor) pfMerge(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	hs := make([][]byte, 0, len(tokens))
+	for _, t := range tokens {
+		data, err := getHLL(cli, t.Data.(string))
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		hs = append(hs, data)
+	}
+	h, err := hll.Merge(hs...)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	setKeepTTL(cli, tokens[0].Data.(string), h, model.EventPFAdd)
+	return token.ReplyOk
+}
@@ -0,0 +1,71 @@
+package proc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/hll"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_pfAdd(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) *token.Token {
+		ts := []*token.Token{token.NewString(cmd)}
+		for i, arg := range args {
+			if i == 0 || cmd != cds.PFAdd {
+				ts = append(ts, token.NewString(arg))
+			} else {
+				ts = append(ts, token.NewBulked([]byte(arg)))
+			}
+		}
+		return p.execCmd(c, token.NewArray(ts...))
+	}
+	assert.Equal(t, int64(1), exec(cds.PFAdd, "hll", "a", "b", "c", "d", "e", "f", "g").Data)
+	assert.Equal(t, int64(7), exec(cds.PFCount, "hll").Data)
+	assert.Equal(t, int64(0), exec(cds.PFAdd, "hll", "a", "b").Data)
+	assert.Equal(t, 1, len(p.aof))
+	// the key is created without elements
+	assert.Equal(t, int64(1), exec(cds.PFAdd, "empty").Data)
+	assert.Equal(t, int64(0), exec(cds.PFAdd, "empty").Data)
+	assert.Equal(t, int64(0), exec(cds.PFCount, "empty", "none").Data)
+	assert.Equal(t, 2, len(p.aof))
+
+	args := []string{"other"}
+	for i := 0; i < 1000; i++ {
+		args = append(args, fmt.Sprintf("e%d", i))
+	}
+	assert.Equal(t, int64(1), exec(cds.PFAdd, args...).Data)
+	assert.InDelta(t, 1000, exec(cds.PFCount, "other").Data, 20)
+	assert.InDelta(t, 1007, exec(cds.PFCount, "hll", "other", "none").Data, 20)
+
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.PFMerge, "hll", "other").Data)
+	assert.InDelta(t, 1007, exec(cds.PFCount, "hll").Data, 20)
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.PFMerge, "merged", "none").Data)
+	assert.Equal(t, int64(0), exec(cds.PFCount, "merged").Data)
+
+	// the data propagated is replayed to the same HyperLogLogs
+	mock := p.NewClient(nil)
+	p2 := NewProcessor(1)
+	for _, m := range p.aof {
+		p2.execCmd(mock, m.T)
+	}
+	for _, key := range []string{"hll", "empty", "other", "merged"} {
+		assert.Equal(t, p.get(c, token.NewString(key)), p.get(mock, token.NewString(key)))
+	}
+}
+
+func TestProcessor_pfAdd_invalid(t *testing.T) {
+	key := token.NewString("t_pfadd_invalid")
+	proc.set(cli, key, token.NewString("foo"))
+	assert.Equal(t, token.NewError(hll.ErrInvalid.Error()), proc.pfAdd(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewError(hll.ErrInvalid.Error()), proc.pfCount(cli, key))
+	assert.Equal(t, token.NewError(hll.ErrInvalid.Error()), proc.pfMerge(cli, token.NewString("t_pfadd_dst"), key))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, token.NewString("t_pfadd_dst")))
+	set := token.NewString("t_pfadd_set")
+	proc.sAdd(cli, set, token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.pfCount(cli, token.NewString("t_pfadd_none"), set))
+}
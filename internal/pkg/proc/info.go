@@ -0,0 +1,62 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// infoSection generates the lines of a section of INFO
+type infoSection struct {
+	name  string
+	lines func() []string
+}
+
+func (p *Processor) infoSections() []infoSection {
+	return []infoSection{
+		{"Server", func() []string {
+			return []string{fmt.Sprintf("hz:%d", p.hz)}
+		}},
+		{"Stats", func() []string {
+			var expired int64
+			for _, d := range p.data {
+				expired += d.ExpiredKeys()
+			}
+			return []string{
+				fmt.Sprintf("expired_keys:%d", expired),
+				fmt.Sprintf("expired_time_cap_reached_count:%d", p.expireCycle.timeCapReached),
+				fmt.Sprintf("expire_cycle_cpu_milliseconds:%d", p.expireCycle.cycleTime.Milliseconds()),
+			}
+		}},
+	}
+}
+
+// info returns the sections required, all of them if none is given or
+// "all" / "default" is given.
+func (p *Processor) info(_ *model.Client, tokens ...*token.Token) *token.Token {
+	names, err := tokensToMembers(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	required := make(map[string]bool)
+	for _, name := range names {
+		required[strings.ToLower(name)] = true
+	}
+	all := len(required) == 0 || required["all"] || required["default"] || required["everything"]
+	var b strings.Builder
+	for _, section := range p.infoSections() {
+		if !all && !required[strings.ToLower(section.name)] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + section.name + "\r\n")
+		for _, line := range section.lines() {
+			b.WriteString(line + "\r\n")
+		}
+	}
+	return token.NewBulked([]byte(b.String()))
+}
@@ -0,0 +1,242 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/glob"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// names of value types
+const (
+	typeNone   = "none"
+	typeString = "string"
+	typeList   = "list"
+	typeHash   = "hash"
+	typeSet    = "set"
+	typeZSet   = "zset"
+	typeStream = "stream"
+)
+
+// typeName returns the name of type of value, "none" if nil
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return typeNone
+	case *model.List:
+		return typeList
+	case *model.Hash:
+		return typeHash
+	case *model.Set:
+		return typeSet
+	case *model.ZSet:
+		return typeZSet
+	case *model.Stream:
+		return typeStream
+	}
+	if isString(v) {
+		return typeString
+	}
+	return typeNone
+}
+
+// encodings of values which are not defined in package model
+const (
+	encInt       = "int"
+	encEmbStr    = "embstr"
+	encRaw       = "raw"
+	encQuickList = "quicklist"
+	encHashTable = "hashtable"
+	encSkipList  = "skiplist"
+	encStream    = "stream"
+	// strings not longer than the limit are embedded in redis objects
+	embStrLimit = 44
+)
+
+// encodingOf returns the name of internal encoding of value the same as redis
+func encodingOf(v interface{}) string {
+	switch v := v.(type) {
+	case int, int64:
+		return encInt
+	case []byte:
+		return stringEncoding(string(v))
+	case string:
+		return stringEncoding(v)
+	case *model.List:
+		return encQuickList
+	case *model.Hash:
+		return encHashTable
+	case *model.Set:
+		return v.Encoding()
+	case *model.ZSet:
+		return encSkipList
+	case *model.Stream:
+		return encStream
+	}
+	return encRaw
+}
+
+// stringEncoding returns the encoding of string value
+func stringEncoding(s string) string {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) <= 20 {
+		return encInt
+	}
+	if len(s) <= embStrLimit {
+		return encEmbStr
+	}
+	return encRaw
+}
+
+// isTypeName reports whether s is the name of a value type
+func isTypeName(s string) bool {
+	switch s {
+	case typeString, typeList, typeHash, typeSet, typeZSet, typeStream:
+		return true
+	}
+	return false
+}
+
+// keys returns the sorted keys matching the pattern
+func (p *Processor) keys(cli *model.Client, tokens ...*token.Token) *token.Token {
+	pattern, err := tokenToBytes(tokens[0])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	keys := make([]string, 0)
+	for _, key := range cli.Keys() {
+		if glob.Match(string(pattern), key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return membersToArray(keys)
+}
+
+// scan iterates keys by "cursor [MATCH pattern] [COUNT count] [TYPE type]",
+// each call visits about count keys of the database.
+func (p *Processor) scan(cli *model.Client, tokens ...*token.Token) *token.Token {
+	opt, err := parseScan(tokens, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0)
+	next := cli.Data.Scan(uint64(opt.cursor), int(opt.count), func(key string, v interface{}) {
+		if opt.matched(key) && (opt.typ == "" || typeName(v) == opt.typ) {
+			ts = append(ts, token.NewBulked([]byte(key)))
+		}
+	})
+	return scanReply(next, ts)
+}
+
+// keyType returns the type name of value of the key
+func (p *Processor) keyType(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewString(typeName(cli.Get(tokens[0].Data.(string))))
+}
+
+// renameKey renames the key to the new key keeping its expiration, the value
+// of the new key is overwritten if nx is false. It reports whether renamed.
+func (p *Processor) renameKey(cli *model.Client, key, newKey string, nx bool) (bool, error) {
+	expire, ok := cli.GetExpire(key)
+	if !ok {
+		return false, errNoSuchKey
+	}
+	if nx && cli.Data.Peek(newKey) != nil {
+		return false, nil
+	}
+	if key == newKey {
+		return true, nil
+	}
+	v := cli.GetMutable(key)
+	cli.Remove(key)
+	cli.Set(newKey, v, expire)
+	cli.Notify(model.NotifyGeneric, model.EventRenameFrom, key)
+	cli.Notify(model.NotifyGeneric, model.EventRenameTo, newKey)
+	p.signalKey(cli, newKey)
+	return true, nil
+}
+
+// rename renames the key by "key newkey", the new key is overwritten if exists
+func (p *Processor) rename(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	if _, err := p.renameKey(cli, tokens[0].Data.(string), tokens[1].Data.(string), false); err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.ReplyOk
+}
+
+// renameNX renames the key by "key newkey" only if the new key does not
+// exist, it returns 1 if renamed, otherwise 0.
+func (p *Processor) renameNX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	ok, err := p.renameKey(cli, tokens[0].Data.(string), tokens[1].Data.(string), true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if !ok {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(1)
+}
+
+// touch updates the last access of the keys, it returns the number of keys existing
+func (p *Processor) touch(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	for _, key := range tokens {
+		if cli.Get(key.Data.(string)) != nil {
+			n++
+		}
+	}
+	return token.NewInteger(n)
+}
+
+// object inspects the internals of the value of key by "ENCODING key",
+// "REFCOUNT key", "IDLETIME key" or "FREQ key". The inspection does not
+// update the last access of the key.
+func (p *Processor) object(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[0], "subcommand", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	sub := strings.ToUpper(tokens[0].Data.(string))
+	switch sub {
+	case cds.Encoding, cds.RefCount, cds.IdleTime, cds.Freq:
+	default:
+		return token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", sub))
+	}
+	if len(tokens) != 2 {
+		return token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", sub))
+	}
+	if err := checkKeyType(tokens[1]); err != nil {
+		return token.NewError(err.Error())
+	}
+	item := cli.Data.Peek(tokens[1].Data.(string))
+	if item == nil {
+		return token.NewBulked(nil)
+	}
+	switch sub {
+	case cds.Encoding:
+		return token.NewBulked([]byte(encodingOf(item.Row)))
+	case cds.RefCount:
+		return token.NewInteger(1)
+	case cds.IdleTime:
+		return token.NewInteger(int64(item.Idle() / time.Second))
+	}
+	return token.NewInteger(int64(item.Freq()))
+}
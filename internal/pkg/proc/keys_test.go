@@ -0,0 +1,138 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// scanAll follows the cursor of scan until it is finished
+func scanAll(t *testing.T, p *Processor, c *model.Client, args ...*token.Token) []string {
+	var keys []string
+	var cursor int64
+	for {
+		reply := p.scan(c, append([]*token.Token{token.NewInteger(cursor)}, args...)...)
+		assert.Nil(t, reply.Error())
+		data := reply.Data.([]*token.Token)
+		for _, key := range data[1].Data.([]*token.Token) {
+			keys = append(keys, string(key.Data.([]byte)))
+		}
+		cursor, _ = strconv.ParseInt(string(data[0].Data.([]byte)), 10, 64)
+		if cursor == 0 {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestProcessor_keys(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	p.set(c, token.NewString("one"), token.NewString("1"))
+	p.set(c, token.NewString("two"), token.NewString("2"))
+	p.set(c, token.NewString("three"), token.NewString("3"), token.NewString(cds.TimeoutMilSec), token.NewInteger(-1))
+	p.rPush(c, token.NewString("list"), token.NewString("a"))
+	assert.Equal(t, bulkedArray("list", "one", "two"), p.keys(c, token.NewString("*")))
+	assert.Equal(t, bulkedArray("one", "two"), p.keys(c, token.NewString("*o*")))
+	assert.Equal(t, bulkedArray(), p.keys(c, token.NewString("x*")))
+}
+
+func TestProcessor_scan(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	var all []string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("k%02d", i)
+		all = append(all, key)
+		p.set(c, token.NewString(key), token.NewInteger(int64(i)))
+	}
+	p.sAdd(c, token.NewString("set"), token.NewString("a"))
+	assert.Equal(t, append(append([]string{}, all...), "set"), scanAll(t, p, c))
+	assert.Equal(t, all[10:20], scanAll(t, p, c, token.NewString(cds.Match), token.NewString("k1?"),
+		token.NewString(cds.Count), token.NewInteger(3)))
+	assert.Equal(t, []string{"set"}, scanAll(t, p, c, token.NewString("type"), token.NewString("SET")))
+	assert.Equal(t, all, scanAll(t, p, c, token.NewString(cds.Type), token.NewString("string")))
+	assert.Equal(t, token.NewError(errUnknownType.Error()),
+		p.scan(c, token.NewInteger(0), token.NewString(cds.Type), token.NewString("foo")))
+	assert.Equal(t, token.NewError(errArgNotRecognized.Error()),
+		p.hScan(c, token.NewString("h"), token.NewInteger(0), token.NewString(cds.Type), token.NewString("hash")))
+
+	// keys are split between new data and origin data when frozen
+	assert.Nil(t, p.data[0].Freeze())
+	p.del(c, token.NewString("k00"))
+	p.set(c, token.NewString("k01"), token.NewString("x"))
+	p.set(c, token.NewString("new"), token.NewString("x"))
+	expected := append(append([]string{}, all[1:]...), "new", "set")
+	sort.Strings(expected)
+	assert.Equal(t, expected, scanAll(t, p, c, token.NewString(cds.Count), token.NewInteger(7)))
+	assert.Nil(t, p.data[0].ToMove())
+	assert.Equal(t, expected, scanAll(t, p, c, token.NewString(cds.Count), token.NewInteger(7)))
+	assert.Equal(t, bulkedArray(expected...), p.keys(c, token.NewString("*")))
+}
+
+func TestProcessor_keyType(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	p.set(c, token.NewString("str"), token.NewString("1"))
+	p.rPush(c, token.NewString("list"), token.NewString("a"))
+	p.sAdd(c, token.NewString("set"), token.NewString("a"))
+	assert.Equal(t, token.NewString(typeString), p.keyType(c, token.NewString("str")))
+	assert.Equal(t, token.NewString(typeList), p.keyType(c, token.NewString("list")))
+	assert.Equal(t, token.NewString(typeSet), p.keyType(c, token.NewString("set")))
+	assert.Equal(t, token.NewString(typeNone), p.keyType(c, token.NewString("none")))
+}
+
+func TestProcessor_rename(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	p.set(c, token.NewString("a"), token.NewString("1"), token.NewString(cds.TimeoutSec), token.NewInteger(100))
+	p.set(c, token.NewString("b"), token.NewString("2"))
+	assert.Equal(t, token.NewError(errNoSuchKey.Error()), p.rename(c, token.NewString("x"), token.NewString("y")))
+	assert.Equal(t, token.NewInteger(0), p.renameNX(c, token.NewString("a"), token.NewString("b")))
+	assert.Equal(t, token.ReplyOk, p.rename(c, token.NewString("a"), token.NewString("a")))
+	assert.Equal(t, token.ReplyOk, p.rename(c, token.NewString("a"), token.NewString("b")))
+	assert.Nil(t, c.Get("a"))
+	assert.Equal(t, "1", c.Get("b"))
+	ttl := p.ttl(c, token.NewString("b")).Data.(int64)
+	assert.True(t, ttl > 0 && ttl <= 100)
+	assert.Equal(t, token.NewInteger(1), p.renameNX(c, token.NewString("b"), token.NewString("c")))
+	assert.Equal(t, "1", c.Get("c"))
+	assert.Equal(t, token.NewError(errNoSuchKey.Error()), p.renameNX(c, token.NewString("b"), token.NewString("c")))
+}
+
+func TestProcessor_object(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	p.set(c, token.NewString("int"), token.NewString("12"))
+	p.set(c, token.NewString("str"), token.NewString("abc"))
+	p.set(c, token.NewString("raw"), token.NewString(strings.Repeat("a", embStrLimit+1)))
+	p.sAdd(c, token.NewString("set"), token.NewString("1"))
+	p.hSet(c, token.NewString("hash"), token.NewString("f"), token.NewString("v"))
+	encoding := func(key string) *token.Token {
+		return p.object(c, token.NewString("encoding"), token.NewString(key))
+	}
+	assert.Equal(t, token.NewBulked([]byte(encInt)), encoding("int"))
+	assert.Equal(t, token.NewBulked([]byte(encEmbStr)), encoding("str"))
+	assert.Equal(t, token.NewBulked([]byte(encRaw)), encoding("raw"))
+	assert.Equal(t, token.NewBulked([]byte(model.EncIntSet)), encoding("set"))
+	assert.Equal(t, token.NewBulked([]byte(encHashTable)), encoding("hash"))
+	assert.Equal(t, token.NewBulked(nil), encoding("none"))
+	assert.Equal(t, token.NewInteger(1), p.object(c, token.NewString(cds.RefCount), token.NewString("str")))
+	assert.Equal(t, token.NewInteger(0), p.object(c, token.NewString(cds.IdleTime), token.NewString("str")))
+	assert.Equal(t, token.NewInteger(5), p.object(c, token.NewString(cds.Freq), token.NewString("str")))
+	assert.Equal(t, token.NewError("unknown subcommand or wrong number of arguments for 'FOO'"),
+		p.object(c, token.NewString("foo"), token.NewString("str")))
+	assert.Equal(t, token.NewError("unknown subcommand or wrong number of arguments for 'FREQ'"),
+		p.object(c, token.NewString(cds.Freq)))
+
+	assert.Equal(t, token.NewInteger(2), p.touch(c, token.NewString("str"), token.NewString("none"),
+		token.NewString("int")))
+}
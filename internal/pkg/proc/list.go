@@ -0,0 +1,531 @@
+package proc
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// getList returns the list stored at key, nil if the key does not exist.
+// The list returned is allowed to be modified in place if mutable is true.
+func getList(cli *model.Client, key string, mutable bool) (*model.List, error) {
+	var v interface{}
+	if mutable {
+		v = cli.GetMutable(key)
+	} else {
+		v = cli.Get(key)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	l, ok := v.(*model.List)
+	if !ok {
+		return nil, errWrongType
+	}
+	return l, nil
+}
+
+// afterListChanged publishes the event of the command changing the list and
+// deletes the list if it becomes empty, since empty list is never stored.
+func afterListChanged(cli *model.Client, key string, l *model.List, event string) {
+	cli.Notify(model.NotifyList, event, key)
+	if l.Len() == 0 {
+		cli.Del(key)
+	}
+}
+
+// listEvent returns the event of pushing or popping at the head or tail
+func listEvent(front, push bool) string {
+	switch {
+	case front && push:
+		return model.EventLPush
+	case push:
+		return model.EventRPush
+	case front:
+		return model.EventLPop
+	}
+	return model.EventRPop
+}
+
+// push inserts values at the head or tail of the list, and returns the length
+// of the list. The list is created if not exist unless exist is true.
+func (p *Processor) push(cli *model.Client, tokens []*token.Token, front, exist bool) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	values := make([][]byte, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		v, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		values = append(values, v)
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		if exist {
+			return token.NewInteger(0)
+		}
+		l = model.NewList()
+		cli.Set(k, l, 0)
+		p.signalKey(cli, k)
+	}
+	for _, v := range values {
+		if front {
+			l.PushFront(v)
+		} else {
+			l.PushBack(v)
+		}
+	}
+	cli.Notify(model.NotifyList, listEvent(front, true), k)
+	return token.NewInteger(int64(l.Len()))
+}
+
+func (p *Processor) lPush(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.push(cli, tokens, true, false)
+}
+
+func (p *Processor) rPush(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.push(cli, tokens, false, false)
+}
+
+func (p *Processor) lPushX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.push(cli, tokens, true, true)
+}
+
+func (p *Processor) rPushX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.push(cli, tokens, false, true)
+}
+
+// pop removes and returns the head or tail of the list. An array is returned
+// if count is given, which is a nil array if the key doesn't exist.
+func (p *Processor) pop(cli *model.Client, tokens []*token.Token, front bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	var count int64 = 1
+	withCount := len(tokens) > 1
+	if withCount {
+		if err := checkType(tokens[1], "count", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		if count = tokens[1].Data.(int64); count < 0 {
+			return token.NewError(errNotPositive.Error())
+		}
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		if withCount {
+			return token.NewNilArray()
+		}
+		return token.NewBulked(nil)
+	}
+	popFn := l.PopBack
+	if front {
+		popFn = l.PopFront
+	}
+	if !withCount {
+		v := popFn()
+		afterListChanged(cli, k, l, listEvent(front, false))
+		return token.NewBulked(v)
+	}
+	ts := make([]*token.Token, 0)
+	for ; count > 0 && l.Len() > 0; count-- {
+		ts = append(ts, token.NewBulked(popFn()))
+	}
+	if len(ts) > 0 {
+		afterListChanged(cli, k, l, listEvent(front, false))
+	}
+	return token.NewArray(ts...)
+}
+
+func (p *Processor) lPop(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.pop(cli, tokens, true)
+}
+
+func (p *Processor) rPop(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.pop(cli, tokens, false)
+}
+
+func (p *Processor) lLen(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	l, err := getList(cli, key.Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(int64(l.Len()))
+}
+
+// lRange returns entries from start to stop inclusively,
+// negative index counts from the tail.
+func (p *Processor) lRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	for _, t := range tokens[1:3] {
+		if err := checkType(t, "index", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	l, err := getList(cli, key.Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0)
+	if l == nil {
+		return token.NewArray(ts...)
+	}
+	start, stop, ok := normRange(tokens[1].Data.(int64), tokens[2].Data.(int64), int64(l.Len()))
+	if !ok {
+		return token.NewArray(ts...)
+	}
+	for _, v := range l.Range(int(start), int(stop)) {
+		ts = append(ts, token.NewBulked(v))
+	}
+	return token.NewArray(ts...)
+}
+
+func (p *Processor) lIndex(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "index", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	l, err := getList(cli, key.Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.NewBulked(nil)
+	}
+	v, ok := l.Index(int(tokens[1].Data.(int64)))
+	if !ok {
+		return token.NewBulked(nil)
+	}
+	return token.NewBulked(v)
+}
+
+func (p *Processor) lSet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "index", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	v, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.NewError("no such key")
+	}
+	if !l.Set(int(tokens[1].Data.(int64)), v) {
+		return token.NewError("index out of range")
+	}
+	cli.Notify(model.NotifyList, model.EventLSet, k)
+	return token.ReplyOk
+}
+
+// lTrim keeps entries from start to stop inclusively,
+// negative index counts from the tail.
+func (p *Processor) lTrim(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	for _, t := range tokens[1:3] {
+		if err := checkType(t, "index", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.ReplyOk
+	}
+	start, stop, ok := normRange(tokens[1].Data.(int64), tokens[2].Data.(int64), int64(l.Len()))
+	if ok {
+		l.Trim(int(start), int(stop))
+	} else {
+		l.Trim(0, -1)
+	}
+	afterListChanged(cli, k, l, model.EventLTrim)
+	return token.ReplyOk
+}
+
+// lRem removes entries equal to value, count > 0 removes from head to tail,
+// count < 0 removes from tail to head, count = 0 removes all.
+func (p *Processor) lRem(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "count", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	v, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.NewInteger(0)
+	}
+	n := l.Remove(v, int(tokens[1].Data.(int64)))
+	if n > 0 {
+		afterListChanged(cli, k, l, model.EventLRem)
+	}
+	return token.NewInteger(int64(n))
+}
+
+// lInsert inserts value before or after pivot, returns the length of list,
+// -1 if pivot is not found.
+func (p *Processor) lInsert(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key := tokens[0]
+	if err := checkKeyType(key); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "argument", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	var before bool
+	switch strings.ToUpper(tokens[1].Data.(string)) {
+	case cds.Before:
+		before = true
+	case cds.After:
+	default:
+		return token.NewError("argument not recognized")
+	}
+	pivot, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	v, err := tokenToBytes(tokens[3])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	k := key.Data.(string)
+	l, err := getList(cli, k, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if l == nil {
+		return token.NewInteger(0)
+	}
+	if !l.Insert(pivot, v, before) {
+		return token.NewInteger(-1)
+	}
+	cli.Notify(model.NotifyList, model.EventLInsert, k)
+	return token.NewInteger(int64(l.Len()))
+}
+
+// parseListSide returns whether the side of LEFT|RIGHT is the head
+func parseListSide(t *token.Token) (bool, error) {
+	switch side, _ := optionOf(t); side {
+	case cds.Left:
+		return true, nil
+	case cds.Right:
+		return false, nil
+	}
+	return false, errSyntax
+}
+
+// popFirst pops the head or tail of the first non-empty list of keys, and
+// returns the array of the key and the value, nil if all lists are empty.
+// Keys of other types are skipped unless strict, which returns the error.
+func (p *Processor) popFirst(cli *model.Client, keys []string, front, strict bool) *token.Token {
+	for _, key := range keys {
+		l, err := getList(cli, key, true)
+		if err != nil && strict {
+			return token.NewError(err.Error())
+		}
+		if l == nil {
+			continue
+		}
+		popFn, cmd := l.PopBack, cds.RPop
+		if front {
+			popFn, cmd = l.PopFront, cds.LPop
+		}
+		v := popFn()
+		afterListChanged(cli, key, l, listEvent(front, false))
+		p.propagateAs(token.NewArray(token.NewString(cmd), token.NewString(key)))
+		return token.NewArray(token.NewBulked([]byte(key)), token.NewBulked(v))
+	}
+	return nil
+}
+
+// blockingPop pops from the first non-empty list of "key [key ...] timeout",
+// the client is blocked until any list is pushed if all lists are empty.
+func (p *Processor) blockingPop(cli *model.Client, tokens []*token.Token, front bool) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeysType(tokens[:len(tokens)-1]); err != nil {
+		return token.NewError(err.Error())
+	}
+	timeout, err := parseTimeout(tokens[len(tokens)-1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	keys := make([]string, 0, len(tokens)-1)
+	for _, t := range tokens[:len(tokens)-1] {
+		keys = append(keys, t.Data.(string))
+	}
+	if reply := p.popFirst(cli, keys, front, true); reply != nil {
+		return reply
+	}
+	p.propagateAs()
+	return p.block(cli, keys, timeout, token.NewNilArray(), func() *token.Token {
+		return p.popFirst(cli, keys, front, false)
+	})
+}
+
+func (p *Processor) bLPop(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.blockingPop(cli, tokens, true)
+}
+
+func (p *Processor) bRPop(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.blockingPop(cli, tokens, false)
+}
+
+// listMove pops the value from the side of the source list and pushes it to
+// the side of the destination list, nil is returned if the source is empty.
+func (p *Processor) listMove(cli *model.Client, src, dst string, srcFront, dstFront bool) (*token.Token, error) {
+	l, err := getList(cli, src, true)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	dl, err := getList(cli, dst, true)
+	if err != nil {
+		return nil, err
+	}
+	popFn := l.PopBack
+	if srcFront {
+		popFn = l.PopFront
+	}
+	v := popFn()
+	if dl == nil {
+		dl = model.NewList()
+		cli.Set(dst, dl, 0)
+		p.signalKey(cli, dst)
+	}
+	if dstFront {
+		dl.PushFront(v)
+	} else {
+		dl.PushBack(v)
+	}
+	afterListChanged(cli, src, l, listEvent(srcFront, false))
+	cli.Notify(model.NotifyList, listEvent(dstFront, true), dst)
+	return token.NewBulked(v), nil
+}
+
+// parseMove parses "source destination LEFT|RIGHT LEFT|RIGHT"
+func parseMove(tokens []*token.Token) (src, dst string, srcFront, dstFront bool, err error) {
+	if len(tokens) < 4 {
+		err = errors.New(eStrArgMore)
+		return
+	}
+	if err = checkKeysType(tokens[:2]); err != nil {
+		return
+	}
+	if srcFront, err = parseListSide(tokens[2]); err != nil {
+		return
+	}
+	if dstFront, err = parseListSide(tokens[3]); err != nil {
+		return
+	}
+	return tokens[0].Data.(string), tokens[1].Data.(string), srcFront, dstFront, nil
+}
+
+// lMove moves the value from the source to the destination by "source
+// destination LEFT|RIGHT LEFT|RIGHT", nil is returned if the source is empty.
+func (p *Processor) lMove(cli *model.Client, tokens ...*token.Token) *token.Token {
+	src, dst, srcFront, dstFront, err := parseMove(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	reply, err := p.listMove(cli, src, dst, srcFront, dstFront)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if reply == nil {
+		p.propagateAs()
+		return token.NewBulked(nil)
+	}
+	return reply
+}
+
+// bLMove is the blocking lmove by "source destination LEFT|RIGHT LEFT|RIGHT
+// timeout", the client is blocked until the source is pushed if it is empty.
+// The destination of other types keeps the client blocked.
+func (p *Processor) bLMove(cli *model.Client, tokens ...*token.Token) *token.Token {
+	src, dst, srcFront, dstFront, err := parseMove(tokens[:4])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	timeout, err := parseTimeout(tokens[4])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	rewrite := token.NewArray(append([]*token.Token{token.NewString(cds.LMove)}, tokens[:4]...)...)
+	retry := func() *token.Token {
+		reply, err := p.listMove(cli, src, dst, srcFront, dstFront)
+		if err != nil || reply == nil {
+			return nil
+		}
+		p.propagateAs(rewrite)
+		return reply
+	}
+	reply, err := p.listMove(cli, src, dst, srcFront, dstFront)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if reply != nil {
+		p.propagateAs(rewrite)
+		return reply
+	}
+	p.propagateAs()
+	return p.block(cli, []string{src}, timeout, token.NewBulked(nil), retry)
+}
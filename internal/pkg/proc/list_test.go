@@ -0,0 +1,158 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkedArray(values ...string) *token.Token {
+	ts := make([]*token.Token, 0, len(values))
+	for _, v := range values {
+		ts = append(ts, token.NewBulked([]byte(v)))
+	}
+	return token.NewArray(ts...)
+}
+
+func bulkedSet(values ...string) *token.Token {
+	return token.NewSet(bulkedArray(values...).Data.([]*token.Token)...)
+}
+
+func bulkedMap(values ...string) *token.Token {
+	return token.NewMap(bulkedArray(values...).Data.([]*token.Token)...)
+}
+
+func TestProcessor_push(t *testing.T) {
+	key := token.NewString("t_push")
+	assert.Equal(t, token.NewError(eStrArgMore), proc.lPush(cli, key))
+	assert.Equal(t, token.NewInteger(0), proc.lPushX(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(2), proc.lPush(cli, key, token.NewString("b"), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(4), proc.rPush(cli, key, token.NewBulked([]byte("c")), token.NewInteger(4)))
+	assert.Equal(t, token.NewInteger(5), proc.rPushX(cli, key, token.NewString("e")))
+	assert.Equal(t, bulkedArray("a", "b", "c", "4", "e"),
+		proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(5), proc.lLen(cli, key))
+
+	proc.set(cli, token.NewString("t_push_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.lPush(cli, token.NewString("t_push_str"), token.NewString("a")))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.lLen(cli, token.NewString("t_push_str")))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.get(cli, key))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.incr(cli, key))
+}
+
+func TestProcessor_pop(t *testing.T) {
+	key := token.NewString("t_pop")
+	assert.Equal(t, token.NewBulked(nil), proc.lPop(cli, key))
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("b"), token.NewString("c"), token.NewString("d"))
+	assert.Equal(t, token.NewBulked([]byte("a")), proc.lPop(cli, key))
+	assert.Equal(t, token.NewBulked([]byte("d")), proc.rPop(cli, key))
+	assert.Equal(t, token.NewError("value is out of range, must be positive"),
+		proc.rPop(cli, key, token.NewInteger(-1)))
+	assert.Equal(t, bulkedArray(), proc.rPop(cli, key, token.NewInteger(0)))
+	assert.Equal(t, bulkedArray("c", "b"), proc.rPop(cli, key, token.NewInteger(3)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+	assert.Equal(t, token.NewNilArray(), proc.lPop(cli, key, token.NewInteger(1)))
+	assert.Equal(t, token.NewNilArray(), proc.rPop(cli, key, token.NewInteger(0)))
+}
+
+func TestProcessor_lRange(t *testing.T) {
+	key := token.NewString("t_lrange")
+	assert.Equal(t, bulkedArray(), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("b"), token.NewString("c"))
+	assert.Equal(t, bulkedArray("b", "c"), proc.lRange(cli, key, token.NewInteger(-2), token.NewInteger(10)))
+	assert.Equal(t, bulkedArray(), proc.lRange(cli, key, token.NewInteger(2), token.NewInteger(1)))
+	assert.Equal(t, token.NewBulked([]byte("c")), proc.lIndex(cli, key, token.NewInteger(-1)))
+	assert.Equal(t, token.NewBulked(nil), proc.lIndex(cli, key, token.NewInteger(3)))
+}
+
+func TestProcessor_lSet(t *testing.T) {
+	key := token.NewString("t_lset")
+	assert.Equal(t, token.NewError("no such key"), proc.lSet(cli, key, token.NewInteger(0), token.NewString("a")))
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("b"))
+	assert.Equal(t, token.NewError("index out of range"), proc.lSet(cli, key, token.NewInteger(2), token.NewString("c")))
+	assert.Equal(t, token.ReplyOk, proc.lSet(cli, key, token.NewInteger(-1), token.NewString("c")))
+	assert.Equal(t, bulkedArray("a", "c"), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+}
+
+func TestProcessor_lTrim(t *testing.T) {
+	key := token.NewString("t_ltrim")
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("b"), token.NewString("c"))
+	assert.Equal(t, token.ReplyOk, proc.lTrim(cli, key, token.NewInteger(1), token.NewInteger(-1)))
+	assert.Equal(t, bulkedArray("b", "c"), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.ReplyOk, proc.lTrim(cli, key, token.NewInteger(5), token.NewInteger(10)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+}
+
+func TestProcessor_lRem(t *testing.T) {
+	key := token.NewString("t_lrem")
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("b"), token.NewString("a"),
+		token.NewString("c"), token.NewString("a"))
+	assert.Equal(t, token.NewInteger(1), proc.lRem(cli, key, token.NewInteger(-1), token.NewString("a")))
+	assert.Equal(t, bulkedArray("a", "b", "a", "c"), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(2), proc.lRem(cli, key, token.NewInteger(0), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(0), proc.lRem(cli, key, token.NewInteger(0), token.NewString("a")))
+	assert.Equal(t, bulkedArray("b", "c"), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+}
+
+func TestProcessor_lInsert(t *testing.T) {
+	key := token.NewString("t_linsert")
+	assert.Equal(t, token.NewInteger(0),
+		proc.lInsert(cli, key, token.NewString(cds.Before), token.NewString("a"), token.NewString("b")))
+	proc.rPush(cli, key, token.NewString("a"), token.NewString("c"))
+	assert.Equal(t, token.NewError("argument not recognized"),
+		proc.lInsert(cli, key, token.NewString("MIDDLE"), token.NewString("a"), token.NewString("b")))
+	assert.Equal(t, token.NewInteger(-1),
+		proc.lInsert(cli, key, token.NewString(cds.Before), token.NewString("x"), token.NewString("b")))
+	assert.Equal(t, token.NewInteger(3),
+		proc.lInsert(cli, key, token.NewString(cds.After), token.NewString("a"), token.NewString("b")))
+	assert.Equal(t, token.NewInteger(4),
+		proc.lInsert(cli, key, token.NewString("before"), token.NewString("a"), token.NewString("0")))
+	assert.Equal(t, bulkedArray("0", "a", "b", "c"), proc.lRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+}
+
+func TestProcessor_lMove(t *testing.T) {
+	src, dst := token.NewString("t_lmove_src"), token.NewString("t_lmove_dst")
+	left, right := token.NewString(cds.Left), token.NewString(cds.Right)
+	assert.Equal(t, token.NewBulked(nil), proc.lMove(cli, src, dst, left, right))
+	proc.rPush(cli, src, token.NewString("a"), token.NewString("b"), token.NewString("c"))
+	assert.Equal(t, token.NewBulked([]byte("a")), proc.lMove(cli, src, dst, left, right))
+	assert.Equal(t, token.NewBulked([]byte("c")), proc.lMove(cli, src, dst, right, left))
+	assert.Equal(t, bulkedArray("c", "a"), proc.lRange(cli, dst, token.NewInteger(0), token.NewInteger(-1)))
+	// the list is rotated if the source is the destination
+	assert.Equal(t, token.NewBulked([]byte("c")), proc.lMove(cli, dst, dst, left, right))
+	assert.Equal(t, bulkedArray("a", "c"), proc.lRange(cli, dst, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.NewBulked([]byte("b")), proc.lMove(cli, src, dst, left, left))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, src))
+
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.lMove(cli, src, dst, left, token.NewString("UP")))
+	proc.set(cli, token.NewString("t_lmove_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.lMove(cli, dst, token.NewString("t_lmove_str"), left, right))
+	assert.Equal(t, token.NewInteger(3), proc.lLen(cli, dst))
+	// the client without connection is never blocked
+	assert.Equal(t, token.NewNilArray(), proc.bLPop(cli, src, token.NewInteger(0)))
+	assert.Equal(t, bulkedArray("t_lmove_dst", "b"), proc.bLPop(cli, src, dst, token.NewString("0.1")))
+	assert.Equal(t, token.NewError(errTimeoutNegative.Error()), proc.bRPop(cli, src, token.NewInteger(-1)))
+	assert.Equal(t, token.NewError(errTimeoutFloat.Error()), proc.bRPop(cli, src, token.NewString("x")))
+}
+
+func TestProcessor_GenBin_list(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewMockClient()
+	p.rPush(c, token.NewString("list"), token.NewString("a"), token.NewString("b"))
+	p.expire(c, token.NewString("list"), token.NewInteger(100))
+	expire, _ := c.GetExpire("list")
+	_ = p.data[0].Freeze()
+	ch := make(chan []byte)
+	go p.GenBin(0, ch)
+	var dump []byte
+	for d := range ch {
+		dump = append(dump, d...)
+	}
+	_ = p.data[0].ToMove()
+	push, _ := token.NewArray(token.NewString(cds.RPush), token.NewString("list"),
+		token.NewBulked([]byte("a")), token.NewBulked([]byte("b"))).Serialize()
+	expireAt, _ := expireAtToken("list", expire).Serialize()
+	assert.Equal(t, string(push)+string(expireAt), string(dump))
+}
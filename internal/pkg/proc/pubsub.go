@@ -0,0 +1,171 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+const (
+	eStrSubscriberMode   = "Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context"
+	eStrSubscribeInMulti = "subscription inside multi is not allowed"
+)
+
+// isSubscription reports whether the command subscribes or unsubscribes, whose
+// replies are pushed to the client directly.
+func isSubscription(cmd string) bool {
+	switch cmd {
+	case cds.PSubscribe, cds.PUnsub, cds.Subscribe, cds.Unsubscribe:
+		return true
+	}
+	return false
+}
+
+// allowedInSubscriberMode reports whether the command is allowed to be executed
+// by the client subscribing channels or patterns.
+func allowedInSubscriberMode(cmd string) bool {
+	return cmd == cds.Ping || isSubscription(cmd)
+}
+
+// subReply returns the reply of subscription commands
+func subReply(kind string, name *token.Token, count int) *token.Token {
+	return token.NewPush(token.NewBulked([]byte(kind)), name, token.NewInteger(int64(count)))
+}
+
+// pushSubReplies pushes the replies to the client directly since one reply
+// is required for each channel, nil is returned as the reply of command.
+func pushSubReplies(cli *model.Client, replies []*token.Token) *token.Token {
+	cli.Push(replies...)
+	return nil
+}
+
+// subscribeGeneric subscribes channels, or patterns if pattern is true. The
+// subscriptions are never executed by EXEC since they are rejected by execCmd
+// inside MULTI.
+func (p *Processor) subscribeGeneric(cli *model.Client, tokens []*token.Token, pattern bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	if cli.Out == nil {
+		return token.NewError("subscription is not supported by the client")
+	}
+	names, err := tokensToMembers(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	replies := make([]*token.Token, 0, len(names))
+	for _, name := range names {
+		kind := model.MsgSubscribe
+		if pattern {
+			kind = model.MsgPSubscribe
+			p.pubsub.PSubscribe(cli, name)
+		} else {
+			p.pubsub.Subscribe(cli, name)
+		}
+		replies = append(replies, subReply(kind, token.NewBulked([]byte(name)), cli.Sub.Count()))
+	}
+	return pushSubReplies(cli, replies)
+}
+
+// unsubscribeGeneric unsubscribes the channels, or patterns if pattern is
+// true, all of them are unsubscribed if none is given.
+func (p *Processor) unsubscribeGeneric(cli *model.Client, tokens []*token.Token, pattern bool) *token.Token {
+	if cli.Out == nil {
+		return token.NewError("subscription is not supported by the client")
+	}
+	names, err := tokensToMembers(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	kind, subscribed := model.MsgUnsubscribe, cli.Sub.Channels
+	if pattern {
+		kind, subscribed = model.MsgPUnsubscribe, cli.Sub.Patterns
+	}
+	if len(names) == 0 {
+		for name := range subscribed {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return pushSubReplies(cli, []*token.Token{subReply(kind, token.NewBulked(nil), cli.Sub.Count())})
+	}
+	replies := make([]*token.Token, 0, len(names))
+	for _, name := range names {
+		if pattern {
+			p.pubsub.PUnsubscribe(cli, name)
+		} else {
+			p.pubsub.Unsubscribe(cli, name)
+		}
+		replies = append(replies, subReply(kind, token.NewBulked([]byte(name)), cli.Sub.Count()))
+	}
+	return pushSubReplies(cli, replies)
+}
+
+func (p *Processor) subscribe(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.subscribeGeneric(cli, tokens, false)
+}
+
+func (p *Processor) pSubscribe(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.subscribeGeneric(cli, tokens, true)
+}
+
+func (p *Processor) unsubscribe(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.unsubscribeGeneric(cli, tokens, false)
+}
+
+func (p *Processor) pUnsubscribe(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.unsubscribeGeneric(cli, tokens, true)
+}
+
+// publish returns the number of clients received the message
+func (p *Processor) publish(_ *model.Client, tokens ...*token.Token) *token.Token {
+	channel, err := tokenToBytes(tokens[0])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	msg, err := tokenToBytes(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewInteger(int64(p.pubsub.Publish(string(channel), msg)))
+}
+
+// pubSub introspects the pub/sub state, "CHANNELS [pattern]", "NUMSUB
+// [channel ...]" or "NUMPAT".
+func (p *Processor) pubSub(_ *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[0], "subcommand", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	args, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	sub := strings.ToUpper(tokens[0].Data.(string))
+	switch sub {
+	case cds.Channels:
+		if len(args) > 1 {
+			break
+		}
+		var pattern string
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		return membersToArray(p.pubsub.Channels(pattern))
+	case cds.NumSub:
+		ts := make([]*token.Token, 0, len(args)*2)
+		for _, channel := range args {
+			ts = append(ts, token.NewBulked([]byte(channel)), token.NewInteger(int64(p.pubsub.NumSub(channel))))
+		}
+		return token.NewArray(ts...)
+	case cds.NumPat:
+		if len(args) > 0 {
+			break
+		}
+		return token.NewInteger(int64(p.pubsub.NumPat()))
+	}
+	return token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", sub))
+}
@@ -0,0 +1,107 @@
+package proc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func doCmd(c *model.Client, ts ...*token.Token) *token.Token {
+	rsp := make(chan *token.Token, 1)
+	proc.Do(&model.CmdTask{Cli: c, Req: token.NewArray(ts...), Rsp: rsp})
+	return <-rsp
+}
+
+func msgArray(values ...interface{}) *token.Token {
+	ts := make([]*token.Token, 0, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			ts = append(ts, token.NewBulked([]byte(v)))
+		case int:
+			ts = append(ts, token.NewInteger(int64(v)))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+func TestProcessor_subscribe(t *testing.T) {
+	sub, pub := proc.NewClient(nil), proc.NewClient(nil)
+	assert.Nil(t, doCmd(sub, append([]*token.Token{token.NewString(cds.Subscribe)}, stringTokens("t_ch1", "t_ch2")...)...))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgSubscribe, "t_ch1", 1), msgArray(model.MsgSubscribe, "t_ch2", 2)},
+		sub.Out.Pop())
+	assert.Nil(t, doCmd(sub, token.NewString(cds.PSubscribe), token.NewString("t_ch*")))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgPSubscribe, "t_ch*", 3)}, sub.Out.Pop())
+
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte(strPong)), token.NewBulked([]byte{})),
+		doCmd(sub, token.NewString(cds.Ping)))
+	sub.Out.Pop()
+	assert.Equal(t, token.NewError("ERR "+eStrSubscriberMode, cds.Get), doCmd(sub, token.NewString(cds.Get), token.NewString("a")))
+	sub.Out.Pop()
+
+	assert.Equal(t, token.NewInteger(2), doCmd(pub, token.NewString(cds.Publish), token.NewString("t_ch1"), token.NewString("hi")))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgMessage, "t_ch1", "hi"), msgArray(model.MsgPMessage, "t_ch*", "t_ch1", "hi")},
+		sub.Out.Pop())
+	assert.Equal(t, token.NewInteger(1), doCmd(pub, token.NewString(cds.Publish), token.NewString("t_ch3"), token.NewInteger(1)))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgPMessage, "t_ch*", "t_ch3", "1")}, sub.Out.Pop())
+	assert.Equal(t, token.NewInteger(0), doCmd(pub, token.NewString(cds.Publish), token.NewString("t_x"), token.NewString("hi")))
+
+	assert.Equal(t, bulkedArray("t_ch1", "t_ch2"), proc.pubSub(pub, token.NewString(cds.Channels)))
+	assert.Equal(t, bulkedArray("t_ch2"), proc.pubSub(pub, token.NewString("channels"), token.NewString("*2")))
+	assert.Equal(t, msgArray("t_ch1", 1, "t_x", 0),
+		proc.pubSub(pub, token.NewString(cds.NumSub), token.NewString("t_ch1"), token.NewString("t_x")))
+	assert.Equal(t, token.NewInteger(1), proc.pubSub(pub, token.NewString(cds.NumPat)))
+	assert.Equal(t, token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", "FOO")),
+		proc.pubSub(pub, token.NewString("foo")))
+
+	assert.Nil(t, doCmd(sub, token.NewString(cds.Unsubscribe), token.NewString("t_ch1")))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgUnsubscribe, "t_ch1", 2)}, sub.Out.Pop())
+	assert.Nil(t, doCmd(sub, token.NewString(cds.PUnsub)))
+	assert.Equal(t, []*token.Token{msgArray(model.MsgPUnsubscribe, "t_ch*", 1)}, sub.Out.Pop())
+	assert.Nil(t, doCmd(sub, token.NewString(cds.PUnsub)))
+	assert.Equal(t, []*token.Token{token.NewArray(token.NewBulked([]byte(model.MsgPUnsubscribe)),
+		token.NewBulked(nil), token.NewInteger(1))}, sub.Out.Pop())
+
+	rsp := make(chan struct{}, 1)
+	proc.Do(&model.CloseTask{Cli: sub, Rsp: rsp})
+	<-rsp
+	assert.Equal(t, 0, sub.Sub.Count())
+	assert.Equal(t, token.NewInteger(0), proc.pubSub(pub, token.NewString(cds.NumSub), token.NewString("t_ch2")).Data.([]*token.Token)[1])
+	assert.Equal(t, token.NewError("subscription is not supported by the client"), proc.subscribe(cli, token.NewString("t_ch1")))
+}
+
+func TestProcessor_subscribe_multi(t *testing.T) {
+	sub := proc.NewClient(nil)
+	assert.Equal(t, token.ReplyOk, doCmd(sub, token.NewString(cds.Multi)))
+	assert.Equal(t, token.ReplyQueued, doCmd(sub, token.NewString(cds.Ping)))
+	for _, name := range []string{cds.Subscribe, cds.PSubscribe, cds.Unsubscribe, cds.PUnsub} {
+		assert.Equal(t, token.NewError("ERR "+eStrSubscribeInMulti), doCmd(sub, token.NewString(name), token.NewString("t_x")))
+	}
+	assert.Equal(t, token.NewError(errExecAbort.Error()), doCmd(sub, token.NewString(cds.Exec)))
+	assert.Equal(t, 0, sub.Sub.Count())
+	// nothing is pushed besides the replies of commands
+	assert.Len(t, sub.Out.Pop(), 7)
+	assert.Equal(t, token.NewString(strPong), doCmd(sub, token.NewString(cds.Ping)))
+}
+
+func TestProcessor_subscribe_resp3(t *testing.T) {
+	sub := proc.NewClient(nil)
+	reply := doCmd(sub, token.NewString(cds.Hello), token.NewInteger(token.Resp3))
+	assert.Equal(t, label.Map, sub.Out.Pop()[0].Label)
+	assert.Equal(t, label.Map, reply.Label)
+	assert.Nil(t, doCmd(sub, token.NewString(cds.Subscribe), token.NewString("t_ch_resp3")))
+	assert.Equal(t, []*token.Token{token.NewPush(msgArray(model.MsgSubscribe, "t_ch_resp3", 1).Data.([]*token.Token)...)},
+		sub.Out.Pop())
+	// normal commands share the connection with messages pushed
+	assert.Equal(t, token.NewBulked(nil), doCmd(sub, token.NewString(cds.Get), token.NewString("t_ch_resp3")))
+	assert.Equal(t, []*token.Token{token.NewNull()}, sub.Out.Pop())
+	doCmd(proc.NewClient(nil), token.NewString(cds.Publish), token.NewString("t_ch_resp3"), token.NewString("hi"))
+	assert.Equal(t, []*token.Token{token.NewPush(msgArray(model.MsgMessage, "t_ch_resp3", "hi").Data.([]*token.Token)...)},
+		sub.Out.Pop())
+	doCmd(sub, token.NewString(cds.Unsubscribe))
+}
@@ -0,0 +1,83 @@
+package proc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/glob"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+const scanDefaultCount = 10
+
+// scanOption stores the arguments of scan family commands
+type scanOption struct {
+	cursor int64
+	match  string
+	count  int64
+	// type of values, only supported by scan on keys
+	typ string
+}
+
+// parseScan parses arguments "cursor [MATCH pattern] [COUNT count]", and
+// "[TYPE type]" if withType is true.
+func parseScan(tokens []*token.Token, withType bool) (*scanOption, error) {
+	if err := checkType(tokens[0], "cursor", label.Integer); err != nil {
+		return nil, err
+	}
+	opt := &scanOption{cursor: tokens[0].Data.(int64), count: scanDefaultCount}
+	if opt.cursor < 0 {
+		return nil, errInvalidCursor
+	}
+	for i := 1; i < len(tokens); i++ {
+		if err := checkType(tokens[i], "argument", label.String); err != nil {
+			return nil, err
+		}
+		arg := strings.ToUpper(tokens[i].Data.(string))
+		i++
+		if len(tokens) < i+1 {
+			return nil, errArgMissing(arg)
+		}
+		switch arg {
+		case cds.Match:
+			pattern, err := tokenToBytes(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			opt.match = string(pattern)
+		case cds.Count:
+			if err := checkType(tokens[i], "count", label.Integer); err != nil {
+				return nil, err
+			}
+			if opt.count = tokens[i].Data.(int64); opt.count < 1 {
+				return nil, errSyntax
+			}
+		case cds.Type:
+			if !withType {
+				return nil, errArgNotRecognized
+			}
+			typ, err := tokenToBytes(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			if opt.typ = strings.ToLower(string(typ)); !isTypeName(opt.typ) {
+				return nil, errUnknownType
+			}
+		default:
+			return nil, errArgNotRecognized
+		}
+	}
+	return opt, nil
+}
+
+// matched reports whether the element matches the pattern of the option
+func (opt *scanOption) matched(s string) bool {
+	return opt.match == "" || glob.Match(opt.match, s)
+}
+
+// scanReply returns the reply of scan family commands
+func scanReply(next uint64, ts []*token.Token) *token.Token {
+	return token.NewArray(token.NewBulked([]byte(strconv.FormatUint(next, 10))), token.NewArray(ts...))
+}
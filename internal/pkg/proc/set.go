@@ -0,0 +1,508 @@
+package proc
+
+import (
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// operations of set algebra
+const (
+	setInter = iota
+	setUnion
+	setDiff
+)
+
+// setStoreEvents are the keyspace events of storing the results of set algebra
+var setStoreEvents = [...]string{
+	setInter: model.EventSInterStore, setUnion: model.EventSUnionStore, setDiff: model.EventSDiffStore,
+}
+
+// getSet returns the set stored at key, nil if the key does not exist.
+// The set returned is allowed to be modified in place if mutable is true.
+func getSet(cli *model.Client, key string, mutable bool) (*model.Set, error) {
+	var v interface{}
+	if mutable {
+		v = cli.GetMutable(key)
+	} else {
+		v = cli.Get(key)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(*model.Set)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// getOrNewSet returns the set stored at key for modification,
+// a new set is stored if the key does not exist.
+func getOrNewSet(cli *model.Client, key string) (*model.Set, error) {
+	s, err := getSet(cli, key, true)
+	if err != nil || s != nil {
+		return s, err
+	}
+	s = model.NewSet()
+	cli.Set(key, s, 0)
+	return s, nil
+}
+
+// afterSetChanged publishes the event of the command changing the set and
+// deletes the key if the set is empty
+func afterSetChanged(cli *model.Client, key string, s *model.Set, event string) {
+	cli.Notify(model.NotifySet, event, key)
+	if s.Len() == 0 {
+		cli.Del(key)
+	}
+}
+
+// tokensToMembers converts the value tokens to members
+func tokensToMembers(ts []*token.Token) ([]string, error) {
+	members := make([]string, 0, len(ts))
+	for _, t := range ts {
+		v, err := tokenToBytes(t)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, string(v))
+	}
+	return members, nil
+}
+
+// membersToArray converts the members to array of bulked strings
+func membersToArray(members []string) *token.Token {
+	ts := make([]*token.Token, 0, len(members))
+	for _, m := range members {
+		ts = append(ts, token.NewBulked([]byte(m)))
+	}
+	return token.NewArray(ts...)
+}
+
+// membersToSet converts the members to set of bulked strings
+func membersToSet(members []string) *token.Token {
+	return token.NewSet(membersToArray(members).Data.([]*token.Token)...)
+}
+
+func (p *Processor) sAdd(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	s, err := getOrNewSet(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	for _, m := range members {
+		if s.Add(m) {
+			n++
+		}
+	}
+	if n > 0 {
+		cli.Notify(model.NotifySet, model.EventSAdd, key)
+	}
+	return token.NewInteger(n)
+}
+
+func (p *Processor) sRem(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	s, err := getSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if s != nil {
+		for _, m := range members {
+			if s.Remove(m) {
+				n++
+			}
+		}
+	}
+	if n > 0 {
+		afterSetChanged(cli, key, s, model.EventSRem)
+	}
+	return token.NewInteger(n)
+}
+
+func (p *Processor) sCard(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(int64(s.Len()))
+}
+
+func (p *Processor) sIsMember(cli *model.Client, tokens ...*token.Token) *token.Token {
+	ret := p.sMIsMember(cli, tokens[:2]...)
+	if ret.Label == label.Error {
+		return ret
+	}
+	return ret.Data.([]*token.Token)[0]
+}
+
+func (p *Processor) sMIsMember(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(members))
+	for _, m := range members {
+		if s != nil && s.Has(m) {
+			ts = append(ts, token.NewInteger(1))
+		} else {
+			ts = append(ts, token.NewInteger(0))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+func (p *Processor) sMembers(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return membersToSet(nil)
+	}
+	return membersToSet(s.Members())
+}
+
+// parseSetCount parses the optional count of spop and srandmember
+func parseSetCount(tokens []*token.Token) (int64, bool, error) {
+	if len(tokens) < 2 {
+		return 1, false, nil
+	}
+	if err := checkType(tokens[1], "count", label.Integer); err != nil {
+		return 0, false, err
+	}
+	return tokens[1].Data.(int64), true, nil
+}
+
+// sPop removes random members, which is propagated as srem of the members
+// popped to make the replay deterministic.
+func (p *Processor) sPop(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	count, withCount, err := parseSetCount(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if count < 0 {
+		return token.NewError(errNotPositive.Error())
+	}
+	key := tokens[0].Data.(string)
+	s, err := getSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil || count == 0 {
+		p.propagateAs()
+		if withCount {
+			return membersToArray(nil)
+		}
+		return token.NewBulked(nil)
+	}
+	members := randElements(s, s.Members, count)
+	rem := []*token.Token{token.NewString(cds.SRem), tokens[0]}
+	for _, m := range members {
+		s.Remove(m)
+		rem = append(rem, token.NewBulked([]byte(m)))
+	}
+	afterSetChanged(cli, key, s, model.EventSPop)
+	p.propagateAs(token.NewArray(rem...))
+	if !withCount {
+		return token.NewBulked([]byte(members[0]))
+	}
+	return membersToArray(members)
+}
+
+// sRandMember returns random members. A positive count returns distinct
+// members, a negative count allows the same member returned multiple times.
+func (p *Processor) sRandMember(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	count, withCount, err := parseSetCount(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if err = checkRandCount(count); err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil || count == 0 {
+		if withCount {
+			return membersToArray(nil)
+		}
+		return token.NewBulked(nil)
+	}
+	members := randElements(s, s.Members, count)
+	if !withCount {
+		return token.NewBulked([]byte(members[0]))
+	}
+	return membersToArray(members)
+}
+
+func (p *Processor) sMove(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	member, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	src, dst := tokens[0].Data.(string), tokens[1].Data.(string)
+	srcSet, err := getSet(cli, src, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if _, err = getSet(cli, dst, false); err != nil {
+		return token.NewError(err.Error())
+	}
+	if srcSet == nil || !srcSet.Has(string(member)) {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	if src == dst {
+		p.propagateAs()
+		return token.NewInteger(1)
+	}
+	srcSet, _ = getSet(cli, src, true)
+	srcSet.Remove(string(member))
+	afterSetChanged(cli, src, srcSet, model.EventSRem)
+	dstSet, _ := getOrNewSet(cli, dst)
+	dstSet.Add(string(member))
+	cli.Notify(model.NotifySet, model.EventSAdd, dst)
+	return token.NewInteger(1)
+}
+
+// setAlgebra returns the members of the result of the operation on sets
+// stored at keys, missing keys are regarded as empty sets.
+func setAlgebra(cli *model.Client, keys []*token.Token, op int) ([]string, error) {
+	if err := checkKeysType(keys); err != nil {
+		return nil, err
+	}
+	sets := make([]*model.Set, 0, len(keys))
+	for _, k := range keys {
+		s, err := getSet(cli, k.Data.(string), false)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			s = model.NewSet()
+		}
+		sets = append(sets, s)
+	}
+	var members []string
+	switch op {
+	case setInter:
+		smallest := sets[0]
+		for _, s := range sets[1:] {
+			if s.Len() < smallest.Len() {
+				smallest = s
+			}
+		}
+		for _, m := range smallest.Members() {
+			found := true
+			for _, s := range sets {
+				if !s.Has(m) {
+					found = false
+					break
+				}
+			}
+			if found {
+				members = append(members, m)
+			}
+		}
+	case setUnion:
+		union := model.NewSet()
+		for _, s := range sets {
+			for _, m := range s.Members() {
+				if union.Add(m) {
+					members = append(members, m)
+				}
+			}
+		}
+	case setDiff:
+		for _, m := range sets[0].Members() {
+			found := false
+			for _, s := range sets[1:] {
+				if s.Has(m) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				members = append(members, m)
+			}
+		}
+	}
+	return members, nil
+}
+
+func (p *Processor) setAlgebraGeneric(cli *model.Client, tokens []*token.Token, op int) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	members, err := setAlgebra(cli, tokens, op)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return membersToSet(members)
+}
+
+// setAlgebraStore stores the result of the operation at the first key and
+// returns the number of members in it. The key is deleted if the result is empty.
+func (p *Processor) setAlgebraStore(cli *model.Client, tokens []*token.Token, op int) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := setAlgebra(cli, tokens[1:], op)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	dst := tokens[0].Data.(string)
+	if len(members) == 0 {
+		cli.Del(dst)
+		return token.NewInteger(0)
+	}
+	s := model.NewSet()
+	for _, m := range members {
+		s.Add(m)
+	}
+	cli.Set(dst, s, 0)
+	cli.Notify(model.NotifySet, setStoreEvents[op], dst)
+	return token.NewInteger(int64(len(members)))
+}
+
+func (p *Processor) sInter(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraGeneric(cli, tokens, setInter)
+}
+
+func (p *Processor) sUnion(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraGeneric(cli, tokens, setUnion)
+}
+
+func (p *Processor) sDiff(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraGeneric(cli, tokens, setDiff)
+}
+
+func (p *Processor) sInterStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraStore(cli, tokens, setInter)
+}
+
+func (p *Processor) sUnionStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraStore(cli, tokens, setUnion)
+}
+
+func (p *Processor) sDiffStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.setAlgebraStore(cli, tokens, setDiff)
+}
+
+// sInterCard returns the cardinality of the intersection, "numkeys key
+// [key ...] [LIMIT limit]". Limit 0 means unlimited.
+func (p *Processor) sInterCard(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkType(tokens[0], "numkeys", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	numKeys := tokens[0].Data.(int64)
+	if numKeys < 1 {
+		return token.NewError("numkeys should be greater than 0")
+	}
+	if numKeys > int64(len(tokens)-1) {
+		return token.NewError("number of keys can't be greater than number of args")
+	}
+	keys, args := tokens[1:numKeys+1], tokens[numKeys+1:]
+	var limit int64
+	for i := 0; i < len(args); i++ {
+		if err := checkType(args[i], "argument", label.String); err != nil {
+			return token.NewError(err.Error())
+		}
+		arg := strings.ToUpper(args[i].Data.(string))
+		if arg != cds.Limit {
+			return token.NewError(errSyntax.Error())
+		}
+		i++
+		if len(args) < i+1 {
+			return token.NewError(errArgMissing(arg).Error())
+		}
+		if err := checkType(args[i], "limit", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		if limit = args[i].Data.(int64); limit < 0 {
+			return token.NewError("LIMIT can't be negative")
+		}
+	}
+	members, err := setAlgebra(cli, keys, setInter)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	n := int64(len(members))
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	return token.NewInteger(n)
+}
+
+func (p *Processor) sScan(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseScan(tokens[1:], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return scanReply(0, []*token.Token{})
+	}
+	ts := make([]*token.Token, 0)
+	next := s.Scan(uint64(opt.cursor), int(opt.count), func(member string) {
+		if opt.matched(member) {
+			ts = append(ts, token.NewBulked([]byte(member)))
+		}
+	})
+	return scanReply(next, ts)
+}
@@ -0,0 +1,173 @@
+package proc
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func stringTokens(values ...string) []*token.Token {
+	ts := make([]*token.Token, 0, len(values))
+	for _, v := range values {
+		ts = append(ts, token.NewString(v))
+	}
+	return ts
+}
+
+func TestProcessor_sAdd(t *testing.T) {
+	key := token.NewString("t_sadd")
+	assert.Equal(t, token.NewInteger(3), proc.sAdd(cli, append([]*token.Token{key}, stringTokens("3", "1", "2", "1")...)...))
+	assert.Equal(t, token.NewInteger(1), proc.sAdd(cli, key, token.NewInteger(4), token.NewBulked([]byte("1"))))
+	assert.Equal(t, bulkedSet("1", "2", "3", "4"), proc.sMembers(cli, key))
+	assert.Equal(t, model.EncIntSet, cli.Get("t_sadd").(*model.Set).Encoding())
+	assert.Equal(t, token.NewInteger(1), proc.sAdd(cli, key, token.NewString("a")))
+	assert.Equal(t, model.EncHashSet, cli.Get("t_sadd").(*model.Set).Encoding())
+	assert.Equal(t, token.NewInteger(5), proc.sCard(cli, key))
+	assert.Equal(t, token.NewInteger(1), proc.sIsMember(cli, key, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(0), proc.sIsMember(cli, key, token.NewString("b")))
+	assert.Equal(t, token.NewArray(token.NewInteger(1), token.NewInteger(0)),
+		proc.sMIsMember(cli, key, token.NewInteger(1), token.NewString("b")))
+
+	assert.Equal(t, token.NewInteger(2), proc.sRem(cli, key, token.NewString("a"), token.NewString("1"), token.NewString("b")))
+	assert.Equal(t, token.NewInteger(3), proc.sRem(cli, append([]*token.Token{key}, stringTokens("2", "3", "4")...)...))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+	assert.Equal(t, bulkedSet(), proc.sMembers(cli, key))
+
+	proc.set(cli, token.NewString("t_sadd_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.sAdd(cli, token.NewString("t_sadd_str"), token.NewString("a")))
+}
+
+func TestProcessor_sPop(t *testing.T) {
+	key := token.NewString("t_spop")
+	assert.Equal(t, token.NewBulked(nil), proc.sPop(cli, key))
+	assert.Equal(t, bulkedArray(), proc.sRandMember(cli, key, token.NewInteger(1)))
+	proc.sAdd(cli, append([]*token.Token{key}, stringTokens("a", "b", "c")...)...)
+	assert.Equal(t, bulkedArray("a", "b", "c"), sortedArray(proc.sRandMember(cli, key, token.NewInteger(5))))
+	assert.Equal(t, 5, len(proc.sRandMember(cli, key, token.NewInteger(-5)).Data.([]*token.Token)))
+	assert.Equal(t, token.NewError(errOutOfRange.Error()), proc.sRandMember(cli, key, token.NewInteger(math.MinInt64)))
+	assert.Equal(t, token.NewError(errOutOfRange.Error()), proc.sRandMember(cli, key, token.NewInteger(-maxRandCount-1)))
+	popped := proc.sPop(cli, key)
+	assert.Equal(t, token.NewInteger(0), proc.sIsMember(cli, key, popped))
+	assert.Equal(t, 2, len(proc.sPop(cli, key, token.NewInteger(5)).Data.([]*token.Token)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+
+	// members are picked one by one from large sets
+	for i := 0; i < 1000; i++ {
+		proc.sAdd(cli, key, token.NewString("m"+strconv.Itoa(i)))
+	}
+	members := proc.sRandMember(cli, key, token.NewInteger(100)).Data.([]*token.Token)
+	assert.Equal(t, 100, len(members))
+	distinct := make(map[string]bool)
+	for _, m := range members {
+		distinct[string(m.Data.([]byte))] = true
+		assert.Equal(t, token.NewInteger(1), proc.sIsMember(cli, key, m))
+	}
+	assert.Equal(t, 100, len(distinct))
+	assert.Equal(t, 100, len(proc.sPop(cli, key, token.NewInteger(100)).Data.([]*token.Token)))
+	assert.Equal(t, token.NewInteger(900), proc.sCard(cli, key))
+}
+
+func TestProcessor_sPop_propagate(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("t_spop_propagate")
+	p.execCmd(c, token.NewArray(append([]*token.Token{token.NewString(cds.SAdd), key}, stringTokens("a", "b")...)...))
+	ret := p.execCmd(c, token.NewArray(token.NewString(cds.SPop), key))
+	assert.Equal(t, 2, len(p.aof))
+	assert.Equal(t, token.NewArray(token.NewString(cds.SRem), key, token.NewBulked(ret.Data.([]byte))), p.aof[1].T)
+	p.execCmd(c, token.NewArray(token.NewString(cds.SPop), token.NewString("t_spop_none")))
+	assert.Equal(t, 2, len(p.aof))
+}
+
+func TestProcessor_sMove(t *testing.T) {
+	src, dst := token.NewString("t_smove_src"), token.NewString("t_smove_dst")
+	proc.sAdd(cli, src, token.NewString("a"), token.NewString("b"))
+	assert.Equal(t, token.NewInteger(0), proc.sMove(cli, src, dst, token.NewString("c")))
+	assert.Equal(t, token.NewInteger(1), proc.sMove(cli, src, src, token.NewString("a")))
+	assert.Equal(t, token.NewInteger(1), proc.sMove(cli, src, dst, token.NewString("a")))
+	assert.Equal(t, bulkedSet("b"), proc.sMembers(cli, src))
+	assert.Equal(t, bulkedSet("a"), proc.sMembers(cli, dst))
+	assert.Equal(t, token.NewInteger(1), proc.sMove(cli, src, dst, token.NewString("b")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, src))
+
+	proc.set(cli, token.NewString("t_smove_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.sMove(cli, dst, token.NewString("t_smove_str"), token.NewString("a")))
+}
+
+func TestProcessor_setAlgebra(t *testing.T) {
+	a, b, c := token.NewString("t_salg_a"), token.NewString("t_salg_b"), token.NewString("t_salg_c")
+	proc.sAdd(cli, append([]*token.Token{a}, stringTokens("1", "2", "3", "x")...)...)
+	proc.sAdd(cli, append([]*token.Token{b}, stringTokens("2", "3", "4")...)...)
+	proc.sAdd(cli, append([]*token.Token{c}, stringTokens("3", "5")...)...)
+	none := token.NewString("t_salg_none")
+
+	assert.Equal(t, bulkedSet("3"), proc.sInter(cli, a, b, c))
+	assert.Equal(t, bulkedSet(), proc.sInter(cli, a, none))
+	assert.Equal(t, bulkedSet("1", "2", "3", "4", "5", "x"), sortedArray(proc.sUnion(cli, a, b, c)))
+	assert.Equal(t, bulkedSet("1", "x"), sortedArray(proc.sDiff(cli, a, b, c)))
+	assert.Equal(t, bulkedSet("1", "2", "3", "x"), sortedArray(proc.sDiff(cli, a, none)))
+
+	dst := token.NewString("t_salg_dst")
+	proc.set(cli, dst, token.NewString("a"), token.NewString(cds.TimeoutSec), token.NewInteger(100))
+	assert.Equal(t, token.NewInteger(2), proc.sInterStore(cli, dst, a, b))
+	assert.Equal(t, bulkedSet("2", "3"), proc.sMembers(cli, dst))
+	assert.Equal(t, token.NewInteger(-1), proc.ttl(cli, dst))
+	assert.Equal(t, token.NewInteger(6), proc.sUnionStore(cli, dst, a, b, c))
+	assert.Equal(t, token.NewInteger(2), proc.sDiffStore(cli, dst, a, b))
+	assert.Equal(t, token.NewInteger(0), proc.sInterStore(cli, dst, a, none))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, dst))
+
+	assert.Equal(t, token.NewInteger(2), proc.sInterCard(cli, token.NewInteger(2), a, b))
+	assert.Equal(t, token.NewInteger(1), proc.sInterCard(cli, token.NewInteger(2), a, b,
+		token.NewString("limit"), token.NewInteger(1)))
+	assert.Equal(t, token.NewInteger(2), proc.sInterCard(cli, token.NewInteger(2), a, b,
+		token.NewString(cds.Limit), token.NewInteger(0)))
+	assert.Equal(t, token.NewError("number of keys can't be greater than number of args"),
+		proc.sInterCard(cli, token.NewInteger(3), a, b))
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.sInterCard(cli, token.NewInteger(1), a, b))
+
+	proc.set(cli, token.NewString("t_salg_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.sUnion(cli, a, token.NewString("t_salg_str")))
+}
+
+func TestProcessor_sScan(t *testing.T) {
+	key := token.NewString("t_sscan")
+	for i := 0; i < 30; i++ {
+		proc.sAdd(cli, key, token.NewInteger(int64(i)))
+	}
+	members := make(map[string]bool)
+	var cursor int64
+	for {
+		data := proc.sScan(cli, key, token.NewInteger(cursor), token.NewString(cds.Count), token.NewInteger(4)).Data.([]*token.Token)
+		for _, m := range data[1].Data.([]*token.Token) {
+			members[string(m.Data.([]byte))] = true
+		}
+		next, _ := ItfToInt(data[0].Data)
+		if cursor = next.(int64); cursor == 0 {
+			break
+		}
+	}
+	assert.Equal(t, 30, len(members))
+}
+
+func TestProcessor_GenBin_set(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewMockClient()
+	p.sAdd(c, token.NewString("set"), token.NewInteger(2), token.NewInteger(1))
+	ch := make(chan []byte)
+	_ = p.data[0].Freeze()
+	go p.GenBin(0, ch)
+	var dump []byte
+	for d := range ch {
+		dump = append(dump, d...)
+	}
+	_ = p.data[0].ToMove()
+	add, _ := token.NewArray(token.NewString(cds.SAdd), token.NewString("set"),
+		token.NewBulked([]byte("1")), token.NewBulked([]byte("2"))).Serialize()
+	assert.Equal(t, string(add), string(dump))
+}
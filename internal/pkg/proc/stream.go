@@ -0,0 +1,688 @@
+package proc
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// streamTrimLimit is the default max number of entries removed by the
+// approximate trimming, which is 100 times of the entries of a node.
+const streamTrimLimit = 10000
+
+var (
+	errStreamID          = errors.New("Invalid stream ID specified as stream command argument")
+	errXAddIDSmall       = errors.New("The ID specified in XADD is equal or smaller than the target stream top item")
+	errXAddIDZero        = errors.New("The ID specified in XADD must be greater than 0-0")
+	errStreamExhausted   = errors.New("The stream has exhausted the last possible ID, unable to add more items")
+	errXSetIDSmall       = errors.New("The ID specified in XSETID is smaller than the target stream top item")
+	errXSetIDDeleted     = errors.New("The ID specified in XSETID is smaller than the provided max_deleted_entry_id")
+	errXSetIDAdded       = errors.New("The entries_added specified in XSETID is smaller than the target stream length")
+	errMaxLenNegative    = errors.New("The MAXLEN argument must be >= 0.")
+	errLimitNegative     = errors.New("The LIMIT argument must be >= 0.")
+	errLimitNoApprox     = errors.New("syntax error, LIMIT cannot be used without the special ~ option")
+	errStreamStart       = errors.New("invalid start ID for the interval")
+	errStreamEnd         = errors.New("invalid end ID for the interval")
+	errNoSuchKey         = errors.New("no such key")
+	errXReadGroupMissing = errors.New("Missing GROUP option for XREADGROUP")
+	errXReadGreaterID    = errors.New("The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.")
+	errXReadGroupLastID  = errors.New("The $ ID is meaningless in the context of XREADGROUP: you want to read " +
+		"the history of this consumer by specifying a proper ID, or use the > ID to get new messages. " +
+		"The $ ID would just return an empty result set.")
+)
+
+// nowMs returns the unix time in milliseconds
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// getStream returns the stream stored at key, nil if the key does not exist.
+// The stream returned is allowed to be modified in place if mutable is true.
+func getStream(cli *model.Client, key string, mutable bool) (*model.Stream, error) {
+	var v interface{}
+	if mutable {
+		v = cli.GetMutable(key)
+	} else {
+		v = cli.Get(key)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(*model.Stream)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// parseStreamIDString parses the ID like "ms-seq" or "ms", the sequence
+// missing is filled by missingSeq and reported by false.
+func parseStreamIDString(s string, missingSeq uint64) (model.StreamID, bool, error) {
+	var id model.StreamID
+	var err error
+	i := strings.IndexByte(s, '-')
+	if i < 0 {
+		if id.Ms, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return id, false, errStreamID
+		}
+		id.Seq = missingSeq
+		return id, false, nil
+	}
+	if id.Ms, err = strconv.ParseUint(s[:i], 10, 64); err != nil {
+		return id, false, errStreamID
+	}
+	if id.Seq, err = strconv.ParseUint(s[i+1:], 10, 64); err != nil {
+		return id, false, errStreamID
+	}
+	return id, true, nil
+}
+
+// parseStreamID parses the ID token, the sequence missing is 0
+func parseStreamID(t *token.Token) (model.StreamID, error) {
+	data, err := tokenToBytes(t)
+	if err != nil {
+		return model.StreamID{}, err
+	}
+	id, _, err := parseStreamIDString(string(data), 0)
+	return id, err
+}
+
+// parseRangeID parses the bound of range like "-", "+", "ms", "ms-seq" and
+// the exclusive one "(ms-seq". The sequence missing is 0 for the start, or
+// the max for the end.
+func parseRangeID(t *token.Token, end bool) (model.StreamID, error) {
+	data, err := tokenToBytes(t)
+	if err != nil {
+		return model.StreamID{}, err
+	}
+	s := string(data)
+	switch s {
+	case "-":
+		return model.StreamID{}, nil
+	case "+":
+		return model.MaxStreamID, nil
+	}
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	var missingSeq uint64
+	if end {
+		missingSeq = math.MaxUint64
+	}
+	id, _, err := parseStreamIDString(s, missingSeq)
+	if err != nil || !exclusive {
+		return id, err
+	}
+	var ok bool
+	if end {
+		if id, ok = id.Prev(); !ok {
+			return id, errStreamEnd
+		}
+	} else if id, ok = id.Next(); !ok {
+		return id, errStreamStart
+	}
+	return id, nil
+}
+
+// idToken returns the bulked token of the stream ID
+func idToken(id model.StreamID) *token.Token {
+	return token.NewBulked([]byte(id.String()))
+}
+
+// entryReply returns the reply of entry like [id, [field, value, ...]]
+func entryReply(e model.StreamEntry) *token.Token {
+	fields := make([]*token.Token, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, token.NewBulked(f))
+	}
+	return token.NewArray(idToken(e.ID), token.NewArray(fields...))
+}
+
+func entriesReply(entries []model.StreamEntry) *token.Token {
+	ts := make([]*token.Token, 0, len(entries))
+	for _, e := range entries {
+		ts = append(ts, entryReply(e))
+	}
+	return token.NewArray(ts...)
+}
+
+// streamAddOption is the options of xadd and xtrim
+type streamAddOption struct {
+	noMkStream bool
+	// trimming strategy by MAXLEN or MINID
+	trim, minID bool
+	approx      bool
+	maxLen      int64
+	threshold   model.StreamID
+	// max number of entries removed, 0 means unlimited
+	limit    int64
+	limitSet bool
+}
+
+// parseStreamAdd parses "[NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT
+// count]]" from the argument after key until the one not recognized, which
+// is the ID of xadd. It returns the index of the argument not recognized.
+func parseStreamAdd(tokens []*token.Token, xadd bool) (*streamAddOption, int, error) {
+	opt := &streamAddOption{}
+	i := 1
+loop:
+	for ; i < len(tokens); i++ {
+		arg, _ := optionOf(tokens[i])
+		switch {
+		case arg == cds.NoMkStream && xadd:
+			opt.noMkStream = true
+		case arg == cds.MaxLen || arg == cds.MinID:
+			if opt.trim {
+				return nil, 0, errSyntax
+			}
+			opt.trim, opt.minID = true, arg == cds.MinID
+			if i+1 < len(tokens) {
+				if s, _ := optionOf(tokens[i+1]); s == "~" || s == "=" {
+					opt.approx = s == "~"
+					i++
+				}
+			}
+			if i++; i >= len(tokens) {
+				return nil, 0, errArgMissing(arg)
+			}
+			if opt.minID {
+				id, err := parseStreamID(tokens[i])
+				if err != nil {
+					return nil, 0, err
+				}
+				opt.threshold = id
+				continue
+			}
+			if err := checkType(tokens[i], "threshold", label.Integer); err != nil {
+				return nil, 0, err
+			}
+			if opt.maxLen = tokens[i].Data.(int64); opt.maxLen < 0 {
+				return nil, 0, errMaxLenNegative
+			}
+		case arg == cds.Limit:
+			if i++; i >= len(tokens) {
+				return nil, 0, errArgMissing(arg)
+			}
+			if err := checkType(tokens[i], "limit", label.Integer); err != nil {
+				return nil, 0, err
+			}
+			if opt.limit = tokens[i].Data.(int64); opt.limit < 0 {
+				return nil, 0, errLimitNegative
+			}
+			opt.limitSet = true
+		case xadd:
+			break loop
+		default:
+			return nil, 0, errSyntax
+		}
+	}
+	if opt.limitSet && !opt.approx {
+		return nil, 0, errLimitNoApprox
+	}
+	if opt.approx && !opt.limitSet {
+		opt.limit = streamTrimLimit
+	}
+	return opt, i, nil
+}
+
+// apply trims the stream by the strategy, returns the number of entries removed
+func (opt *streamAddOption) apply(s *model.Stream) int64 {
+	if !opt.trim {
+		return 0
+	}
+	if opt.minID {
+		return s.TrimMinID(opt.threshold, opt.approx, opt.limit)
+	}
+	return s.TrimMaxLen(opt.maxLen, opt.approx, opt.limit)
+}
+
+// trimToken returns the request trimming the stream exactly to the entries
+// left, which is propagated instead of the approximate trimming.
+func trimToken(key string, s *model.Stream) *token.Token {
+	if e, ok := s.First(); ok {
+		return token.NewArray(token.NewString(cds.XTrim), token.NewString(key),
+			token.NewString(cds.MinID), idToken(e.ID))
+	}
+	return token.NewArray(token.NewString(cds.XTrim), token.NewString(key),
+		token.NewString(cds.MaxLen), token.NewInteger(0))
+}
+
+// xAddID returns the ID of the entry added to the stream. The ID is generated
+// by the time if auto, or the sequence is generated if seq is not given.
+func xAddID(s *model.Stream, id model.StreamID, auto, seqGiven bool) (model.StreamID, error) {
+	last := s.LastID
+	if auto {
+		if ms := uint64(nowMs()); ms > last.Ms {
+			return model.StreamID{Ms: ms}, nil
+		}
+		next, ok := last.Next()
+		if !ok {
+			return next, errStreamExhausted
+		}
+		return next, nil
+	}
+	if !seqGiven {
+		switch {
+		case id.Ms < last.Ms || (id.Ms == last.Ms && last.Seq == math.MaxUint64):
+			return id, errXAddIDSmall
+		case id.Ms == last.Ms:
+			return model.StreamID{Ms: id.Ms, Seq: last.Seq + 1}, nil
+		}
+		return model.StreamID{Ms: id.Ms}, nil
+	}
+	if id.IsZero() {
+		return id, errXAddIDZero
+	}
+	if !last.Less(id) {
+		return id, errXAddIDSmall
+	}
+	return id, nil
+}
+
+// xAdd appends the entry by "key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold
+// [LIMIT count]] *|id field value [field value ...]", and returns the ID.
+// The ID generated and the exact trimming are propagated.
+func (p *Processor) xAdd(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, i, err := parseStreamAdd(tokens, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if i >= len(tokens) || len(tokens[i+1:]) == 0 || len(tokens[i+1:])%2 != 0 {
+		return token.NewError(errArgNumber.Error())
+	}
+	data, err := tokenToBytes(tokens[i])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var id model.StreamID
+	auto, seqGiven := string(data) == "*", true
+	if !auto {
+		if s := string(data); strings.HasSuffix(s, "-*") {
+			id, _, err = parseStreamIDString(s[:len(s)-2], 0)
+			seqGiven = false
+		} else {
+			// the sequence missing is 0 instead of generated
+			id, _, err = parseStreamIDString(s, 0)
+		}
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	fields := make([][]byte, 0, len(tokens)-i-1)
+	for _, t := range tokens[i+1:] {
+		f, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		fields = append(fields, f)
+	}
+	key := tokens[0].Data.(string)
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	created := s == nil
+	if created {
+		if opt.noMkStream {
+			p.propagateAs()
+			return token.NewBulked(nil)
+		}
+		s = model.NewStream()
+	}
+	if id, err = xAddID(s, id, auto, seqGiven); err != nil {
+		return token.NewError(err.Error())
+	}
+	s.Append(id, fields)
+	if created {
+		cli.Set(key, s, 0)
+	}
+	cli.Notify(model.NotifyStream, model.EventXAdd, key)
+	row := []*token.Token{token.NewString(cds.XAdd), tokens[0], idToken(id)}
+	ts := []*token.Token{token.NewArray(append(row, tokens[i+1:]...)...)}
+	if opt.apply(s) > 0 {
+		cli.Notify(model.NotifyStream, model.EventXTrim, key)
+		ts = append(ts, trimToken(key, s))
+	}
+	p.propagateAs(ts...)
+	p.signalKey(cli, key)
+	return idToken(id)
+}
+
+func (p *Processor) xLen(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	s, err := getStream(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(int64(s.Len()))
+}
+
+// xRangeGeneric returns the entries by "key start end [COUNT count]", or
+// "key end start [COUNT count]" in reversed order if rev.
+func (p *Processor) xRangeGeneric(cli *model.Client, tokens []*token.Token, rev bool) *token.Token {
+	if len(tokens) < 3 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	startToken, endToken := tokens[1], tokens[2]
+	if rev {
+		startToken, endToken = endToken, startToken
+	}
+	start, err := parseRangeID(startToken, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	end, err := parseRangeID(endToken, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	count := int64(-1)
+	if len(tokens) > 3 {
+		if arg, _ := optionOf(tokens[3]); arg != cds.Count || len(tokens) != 5 {
+			return token.NewError(errSyntax.Error())
+		}
+		if err := checkType(tokens[4], "count", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		if count = tokens[4].Data.(int64); count <= 0 {
+			return entriesReply(nil)
+		}
+	}
+	s, err := getStream(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return entriesReply(nil)
+	}
+	return entriesReply(s.Range(start, end, int(count), rev))
+}
+
+func (p *Processor) xRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.xRangeGeneric(cli, tokens, false)
+}
+
+func (p *Processor) xRevRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.xRangeGeneric(cli, tokens, true)
+}
+
+// xDel removes the entries of IDs, returns the number of entries removed
+func (p *Processor) xDel(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	ids := make([]model.StreamID, 0, len(tokens)-1)
+	for _, t := range tokens[1:] {
+		id, err := parseStreamID(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		ids = append(ids, id)
+	}
+	key := tokens[0].Data.(string)
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if s != nil {
+		for _, id := range ids {
+			if s.Delete(id) {
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		p.propagateAs()
+	} else {
+		cli.Notify(model.NotifyStream, model.EventXDel, key)
+	}
+	return token.NewInteger(n)
+}
+
+// xTrim trims the stream by "key MAXLEN|MINID [=|~] threshold [LIMIT count]",
+// returns the number of entries removed. The exact trimming is propagated.
+func (p *Processor) xTrim(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, _, err := parseStreamAdd(tokens, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if !opt.trim {
+		return token.NewError(errSyntax.Error())
+	}
+	key := tokens[0].Data.(string)
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if s != nil {
+		n = opt.apply(s)
+	}
+	if n == 0 {
+		p.propagateAs()
+		return token.NewInteger(0)
+	}
+	cli.Notify(model.NotifyStream, model.EventXTrim, key)
+	p.propagateAs(trimToken(key, s))
+	return token.NewInteger(n)
+}
+
+// xSetID sets the last ID of the stream by "key last-id [ENTRIESADDED
+// entries-added] [MAXDELETEDID max-deleted-id]".
+func (p *Processor) xSetID(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	id, err := parseStreamID(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	added := int64(-1)
+	var maxDeleted model.StreamID
+	for i := 2; i < len(tokens); i += 2 {
+		arg, _ := optionOf(tokens[i])
+		if i+1 >= len(tokens) {
+			return token.NewError(errSyntax.Error())
+		}
+		switch arg {
+		case cds.EntriesAdded:
+			if err := checkType(tokens[i+1], "entries-added", label.Integer); err != nil {
+				return token.NewError(err.Error())
+			}
+			if added = tokens[i+1].Data.(int64); added < 0 {
+				return token.NewError("entries_added must be positive")
+			}
+		case cds.MaxDeletedID:
+			if maxDeleted, err = parseStreamID(tokens[i+1]); err != nil {
+				return token.NewError(err.Error())
+			}
+			if id.Less(maxDeleted) {
+				return token.NewError(errXSetIDDeleted.Error())
+			}
+		default:
+			return token.NewError(errSyntax.Error())
+		}
+	}
+	key := tokens[0].Data.(string)
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return token.NewError(errNoSuchKey.Error())
+	}
+	if last, ok := s.Last(); ok && id.Less(last.ID) {
+		return token.NewError(errXSetIDSmall.Error())
+	}
+	if added >= 0 && int64(s.Len()) > added {
+		return token.NewError(errXSetIDAdded.Error())
+	}
+	s.LastID = id
+	if added >= 0 {
+		s.EntriesAdded = added
+	}
+	if !maxDeleted.IsZero() {
+		s.MaxDeletedID = maxDeleted
+	}
+	cli.Notify(model.NotifyStream, model.EventXSetID, key)
+	return token.ReplyOk
+}
+
+// streamReadOption is the options of xread and xreadgroup
+type streamReadOption struct {
+	group, consumer string
+	// max number of entries of each stream, 0 means unlimited
+	count   int
+	block   bool
+	timeout time.Duration
+	noAck   bool
+	keys    []string
+	ids     []*token.Token
+}
+
+// parseStreamRead parses "[COUNT count] [BLOCK milliseconds] STREAMS key
+// [key ...] id [id ...]", along with "GROUP group consumer" and "NOACK"
+// of xreadgroup if group.
+func parseStreamRead(tokens []*token.Token, group bool) (*streamReadOption, error) {
+	opt := &streamReadOption{}
+	cmd := cds.XRead
+	if group {
+		cmd = cds.XReadGroup
+	}
+	for i := 0; i < len(tokens); i++ {
+		arg, _ := optionOf(tokens[i])
+		switch {
+		case arg == cds.Count || arg == cds.Block:
+			if i++; i >= len(tokens) {
+				return nil, errArgMissing(arg)
+			}
+			if err := checkType(tokens[i], strings.ToLower(arg), label.Integer); err != nil {
+				return nil, err
+			}
+			n := tokens[i].Data.(int64)
+			if arg == cds.Block {
+				if n < 0 {
+					return nil, errTimeoutNegative
+				}
+				opt.block, opt.timeout = true, time.Duration(n)*time.Millisecond
+			} else if n > 0 {
+				opt.count = int(n)
+			}
+		case arg == cds.NoAck && group:
+			opt.noAck = true
+		case arg == cds.Group && group:
+			if i+2 >= len(tokens) {
+				return nil, errArgMissing(arg)
+			}
+			names, err := tokensToMembers(tokens[i+1 : i+3])
+			if err != nil {
+				return nil, err
+			}
+			opt.group, opt.consumer = names[0], names[1]
+			i += 2
+		case arg == cds.Streams:
+			rest := tokens[i+1:]
+			if len(rest) == 0 || len(rest)%2 != 0 {
+				return nil, errors.New("Unbalanced '" + cmd + "' list of streams: " +
+					"for each stream key an ID or '$' must be specified.")
+			}
+			if err := checkKeysType(rest[:len(rest)/2]); err != nil {
+				return nil, err
+			}
+			for _, t := range rest[:len(rest)/2] {
+				opt.keys = append(opt.keys, t.Data.(string))
+			}
+			opt.ids = rest[len(rest)/2:]
+			if group && opt.group == "" {
+				return nil, errXReadGroupMissing
+			}
+			return opt, nil
+		default:
+			return nil, errSyntax
+		}
+	}
+	return nil, errSyntax
+}
+
+// xRead returns the entries after the IDs of streams by "[COUNT count] [BLOCK
+// milliseconds] STREAMS key [key ...] id [id ...]", the ID "$" means the last
+// ID of the stream. If no entry is found, the client is blocked until any
+// entry is added if BLOCK is given, otherwise nil is returned.
+func (p *Processor) xRead(cli *model.Client, tokens ...*token.Token) *token.Token {
+	opt, err := parseStreamRead(tokens, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ids := make([]model.StreamID, len(opt.keys))
+	for i, t := range opt.ids {
+		data, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		switch string(data) {
+		case "$":
+			s, err := getStream(cli, opt.keys[i], false)
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			if s != nil {
+				ids[i] = s.LastID
+			}
+		case ">":
+			return token.NewError(errXReadGreaterID.Error())
+		default:
+			if ids[i], _, err = parseStreamIDString(string(data), 0); err != nil {
+				return token.NewError(err.Error())
+			}
+		}
+	}
+	read := func() *token.Token {
+		var ts []*token.Token
+		for i, key := range opt.keys {
+			s, err := getStream(cli, key, false)
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			start, ok := ids[i].Next()
+			if s == nil || !ok {
+				continue
+			}
+			if entries := s.Range(start, model.MaxStreamID, opt.count, false); len(entries) > 0 {
+				ts = append(ts, token.NewArray(token.NewBulked([]byte(key)), entriesReply(entries)))
+			}
+		}
+		if len(ts) == 0 {
+			return nil
+		}
+		return token.NewArray(ts...)
+	}
+	if reply := read(); reply != nil {
+		return reply
+	}
+	if !opt.block {
+		return token.NewNilArray()
+	}
+	return p.block(cli, opt.keys, opt.timeout, token.NewNilArray(), read)
+}
@@ -0,0 +1,844 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// default number of entries claimed by xautoclaim
+const autoClaimCount = 100
+
+var (
+	errBusyGroup        = errors.New("BUSYGROUP Consumer Group name already exists")
+	errXGroupKeyMissing = errors.New("The XGROUP subcommand requires the key to exist. " +
+		"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	errEntriesRead    = errors.New("value for ENTRIESREAD must be positive or -1")
+	errAutoClaimCount = errors.New("COUNT must be > 0")
+)
+
+// errNoGroup returns the error that the key or the consumer group does not exist
+func errNoGroup(key, group string) error {
+	return fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+// getGroup returns the stream and its consumer group for modification,
+// the error NOGROUP is returned if either does not exist.
+func getGroup(cli *model.Client, key, group string) (*model.Stream, *model.ConsumerGroup, error) {
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var g *model.ConsumerGroup
+	if s != nil {
+		g = s.Group(group)
+	}
+	if g == nil {
+		return nil, nil, errNoGroup(key, group)
+	}
+	return s, g, nil
+}
+
+// streamRequest returns the request of command with arguments, the arguments
+// of string, integer and stream ID are converted to tokens.
+func streamRequest(cmd string, args ...interface{}) *token.Token {
+	row := []*token.Token{token.NewString(cmd)}
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			row = append(row, token.NewString(v))
+		case int64:
+			row = append(row, token.NewInteger(v))
+		case model.StreamID:
+			row = append(row, idToken(v))
+		}
+	}
+	return token.NewArray(row...)
+}
+
+// claimToken returns the request which restores the pending entry when
+// replayed, like redis propagates the entries delivered.
+func claimToken(key, group string, pe *model.PendingEntry) *token.Token {
+	return streamRequest(cds.XClaim, key, group, pe.Consumer.Name, int64(0), pe.ID,
+		cds.Time, pe.DeliveryTime, cds.RetryCount, pe.DeliveryCount, cds.Force, cds.JustID)
+}
+
+// setIDToken returns the request which sets the last delivered ID and the
+// entries read of the group.
+func setIDToken(key string, g *model.ConsumerGroup) *token.Token {
+	return streamRequest(cds.XGroup, cds.SetID, key, g.Name, g.LastID, cds.EntriesRead, g.EntriesRead)
+}
+
+// parseEntriesRead parses the value of ENTRIESREAD
+func parseEntriesRead(t *token.Token) (int64, error) {
+	if err := checkType(t, "entries-read", label.Integer); err != nil {
+		return 0, err
+	}
+	n := t.Data.(int64)
+	if n < 0 && n != -1 {
+		return 0, errEntriesRead
+	}
+	return n, nil
+}
+
+// parseGroupID parses the ID of the consumer group, "$" means the last ID of
+// the stream.
+func parseGroupID(t *token.Token, s *model.Stream) (model.StreamID, error) {
+	data, err := tokenToBytes(t)
+	if err != nil {
+		return model.StreamID{}, err
+	}
+	if string(data) == "$" {
+		if s == nil {
+			return model.StreamID{}, nil
+		}
+		return s.LastID, nil
+	}
+	id, _, err := parseStreamIDString(string(data), 0)
+	return id, err
+}
+
+// xGroup manages consumer groups by subcommands:
+//
+//	CREATE key group id|$ [MKSTREAM] [ENTRIESREAD entries-read]
+//	SETID key group id|$ [ENTRIESREAD entries-read]
+//	DESTROY key group
+//	CREATECONSUMER key group consumer
+//	DELCONSUMER key group consumer
+//
+// The ID "$" is propagated as the last ID of the stream.
+func (p *Processor) xGroup(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if len(tokens) < 3 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkType(tokens[0], "subcommand", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkKeyType(tokens[1]); err != nil {
+		return token.NewError(err.Error())
+	}
+	names, err := tokensToMembers(tokens[2:3])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	sub := strings.ToUpper(tokens[0].Data.(string))
+	key, group := tokens[1].Data.(string), names[0]
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	switch {
+	case (sub == cds.Create || sub == cds.SetID) && len(tokens) >= 4:
+		var mkStream bool
+		entriesRead := int64(-1)
+		for i := 4; i < len(tokens); i++ {
+			switch arg, _ := optionOf(tokens[i]); {
+			case arg == cds.MkStream && sub == cds.Create:
+				mkStream = true
+			case arg == cds.EntriesRead && i+1 < len(tokens):
+				if entriesRead, err = parseEntriesRead(tokens[i+1]); err != nil {
+					return token.NewError(err.Error())
+				}
+				i++
+			default:
+				return token.NewError(errSyntax.Error())
+			}
+		}
+		id, err := parseGroupID(tokens[3], s)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		if s == nil && !mkStream {
+			return token.NewError(errXGroupKeyMissing.Error())
+		}
+		if sub == cds.SetID {
+			g := s.Group(group)
+			if g == nil {
+				return token.NewError("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+			}
+			g.LastID, g.EntriesRead = id, entriesRead
+		} else {
+			if s == nil {
+				s = model.NewStream()
+				cli.Set(key, s, 0)
+			}
+			if s.CreateGroup(group, id, entriesRead) == nil {
+				return token.NewError(errBusyGroup.Error())
+			}
+		}
+		event := model.EventXGroupCreate
+		if sub == cds.SetID {
+			event = model.EventXGroupSetID
+		}
+		cli.Notify(model.NotifyStream, event, key)
+		rewritten := append([]*token.Token{token.NewString(cds.XGroup)}, tokens...)
+		rewritten[4] = idToken(id)
+		p.propagateAs(token.NewArray(rewritten...))
+		return token.ReplyOk
+	case sub == cds.Destroy && len(tokens) == 3:
+		if s == nil || !s.DestroyGroup(group) {
+			p.propagateAs()
+			return token.NewInteger(0)
+		}
+		cli.Notify(model.NotifyStream, model.EventXGroupDestroy, key)
+		return token.NewInteger(1)
+	case (sub == cds.CreateConsumer || sub == cds.DelConsumer) && len(tokens) == 4:
+		if s == nil {
+			return token.NewError(errXGroupKeyMissing.Error())
+		}
+		g := s.Group(group)
+		if g == nil {
+			return token.NewError("NOGROUP No such consumer group '%s' for key name '%s'", group, key)
+		}
+		consumer, err := tokensToMembers(tokens[3:])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		if sub == cds.CreateConsumer {
+			if g.CreateConsumer(consumer[0], nowMs()) == nil {
+				p.propagateAs()
+				return token.NewInteger(0)
+			}
+			cli.Notify(model.NotifyStream, model.EventXGroupCreateConsumer, key)
+			return token.NewInteger(1)
+		}
+		n := g.DelConsumer(consumer[0])
+		if n < 0 {
+			p.propagateAs()
+			return token.NewInteger(0)
+		}
+		cli.Notify(model.NotifyStream, model.EventXGroupDelConsumer, key)
+		return token.NewInteger(int64(n))
+	}
+	return token.NewError("unknown subcommand or wrong number of arguments for '%s'", sub)
+}
+
+// xReadGroup reads entries as the consumer of the group by "GROUP group
+// consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...]
+// id [id ...]". The ID ">" reads the entries never delivered to the group,
+// which are added to the pending entries unless NOACK is given. Other IDs
+// read the history of the pending entries of the consumer. The client is
+// blocked if BLOCK is given and no entry is read by ">".
+//
+// The entries delivered are propagated as xclaim, along with the last
+// delivered ID set by xgroup.
+func (p *Processor) xReadGroup(cli *model.Client, tokens ...*token.Token) *token.Token {
+	opt, err := parseStreamRead(tokens, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ids := make([]model.StreamID, len(opt.keys))
+	history := make([]bool, len(opt.keys))
+	for i, t := range opt.ids {
+		data, err := tokenToBytes(t)
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		switch string(data) {
+		case ">":
+		case "$":
+			return token.NewError(errXReadGroupLastID.Error())
+		default:
+			if ids[i], _, err = parseStreamIDString(string(data), 0); err != nil {
+				return token.NewError(err.Error())
+			}
+			history[i] = true
+		}
+	}
+	for _, key := range opt.keys {
+		if _, _, err := getGroup(cli, key, opt.group); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	read := func() *token.Token {
+		var ts, props []*token.Token
+		now := nowMs()
+		for i, key := range opt.keys {
+			s, g, err := getGroup(cli, key, opt.group)
+			if err != nil {
+				return token.NewError(err.Error())
+			}
+			c := g.Consumer(opt.consumer)
+			if c == nil {
+				c = g.CreateConsumer(opt.consumer, now)
+				cli.Notify(model.NotifyStream, model.EventXGroupCreateConsumer, key)
+				props = append(props, streamRequest(cds.XGroup, cds.CreateConsumer, key, opt.group, opt.consumer))
+			}
+			c.SeenTime = now
+			if history[i] {
+				var entries []*token.Token
+				if start, ok := ids[i].Next(); ok {
+					for _, pe := range g.PendingRange(start, model.MaxStreamID, c, opt.count) {
+						if e, ok := s.Get(pe.ID); ok {
+							entries = append(entries, entryReply(e))
+						} else {
+							entries = append(entries, token.NewArray(idToken(pe.ID), token.NewBulked(nil)))
+						}
+					}
+				}
+				ts = append(ts, token.NewArray(token.NewBulked([]byte(key)), token.NewArray(entries...)))
+				continue
+			}
+			start, ok := g.LastID.Next()
+			if !ok {
+				continue
+			}
+			entries := s.Range(start, model.MaxStreamID, opt.count, false)
+			if len(entries) == 0 {
+				continue
+			}
+			c.ActiveTime = now
+			for _, e := range entries {
+				s.Advance(g, e.ID)
+				if !opt.noAck {
+					props = append(props, claimToken(key, opt.group, g.Deliver(e.ID, c, now)))
+				}
+			}
+			props = append(props, setIDToken(key, g))
+			ts = append(ts, token.NewArray(token.NewBulked([]byte(key)), entriesReply(entries)))
+			cli.Touch(key)
+		}
+		p.propagateAs(props...)
+		if len(ts) == 0 {
+			return nil
+		}
+		return token.NewArray(ts...)
+	}
+	if reply := read(); reply != nil {
+		return reply
+	}
+	if !opt.block {
+		return token.NewNilArray()
+	}
+	return p.block(cli, opt.keys, opt.timeout, token.NewNilArray(), read)
+}
+
+// parseStreamIDs parses the IDs of tokens
+func parseStreamIDs(tokens []*token.Token) ([]model.StreamID, error) {
+	ids := make([]model.StreamID, 0, len(tokens))
+	for _, t := range tokens {
+		id, err := parseStreamID(t)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// xAck acknowledges the pending entries of the group by "key group id [id
+// ...]", returns the number of entries acknowledged.
+func (p *Processor) xAck(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	names, err := tokensToMembers(tokens[1:2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ids, err := parseStreamIDs(tokens[2:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	s, err := getStream(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if s != nil {
+		if g := s.Group(names[0]); g != nil {
+			for _, id := range ids {
+				if g.Ack(id) {
+					n++
+				}
+			}
+		}
+	}
+	if n == 0 {
+		p.propagateAs()
+	} else {
+		cli.Touch(key)
+	}
+	return token.NewInteger(n)
+}
+
+// xPending returns the summary of the pending entries of the group by "key
+// group", or the pending entries by "key group [IDLE min-idle-time] start
+// end count [consumer]".
+func (p *Processor) xPending(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	names, err := tokensToMembers(tokens[1:2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key, group := tokens[0].Data.(string), names[0]
+	var minIdle int64
+	args := tokens[2:]
+	if len(args) > 0 {
+		if arg, _ := optionOf(args[0]); arg == cds.Idle {
+			if len(args) < 2 {
+				return token.NewError(errSyntax.Error())
+			}
+			if err := checkType(args[1], "min-idle-time", label.Integer); err != nil {
+				return token.NewError(err.Error())
+			}
+			minIdle = args[1].Data.(int64)
+			args = args[2:]
+		}
+	}
+	if len(args) > 0 && len(args) != 3 && len(args) != 4 || len(args) == 0 && len(tokens) > 2 {
+		return token.NewError(errSyntax.Error())
+	}
+	var start, end model.StreamID
+	var count int64
+	if len(args) > 0 {
+		if start, err = parseRangeID(args[0], false); err != nil {
+			return token.NewError(err.Error())
+		}
+		if end, err = parseRangeID(args[1], true); err != nil {
+			return token.NewError(err.Error())
+		}
+		if err := checkType(args[2], "count", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		count = args[2].Data.(int64)
+	}
+	s, err := getStream(cli, key, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var g *model.ConsumerGroup
+	if s != nil {
+		g = s.Group(group)
+	}
+	if g == nil {
+		return token.NewError(errNoGroup(key, group).Error())
+	}
+	if len(args) == 0 {
+		pes := g.PendingRange(model.StreamID{}, model.MaxStreamID, nil, 0)
+		if len(pes) == 0 {
+			return token.NewArray(token.NewInteger(0), token.NewBulked(nil), token.NewBulked(nil), token.NewBulked(nil))
+		}
+		var consumers []*token.Token
+		for _, c := range g.Consumers() {
+			if c.Pending() > 0 {
+				consumers = append(consumers, token.NewArray(token.NewBulked([]byte(c.Name)),
+					token.NewBulked([]byte(fmt.Sprint(c.Pending())))))
+			}
+		}
+		return token.NewArray(token.NewInteger(int64(len(pes))), idToken(pes[0].ID),
+			idToken(pes[len(pes)-1].ID), token.NewArray(consumers...))
+	}
+	var c *model.Consumer
+	if len(args) == 4 {
+		consumer, err := tokensToMembers(args[3:])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		if c = g.Consumer(consumer[0]); c == nil {
+			return token.NewArray()
+		}
+	}
+	now := nowMs()
+	ts := make([]*token.Token, 0)
+	for _, pe := range g.PendingRange(start, end, c, 0) {
+		if int64(len(ts)) >= count {
+			break
+		}
+		idle := now - pe.DeliveryTime
+		if idle < minIdle {
+			continue
+		}
+		ts = append(ts, token.NewArray(idToken(pe.ID), token.NewBulked([]byte(pe.Consumer.Name)),
+			token.NewInteger(idle), token.NewInteger(pe.DeliveryCount)))
+	}
+	return token.NewArray(ts...)
+}
+
+// claimOption is the options of xclaim
+type claimOption struct {
+	// unix time in milliseconds of the delivery, the current time by default
+	deliveryTime int64
+	// delivery count set, -1 to increase it
+	retryCount int64
+	force      bool
+	justID     bool
+	lastID     *model.StreamID
+}
+
+// parseClaim parses "[IDLE ms] [TIME unix-time-milliseconds] [RETRYCOUNT
+// count] [FORCE] [JUSTID] [LASTID lastid]"
+func parseClaim(tokens []*token.Token, now int64) (*claimOption, error) {
+	opt := &claimOption{deliveryTime: now, retryCount: -1}
+	for i := 0; i < len(tokens); i++ {
+		arg, _ := optionOf(tokens[i])
+		switch arg {
+		case cds.Force:
+			opt.force = true
+		case cds.JustID:
+			opt.justID = true
+		case cds.Idle, cds.Time, cds.RetryCount:
+			if i++; i >= len(tokens) {
+				return nil, errArgMissing(arg)
+			}
+			if err := checkType(tokens[i], strings.ToLower(arg), label.Integer); err != nil {
+				return nil, err
+			}
+			n := tokens[i].Data.(int64)
+			switch arg {
+			case cds.Idle:
+				opt.deliveryTime = now - n
+			case cds.Time:
+				opt.deliveryTime = n
+			default:
+				opt.retryCount = n
+			}
+		case cds.LastID:
+			if i++; i >= len(tokens) {
+				return nil, errArgMissing(arg)
+			}
+			id, err := parseStreamID(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			opt.lastID = &id
+		default:
+			return nil, errSyntax
+		}
+	}
+	if opt.deliveryTime > now {
+		opt.deliveryTime = now
+	}
+	return opt, nil
+}
+
+// claimArgs checks and returns the key, group, consumer and min idle time of
+// "key group consumer min-idle-time"
+func claimArgs(tokens []*token.Token) (string, string, string, int64, error) {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return "", "", "", 0, err
+	}
+	names, err := tokensToMembers(tokens[1:3])
+	if err != nil {
+		return "", "", "", 0, err
+	}
+	if err := checkType(tokens[3], "min-idle-time", label.Integer); err != nil {
+		return "", "", "", 0, err
+	}
+	minIdle := tokens[3].Data.(int64)
+	if minIdle < 0 {
+		minIdle = 0
+	}
+	return tokens[0].Data.(string), names[0], names[1], minIdle, nil
+}
+
+// claimConsumer returns the consumer of the group, the request creating the
+// consumer is appended to props if it is created.
+func claimConsumer(cli *model.Client, g *model.ConsumerGroup, key, name string, now int64, props []*token.Token) (*model.Consumer, []*token.Token) {
+	c := g.Consumer(name)
+	if c == nil {
+		c = g.CreateConsumer(name, now)
+		cli.Notify(model.NotifyStream, model.EventXGroupCreateConsumer, key)
+		props = append(props, streamRequest(cds.XGroup, cds.CreateConsumer, key, g.Name, name))
+	}
+	c.SeenTime = now
+	return c, props
+}
+
+// xClaim transfers the pending entries idle for min-idle-time at least to
+// the consumer by "key group consumer min-idle-time id [id ...] [IDLE ms]
+// [TIME unix-time-milliseconds] [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID
+// lastid]". Entries deleted from the stream are removed from the pending
+// entries.
+func (p *Processor) xClaim(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, group, consumer, minIdle, err := claimArgs(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	i := 4
+	var ids []model.StreamID
+	for ; i < len(tokens); i++ {
+		id, err := parseStreamID(tokens[i])
+		if err != nil {
+			if len(ids) == 0 {
+				return token.NewError(err.Error())
+			}
+			break
+		}
+		ids = append(ids, id)
+	}
+	now := nowMs()
+	opt, err := parseClaim(tokens[i:], now)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	s, g, err := getGroup(cli, key, group)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var props []*token.Token
+	if opt.lastID != nil && g.LastID.Less(*opt.lastID) {
+		g.LastID = *opt.lastID
+		props = append(props, setIDToken(key, g))
+	}
+	c, props := claimConsumer(cli, g, key, consumer, now, props)
+	ts := make([]*token.Token, 0, len(ids))
+	for _, id := range ids {
+		pe := g.GetPending(id)
+		e, exists := s.Get(id)
+		forced := false
+		if pe == nil {
+			if !opt.force || !exists {
+				continue
+			}
+			pe, forced = g.Deliver(id, c, now), true
+		}
+		if !exists {
+			g.Ack(id)
+			props = append(props, streamRequest(cds.XAck, key, group, id))
+			continue
+		}
+		if !forced && minIdle > 0 && now-pe.DeliveryTime < minIdle {
+			continue
+		}
+		pe.DeliveryTime = opt.deliveryTime
+		if opt.retryCount >= 0 {
+			pe.DeliveryCount = opt.retryCount
+		} else if !opt.justID {
+			pe.DeliveryCount++
+		}
+		if pe.Consumer != c {
+			g.Claim(pe, c)
+		}
+		c.ActiveTime = now
+		if opt.justID {
+			ts = append(ts, idToken(id))
+		} else {
+			ts = append(ts, entryReply(e))
+		}
+		props = append(props, claimToken(key, group, pe))
+	}
+	if len(props) > 0 {
+		cli.Touch(key)
+	}
+	p.propagateAs(props...)
+	return token.NewArray(ts...)
+}
+
+// xAutoClaim transfers the pending entries idle for min-idle-time at least
+// to the consumer by "key group consumer min-idle-time start [COUNT count]
+// [JUSTID]", which scans count entries from start at most. It returns the
+// ID to start the next scan, "0-0" if all scanned, along with the entries
+// claimed and the IDs of entries deleted from the stream.
+func (p *Processor) xAutoClaim(cli *model.Client, tokens ...*token.Token) *token.Token {
+	key, group, consumer, minIdle, err := claimArgs(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	start, err := parseRangeID(tokens[4], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	count := int64(autoClaimCount)
+	var justID bool
+	for i := 5; i < len(tokens); i++ {
+		arg, _ := optionOf(tokens[i])
+		switch {
+		case arg == cds.JustID:
+			justID = true
+		case arg == cds.Count && i+1 < len(tokens):
+			i++
+			if err := checkType(tokens[i], "count", label.Integer); err != nil {
+				return token.NewError(err.Error())
+			}
+			if count = tokens[i].Data.(int64); count < 1 || count > math.MaxInt64/10 {
+				return token.NewError(errAutoClaimCount.Error())
+			}
+		default:
+			return token.NewError(errSyntax.Error())
+		}
+	}
+	s, g, err := getGroup(cli, key, group)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	now := nowMs()
+	c, props := claimConsumer(cli, g, key, consumer, now, nil)
+	claimed := make([]*token.Token, 0)
+	deleted := make([]*token.Token, 0)
+	pes := g.PendingRange(start, model.MaxStreamID, nil, 0)
+	attempts := count * 10
+	k := 0
+	for ; k < len(pes) && attempts > 0 && int64(len(claimed)) < count; k++ {
+		attempts--
+		pe := pes[k]
+		e, exists := s.Get(pe.ID)
+		if !exists {
+			g.Ack(pe.ID)
+			deleted = append(deleted, idToken(pe.ID))
+			props = append(props, streamRequest(cds.XAck, key, group, pe.ID))
+			continue
+		}
+		if minIdle > 0 && now-pe.DeliveryTime < minIdle {
+			continue
+		}
+		pe.DeliveryTime = now
+		if !justID {
+			pe.DeliveryCount++
+		}
+		if pe.Consumer != c {
+			g.Claim(pe, c)
+		}
+		c.ActiveTime = now
+		if justID {
+			claimed = append(claimed, idToken(pe.ID))
+		} else {
+			claimed = append(claimed, entryReply(e))
+		}
+		props = append(props, claimToken(key, group, pe))
+	}
+	next := model.StreamID{}
+	if k < len(pes) {
+		next = pes[k].ID
+	}
+	if len(props) > 0 {
+		cli.Touch(key)
+	}
+	p.propagateAs(props...)
+	return token.NewArray(idToken(next), token.NewArray(claimed...), token.NewArray(deleted...))
+}
+
+// xInfo returns the information by subcommands:
+//
+//	STREAM key
+//	GROUPS key
+//	CONSUMERS key group
+func (p *Processor) xInfo(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkType(tokens[0], "subcommand", label.String); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkKeyType(tokens[1]); err != nil {
+		return token.NewError(err.Error())
+	}
+	sub := strings.ToUpper(tokens[0].Data.(string))
+	key := tokens[1].Data.(string)
+	if (sub != cds.StreamArg && sub != cds.Groups || len(tokens) != 2) && (sub != cds.Consumers || len(tokens) != 3) {
+		return token.NewError("unknown subcommand or wrong number of arguments for '%s'", sub)
+	}
+	s, err := getStream(cli, key, false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if s == nil {
+		return token.NewError(errNoSuchKey.Error())
+	}
+	field := func(name string) *token.Token { return token.NewBulked([]byte(name)) }
+	now := nowMs()
+	switch sub {
+	case cds.StreamArg:
+		var firstID model.StreamID
+		first, last := token.NewBulked(nil), token.NewBulked(nil)
+		if e, ok := s.First(); ok {
+			firstID, first = e.ID, entryReply(e)
+		}
+		if e, ok := s.Last(); ok {
+			last = entryReply(e)
+		}
+		return token.NewArray(
+			field("length"), token.NewInteger(int64(s.Len())),
+			field("nodes"), token.NewInteger(int64(s.Nodes())),
+			field("last-generated-id"), idToken(s.LastID),
+			field("max-deleted-entry-id"), idToken(s.MaxDeletedID),
+			field("entries-added"), token.NewInteger(s.EntriesAdded),
+			field("recorded-first-entry-id"), idToken(firstID),
+			field("groups"), token.NewInteger(int64(len(s.Groups()))),
+			field("first-entry"), first,
+			field("last-entry"), last)
+	case cds.Groups:
+		groups := s.Groups()
+		ts := make([]*token.Token, 0, len(groups))
+		for _, g := range groups {
+			entriesRead, lag := token.NewBulked(nil), token.NewBulked(nil)
+			if g.EntriesRead >= 0 {
+				entriesRead = token.NewInteger(g.EntriesRead)
+			}
+			if n, ok := s.Lag(g); ok {
+				lag = token.NewInteger(n)
+			}
+			ts = append(ts, token.NewArray(
+				field("name"), field(g.Name),
+				field("consumers"), token.NewInteger(int64(len(g.Consumers()))),
+				field("pending"), token.NewInteger(int64(g.PendingLen())),
+				field("last-delivered-id"), idToken(g.LastID),
+				field("entries-read"), entriesRead,
+				field("lag"), lag))
+		}
+		return token.NewArray(ts...)
+	}
+	names, err := tokensToMembers(tokens[2:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	g := s.Group(names[0])
+	if g == nil {
+		return token.NewError("NOGROUP No such consumer group '%s' for key name '%s'", names[0], key)
+	}
+	consumers := g.Consumers()
+	ts := make([]*token.Token, 0, len(consumers))
+	for _, c := range consumers {
+		inactive := int64(-1)
+		if c.ActiveTime >= 0 {
+			inactive = now - c.ActiveTime
+		}
+		ts = append(ts, token.NewArray(
+			field("name"), field(c.Name),
+			field("pending"), token.NewInteger(int64(c.Pending())),
+			field("idle"), token.NewInteger(now-c.SeenTime),
+			field("inactive"), token.NewInteger(inactive)))
+	}
+	return token.NewArray(ts...)
+}
+
+// dumpStream returns the requests which restore the stream with its groups
+// when replayed.
+func dumpStream(key string, s *model.Stream) []*token.Token {
+	var ts []*token.Token
+	entries := s.Range(model.StreamID{}, model.MaxStreamID, 0, false)
+	for _, e := range entries {
+		row := []*token.Token{token.NewString(cds.XAdd), token.NewString(key), idToken(e.ID)}
+		for _, f := range e.Fields {
+			row = append(row, token.NewBulked(f))
+		}
+		ts = append(ts, token.NewArray(row...))
+	}
+	if len(entries) == 0 {
+		// creates the empty stream by adding an entry trimmed at once
+		ts = append(ts, streamRequest(cds.XAdd, key, cds.MaxLen, int64(0),
+			model.StreamID{Seq: 1}, "", ""))
+	}
+	ts = append(ts, streamRequest(cds.XSetID, key, s.LastID,
+		cds.EntriesAdded, s.EntriesAdded, cds.MaxDeletedID, s.MaxDeletedID))
+	for _, g := range s.Groups() {
+		ts = append(ts, streamRequest(cds.XGroup, cds.Create, key, g.Name, g.LastID,
+			cds.EntriesRead, g.EntriesRead))
+		for _, c := range g.Consumers() {
+			ts = append(ts, streamRequest(cds.XGroup, cds.CreateConsumer, key, g.Name, c.Name))
+		}
+		for _, pe := range g.PendingRange(model.StreamID{}, model.MaxStreamID, nil, 0) {
+			ts = append(ts, claimToken(key, g.Name, pe))
+		}
+	}
+	return ts
+}
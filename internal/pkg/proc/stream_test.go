@@ -0,0 +1,321 @@
+package proc
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamCmd returns the request whose arguments of integer are converted to
+// integer tokens, and others to string tokens.
+func streamCmd(cmd string, args ...string) *token.Token {
+	ts := []*token.Token{token.NewString(cmd)}
+	for _, arg := range args {
+		if n, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			ts = append(ts, token.NewInteger(n))
+		} else {
+			ts = append(ts, token.NewString(arg))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// argsOf returns the arguments of the request as strings
+func argsOf(req *token.Token) []string {
+	var args []string
+	for _, t := range req.Data.([]*token.Token) {
+		data, _ := tokenToBytes(t)
+		args = append(args, string(data))
+	}
+	return args
+}
+
+// entryArray returns the reply of entries whose fields are "f" and "v"
+func entryArray(ids ...string) *token.Token {
+	ts := make([]*token.Token, 0, len(ids))
+	for _, id := range ids {
+		ts = append(ts, token.NewArray(token.NewBulked([]byte(id)),
+			token.NewArray(token.NewBulked([]byte("f")), token.NewBulked([]byte("v")))))
+	}
+	return token.NewArray(ts...)
+}
+
+func TestProcessor_xAdd(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) interface{} {
+		return p.execCmd(c, streamCmd(cmd, args...)).Data
+	}
+	assert.Equal(t, []byte("1-1"), exec(cds.XAdd, "s", "1-1", "f", "v"))
+	assert.Equal(t, []byte("1-2"), exec(cds.XAdd, "s", "1-*", "f", "v"))
+	assert.Equal(t, "ERR "+errXAddIDSmall.Error(), exec(cds.XAdd, "s", "1", "f", "v"))
+	assert.Equal(t, "ERR "+errXAddIDZero.Error(), exec(cds.XAdd, "s", "0-0", "f", "v"))
+	assert.Equal(t, "ERR "+errStreamID.Error(), exec(cds.XAdd, "s", "a-1", "f", "v"))
+	assert.Equal(t, "ERR "+errArity(cds.XAdd).Error(), exec(cds.XAdd, "s", "*", "f", "v", "f"))
+	assert.Equal(t, []byte("2-0"), exec(cds.XAdd, "s", "2", "f", "v"))
+	id := exec(cds.XAdd, "s", "*", "f", "v").([]byte)
+	assert.Equal(t, []byte(strconv.FormatInt(nowMs(), 10))[:8], id[:8])
+	assert.Equal(t, int64(4), exec(cds.XLen, "s"))
+	assert.Equal(t, int64(0), exec(cds.XLen, "none"))
+	assert.Nil(t, exec(cds.XAdd, "none", cds.NoMkStream, "*", "f", "v"))
+	assert.Equal(t, int64(0), exec(cds.Exists, "none"))
+	// the ID generated is propagated
+	assert.Equal(t, 4, len(p.aof))
+	assert.Equal(t, []string{cds.XAdd, "s", string(id), "f", "v"}, argsOf(p.aof[3].T))
+
+	for i := 1; i <= 3; i++ {
+		exec(cds.XAdd, "trim", cds.MaxLen, "2", strconv.Itoa(i), "f", "v")
+	}
+	assert.Equal(t, entryArray("2-0", "3-0"), p.execCmd(c, streamCmd(cds.XRange, "trim", "-", "+")))
+	assert.Equal(t, []string{cds.XTrim, "trim", cds.MinID, "2-0"}, argsOf(p.aof[len(p.aof)-1].T))
+	assert.Equal(t, "ERR "+errLimitNoApprox.Error(), exec(cds.XAdd, "trim", cds.MaxLen, "2", cds.Limit, "10", "*", "f", "v"))
+	assert.Equal(t, "ERR "+errMaxLenNegative.Error(), exec(cds.XAdd, "trim", cds.MaxLen, "-1", "*", "f", "v"))
+
+	exec(cds.Set, "str", "v")
+	assert.Equal(t, errWrongType.Error(), exec(cds.XAdd, "str", "*", "f", "v"))
+	assert.Equal(t, errWrongType.Error(), exec(cds.XLen, "str"))
+	assert.Equal(t, typeStream, typeName(c.Get("s")))
+}
+
+func TestProcessor_xRange(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) *token.Token {
+		return p.execCmd(c, streamCmd(cmd, args...))
+	}
+	for i := 1; i <= 5; i++ {
+		exec(cds.XAdd, "s", strconv.Itoa(i), "f", "v")
+	}
+	assert.Equal(t, entryArray("1-0", "2-0", "3-0", "4-0", "5-0"), exec(cds.XRange, "s", "-", "+"))
+	assert.Equal(t, entryArray("2-0", "3-0"), exec(cds.XRange, "s", "(1-0", "+", cds.Count, "2"))
+	assert.Equal(t, entryArray("2-0", "3-0"), exec(cds.XRange, "s", "2", "3"))
+	assert.Equal(t, entryArray("5-0", "4-0"), exec(cds.XRevRange, "s", "+", "-", cds.Count, "2"))
+	assert.Equal(t, entryArray("3-0"), exec(cds.XRevRange, "s", "(4-0", "(2-0"))
+	assert.Equal(t, entryArray(), exec(cds.XRange, "s", "-", "+", cds.Count, "0"))
+	assert.Equal(t, entryArray(), exec(cds.XRange, "none", "-", "+"))
+	assert.Equal(t, token.NewError("ERR "+errStreamStart.Error()), exec(cds.XRange, "s", "(18446744073709551615-18446744073709551615", "+"))
+
+	assert.Equal(t, int64(2), exec(cds.XDel, "s", "2-0", "4-0", "6-0").Data)
+	assert.Equal(t, entryArray("1-0", "3-0", "5-0"), exec(cds.XRange, "s", "-", "+"))
+	assert.Equal(t, int64(0), exec(cds.XDel, "s", "2-0").Data)
+	assert.Equal(t, int64(1), exec(cds.XTrim, "s", cds.MinID, "=", "3").Data)
+	assert.Equal(t, token.NewError("ERR "+errXSetIDSmall.Error()), exec(cds.XSetID, "s", "4"))
+	// only whole nodes are removed by the approximate trimming
+	assert.Equal(t, int64(0), exec(cds.XTrim, "s", cds.MaxLen, "~", "1").Data)
+	assert.Equal(t, int64(2), exec(cds.XTrim, "s", cds.MaxLen, "0").Data)
+	assert.Equal(t, token.NewError("ERR "+errSyntax.Error()), exec(cds.XTrim, "s", cds.Count, "0"))
+	assert.Equal(t, int64(0), exec(cds.XLen, "s").Data)
+	// an empty stream is kept
+	assert.Equal(t, int64(1), exec(cds.Exists, "s").Data)
+
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.XSetID, "s", "10", cds.EntriesAdded, "20").Data)
+	assert.Equal(t, token.NewError("ERR "+errXAddIDSmall.Error()), exec(cds.XAdd, "s", "10", "f", "v"))
+	assert.Equal(t, token.NewError("ERR "+errNoSuchKey.Error()), exec(cds.XSetID, "none", "1"))
+
+	// deletions of nothing are not propagated
+	assert.Equal(t, 9, len(p.aof))
+	assert.Equal(t, [][]string{
+		{cds.XDel, "s", "2-0", "4-0", "6-0"},
+		{cds.XTrim, "s", cds.MinID, "3-0"},
+		{cds.XTrim, "s", cds.MaxLen, "0"},
+		{cds.XSetID, "s", "10", cds.EntriesAdded, "20"},
+	}, [][]string{argsOf(p.aof[5].T), argsOf(p.aof[6].T), argsOf(p.aof[7].T), argsOf(p.aof[8].T)})
+}
+
+func TestProcessor_xReadGroup(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	// the flag of replies of write commands is ignored
+	exec := func(cmd string, args ...string) *token.Token {
+		r := p.execCmd(c, streamCmd(cmd, args...))
+		return &token.Token{Data: r.Data, Label: r.Label}
+	}
+	streamReply := func(key string, entries *token.Token) *token.Token {
+		return token.NewArray(token.NewArray(token.NewBulked([]byte(key)), entries))
+	}
+	for i := 1; i <= 3; i++ {
+		exec(cds.XAdd, "s", strconv.Itoa(i), "f", "v")
+	}
+	assert.Equal(t, token.NewError("ERR "+errXGroupKeyMissing.Error()), exec(cds.XGroup, cds.Create, "none", "g", "$"))
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.XGroup, cds.Create, "s", "g", "0").Data)
+	assert.Equal(t, token.NewError(errBusyGroup.Error()), exec(cds.XGroup, cds.Create, "s", "g", "$"))
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.XGroup, cds.Create, "s", "last", "$").Data)
+	assert.Equal(t, []string{cds.XGroup, cds.Create, "s", "last", "3-0"}, argsOf(p.aof[len(p.aof)-1].T))
+	assert.Equal(t, token.ReplyOk.Data, exec(cds.XGroup, cds.Create, "empty", "g", "$", cds.MkStream).Data)
+
+	assert.Equal(t, streamReply("s", entryArray("1-0", "2-0")),
+		exec(cds.XReadGroup, cds.Group, "g", "alice", cds.Count, "2", cds.Streams, "s", ">"))
+	assert.Equal(t, streamReply("s", entryArray("3-0")),
+		exec(cds.XReadGroup, cds.Group, "g", "bob", cds.Streams, "s", ">"))
+	assert.Equal(t, token.NewNilArray(), exec(cds.XReadGroup, cds.Group, "g", "bob", cds.Streams, "s", ">"))
+	assert.Equal(t, streamReply("s", entryArray("2-0")),
+		exec(cds.XReadGroup, cds.Group, "g", "alice", cds.Streams, "s", "1"))
+	assert.Equal(t, token.NewError(errNoGroup("s", "none").Error()),
+		exec(cds.XReadGroup, cds.Group, "none", "alice", cds.Streams, "s", ">"))
+	assert.Equal(t, token.NewError("ERR "+errXReadGroupLastID.Error()),
+		exec(cds.XReadGroup, cds.Group, "g", "alice", cds.Streams, "s", "$"))
+
+	assert.Equal(t, token.NewArray(token.NewInteger(3), token.NewBulked([]byte("1-0")), token.NewBulked([]byte("3-0")),
+		token.NewArray(
+			token.NewArray(token.NewBulked([]byte("alice")), token.NewBulked([]byte("2"))),
+			token.NewArray(token.NewBulked([]byte("bob")), token.NewBulked([]byte("1"))))),
+		exec(cds.XPending, "s", "g"))
+	pending := exec(cds.XPending, "s", "g", "-", "+", "10", "alice").Data.([]*token.Token)
+	assert.Equal(t, 2, len(pending))
+	assert.Equal(t, int64(1), pending[0].Data.([]*token.Token)[3].Data)
+	assert.Equal(t, 0, len(exec(cds.XPending, "s", "g", cds.Idle, "100000", "-", "+", "10").Data.([]*token.Token)))
+
+	assert.Equal(t, int64(1), exec(cds.XAck, "s", "g", "1-0", "1-0").Data)
+	// the entry deleted is removed from the pending entries when claimed
+	exec(cds.XDel, "s", "2-0")
+	assert.Empty(t, exec(cds.XClaim, "s", "g", "bob", "0", "2-0").Data)
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("3-0"))),
+		exec(cds.XClaim, "s", "g", "carol", "0", "3-0", cds.RetryCount, "5", cds.JustID))
+	pending = exec(cds.XPending, "s", "g", "-", "+", "10").Data.([]*token.Token)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, []byte("carol"), pending[0].Data.([]*token.Token)[1].Data)
+	assert.Equal(t, int64(5), pending[0].Data.([]*token.Token)[3].Data)
+
+	exec(cds.XAdd, "s", "4", "f", "v")
+	exec(cds.XReadGroup, cds.Group, "g", "alice", cds.Streams, "s", ">")
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("0-0")), entryArray("3-0", "4-0"), token.NewArray([]*token.Token{}...)),
+		exec(cds.XAutoClaim, "s", "g", "bob", "0", "-"))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("4-0")),
+		token.NewArray(token.NewBulked([]byte("3-0"))), token.NewArray([]*token.Token{}...)),
+		exec(cds.XAutoClaim, "s", "g", "alice", "0", "-", cds.Count, "1", cds.JustID))
+
+	groups := exec(cds.XInfo, cds.Groups, "s").Data.([]*token.Token)
+	assert.Equal(t, 2, len(groups))
+	assert.Equal(t, token.NewArray(
+		token.NewBulked([]byte("name")), token.NewBulked([]byte("g")),
+		token.NewBulked([]byte("consumers")), token.NewInteger(3),
+		token.NewBulked([]byte("pending")), token.NewInteger(2),
+		token.NewBulked([]byte("last-delivered-id")), token.NewBulked([]byte("4-0")),
+		token.NewBulked([]byte("entries-read")), token.NewInteger(4),
+		token.NewBulked([]byte("lag")), token.NewInteger(0)), groups[0])
+	info := exec(cds.XInfo, cds.StreamArg, "s").Data.([]*token.Token)
+	assert.Equal(t, int64(3), info[1].Data)
+	assert.Equal(t, []byte("2-0"), info[7].Data)
+	assert.Equal(t, 3, len(exec(cds.XInfo, cds.Consumers, "s", "g").Data.([]*token.Token)))
+	assert.Equal(t, int64(1), exec(cds.XGroup, cds.DelConsumer, "s", "g", "alice").Data)
+	assert.Equal(t, int64(1), exec(cds.XGroup, cds.Destroy, "s", "last").Data)
+
+	// the data propagated is replayed to the same stream and groups
+	p2 := NewProcessor(1)
+	mock := p2.NewClient(nil)
+	for _, m := range p.aof {
+		assert.NotEqual(t, label.Error, p2.execCmd(mock, m.T).Label, argsOf(m.T))
+	}
+	for _, req := range []*token.Token{
+		streamCmd(cds.XRange, "s", "-", "+"),
+		streamCmd(cds.XInfo, cds.Groups, "s"),
+		streamCmd(cds.XInfo, cds.Groups, "empty"),
+		streamCmd(cds.XPending, "s", "g"),
+	} {
+		assert.Equal(t, p.execCmd(c, req), p2.execCmd(mock, req))
+	}
+	pe := c.Get("s").(*model.Stream).Group("g").GetPending(model.StreamID{Ms: 4})
+	pe2 := mock.Get("s").(*model.Stream).Group("g").GetPending(model.StreamID{Ms: 4})
+	assert.Equal(t, pe.Consumer.Name, pe2.Consumer.Name)
+	assert.Equal(t, pe.DeliveryTime, pe2.DeliveryTime)
+	assert.Equal(t, pe.DeliveryCount, pe2.DeliveryCount)
+}
+
+func TestProcessor_xRead_block(t *testing.T) {
+	p := NewProcessor(1)
+	p.Msgs.Set = make(chan *SetMsg, 16)
+	do := func(c *model.Client, cmd string, args ...string) *token.Token {
+		rsp := make(chan *token.Token, 1)
+		p.Do(&model.CmdTask{Cli: c, Req: streamCmd(cmd, args...), Rsp: rsp})
+		return <-rsp
+	}
+	reader, writer := p.NewClient(nil), p.NewClient(nil)
+	assert.Equal(t, token.NewNilArray(), do(reader, cds.XRead, cds.Streams, "s", "$"))
+	reader.Out.Pop()
+	assert.Nil(t, do(reader, cds.XRead, cds.Block, "0", cds.Streams, "s", "$"))
+	assert.NotNil(t, reader.Block)
+	// requests are executed after the client is unblocked
+	assert.Nil(t, do(reader, cds.Ping))
+	assert.Equal(t, []byte("1-0"), do(writer, cds.XAdd, "s", "1", "f", "v").Data)
+	assert.Nil(t, reader.Block)
+	assert.Equal(t, []*token.Token{
+		token.NewArray(token.NewArray(token.NewBulked([]byte("s")), entryArray("1-0"))),
+		token.NewString(strPong),
+	}, reader.Out.Pop())
+
+	// the entries delivered by the blocked xreadgroup are propagated
+	assert.Equal(t, token.ReplyOk.Data, do(writer, cds.XGroup, cds.Create, "s", "g", "$").Data)
+	assert.Nil(t, do(reader, cds.XReadGroup, cds.Group, "g", "c", cds.Block, "0", cds.Streams, "s", ">"))
+	assert.Equal(t, 0, len(reader.Out.Pop()))
+	do(writer, cds.XAdd, "s", "2", "f", "v")
+	assert.Equal(t, []*token.Token{
+		token.NewArray(token.NewArray(token.NewBulked([]byte("s")), entryArray("2-0"))),
+	}, reader.Out.Pop())
+	var reqs []string
+	for len(p.Msgs.Set) > 0 {
+		reqs = append(reqs, (<-p.Msgs.Set).T.Data.([]*token.Token)[0].Data.(string))
+	}
+	assert.Equal(t, []string{cds.XAdd, cds.XGroup, cds.XGroup, cds.XAdd, cds.XClaim, cds.XGroup}, reqs)
+
+	// the timeout reply is returned after the deadline
+	assert.Nil(t, do(reader, cds.XRead, cds.Block, "1", cds.Streams, "s", "$"))
+	<-time.After(2 * time.Millisecond)
+	rsp := make(chan time.Duration, 1)
+	p.Do(&model.ExpireTask{Rsp: rsp})
+	<-rsp
+	assert.Nil(t, reader.Block)
+	assert.Equal(t, []*token.Token{token.NewNilArray()}, reader.Out.Pop())
+	assert.Equal(t, 0, len(p.blocking.clients))
+
+	// the client closed is unblocked
+	assert.Nil(t, do(reader, cds.XRead, cds.Block, "0", cds.Streams, "s", "$"))
+	p.Do(&model.CloseTask{Cli: reader, Rsp: make(chan struct{}, 1)})
+	assert.Equal(t, 0, len(p.blocking.clients))
+	assert.Equal(t, token.NewError("ERR "+errTimeoutNegative.Error()), do(writer, cds.XRead, cds.Block, "-1", cds.Streams, "s", "$"))
+}
+
+func TestProcessor_GenBin_stream(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	exec := func(cmd string, args ...string) *token.Token {
+		return p.execCmd(c, streamCmd(cmd, args...))
+	}
+	for i := 1; i <= 3; i++ {
+		exec(cds.XAdd, "s", strconv.Itoa(i), "f", "v")
+	}
+	exec(cds.XDel, "s", "2")
+	exec(cds.XGroup, cds.Create, "s", "g", "0")
+	exec(cds.XGroup, cds.CreateConsumer, "s", "g", "idle")
+	exec(cds.XReadGroup, cds.Group, "g", "c", cds.Count, "1", cds.Streams, "s", ">")
+	exec(cds.XGroup, cds.Create, "empty", "g", "$", cds.MkStream)
+	exec(cds.PExpire, "empty", "100000")
+
+	p2 := NewProcessor(1)
+	mock := p2.NewClient(nil)
+	_ = p.data[0].Freeze()
+	for key, item := range p.data[0].GetOrigin() {
+		for _, req := range dumpItem(key, item) {
+			assert.NotEqual(t, label.Error, p2.execCmd(mock, req).Label, argsOf(req))
+		}
+	}
+	_ = p.data[0].ToMove()
+	for _, req := range []*token.Token{
+		streamCmd(cds.XRange, "s", "-", "+"),
+		streamCmd(cds.XInfo, cds.Groups, "s"),
+		streamCmd(cds.XInfo, cds.Groups, "empty"),
+		streamCmd(cds.XPending, "s", "g"),
+		streamCmd(cds.XLen, "empty"),
+	} {
+		assert.Equal(t, p.execCmd(c, req), p2.execCmd(mock, req))
+	}
+	info, info2 := exec(cds.XInfo, cds.StreamArg, "s"), p2.execCmd(mock, streamCmd(cds.XInfo, cds.StreamArg, "s"))
+	assert.Equal(t, info, info2)
+	assert.Equal(t, 2, len(p2.execCmd(mock, streamCmd(cds.XInfo, cds.Consumers, "s", "g")).Data.([]*token.Token)))
+	assert.Less(t, int64(0), p2.execCmd(mock, streamCmd(cds.PTTL, "empty")).Data)
+}
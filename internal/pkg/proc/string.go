@@ -0,0 +1,325 @@
+package proc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// maxStringSize is the max length of string values like proto-max-bulk-len of redis
+const maxStringSize = 512 << 20
+
+var (
+	errStringSize  = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+	errOffsetRange = errors.New("offset is out of range")
+)
+
+// getString returns the value of key as bytes, nil if the key does not
+// exist. The bytes returned should not be modified in place.
+func getString(cli *model.Client, key string) ([]byte, error) {
+	v := cli.Get(key)
+	if v == nil {
+		return nil, nil
+	}
+	if !isString(v) {
+		return nil, errWrongType
+	}
+	data, err := ItfToBulked(v)
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
+
+// stringReply returns the bulked reply of data, nil reply if data is nil
+func stringReply(data []byte) *token.Token {
+	if data == nil {
+		return token.NewBulked(nil)
+	}
+	return token.NewBulked(data)
+}
+
+// setKeepTTL replaces the string of key without changing its expiration and
+// publishes the event of the command
+func setKeepTTL(cli *model.Client, key string, value interface{}, event string) {
+	expire, _ := cli.GetExpire(key)
+	cli.Set(key, value, expire)
+	cli.Notify(model.NotifyString, event, key)
+}
+
+// append appends the value to the string of key and returns the length
+func (p *Processor) append(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	value, err := tokenToBytes(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	old, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if len(old)+len(value) > maxStringSize {
+		return token.NewError(errStringSize.Error())
+	}
+	// the bytes stored may be shared, so a new one is allocated
+	data := make([]byte, 0, len(old)+len(value))
+	data = append(append(data, old...), value...)
+	setKeepTTL(cli, key, data, model.EventAppend)
+	return token.NewInteger(int64(len(data)))
+}
+
+func (p *Processor) strLen(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	data, err := getString(cli, tokens[0].Data.(string))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	return token.NewInteger(int64(len(data)))
+}
+
+// getRange returns the substring between start and end which are inclusive
+// and may be negative counting from the tail.
+func (p *Processor) getRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "start", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[2], "end", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	data, err := getString(cli, tokens[0].Data.(string))
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	start, end, ok := normRange(tokens[1].Data.(int64), tokens[2].Data.(int64), int64(len(data)))
+	if !ok {
+		return token.NewBulked([]byte{})
+	}
+	return token.NewBulked(data[start : end+1])
+}
+
+// setRange overwrites the string of key from offset with the value, the
+// string is padded with zero bytes if shorter than offset.
+func (p *Processor) setRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "offset", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	value, err := tokenToBytes(tokens[2])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key, offset := tokens[0].Data.(string), tokens[1].Data.(int64)
+	if offset < 0 {
+		return token.NewError(errOffsetRange.Error())
+	}
+	old, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	// nothing is changed if value is empty, and the key is not created
+	if len(value) == 0 {
+		p.propagateAs()
+		return token.NewInteger(int64(len(old)))
+	}
+	if offset > maxStringSize-int64(len(value)) {
+		return token.NewError(errStringSize.Error())
+	}
+	n := len(old)
+	if end := int(offset) + len(value); end > n {
+		n = end
+	}
+	data := make([]byte, n)
+	copy(data, old)
+	copy(data[offset:], value)
+	setKeepTTL(cli, key, data, model.EventSetRange)
+	return token.NewInteger(int64(n))
+}
+
+// mGet returns the values of keys, nil for the key not existing or not
+// holding a string.
+func (p *Processor) mGet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens); err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(tokens))
+	for _, key := range tokens {
+		data, _ := getString(cli, key.Data.(string))
+		ts = append(ts, stringReply(data))
+	}
+	return token.NewArray(ts...)
+}
+
+// parseKeyValues parses pairs of key and value, all of them are checked
+// before anything is changed.
+func parseKeyValues(tokens []*token.Token) ([]string, [][]byte, error) {
+	if len(tokens) < 2 || len(tokens)%2 != 0 {
+		return nil, nil, errArgNumber
+	}
+	keys := make([]string, 0, len(tokens)/2)
+	values := make([][]byte, 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		if err := checkKeyType(tokens[i]); err != nil {
+			return nil, nil, err
+		}
+		value, err := tokenToBytes(tokens[i+1])
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, tokens[i].Data.(string))
+		values = append(values, value)
+	}
+	return keys, values, nil
+}
+
+// mSet sets all the pairs of key and value
+func (p *Processor) mSet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	keys, values, err := parseKeyValues(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	for i, key := range keys {
+		cli.Set(key, values[i], 0)
+		cli.Notify(model.NotifyString, model.EventSet, key)
+	}
+	return token.ReplyOk
+}
+
+// mSetNX sets all the pairs only if none of the keys exists, returns 1 if set
+func (p *Processor) mSetNX(cli *model.Client, tokens ...*token.Token) *token.Token {
+	keys, values, err := parseKeyValues(tokens)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	for _, key := range keys {
+		if cli.Get(key) != nil {
+			p.propagateAs()
+			return token.NewInteger(0)
+		}
+	}
+	for i, key := range keys {
+		cli.Set(key, values[i], 0)
+		cli.Notify(model.NotifyString, model.EventSet, key)
+	}
+	return token.NewInteger(1)
+}
+
+// getSet sets the value of key and returns the old one
+func (p *Processor) getSet(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	value, err := tokenToBytes(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	old, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	cli.Set(key, value, 0)
+	cli.Notify(model.NotifyString, model.EventSet, key)
+	return stringReply(old)
+}
+
+// getDel deletes the key and returns its value
+func (p *Processor) getDel(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	data, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if data == nil {
+		p.propagateAs()
+		return token.NewBulked(nil)
+	}
+	cli.Del(key)
+	p.propagateAs(token.NewArray(token.NewString(cds.Del), tokens[0]))
+	return token.NewBulked(data)
+}
+
+// getEx returns the value of key and sets its expiration by one of options
+// "EX seconds", "PX milliseconds", "EXAT timestamp", "PXAT timestamp-ms" and
+// "PERSIST".
+func (p *Processor) getEx(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	var expire int64
+	var persist, set bool
+	for i := 1; i < len(tokens); i++ {
+		arg, ok := optionOf(tokens[i])
+		if !ok || set {
+			return token.NewError(errSyntax.Error())
+		}
+		set = true
+		if arg == cds.PersistArg {
+			persist = true
+			continue
+		}
+		var unit time.Duration
+		abs := arg == cds.ExpireAtSec || arg == cds.ExpireAtMilSec
+		switch arg {
+		case cds.TimeoutSec, cds.ExpireAtSec:
+			unit = time.Second
+		case cds.TimeoutMilSec, cds.ExpireAtMilSec:
+			unit = time.Millisecond
+		default:
+			return token.NewError(errSyntax.Error())
+		}
+		i++
+		if len(tokens) < i+1 {
+			return token.NewError(errArgMissing(arg).Error())
+		}
+		if err := checkType(tokens[i], "timeout", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		if tokens[i].Data.(int64) <= 0 {
+			return token.NewError(eStrExpireInvalid)
+		}
+		var err error
+		if expire, err = toExpire(tokens[i].Data.(int64), unit, abs); err != nil {
+			return token.NewError(err.Error())
+		}
+	}
+	key := tokens[0].Data.(string)
+	data, err := getString(cli, key)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	switch {
+	case data == nil || !set:
+		p.propagateAs()
+	case persist:
+		if cur, _ := cli.GetExpire(key); cur == 0 {
+			p.propagateAs()
+			break
+		}
+		cli.SetExpire(key, 0)
+		p.propagateAs(token.NewArray(token.NewString(cds.Persist), tokens[0]))
+	case expire <= time.Now().UnixNano():
+		cli.Del(key)
+		p.propagateAs(token.NewArray(token.NewString(cds.Del), tokens[0]))
+	default:
+		cli.SetExpire(key, expire)
+		p.propagateAs(expireAtToken(key, expire))
+	}
+	return stringReply(data)
+}
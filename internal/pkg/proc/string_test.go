@@ -0,0 +1,170 @@
+package proc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_append(t *testing.T) {
+	key := token.NewString("t_append")
+	assert.Equal(t, token.NewInteger(5), proc.append(cli, key, token.NewString("hello")))
+	assert.Equal(t, token.NewInteger(1), proc.expire(cli, key, token.NewInteger(100)))
+	assert.Equal(t, token.NewInteger(11), proc.append(cli, key, token.NewBulked([]byte(" world"))))
+	assert.Equal(t, token.NewBulked([]byte("hello world")), proc.get(cli, key))
+	assert.Equal(t, token.NewInteger(11), proc.strLen(cli, key))
+	expire, _ := cli.GetExpire("t_append")
+	assert.True(t, expire > 0)
+
+	proc.set(cli, token.NewString("t_append_int"), token.NewInteger(12))
+	assert.Equal(t, token.NewInteger(2), proc.strLen(cli, token.NewString("t_append_int")))
+	assert.Equal(t, token.NewInteger(3), proc.append(cli, token.NewString("t_append_int"), token.NewInteger(3)))
+	assert.Equal(t, token.NewInteger(124), proc.incr(cli, token.NewString("t_append_int")))
+	assert.Equal(t, token.NewInteger(0), proc.strLen(cli, token.NewString("t_append_none")))
+
+	proc.rPush(cli, token.NewString("t_append_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.append(cli, token.NewString("t_append_list"), token.NewString("a")))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.strLen(cli, token.NewString("t_append_list")))
+}
+
+func TestProcessor_range(t *testing.T) {
+	key := token.NewString("t_range")
+	proc.set(cli, key, token.NewString("This is a string"))
+	rangeOf := func(start, end int64) *token.Token {
+		return proc.getRange(cli, key, token.NewInteger(start), token.NewInteger(end))
+	}
+	assert.Equal(t, token.NewBulked([]byte("This")), rangeOf(0, 3))
+	assert.Equal(t, token.NewBulked([]byte("ing")), rangeOf(-3, -1))
+	assert.Equal(t, token.NewBulked([]byte("This is a string")), rangeOf(0, -1))
+	assert.Equal(t, token.NewBulked([]byte("string")), rangeOf(10, 100))
+	assert.Equal(t, token.NewBulked([]byte{}), rangeOf(5, 3))
+	assert.Equal(t, token.NewBulked([]byte{}), proc.getRange(cli, token.NewString("t_range_none"),
+		token.NewInteger(0), token.NewInteger(-1)))
+
+	assert.Equal(t, token.NewInteger(16), proc.setRange(cli, key, token.NewInteger(10), token.NewString("STRING")))
+	assert.Equal(t, token.NewBulked([]byte("This is a STRING")), proc.get(cli, key))
+	// zero bytes are padded
+	pad := token.NewString("t_range_pad")
+	assert.Equal(t, token.NewInteger(6), proc.setRange(cli, pad, token.NewInteger(3), token.NewBulked([]byte{0xff, 0, 1})))
+	assert.Equal(t, token.NewBulked([]byte{0, 0, 0, 0xff, 0, 1}), proc.get(cli, pad))
+	assert.Equal(t, token.NewInteger(6), proc.setRange(cli, pad, token.NewInteger(1), token.NewString("a")))
+	assert.Equal(t, token.NewBulked([]byte{0, 'a', 0, 0xff, 0, 1}), proc.get(cli, pad))
+	// empty value doesn't create the key
+	assert.Equal(t, token.NewInteger(0), proc.setRange(cli, token.NewString("t_range_none"), token.NewInteger(3), token.NewString("")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, token.NewString("t_range_none")))
+	assert.Equal(t, token.NewError(errOffsetRange.Error()), proc.setRange(cli, key, token.NewInteger(-1), token.NewString("a")))
+	assert.Equal(t, token.NewError(errStringSize.Error()),
+		proc.setRange(cli, key, token.NewInteger(maxStringSize), token.NewString("a")))
+	assert.Equal(t, token.NewError(errStringSize.Error()),
+		proc.setRange(cli, key, token.NewInteger(math.MaxInt64), token.NewString("a")))
+	assert.Equal(t, token.NewError(errStringSize.Error()),
+		proc.setRange(cli, token.NewString("t_range_none"), token.NewInteger(math.MaxInt64), token.NewString("a")))
+}
+
+func TestProcessor_mSet(t *testing.T) {
+	a, b, c := token.NewString("t_mset_a"), token.NewString("t_mset_b"), token.NewString("t_mset_c")
+	assert.Equal(t, token.ReplyOk, proc.mSet(cli, a, token.NewString("1"), b, token.NewInteger(2)))
+	proc.sAdd(cli, c, token.NewString("1"))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("1")), token.NewBulked([]byte("2")), token.NewBulked(nil),
+		token.NewBulked(nil)), proc.mGet(cli, a, b, c, token.NewString("t_mset_none")))
+	assert.Equal(t, token.NewError(errArgNumber.Error()), proc.mSet(cli, a, token.NewString("1"), b))
+	// nothing is set if any argument is invalid
+	assert.NotNil(t, proc.mSet(cli, a, token.NewString("3"), token.NewInteger(1), token.NewString("1")).Error())
+	assert.Equal(t, token.NewBulked([]byte("1")), proc.get(cli, a))
+
+	d, e := token.NewString("t_mset_d"), token.NewString("t_mset_e")
+	assert.Equal(t, token.NewInteger(0), proc.mSetNX(cli, d, token.NewString("4"), a, token.NewString("4")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, d))
+	assert.Equal(t, token.NewInteger(1), proc.mSetNX(cli, d, token.NewString("4"), e, token.NewString("5")))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("4")), token.NewBulked([]byte("5"))), proc.mGet(cli, d, e))
+}
+
+func TestProcessor_getSet(t *testing.T) {
+	key := token.NewString("t_getset")
+	assert.Equal(t, token.NewBulked(nil), proc.getSet(cli, key, token.NewString("1")))
+	assert.Equal(t, token.NewBulked([]byte("1")), proc.getSet(cli, key, token.NewString("2")))
+	assert.Equal(t, token.NewBulked([]byte("2")), proc.getDel(cli, key))
+	assert.Equal(t, token.NewBulked(nil), proc.getDel(cli, key))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+	proc.rPush(cli, token.NewString("t_getset_list"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.getSet(cli, token.NewString("t_getset_list"), token.NewString("1")))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.getDel(cli, token.NewString("t_getset_list")))
+}
+
+func TestProcessor_getEx(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	key := token.NewString("t_getex")
+	exec := func(ts ...*token.Token) *token.Token {
+		return p.execCmd(c, token.NewArray(append([]*token.Token{token.NewString(cds.GetEx), key}, ts...)...))
+	}
+	assert.Nil(t, exec(token.NewString(cds.TimeoutSec), token.NewInteger(10)).Data)
+	assert.Equal(t, 0, len(p.aof))
+	p.execCmd(c, token.NewArray(token.NewString(cds.Set), key, token.NewString("v")))
+	p.aof = nil
+
+	assert.Equal(t, []byte("v"), exec().Data)
+	assert.Equal(t, 0, len(p.aof))
+	assert.Equal(t, []byte("v"), exec(token.NewString("ex"), token.NewInteger(10)).Data)
+	expire, _ := c.GetExpire("t_getex")
+	assert.InDelta(t, time.Now().Add(10*time.Second).UnixNano(), expire, float64(time.Second))
+	assert.Equal(t, expireAtToken("t_getex", expire), p.aof[0].T)
+
+	at := time.Now().Add(time.Hour).Unix()
+	exec(token.NewString(cds.ExpireAtSec), token.NewInteger(at))
+	expire, _ = c.GetExpire("t_getex")
+	assert.Equal(t, at*int64(time.Second), expire)
+
+	assert.Equal(t, []byte("v"), exec(token.NewString(cds.PersistArg)).Data)
+	expire, _ = c.GetExpire("t_getex")
+	assert.Equal(t, int64(0), expire)
+	assert.Equal(t, token.NewArray(token.NewString(cds.Persist), key), p.aof[2].T)
+	exec(token.NewString(cds.PersistArg))
+	assert.Equal(t, 3, len(p.aof))
+
+	assert.Equal(t, token.NewError("ERR "+eStrExpireInvalid), exec(token.NewString(cds.TimeoutMilSec), token.NewInteger(0)))
+	assert.Equal(t, token.NewError("ERR "+errSyntax.Error()), exec(token.NewString(cds.PersistArg), token.NewString(cds.TimeoutSec),
+		token.NewInteger(1)))
+	assert.Equal(t, token.NewError("ERR "+errArgMissing(cds.TimeoutSec).Error()), exec(token.NewString(cds.TimeoutSec)))
+
+	// expired at once
+	assert.Equal(t, []byte("v"), exec(token.NewString(cds.ExpireAtMilSec), token.NewInteger(1)).Data)
+	assert.Equal(t, token.NewInteger(0), p.exists(c, key))
+	assert.Equal(t, token.NewArray(token.NewString(cds.Del), key), p.aof[3].T)
+}
+
+func TestProcessor_string_propagate(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	for _, req := range [][]*token.Token{
+		{token.NewString(cds.Append), token.NewString("a"), token.NewString("1")},
+		{token.NewString(cds.SetRange), token.NewString("a"), token.NewInteger(3), token.NewString("2")},
+		{token.NewString(cds.MSet), token.NewString("b"), token.NewString("1"), token.NewString("c"), token.NewString("2")},
+		{token.NewString(cds.MSetNX), token.NewString("d"), token.NewString("1")},
+		{token.NewString(cds.GetSet), token.NewString("b"), token.NewString("3")},
+		{token.NewString(cds.GetDel), token.NewString("c")},
+	} {
+		reply := p.execCmd(c, token.NewArray(req...))
+		assert.Nil(t, reply.Error())
+		assert.Equal(t, uint64(token.FlagSet), reply.Flag&token.FlagSet)
+	}
+	assert.Equal(t, 6, len(p.aof))
+	// failed commands are not propagated
+	p.execCmd(c, token.NewArray(token.NewString(cds.MSetNX), token.NewString("d"), token.NewString("1")))
+	p.execCmd(c, token.NewArray(token.NewString(cds.GetDel), token.NewString("c")))
+	assert.Equal(t, 6, len(p.aof))
+
+	// replay the requests propagated
+	mock := model.NewClient(nil, NewProcessor(1).data[0])
+	for _, m := range p.aof {
+		proc.execCmd(mock, m.T)
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		assert.Equal(t, p.get(c, token.NewString(key)), proc.get(mock, token.NewString(key)))
+	}
+}
@@ -0,0 +1,547 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+// cmdFlag describes how a command is executed, the same as redis
+type cmdFlag uint32
+
+const (
+	// the command may modify the data, which is propagated to the aof
+	flagWrite cmdFlag = 1 << iota
+	flagReadOnly
+	// the command may increase the memory used
+	flagDenyOOM
+	flagAdmin
+	flagPubSub
+	flagNoScript
+	flagBlocking
+	flagLoading
+	flagStale
+	flagFast
+	// the command is executed at once instead of being queued by MULTI
+	flagNoMulti
+)
+
+// names of flags in the order of bits
+var cmdFlagNames = []string{
+	"write", "readonly", "denyoom", "admin", "pubsub", "noscript", "blocking", "loading", "stale", "fast", "no_multi",
+}
+
+// groups of commands documented
+const (
+	groupBitmap      = "bitmap"
+	groupConnection  = "connection"
+	groupGeneric     = "generic"
+	groupGeo         = "geo"
+	groupHash        = "hash"
+	groupHyperLogLog = "hyperloglog"
+	groupList        = "list"
+	groupPubSub      = "pubsub"
+	groupServer      = "server"
+	groupSet         = "set"
+	groupSortedSet   = "sorted-set"
+	groupStream      = "stream"
+	groupString      = "string"
+	groupTransaction = "transactions"
+)
+
+// categories of acl implied by groups
+var groupCategories = map[string]string{
+	groupBitmap:      "@bitmap",
+	groupConnection:  "@connection",
+	groupGeneric:     "@keyspace",
+	groupGeo:         "@geo",
+	groupHash:        "@hash",
+	groupHyperLogLog: "@hyperloglog",
+	groupList:        "@list",
+	groupPubSub:      "@pubsub",
+	groupSet:         "@set",
+	groupSortedSet:   "@sortedset",
+	groupStream:      "@stream",
+	groupString:      "@string",
+	groupTransaction: "@transaction",
+}
+
+// command is an entry of the command table
+type command struct {
+	name string
+	proc func(*model.Client, ...*token.Token) *token.Token
+	// the number of arguments including the name if positive, or the
+	// minimum number if negative
+	arity int
+	flags cmdFlag
+	// positions of the first and the last key in the arguments including
+	// the name, the last is counted from the end if negative
+	firstKey, lastKey, step int
+	group                   string
+	summary                 string
+}
+
+// movableKeys returns positions of keys of commands whose keys can't be
+// described by the first and the last position, e.g. by "numkeys"
+var movableKeys = map[string]func([]*token.Token) []int{
+	cds.SInterCard:  numKeysAt(1),
+	cds.XRead:       streamKeys,
+	cds.XReadGroup:  streamKeys,
+	cds.ZInterStore: numKeysAt(2),
+	cds.ZUnionStore: numKeysAt(2),
+}
+
+// commandTable returns the commands supported by the processor
+func (p *Processor) commandTable() []*command {
+	const (
+		r   = flagReadOnly
+		rf  = flagReadOnly | flagFast
+		w   = flagWrite
+		wf  = flagWrite | flagFast
+		wm  = flagWrite | flagDenyOOM
+		wmf = flagWrite | flagDenyOOM | flagFast
+		wb  = flagWrite | flagNoScript | flagBlocking
+		ls  = flagLoading | flagStale
+		tx  = flagNoScript | flagLoading | flagStale | flagFast
+	)
+	return []*command{
+		{cds.Append, p.append, 3, wmf, 1, 1, 1, groupString, "Append a value to the string of a key"},
+		{cds.Decr, p.decr, 2, wmf, 1, 1, 1, groupString, "Decrement the integer of a key by one"},
+		{cds.DecrBy, p.decrBy, 3, wmf, 1, 1, 1, groupString, "Decrement the integer of a key by a number"},
+		{cds.Desc, p.decr, 2, wmf, 1, 1, 1, groupString, "Decrement the integer of a key by one, the same as DECR"},
+		{cds.Get, p.get, 2, rf, 1, 1, 1, groupString, "Get the value of a key"},
+		{cds.GetDel, p.getDel, 2, wf, 1, 1, 1, groupString, "Get the value of a key and delete the key"},
+		{cds.GetEx, p.getEx, -2, wf, 1, 1, 1, groupString, "Get the value of a key and set its expiration"},
+		{cds.GetRange, p.getRange, 4, r, 1, 1, 1, groupString, "Get a substring of the string of a key"},
+		{cds.GetSet, p.getSet, 3, wmf, 1, 1, 1, groupString, "Set the value of a key and return the old value"},
+		{cds.Incr, p.incr, 2, wmf, 1, 1, 1, groupString, "Increment the integer of a key by one"},
+		{cds.IncrBy, p.incrBy, 3, wmf, 1, 1, 1, groupString, "Increment the integer of a key by a number"},
+		{cds.IncrByFlt, p.incrByFloat, 3, wmf, 1, 1, 1, groupString, "Increment the float of a key by a number"},
+		{cds.MGet, p.mGet, -2, rf, 1, -1, 1, groupString, "Get the values of keys"},
+		{cds.MSet, p.mSet, -3, wm, 1, -1, 2, groupString, "Set the values of keys"},
+		{cds.MSetNX, p.mSetNX, -3, wm, 1, -1, 2, groupString, "Set the values of keys only if none of them exists"},
+		{cds.PSetEx, p.pSetEx, 4, wm, 1, 1, 1, groupString, "Set the value of a key expiring in milliseconds"},
+		{cds.Set, p.set, -3, wm, 1, 1, 1, groupString, "Set the value of a key"},
+		{cds.SetEx, p.setEx, 4, wm, 1, 1, 1, groupString, "Set the value of a key expiring in seconds"},
+		{cds.SetNX, p.setNX, 3, wmf, 1, 1, 1, groupString, "Set the value of a key only if it doesn't exist"},
+		{cds.SetRange, p.setRange, 4, wm, 1, 1, 1, groupString, "Overwrite a part of the string of a key"},
+		{cds.StrLen, p.strLen, 2, rf, 1, 1, 1, groupString, "Get the length of the string of a key"},
+
+		{cds.BitCount, p.bitCount, -2, r, 1, 1, 1, groupBitmap, "Count the bits set in a string"},
+		{cds.BitField, p.bitfield, -2, wm, 1, 1, 1, groupBitmap, "Perform integer operations on bit fields of a string"},
+		{cds.BitFieldRO, p.bitfieldRO, -2, rf, 1, 1, 1, groupBitmap, "Get integers of bit fields of a string"},
+		{cds.BitOp, p.bitOp, -4, wm, 2, -1, 1, groupBitmap, "Perform bitwise operations between strings"},
+		{cds.BitPos, p.bitPos, -3, r, 1, 1, 1, groupBitmap, "Find the first bit set or clear in a string"},
+		{cds.GetBit, p.getBit, 3, rf, 1, 1, 1, groupBitmap, "Get the bit at an offset of a string"},
+		{cds.SetBit, p.setBit, 4, wm, 1, 1, 1, groupBitmap, "Set the bit at an offset of a string"},
+
+		{cds.PFAdd, p.pfAdd, -2, wmf, 1, 1, 1, groupHyperLogLog, "Add elements to a hyperloglog"},
+		{cds.PFCount, p.pfCount, -2, r, 1, -1, 1, groupHyperLogLog, "Get the approximated cardinality of hyperloglogs"},
+		{cds.PFMerge, p.pfMerge, -2, wm, 1, -1, 1, groupHyperLogLog, "Merge hyperloglogs into one"},
+
+		{cds.HDel, p.hDel, -3, wf, 1, 1, 1, groupHash, "Delete fields of a hash"},
+		{cds.HExists, p.hExists, 3, rf, 1, 1, 1, groupHash, "Determine whether a field exists in a hash"},
+		{cds.HGet, p.hGet, 3, rf, 1, 1, 1, groupHash, "Get the value of a field of a hash"},
+		{cds.HGetAll, p.hGetAll, 2, r, 1, 1, 1, groupHash, "Get all the fields and values of a hash"},
+		{cds.HIncrBy, p.hIncrBy, 4, wmf, 1, 1, 1, groupHash, "Increment the integer of a field of a hash"},
+		{cds.HIncrByFlt, p.hIncrByFloat, 4, wmf, 1, 1, 1, groupHash, "Increment the float of a field of a hash"},
+		{cds.HKeys, p.hKeys, 2, r, 1, 1, 1, groupHash, "Get all the fields of a hash"},
+		{cds.HLen, p.hLen, 2, rf, 1, 1, 1, groupHash, "Get the number of fields of a hash"},
+		{cds.HMGet, p.hMGet, -3, rf, 1, 1, 1, groupHash, "Get the values of fields of a hash"},
+		{cds.HMSet, p.hMSet, -4, wmf, 1, 1, 1, groupHash, "Set the values of fields of a hash"},
+		{cds.HRandField, p.hRandField, -2, r, 1, 1, 1, groupHash, "Get random fields of a hash"},
+		{cds.HScan, p.hScan, -3, r, 1, 1, 1, groupHash, "Iterate the fields and values of a hash"},
+		{cds.HSet, p.hSet, -4, wmf, 1, 1, 1, groupHash, "Set the values of fields of a hash"},
+		{cds.HSetNX, p.hSetNX, 4, wmf, 1, 1, 1, groupHash, "Set the value of a field of a hash only if it doesn't exist"},
+		{cds.HStrLen, p.hStrLen, 3, rf, 1, 1, 1, groupHash, "Get the length of the value of a field of a hash"},
+		{cds.HVals, p.hVals, 2, r, 1, 1, 1, groupHash, "Get all the values of a hash"},
+
+		{cds.BLMove, p.bLMove, 6, wb | flagDenyOOM, 1, 2, 1, groupList, "Pop an element from a list and push it to another, blocking until available"},
+		{cds.BLPop, p.bLPop, -3, wb, 1, -2, 1, groupList, "Pop the first element of lists, blocking until available"},
+		{cds.BRPop, p.bRPop, -3, wb, 1, -2, 1, groupList, "Pop the last element of lists, blocking until available"},
+		{cds.LIndex, p.lIndex, 3, r, 1, 1, 1, groupList, "Get an element of a list by its index"},
+		{cds.LInsert, p.lInsert, 5, wm, 1, 1, 1, groupList, "Insert an element before or after another one of a list"},
+		{cds.LLen, p.lLen, 2, rf, 1, 1, 1, groupList, "Get the length of a list"},
+		{cds.LMove, p.lMove, 5, wm, 1, 2, 1, groupList, "Pop an element from a list and push it to another"},
+		{cds.LPop, p.lPop, -2, wf, 1, 1, 1, groupList, "Pop the first elements of a list"},
+		{cds.LPush, p.lPush, -3, wmf, 1, 1, 1, groupList, "Prepend elements to a list"},
+		{cds.LPushX, p.lPushX, -3, wmf, 1, 1, 1, groupList, "Prepend elements to a list only if it exists"},
+		{cds.LRange, p.lRange, 4, r, 1, 1, 1, groupList, "Get a range of elements of a list"},
+		{cds.LRem, p.lRem, 4, w, 1, 1, 1, groupList, "Remove elements equal to a value from a list"},
+		{cds.LSet, p.lSet, 4, wm, 1, 1, 1, groupList, "Set an element of a list by its index"},
+		{cds.LTrim, p.lTrim, 4, w, 1, 1, 1, groupList, "Trim a list to a range"},
+		{cds.RPop, p.rPop, -2, wf, 1, 1, 1, groupList, "Pop the last elements of a list"},
+		{cds.RPush, p.rPush, -3, wmf, 1, 1, 1, groupList, "Append elements to a list"},
+		{cds.RPushX, p.rPushX, -3, wmf, 1, 1, 1, groupList, "Append elements to a list only if it exists"},
+
+		{cds.SAdd, p.sAdd, -3, wmf, 1, 1, 1, groupSet, "Add members to a set"},
+		{cds.SCard, p.sCard, 2, rf, 1, 1, 1, groupSet, "Get the number of members of a set"},
+		{cds.SDiff, p.sDiff, -2, r, 1, -1, 1, groupSet, "Get the difference of sets"},
+		{cds.SDiffStore, p.sDiffStore, -3, wm, 1, -1, 1, groupSet, "Store the difference of sets in a key"},
+		{cds.SInter, p.sInter, -2, r, 1, -1, 1, groupSet, "Get the intersection of sets"},
+		{cds.SInterCard, p.sInterCard, -3, r, 0, 0, 0, groupSet, "Get the number of members of the intersection of sets"},
+		{cds.SInterStore, p.sInterStore, -3, wm, 1, -1, 1, groupSet, "Store the intersection of sets in a key"},
+		{cds.SIsMember, p.sIsMember, 3, rf, 1, 1, 1, groupSet, "Determine whether a member belongs to a set"},
+		{cds.SMembers, p.sMembers, 2, r, 1, 1, 1, groupSet, "Get all the members of a set"},
+		{cds.SMIsMember, p.sMIsMember, -3, rf, 1, 1, 1, groupSet, "Determine whether members belong to a set"},
+		{cds.SMove, p.sMove, 4, wf, 1, 2, 1, groupSet, "Move a member from a set to another"},
+		{cds.SPop, p.sPop, -2, wf, 1, 1, 1, groupSet, "Pop random members of a set"},
+		{cds.SRandMember, p.sRandMember, -2, r, 1, 1, 1, groupSet, "Get random members of a set"},
+		{cds.SRem, p.sRem, -3, wf, 1, 1, 1, groupSet, "Remove members from a set"},
+		{cds.SScan, p.sScan, -3, r, 1, 1, 1, groupSet, "Iterate the members of a set"},
+		{cds.SUnion, p.sUnion, -2, r, 1, -1, 1, groupSet, "Get the union of sets"},
+		{cds.SUnionStore, p.sUnionStore, -3, wm, 1, -1, 1, groupSet, "Store the union of sets in a key"},
+
+		{cds.BZPopMax, p.bzPopMax, -3, wb | flagFast, 1, -2, 1, groupSortedSet, "Pop the member with the highest score of sorted sets, blocking until available"},
+		{cds.BZPopMin, p.bzPopMin, -3, wb | flagFast, 1, -2, 1, groupSortedSet, "Pop the member with the lowest score of sorted sets, blocking until available"},
+		{cds.ZAdd, p.zAdd, -4, wmf, 1, 1, 1, groupSortedSet, "Add members to a sorted set, or update their scores"},
+		{cds.ZCard, p.zCard, 2, rf, 1, 1, 1, groupSortedSet, "Get the number of members of a sorted set"},
+		{cds.ZCount, p.zCount, 4, rf, 1, 1, 1, groupSortedSet, "Count the members of a sorted set within a range of scores"},
+		{cds.ZIncrBy, p.zIncrBy, 4, wmf, 1, 1, 1, groupSortedSet, "Increment the score of a member of a sorted set"},
+		{cds.ZInterStore, p.zInterStore, -4, wm, 1, 1, 1, groupSortedSet, "Store the intersection of sorted sets in a key"},
+		{cds.ZLexCount, p.zLexCount, 4, rf, 1, 1, 1, groupSortedSet, "Count the members of a sorted set within a lexicographical range"},
+		{cds.ZMScore, p.zMScore, -3, rf, 1, 1, 1, groupSortedSet, "Get the scores of members of a sorted set"},
+		{cds.ZPopMax, p.zPopMax, -2, wf, 1, 1, 1, groupSortedSet, "Pop the members with the highest scores of a sorted set"},
+		{cds.ZPopMin, p.zPopMin, -2, wf, 1, 1, 1, groupSortedSet, "Pop the members with the lowest scores of a sorted set"},
+		{cds.ZRange, p.zRange, -4, r, 1, 1, 1, groupSortedSet, "Get a range of members of a sorted set"},
+		{cds.ZRangeStore, p.zRangeStore, -5, wm, 1, 2, 1, groupSortedSet, "Store a range of members of a sorted set in a key"},
+		{cds.ZRank, p.zRank, 3, rf, 1, 1, 1, groupSortedSet, "Get the index of a member of a sorted set by ascending scores"},
+		{cds.ZRem, p.zRem, -3, wf, 1, 1, 1, groupSortedSet, "Remove members from a sorted set"},
+		{cds.ZRemRngLex, p.zRemRangeByLex, 4, w, 1, 1, 1, groupSortedSet, "Remove the members of a sorted set within a lexicographical range"},
+		{cds.ZRemRngRank, p.zRemRangeByRank, 4, w, 1, 1, 1, groupSortedSet, "Remove the members of a sorted set within a range of indexes"},
+		{cds.ZRemRngScr, p.zRemRangeByScore, 4, w, 1, 1, 1, groupSortedSet, "Remove the members of a sorted set within a range of scores"},
+		{cds.ZRevRank, p.zRevRank, 3, rf, 1, 1, 1, groupSortedSet, "Get the index of a member of a sorted set by descending scores"},
+		{cds.ZScan, p.zScan, -3, r, 1, 1, 1, groupSortedSet, "Iterate the members and scores of a sorted set"},
+		{cds.ZScore, p.zScore, 3, rf, 1, 1, 1, groupSortedSet, "Get the score of a member of a sorted set"},
+		{cds.ZUnionStore, p.zUnionStore, -4, wm, 1, 1, 1, groupSortedSet, "Store the union of sorted sets in a key"},
+
+		{cds.GeoAdd, p.geoAdd, -5, wm, 1, 1, 1, groupGeo, "Add members with coordinates to a geospatial index"},
+		{cds.GeoDist, p.geoDist, -4, r, 1, 1, 1, groupGeo, "Get the distance between members of a geospatial index"},
+		{cds.GeoHash, p.geoHash, -2, r, 1, 1, 1, groupGeo, "Get the geohashes of members of a geospatial index"},
+		{cds.GeoPos, p.geoPos, -2, r, 1, 1, 1, groupGeo, "Get the coordinates of members of a geospatial index"},
+		{cds.GeoSearch, p.geoSearch, -7, r, 1, 1, 1, groupGeo, "Search the members of a geospatial index within an area"},
+		{cds.GeoSearchSt, p.geoSearchStore, -8, wm, 1, 2, 1, groupGeo, "Store the members of a geospatial index within an area in a key"},
+
+		{cds.XAck, p.xAck, -4, wf, 1, 1, 1, groupStream, "Acknowledge messages of a consumer group"},
+		{cds.XAdd, p.xAdd, -5, wmf, 1, 1, 1, groupStream, "Append a message to a stream"},
+		{cds.XAutoClaim, p.xAutoClaim, -6, wf, 1, 1, 1, groupStream, "Claim messages idle for a time of a consumer group"},
+		{cds.XClaim, p.xClaim, -6, wf, 1, 1, 1, groupStream, "Change the owner of messages of a consumer group"},
+		{cds.XDel, p.xDel, -3, wf, 1, 1, 1, groupStream, "Delete messages from a stream"},
+		{cds.XGroup, p.xGroup, -2, wm, 2, 2, 1, groupStream, "Manage consumer groups and consumers of a stream"},
+		{cds.XInfo, p.xInfo, -2, r, 2, 2, 1, groupStream, "Get information about a stream, its groups or consumers"},
+		{cds.XLen, p.xLen, 2, rf, 1, 1, 1, groupStream, "Get the number of messages of a stream"},
+		{cds.XPending, p.xPending, -3, r, 1, 1, 1, groupStream, "Get the pending messages of a consumer group"},
+		{cds.XRange, p.xRange, -4, r, 1, 1, 1, groupStream, "Get the messages of a stream within a range of ids"},
+		{cds.XRead, p.xRead, -4, r | flagBlocking, 0, 0, 0, groupStream, "Read messages from streams, blocking until available"},
+		{cds.XReadGroup, p.xReadGroup, -7, wb, 0, 0, 0, groupStream, "Read messages from streams by a consumer group, blocking until available"},
+		{cds.XRevRange, p.xRevRange, -4, r, 1, 1, 1, groupStream, "Get the messages of a stream within a range of ids in reverse order"},
+		{cds.XSetID, p.xSetID, -3, wmf, 1, 1, 1, groupStream, "Set the last id of a stream"},
+		{cds.XTrim, p.xTrim, -4, w, 1, 1, 1, groupStream, "Trim the messages of a stream"},
+
+		{cds.Copy, p.copy, -3, wm, 1, 2, 1, groupGeneric, "Copy the value of a key to another"},
+		{cds.Del, p.del, -2, w, 1, -1, 1, groupGeneric, "Delete keys"},
+		{cds.Exists, p.exists, -2, rf, 1, -1, 1, groupGeneric, "Count the keys existing"},
+		{cds.Expire, p.expire, -3, wf, 1, 1, 1, groupGeneric, "Set the expiration of a key in seconds"},
+		{cds.ExpireAt, p.expireAt, -3, wf, 1, 1, 1, groupGeneric, "Set the expiration of a key at a unix time in seconds"},
+		{cds.ExpireTime, p.expireTime, 2, rf, 1, 1, 1, groupGeneric, "Get the unix time of the expiration of a key in seconds"},
+		{cds.Keys, p.keys, 2, r, 0, 0, 0, groupGeneric, "Get the keys matching a pattern"},
+		{cds.KeyType, p.keyType, 2, rf, 1, 1, 1, groupGeneric, "Get the type of the value of a key"},
+		{cds.Move, p.move, 3, wf, 1, 1, 1, groupGeneric, "Move a key to another database"},
+		{cds.Object, p.object, -2, r, 2, 2, 1, groupGeneric, "Inspect the internals of the value of a key"},
+		{cds.PExpire, p.pExpire, -3, wf, 1, 1, 1, groupGeneric, "Set the expiration of a key in milliseconds"},
+		{cds.PExpireAt, p.pExpireAt, -3, wf, 1, 1, 1, groupGeneric, "Set the expiration of a key at a unix time in milliseconds"},
+		{cds.PExpireTime, p.pExpireTime, 2, rf, 1, 1, 1, groupGeneric, "Get the unix time of the expiration of a key in milliseconds"},
+		{cds.Persist, p.persist, 2, wf, 1, 1, 1, groupGeneric, "Remove the expiration of a key"},
+		{cds.PTTL, p.pTTL, 2, rf, 1, 1, 1, groupGeneric, "Get the time to live of a key in milliseconds"},
+		{cds.RandomKey, p.randomKey, 1, r, 0, 0, 0, groupGeneric, "Get a random key"},
+		{cds.Rename, p.rename, 3, w, 1, 2, 1, groupGeneric, "Rename a key"},
+		{cds.RenameNX, p.renameNX, 3, wf, 1, 2, 1, groupGeneric, "Rename a key only if the new one doesn't exist"},
+		{cds.Scan, p.scan, -2, r, 0, 0, 0, groupGeneric, "Iterate the keys"},
+		{cds.Touch, p.touch, -2, rf, 1, -1, 1, groupGeneric, "Update the access time of keys"},
+		{cds.TTL, p.ttl, 2, rf, 1, 1, 1, groupGeneric, "Get the time to live of a key in seconds"},
+		{cds.Unlink, p.del, -2, wf, 1, -1, 1, groupGeneric, "Delete keys, the same as DEL"},
+
+		{cds.PSubscribe, p.pSubscribe, -2, flagPubSub | flagNoScript | ls, 0, 0, 0, groupPubSub, "Listen for messages of channels matching patterns"},
+		{cds.PubSub, p.pubSub, -2, flagPubSub | ls, 0, 0, 0, groupPubSub, "Inspect the state of pub/sub"},
+		{cds.Publish, p.publish, 3, flagPubSub | ls | flagFast, 0, 0, 0, groupPubSub, "Post a message to a channel"},
+		{cds.PUnsub, p.pUnsubscribe, -1, flagPubSub | flagNoScript | ls, 0, 0, 0, groupPubSub, "Stop listening for messages of channels matching patterns"},
+		{cds.Subscribe, p.subscribe, -2, flagPubSub | flagNoScript | ls, 0, 0, 0, groupPubSub, "Listen for messages of channels"},
+		{cds.Unsubscribe, p.unsubscribe, -1, flagPubSub | flagNoScript | ls, 0, 0, 0, groupPubSub, "Stop listening for messages of channels"},
+
+		{cds.Discard, p.discard, 1, tx | flagNoMulti, 0, 0, 0, groupTransaction, "Discard the commands queued in a transaction"},
+		{cds.Exec, p.exec, 1, flagNoScript | ls | flagNoMulti, 0, 0, 0, groupTransaction, "Execute the commands queued in a transaction"},
+		{cds.Multi, p.multi, 1, tx | flagNoMulti, 0, 0, 0, groupTransaction, "Start a transaction"},
+		{cds.Unwatch, p.unwatch, 1, tx, 0, 0, 0, groupTransaction, "Forget the keys watched"},
+		{cds.Watch, p.watch, -2, tx | flagNoMulti, 1, -1, 1, groupTransaction, "Watch keys to execute a transaction only if they aren't modified"},
+
+		{cds.Hello, p.hello, -1, tx, 0, 0, 0, groupConnection, "Switch the protocol and get the properties of the server"},
+		{cds.Ping, p.ping, -1, flagFast, 0, 0, 0, groupConnection, "Ping the server"},
+		{cds.Select, p.sel, 2, ls | flagFast, 0, 0, 0, groupConnection, "Change the database selected"},
+
+		{cds.Command, p.commandCmd, -1, ls, 0, 0, 0, groupServer, "Get information about the commands"},
+		{cds.Config, p.config, -2, flagAdmin | flagNoScript | ls, 0, 0, 0, groupServer, "Get or set configuration parameters"},
+		{cds.DBSize, p.dbSize, 1, rf, 0, 0, 0, groupServer, "Get the number of keys of the database selected"},
+		{cds.FlushAll, p.flushAll, -1, w, 0, 0, 0, groupServer, "Delete the keys of all the databases"},
+		{cds.FlushDB, p.flushDB, -1, w, 0, 0, 0, groupServer, "Delete the keys of the database selected"},
+		{cds.Info, p.info, -1, ls, 0, 0, 0, groupServer, "Get information and statistics about the server"},
+		{cds.SwapDB, p.swapDB, 3, wf, 0, 0, 0, groupServer, "Swap the keys of two databases"},
+	}
+}
+
+// numKeysAt returns positions of keys following "numkeys" at the position
+func numKeysAt(pos int) func([]*token.Token) []int {
+	return func(args []*token.Token) []int {
+		var keys []int
+		if pos > 1 {
+			keys = append(keys, 1)
+		}
+		if pos >= len(args) {
+			return keys
+		}
+		data, err := tokenToBytes(args[pos])
+		if err != nil {
+			return keys
+		}
+		n, err := strconv.Atoi(string(data))
+		if err != nil {
+			return keys
+		}
+		for i := 1; i <= n && pos+i < len(args); i++ {
+			keys = append(keys, pos+i)
+		}
+		return keys
+	}
+}
+
+// streamKeys returns positions of keys following "STREAMS", which are the
+// first half of the rest arguments
+func streamKeys(args []*token.Token) []int {
+	for i := 1; i < len(args); i++ {
+		if arg, _ := optionOf(args[i]); arg != cds.Streams {
+			continue
+		}
+		n := (len(args) - i - 1) / 2
+		keys := make([]int, 0, n)
+		for j := 1; j <= n; j++ {
+			keys = append(keys, i+j)
+		}
+		return keys
+	}
+	return nil
+}
+
+// arityMatched returns true if the number of arguments including the name
+// matches the arity
+func (c *command) arityMatched(n int) bool {
+	if c.arity >= 0 {
+		return n == c.arity
+	}
+	return n >= -c.arity
+}
+
+// keys returns positions of keys in the arguments including the name
+func (c *command) keys(args []*token.Token) []int {
+	if getKeys, ok := movableKeys[c.name]; ok {
+		return getKeys(args)
+	}
+	if c.firstKey <= 0 {
+		return nil
+	}
+	last := c.lastKey
+	if last < 0 {
+		last += len(args)
+	}
+	var keys []int
+	for i := c.firstKey; i <= last && i < len(args); i += c.step {
+		keys = append(keys, i)
+	}
+	return keys
+}
+
+// flagNames returns names of the flags, with "movablekeys" if positions of
+// keys are not fixed
+func (c *command) flagNames() []string {
+	var names []string
+	for i, name := range cmdFlagNames {
+		if c.flags&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if _, ok := movableKeys[c.name]; ok {
+		names = append(names, "movablekeys")
+	}
+	return names
+}
+
+// categories returns the acl categories of the command implied by its group
+// and flags like redis
+func (c *command) categories() []string {
+	var categories []string
+	if category, ok := groupCategories[c.group]; ok {
+		categories = append(categories, category)
+	}
+	if c.flags&flagWrite != 0 {
+		categories = append(categories, "@write")
+	}
+	if c.flags&flagReadOnly != 0 {
+		categories = append(categories, "@read")
+	}
+	if c.flags&flagAdmin != 0 {
+		categories = append(categories, "@admin", "@dangerous")
+	}
+	if c.flags&flagPubSub != 0 && c.group != groupPubSub {
+		categories = append(categories, "@pubsub")
+	}
+	if c.flags&flagBlocking != 0 {
+		categories = append(categories, "@blocking")
+	}
+	if c.flags&flagFast != 0 {
+		categories = append(categories, "@fast")
+	} else {
+		categories = append(categories, "@slow")
+	}
+	return categories
+}
+
+// info returns the reply of COMMAND INFO in the format of redis 6: name,
+// arity, flags, first key, last key, step and acl categories
+func (c *command) info() *token.Token {
+	return token.NewArray(
+		token.NewBulked([]byte(c.name)),
+		token.NewInteger(int64(c.arity)),
+		statusSet(c.flagNames()),
+		token.NewInteger(int64(c.firstKey)),
+		token.NewInteger(int64(c.lastKey)),
+		token.NewInteger(int64(c.step)),
+		statusSet(c.categories()),
+	)
+}
+
+// docs returns the reply of COMMAND DOCS
+func (c *command) docs() *token.Token {
+	return token.NewMap(
+		token.NewBulked([]byte("summary")), token.NewBulked([]byte(c.summary)),
+		token.NewBulked([]byte("group")), token.NewBulked([]byte(c.group)),
+	)
+}
+
+func statusSet(values []string) *token.Token {
+	ts := make([]*token.Token, 0, len(values))
+	for _, v := range values {
+		ts = append(ts, token.NewString(v))
+	}
+	return token.NewSet(ts...)
+}
+
+// sortedCommands returns the commands in order of names
+func (p *Processor) sortedCommands() []*command {
+	names := make([]string, 0, len(p.commands))
+	for name := range p.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmds := make([]*command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, p.commands[name])
+	}
+	return cmds
+}
+
+// lookupCommands returns the commands of names, nil for the unknown ones
+// or all the commands if no name is given
+func (p *Processor) lookupCommands(tokens []*token.Token) ([]*command, error) {
+	if len(tokens) == 0 {
+		return p.sortedCommands(), nil
+	}
+	names, err := tokensToMembers(tokens)
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]*command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, p.commands[strings.ToLower(name)])
+	}
+	return cmds, nil
+}
+
+// commandCmd returns details of the commands by "[COUNT | INFO [name ...] |
+// DOCS [name ...] | GETKEYS name [arg ...]]", details of all the commands
+// are returned if no subcommand is given.
+func (p *Processor) commandCmd(_ *model.Client, tokens ...*token.Token) *token.Token {
+	if len(tokens) == 0 {
+		return commandInfos(p.sortedCommands())
+	}
+	sub, _ := optionOf(tokens[0])
+	switch {
+	case sub == cds.Count && len(tokens) == 1:
+		return token.NewInteger(int64(len(p.commands)))
+	case sub == cds.InfoArg:
+		cmds, err := p.lookupCommands(tokens[1:])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		return commandInfos(cmds)
+	case sub == cds.Docs:
+		cmds, err := p.lookupCommands(tokens[1:])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		// unknown commands are left out like redis
+		ts := make([]*token.Token, 0, len(cmds)*2)
+		for _, c := range cmds {
+			if c != nil {
+				ts = append(ts, token.NewBulked([]byte(c.name)), c.docs())
+			}
+		}
+		return token.NewMap(ts...)
+	case sub == cds.GetKeys && len(tokens) > 1:
+		return p.commandGetKeys(tokens[1:])
+	}
+	return token.NewError(fmt.Sprintf("unknown subcommand or wrong number of arguments for '%s'", sub))
+}
+
+// commandInfos returns details of the commands, nil for the unknown ones
+func commandInfos(cmds []*command) *token.Token {
+	ts := make([]*token.Token, 0, len(cmds))
+	for _, c := range cmds {
+		if c == nil {
+			ts = append(ts, token.NewBulked(nil))
+		} else {
+			ts = append(ts, c.info())
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// commandGetKeys returns the keys of the command with arguments
+func (p *Processor) commandGetKeys(args []*token.Token) *token.Token {
+	name, err := tokenToBytes(args[0])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	c, ok := p.commands[strings.ToLower(string(name))]
+	if !ok {
+		return token.NewError("Invalid command specified")
+	}
+	if !c.arityMatched(len(args)) {
+		return token.NewError("Invalid number of arguments specified for command")
+	}
+	positions := c.keys(args)
+	if len(positions) == 0 {
+		return token.NewError("The command has no key arguments")
+	}
+	keys := make([]*token.Token, 0, len(positions))
+	for _, i := range positions {
+		key, err := tokenToBytes(args[i])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		keys = append(keys, token.NewBulked(key))
+	}
+	return token.NewArray(keys...)
+}
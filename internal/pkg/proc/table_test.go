@@ -0,0 +1,93 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessor_commandTable(t *testing.T) {
+	p := NewProcessor(1)
+	cmds := p.commandTable()
+	assert.Equal(t, len(cmds), len(p.commands))
+	for _, c := range cmds {
+		assert.NotNil(t, c.proc, c.name)
+		assert.NotZero(t, c.arity, c.name)
+		assert.NotEmpty(t, c.summary, c.name)
+		assert.False(t, c.flags&flagWrite != 0 && c.flags&flagReadOnly != 0, c.name)
+		if c.firstKey > 0 {
+			assert.NotZero(t, c.step, c.name)
+		}
+	}
+	for name := range movableKeys {
+		assert.Contains(t, p.commands, name)
+	}
+}
+
+func TestProcessor_arity(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewClient(nil)
+	exec := func(args ...string) *token.Token {
+		return p.execCmd(c, token.NewArray(stringTokens(args...)...))
+	}
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'get' command"), exec(cds.Get, "a", "b"))
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'del' command"), exec(cds.Del))
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'exec' command"), exec(cds.Exec, "a"))
+
+	// arity errors are found when queued, which discard the transaction
+	assert.Equal(t, token.ReplyOk, exec(cds.Multi))
+	assert.Equal(t, token.NewError("ERR wrong number of arguments for 'set' command"), exec(cds.Set, "a"))
+	assert.Equal(t, token.ReplyQueued, exec(cds.Set, "a", "1"))
+	assert.Equal(t, token.NewError(errExecAbort.Error()), exec(cds.Exec))
+	assert.Equal(t, int64(0), exec(cds.Exists, "a").Data)
+	assert.Equal(t, 0, len(p.aof))
+
+	// only the writes are propagated
+	assert.Equal(t, int64(0), exec(cds.Del, "a", "b").Data)
+	exec(cds.Set, "a", "1")
+	exec(cds.Get, "a")
+	exec(cds.Incr, "a")
+	assert.Equal(t, 3, len(p.aof))
+}
+
+func TestProcessor_commandCmd(t *testing.T) {
+	p := NewProcessor(1)
+	c := model.NewClient(nil, p.data[0])
+	exec := func(args ...string) *token.Token {
+		return p.execCmd(c, token.NewArray(stringTokens(append([]string{cds.Command}, args...)...)...))
+	}
+	assert.Equal(t, token.NewInteger(int64(len(p.commands))), exec(cds.Count))
+	assert.Equal(t, len(p.commands), len(exec().Data.([]*token.Token)))
+
+	assert.Equal(t, token.NewArray(
+		token.NewArray(
+			token.NewBulked([]byte(cds.Get)), token.NewInteger(2),
+			token.NewSet(token.NewString("readonly"), token.NewString("fast")),
+			token.NewInteger(1), token.NewInteger(1), token.NewInteger(1),
+			token.NewSet(token.NewString("@string"), token.NewString("@read"), token.NewString("@fast")),
+		),
+		token.NewBulked(nil),
+	), exec(cds.InfoArg, "GET", "none"))
+	info := exec(cds.InfoArg, cds.ZUnionStore).Data.([]*token.Token)[0].Data.([]*token.Token)
+	assert.Equal(t, token.NewSet(token.NewString("write"), token.NewString("denyoom"), token.NewString("movablekeys")), info[2])
+
+	docs := exec(cds.Docs, cds.Get, "none")
+	assert.Equal(t, label.Map, docs.Label)
+	assert.Equal(t, token.NewBulked([]byte(cds.Get)), docs.Data.([]*token.Token)[0])
+	assert.Equal(t, 2, len(docs.Data.([]*token.Token)))
+
+	assert.Equal(t, bulkedArray("a", "c"), exec(cds.GetKeys, cds.MSet, "a", "b", "c", "d"))
+	assert.Equal(t, bulkedArray("a", "b"), exec(cds.GetKeys, cds.BLPop, "a", "b", "0"))
+	assert.Equal(t, bulkedArray("dst", "a", "b"),
+		exec(cds.GetKeys, cds.ZUnionStore, "dst", "2", "a", "b", cds.Weights, "1", "2"))
+	assert.Equal(t, bulkedArray("s1", "s2"),
+		exec(cds.GetKeys, cds.XRead, cds.Count, "1", cds.Streams, "s1", "s2", "0", "0"))
+	assert.Equal(t, token.NewError("ERR Invalid command specified"), exec(cds.GetKeys, "none"))
+	assert.Equal(t, token.NewError("ERR Invalid number of arguments specified for command"), exec(cds.GetKeys, cds.Get))
+	assert.Equal(t, token.NewError("ERR The command has no key arguments"), exec(cds.GetKeys, cds.Ping))
+	assert.Equal(t, token.NewError("ERR unknown subcommand or wrong number of arguments for 'NONE'"), exec("none"))
+}
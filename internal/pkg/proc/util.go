@@ -1,13 +1,38 @@
 package proc
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/label"
 	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 )
 
+var (
+	errWrongType        = errors.New(eStrWrongType)
+	errSyntax           = errors.New("syntax error")
+	errArgNumber        = errors.New("wrong number of arguments")
+	errArgNotRecognized = errors.New("argument not recognized")
+	errInvalidCursor    = errors.New("invalid cursor")
+	errUnknownType      = errors.New("unknown type name")
+	errExpireInvalid    = errors.New(eStrExpireInvalid)
+	errNotInteger       = errors.New("value is not an integer or out of range")
+	errNotFloat         = errors.New("value is not a valid float")
+	errNaNOrInf         = errors.New("increment would produce NaN or Infinity")
+	errOverflow         = errors.New("increment or decrement would overflow")
+	errNotPositive      = errors.New("value is out of range, must be positive")
+	errOutOfRange       = errors.New("value is out of range")
+)
+
+func errArgMissing(arg string) error {
+	return fmt.Errorf("argument missing of %s", arg)
+}
+
+// checkType checks the label of token is one of types. Clients like
+// redis-cli send all the arguments as bulked strings, so a bulked token is
+// converted in place to the first type it is able to be.
 func checkType(t *token.Token, name string, types ...byte) error {
 	matched := false
 	for _, typ := range types {
@@ -15,6 +40,9 @@ func checkType(t *token.Token, name string, types ...byte) error {
 			matched = true
 		}
 	}
+	for i := 0; !matched && i < len(types); i++ {
+		matched = coerce(t, types[i])
+	}
 	if !matched {
 		return fmt.Errorf(eStrMismatch, name, label.ToStr(t.Label),
 			label.ToStr(types...))
@@ -22,10 +50,99 @@ func checkType(t *token.Token, name string, types ...byte) error {
 	return nil
 }
 
+// coerce converts the bulked token to the label, it reports whether converted
+func coerce(t *token.Token, typ byte) bool {
+	if t.Label != label.Bulked {
+		return false
+	}
+	data, _ := t.Data.([]byte)
+	switch typ {
+	case label.String:
+		t.Label, t.Data = label.String, string(data)
+		return true
+	case label.Integer:
+		num, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return false
+		}
+		t.Label, t.Data = label.Integer, num
+		return true
+	}
+	return false
+}
+
 func checkKeyType(t *token.Token) error {
 	return checkType(t, "key", label.String)
 }
 
+func checkKeysType(ts []*token.Token) error {
+	for _, t := range ts {
+		if err := checkKeyType(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenToBytes converts the value token to binary
+func tokenToBytes(t *token.Token) ([]byte, error) {
+	if err := checkType(t, "value", label.Bulked, label.Integer, label.String); err != nil {
+		return nil, err
+	}
+	v, err := ItfToBulked(t.Data)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := v.([]byte)
+	return data, nil
+}
+
+// tokenToFloat converts the number token to float
+func tokenToFloat(t *token.Token) (float64, error) {
+	switch t.Label {
+	case label.Integer:
+		return float64(t.Data.(int64)), nil
+	case label.Bulked, label.String:
+		data, _ := tokenToBytes(t)
+		f, err := strconv.ParseFloat(string(data), 64)
+		if err != nil || math.IsNaN(f) {
+			return 0, errNotFloat
+		}
+		return f, nil
+	}
+	return 0, errNotFloat
+}
+
+// isString reports whether the value stored is of type string
+func isString(v interface{}) bool {
+	switch v.(type) {
+	case []byte, int, int64, string:
+		return true
+	}
+	return false
+}
+
+// normRange converts start and stop which may be negative counting from the
+// tail to the indices of a sequence with length n, reporting false if empty.
+func normRange(start, stop, n int64) (int64, int64, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	return start, stop, true
+}
+
 // ItfToBulked converts interface bulked
 func ItfToBulked(v interface{}) (interface{}, error) {
 	if v == nil {
@@ -3,6 +3,8 @@ package proc
 import (
 	"testing"
 
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -64,3 +66,20 @@ func TestItfToInt(t *testing.T) {
 	assert.Nil(t, val)
 	assert.EqualError(t, err, "value (200) cannot cast to int")
 }
+
+func TestCheckType(t *testing.T) {
+	tk := token.NewBulked([]byte("12"))
+	assert.Nil(t, checkType(tk, "count", label.Integer))
+	assert.Equal(t, token.NewInteger(12), tk)
+	tk = token.NewBulked([]byte("key"))
+	assert.NotNil(t, checkType(tk, "count", label.Integer))
+	assert.Nil(t, checkKeyType(tk))
+	assert.Equal(t, token.NewString("key"), tk)
+	tk = token.NewBulked([]byte("value"))
+	assert.Nil(t, checkType(tk, "value", label.Bulked, label.String))
+	assert.Equal(t, token.NewBulked([]byte("value")), tk)
+	assert.NotNil(t, checkKeyType(token.NewInteger(1)))
+	opt, ok := optionOf(token.NewBulked([]byte("withscores")))
+	assert.True(t, ok)
+	assert.Equal(t, "WITHSCORES", opt)
+}
@@ -0,0 +1,907 @@
+package proc
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/inhzus/go-redis-impl/internal/pkg/model"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+)
+
+var (
+	errMinMaxFloat  = errors.New("min or max is not a float")
+	errMinMaxLex    = errors.New("min or max not valid string range item")
+	errWeightFloat  = errors.New("weight value is not a float")
+	errScoreNaN     = errors.New("resulting score is not a number (NaN)")
+	errNXAndXX      = errors.New("XX and NX options at the same time are not compatible")
+	errGTLTAndNX    = errors.New("GT, LT, and/or NX options at the same time are not compatible")
+	errIncrPair     = errors.New("INCR option supports a single increment-element pair")
+	errLimitNoBy    = errors.New("syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	errLexWithScore = errors.New("syntax error, WITHSCORES not supported in combination with BYLEX")
+)
+
+// getZSet returns the sorted set stored at key, nil if the key does not exist.
+// The sorted set returned is allowed to be modified in place if mutable is true.
+func getZSet(cli *model.Client, key string, mutable bool) (*model.ZSet, error) {
+	var v interface{}
+	if mutable {
+		v = cli.GetMutable(key)
+	} else {
+		v = cli.Get(key)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	z, ok := v.(*model.ZSet)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, nil
+}
+
+// afterZSetChanged publishes the event of the command changing the sorted set
+// and deletes the key if the sorted set is empty
+func afterZSetChanged(cli *model.Client, key string, z *model.ZSet, event string) {
+	cli.Notify(model.NotifyZSet, event, key)
+	if z.Len() == 0 {
+		cli.Del(key)
+	}
+}
+
+// zPopEvent returns the event of popping the lowest or highest members
+func zPopEvent(max bool) string {
+	if max {
+		return model.EventZPopMax
+	}
+	return model.EventZPopMin
+}
+
+// storeZSet replaces the value of key with the sorted set and publishes the
+// event, the key is deleted if the sorted set is empty.
+func (p *Processor) storeZSet(cli *model.Client, key string, z *model.ZSet, event string) {
+	if z.Len() == 0 {
+		cli.Del(key)
+		return
+	}
+	cli.Set(key, z, 0)
+	cli.Notify(model.NotifyZSet, event, key)
+	p.signalKey(cli, key)
+}
+
+// zItemsToArray converts items to the array of members, each of which is
+// followed by its score if withScores.
+func zItemsToArray(items []model.ZItem, withScores bool) *token.Token {
+	ts := make([]*token.Token, 0, len(items))
+	for _, item := range items {
+		ts = append(ts, token.NewBulked([]byte(item.Member)))
+		if withScores {
+			ts = append(ts, token.NewBulked([]byte(token.FormatFloat(item.Score))))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// optionOf returns the upper-cased option if the token is a string or bulked string
+func optionOf(t *token.Token) (string, bool) {
+	switch t.Label {
+	case label.String:
+		return strings.ToUpper(t.Data.(string)), true
+	case label.Bulked:
+		data, _ := t.Data.([]byte)
+		return strings.ToUpper(string(data)), true
+	}
+	return "", false
+}
+
+// parseScoreBound parses the score bound like "1.5", "(1.5", "-inf" and "+inf"
+func parseScoreBound(t *token.Token) (float64, bool, error) {
+	if t.Label == label.Integer {
+		return float64(t.Data.(int64)), false, nil
+	}
+	data, err := tokenToBytes(t)
+	if err != nil {
+		return 0, false, err
+	}
+	s := string(data)
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false, errMinMaxFloat
+	}
+	return f, exclusive, nil
+}
+
+func parseScoreRange(min, max *token.Token) (r model.ScoreRange, err error) {
+	if r.Min, r.MinEx, err = parseScoreBound(min); err != nil {
+		return
+	}
+	r.Max, r.MaxEx, err = parseScoreBound(max)
+	return
+}
+
+// parseLexBound parses the lexicographical bound like "[a", "(a", "-" and "+"
+func parseLexBound(t *token.Token) (model.LexBound, error) {
+	data, err := tokenToBytes(t)
+	if err != nil {
+		return model.LexBound{}, err
+	}
+	s := string(data)
+	switch {
+	case s == "-":
+		return model.LexBound{Inf: -1}, nil
+	case s == "+":
+		return model.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return model.LexBound{Value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return model.LexBound{Value: s[1:], Exclusive: true}, nil
+	}
+	return model.LexBound{}, errMinMaxLex
+}
+
+func parseLexRange(min, max *token.Token) (r model.LexRange, err error) {
+	if r.Min, err = parseLexBound(min); err != nil {
+		return
+	}
+	r.Max, err = parseLexBound(max)
+	return
+}
+
+// zAddGeneric adds or updates the member with score. The score is added to
+// the old one if incr. It returns the resulting score, whether the member
+// is added, and whether the score is changed.
+func zAddGeneric(z *model.ZSet, member string, score float64,
+	nx, xx, gt, lt, incr bool) (float64, bool, bool, error) {
+	old, exists := z.Score(member)
+	if !exists {
+		if xx {
+			return 0, false, false, nil
+		}
+		z.Add(member, score)
+		return score, true, false, nil
+	}
+	if nx {
+		return old, false, false, nil
+	}
+	if incr {
+		if score += old; math.IsNaN(score) {
+			return 0, false, false, errScoreNaN
+		}
+	}
+	if (gt && score <= old) || (lt && score >= old) || score == old {
+		return old, false, false, nil
+	}
+	z.Add(member, score)
+	return score, false, true, nil
+}
+
+// zAdd adds members with scores, "key [NX|XX] [GT|LT] [CH] [INCR] score
+// member [score member ...]".
+func (p *Processor) zAdd(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	var nx, xx, gt, lt, ch, incr bool
+	i := 1
+loop:
+	for ; i < len(tokens); i++ {
+		opt, _ := optionOf(tokens[i])
+		switch opt {
+		case cds.IfNotExist:
+			nx = true
+		case cds.IfExist:
+			xx = true
+		case cds.IfGreater:
+			gt = true
+		case cds.IfLess:
+			lt = true
+		case cds.Changed:
+			ch = true
+		case cds.Increment:
+			incr = true
+		default:
+			break loop
+		}
+	}
+	pairs := tokens[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return token.NewError(errSyntax.Error())
+	}
+	if nx && xx {
+		return token.NewError(errNXAndXX.Error())
+	}
+	if (gt && lt) || (nx && (gt || lt)) {
+		return token.NewError(errGTLTAndNX.Error())
+	}
+	if incr && len(pairs) > 2 {
+		return token.NewError(errIncrPair.Error())
+	}
+	items := make([]model.ZItem, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, err := tokenToFloat(pairs[j])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		member, err := tokenToBytes(pairs[j+1])
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		items = append(items, model.ZItem{Member: string(member), Score: score})
+	}
+	key := tokens[0].Data.(string)
+	z, err := getZSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	created := z == nil
+	if created {
+		z = model.NewZSet()
+	}
+	event := model.EventZAdd
+	if incr {
+		event = model.EventZIncr
+	}
+	var added, changed int64
+	var score float64
+	var updated bool
+	for _, item := range items {
+		var a, c bool
+		if score, a, c, err = zAddGeneric(z, item.Member, item.Score, nx, xx, gt, lt, incr); err != nil {
+			return token.NewError(err.Error())
+		}
+		if a {
+			added++
+		} else if c {
+			changed++
+		}
+		updated = a || c
+	}
+	if added+changed == 0 {
+		p.propagateAs()
+	} else if created {
+		cli.Set(key, z, 0)
+		cli.Notify(model.NotifyZSet, event, key)
+		p.signalKey(cli, key)
+	} else {
+		cli.Notify(model.NotifyZSet, event, key)
+	}
+	if incr {
+		if !updated && (nx || xx || gt || lt) {
+			return token.NewBulked(nil)
+		}
+		return token.NewBulked([]byte(token.FormatFloat(score)))
+	}
+	if ch {
+		return token.NewInteger(added + changed)
+	}
+	return token.NewInteger(added)
+}
+
+func (p *Processor) zIncrBy(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zAdd(cli, tokens[0], token.NewString(cds.Increment), tokens[1], tokens[2])
+}
+
+func (p *Processor) zRem(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	z, err := getZSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	var n int64
+	if z != nil {
+		for _, m := range members {
+			if z.Remove(m) {
+				n++
+			}
+		}
+	}
+	if n > 0 {
+		afterZSetChanged(cli, key, z, model.EventZRem)
+	}
+	return token.NewInteger(n)
+}
+
+func (p *Processor) zCard(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewInteger(0)
+	}
+	return token.NewInteger(int64(z.Len()))
+}
+
+func (p *Processor) zScore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	ret := p.zMScore(cli, tokens[:2]...)
+	if ret.Label == label.Error {
+		return ret
+	}
+	return ret.Data.([]*token.Token)[0]
+}
+
+func (p *Processor) zMScore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	members, err := tokensToMembers(tokens[1:])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	ts := make([]*token.Token, 0, len(members))
+	for _, m := range members {
+		if z == nil {
+			ts = append(ts, token.NewBulked(nil))
+		} else if score, ok := z.Score(m); ok {
+			ts = append(ts, token.NewBulked([]byte(token.FormatFloat(score))))
+		} else {
+			ts = append(ts, token.NewBulked(nil))
+		}
+	}
+	return token.NewArray(ts...)
+}
+
+// zCountGeneric counts members in the score range, or in the lexicographical
+// range if lex.
+func (p *Processor) zCountGeneric(cli *model.Client, tokens []*token.Token, lex bool) *token.Token {
+	if len(tokens) < 3 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	var scoreRange model.ScoreRange
+	var lexRange model.LexRange
+	var err error
+	if lex {
+		lexRange, err = parseLexRange(tokens[1], tokens[2])
+	} else {
+		scoreRange, err = parseScoreRange(tokens[1], tokens[2])
+	}
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewInteger(0)
+	}
+	if lex {
+		return token.NewInteger(int64(z.CountByLex(lexRange)))
+	}
+	return token.NewInteger(int64(z.CountByScore(scoreRange)))
+}
+
+func (p *Processor) zCount(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zCountGeneric(cli, tokens, false)
+}
+
+func (p *Processor) zLexCount(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zCountGeneric(cli, tokens, true)
+}
+
+func (p *Processor) zRankGeneric(cli *model.Client, tokens []*token.Token, rev bool) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	member, err := tokenToBytes(tokens[1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewBulked(nil)
+	}
+	rank, ok := z.Rank(string(member), rev)
+	if !ok {
+		return token.NewBulked(nil)
+	}
+	return token.NewInteger(int64(rank))
+}
+
+func (p *Processor) zRank(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zRankGeneric(cli, tokens, false)
+}
+
+func (p *Processor) zRevRank(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zRankGeneric(cli, tokens, true)
+}
+
+// kinds of zset range
+const (
+	zRangeByRank = iota
+	zRangeByScore
+	zRangeByLex
+)
+
+// zRemRangeEvents are the keyspace events of removing ranges of the kinds
+var zRemRangeEvents = [...]string{
+	zRangeByRank:  model.EventZRemRangeByRank,
+	zRangeByScore: model.EventZRemRangeByScore,
+	zRangeByLex:   model.EventZRemRangeByLex,
+}
+
+// zRangeSpec is the parsed range of zrange like commands
+type zRangeSpec struct {
+	by          int
+	rev         bool
+	start, stop int64
+	score       model.ScoreRange
+	lex         model.LexRange
+	offset      int64
+	limit       int64
+	withScores  bool
+}
+
+// parseZRange parses "start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count]
+// [WITHSCORES]", WITHSCORES is not allowed if store.
+func parseZRange(tokens []*token.Token, store bool) (*zRangeSpec, error) {
+	spec := &zRangeSpec{limit: -1}
+	hasLimit := false
+	for i := 2; i < len(tokens); i++ {
+		opt, _ := optionOf(tokens[i])
+		switch {
+		case opt == cds.ByScore:
+			spec.by = zRangeByScore
+		case opt == cds.ByLex:
+			spec.by = zRangeByLex
+		case opt == cds.Reverse:
+			spec.rev = true
+		case opt == cds.WithScores && !store:
+			spec.withScores = true
+		case opt == cds.Limit:
+			if len(tokens) < i+3 {
+				return nil, errArgMissing(opt)
+			}
+			for _, t := range tokens[i+1 : i+3] {
+				if err := checkType(t, "limit", label.Integer); err != nil {
+					return nil, err
+				}
+			}
+			spec.offset, spec.limit = tokens[i+1].Data.(int64), tokens[i+2].Data.(int64)
+			hasLimit = true
+			i += 2
+		default:
+			return nil, errSyntax
+		}
+	}
+	if hasLimit && spec.by == zRangeByRank {
+		return nil, errLimitNoBy
+	}
+	if spec.withScores && spec.by == zRangeByLex {
+		return nil, errLexWithScore
+	}
+	min, max := tokens[0], tokens[1]
+	if spec.rev {
+		min, max = max, min
+	}
+	var err error
+	switch spec.by {
+	case zRangeByRank:
+		if err = checkType(tokens[0], "start", label.Integer); err != nil {
+			return nil, err
+		}
+		if err = checkType(tokens[1], "stop", label.Integer); err != nil {
+			return nil, err
+		}
+		spec.start, spec.stop = tokens[0].Data.(int64), tokens[1].Data.(int64)
+	case zRangeByScore:
+		spec.score, err = parseScoreRange(min, max)
+	case zRangeByLex:
+		spec.lex, err = parseLexRange(min, max)
+	}
+	return spec, err
+}
+
+// query returns the items of the sorted set in range
+func (spec *zRangeSpec) query(z *model.ZSet) []model.ZItem {
+	if spec.offset < 0 {
+		return make([]model.ZItem, 0)
+	}
+	switch spec.by {
+	case zRangeByScore:
+		return z.RangeByScore(spec.score, spec.rev, int(spec.offset), int(spec.limit))
+	case zRangeByLex:
+		return z.RangeByLex(spec.lex, spec.rev, int(spec.offset), int(spec.limit))
+	}
+	start, stop, ok := normRange(spec.start, spec.stop, int64(z.Len()))
+	if !ok {
+		return make([]model.ZItem, 0)
+	}
+	return z.Range(int(start), int(stop), spec.rev)
+}
+
+func (p *Processor) zRange(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	spec, err := parseZRange(tokens[1:], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return zItemsToArray(nil, false)
+	}
+	return zItemsToArray(spec.query(z), spec.withScores)
+}
+
+func (p *Processor) zRangeStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeysType(tokens[:2]); err != nil {
+		return token.NewError(err.Error())
+	}
+	spec, err := parseZRange(tokens[2:], true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[1].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	dst := model.NewZSet()
+	if z != nil {
+		for _, item := range spec.query(z) {
+			dst.Add(item.Member, item.Score)
+		}
+	}
+	p.storeZSet(cli, tokens[0].Data.(string), dst, model.EventZRangeStore)
+	return token.NewInteger(int64(dst.Len()))
+}
+
+func (p *Processor) zPopGeneric(cli *model.Client, tokens []*token.Token, max bool) *token.Token {
+	if len(tokens) < 1 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	var count int64 = 1
+	if len(tokens) > 1 {
+		if err := checkType(tokens[1], "count", label.Integer); err != nil {
+			return token.NewError(err.Error())
+		}
+		if count = tokens[1].Data.(int64); count < 0 {
+			return token.NewError(errNotPositive.Error())
+		}
+	}
+	key := tokens[0].Data.(string)
+	z, err := getZSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil || count == 0 {
+		p.propagateAs()
+		return zItemsToArray(nil, true)
+	}
+	if count > int64(z.Len()) {
+		count = int64(z.Len())
+	}
+	items := z.Range(0, int(count)-1, max)
+	for _, item := range items {
+		z.Remove(item.Member)
+	}
+	afterZSetChanged(cli, key, z, zPopEvent(max))
+	return zItemsToArray(items, true)
+}
+
+func (p *Processor) zPopMin(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zPopGeneric(cli, tokens, false)
+}
+
+func (p *Processor) zPopMax(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zPopGeneric(cli, tokens, true)
+}
+
+// zRemRangeGeneric removes the members in range of the kind by
+func (p *Processor) zRemRangeGeneric(cli *model.Client, tokens []*token.Token, by int) *token.Token {
+	if len(tokens) < 3 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	args := append([]*token.Token{}, tokens[1:3]...)
+	switch by {
+	case zRangeByScore:
+		args = append(args, token.NewString(cds.ByScore))
+	case zRangeByLex:
+		args = append(args, token.NewString(cds.ByLex))
+	}
+	spec, err := parseZRange(args, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	key := tokens[0].Data.(string)
+	z, err := getZSet(cli, key, true)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return token.NewInteger(0)
+	}
+	items := spec.query(z)
+	for _, item := range items {
+		z.Remove(item.Member)
+	}
+	if len(items) > 0 {
+		afterZSetChanged(cli, key, z, zRemRangeEvents[by])
+	}
+	return token.NewInteger(int64(len(items)))
+}
+
+func (p *Processor) zRemRangeByRank(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zRemRangeGeneric(cli, tokens, zRangeByRank)
+}
+
+func (p *Processor) zRemRangeByScore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zRemRangeGeneric(cli, tokens, zRangeByScore)
+}
+
+func (p *Processor) zRemRangeByLex(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zRemRangeGeneric(cli, tokens, zRangeByLex)
+}
+
+// zSource returns scores of members stored at key which is either a sorted
+// set or a set whose members are scored 1, nil if the key does not exist.
+func zSource(cli *model.Client, key string) (map[string]float64, error) {
+	switch v := cli.Get(key).(type) {
+	case nil:
+		return nil, nil
+	case *model.ZSet:
+		m := make(map[string]float64, v.Len())
+		for _, member := range v.Members() {
+			m[member], _ = v.Score(member)
+		}
+		return m, nil
+	case *model.Set:
+		m := make(map[string]float64, v.Len())
+		for _, member := range v.Members() {
+			m[member] = 1
+		}
+		return m, nil
+	}
+	return nil, errWrongType
+}
+
+// zAggregate aggregates scores, NaN produced by summing infinities is 0
+func zAggregate(agg string, a, b float64) float64 {
+	switch agg {
+	case cds.AggMin:
+		return math.Min(a, b)
+	case cds.AggMax:
+		return math.Max(a, b)
+	}
+	if sum := a + b; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// zStoreGeneric stores the union or the intersection of sources, "destination
+// numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]".
+func (p *Processor) zStoreGeneric(cli *model.Client, tokens []*token.Token, inter bool) *token.Token {
+	if len(tokens) < 3 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	if err := checkType(tokens[1], "numkeys", label.Integer); err != nil {
+		return token.NewError(err.Error())
+	}
+	numKeys := tokens[1].Data.(int64)
+	if numKeys < 1 {
+		return token.NewError("at least 1 input key is needed for this command")
+	}
+	if numKeys > int64(len(tokens)-2) {
+		return token.NewError(errSyntax.Error())
+	}
+	keys, args := tokens[2:numKeys+2], tokens[numKeys+2:]
+	if err := checkKeysType(keys); err != nil {
+		return token.NewError(err.Error())
+	}
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	agg := cds.AggSum
+	for i := 0; i < len(args); i++ {
+		opt, _ := optionOf(args[i])
+		switch {
+		case opt == cds.Weights && int64(len(args)-i-1) >= numKeys:
+			for j := range weights {
+				i++
+				w, err := tokenToFloat(args[i])
+				if err != nil {
+					return token.NewError(errWeightFloat.Error())
+				}
+				weights[j] = w
+			}
+		case opt == cds.Aggregate && len(args) > i+1:
+			i++
+			agg, _ = optionOf(args[i])
+			if agg != cds.AggSum && agg != cds.AggMin && agg != cds.AggMax {
+				return token.NewError(errSyntax.Error())
+			}
+		default:
+			return token.NewError(errSyntax.Error())
+		}
+	}
+	sources := make([]map[string]float64, 0, numKeys)
+	for _, k := range keys {
+		m, err := zSource(cli, k.Data.(string))
+		if err != nil {
+			return token.NewError(err.Error())
+		}
+		sources = append(sources, m)
+	}
+	weighted := func(score, weight float64) float64 {
+		if v := score * weight; !math.IsNaN(v) {
+			return v
+		}
+		return 0
+	}
+	result := make(map[string]float64)
+	if inter {
+		for member, score := range sources[0] {
+			acc, found := weighted(score, weights[0]), true
+			for i, src := range sources[1:] {
+				s, ok := src[member]
+				if !ok {
+					found = false
+					break
+				}
+				acc = zAggregate(agg, acc, weighted(s, weights[i+1]))
+			}
+			if found {
+				result[member] = acc
+			}
+		}
+	} else {
+		for i, src := range sources {
+			for member, score := range src {
+				v := weighted(score, weights[i])
+				if acc, ok := result[member]; ok {
+					v = zAggregate(agg, acc, v)
+				}
+				result[member] = v
+			}
+		}
+	}
+	dst := model.NewZSet()
+	for member, score := range result {
+		dst.Add(member, score)
+	}
+	event := model.EventZUnionStore
+	if inter {
+		event = model.EventZInterStore
+	}
+	p.storeZSet(cli, tokens[0].Data.(string), dst, event)
+	return token.NewInteger(int64(dst.Len()))
+}
+
+func (p *Processor) zUnionStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zStoreGeneric(cli, tokens, false)
+}
+
+func (p *Processor) zInterStore(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.zStoreGeneric(cli, tokens, true)
+}
+
+func (p *Processor) zScan(cli *model.Client, tokens ...*token.Token) *token.Token {
+	if err := checkKeyType(tokens[0]); err != nil {
+		return token.NewError(err.Error())
+	}
+	opt, err := parseScan(tokens[1:], false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	z, err := getZSet(cli, tokens[0].Data.(string), false)
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	if z == nil {
+		return scanReply(0, []*token.Token{})
+	}
+	items := make([]model.ZItem, 0)
+	next := z.Scan(uint64(opt.cursor), int(opt.count), func(member string, score float64) {
+		if opt.matched(member) {
+			items = append(items, model.ZItem{Member: member, Score: score})
+		}
+	})
+	return scanReply(next, zItemsToArray(items, true).Data.([]*token.Token))
+}
+
+// zPopFirst pops the member with the lowest or highest score from the first
+// non-empty sorted set of keys, and returns the array of the key, member and
+// score, nil if all sorted sets are empty. Keys of other types are skipped
+// unless strict, which returns the error.
+func (p *Processor) zPopFirst(cli *model.Client, keys []string, max, strict bool) *token.Token {
+	for _, key := range keys {
+		z, err := getZSet(cli, key, true)
+		if err != nil && strict {
+			return token.NewError(err.Error())
+		}
+		if z == nil {
+			continue
+		}
+		item := z.Range(0, 0, max)[0]
+		z.Remove(item.Member)
+		afterZSetChanged(cli, key, z, zPopEvent(max))
+		cmd := cds.ZPopMin
+		if max {
+			cmd = cds.ZPopMax
+		}
+		p.propagateAs(token.NewArray(token.NewString(cmd), token.NewString(key)))
+		return token.NewArray(token.NewBulked([]byte(key)), token.NewBulked([]byte(item.Member)),
+			token.NewBulked([]byte(token.FormatFloat(item.Score))))
+	}
+	return nil
+}
+
+// bzPopGeneric pops from the first non-empty sorted set of "key [key ...]
+// timeout", the client is blocked until any sorted set is added if all
+// sorted sets are empty.
+func (p *Processor) bzPopGeneric(cli *model.Client, tokens []*token.Token, max bool) *token.Token {
+	if len(tokens) < 2 {
+		return token.NewError(eStrArgMore)
+	}
+	if err := checkKeysType(tokens[:len(tokens)-1]); err != nil {
+		return token.NewError(err.Error())
+	}
+	timeout, err := parseTimeout(tokens[len(tokens)-1])
+	if err != nil {
+		return token.NewError(err.Error())
+	}
+	keys := make([]string, 0, len(tokens)-1)
+	for _, t := range tokens[:len(tokens)-1] {
+		keys = append(keys, t.Data.(string))
+	}
+	if reply := p.zPopFirst(cli, keys, max, true); reply != nil {
+		return reply
+	}
+	p.propagateAs()
+	return p.block(cli, keys, timeout, token.NewNilArray(), func() *token.Token {
+		return p.zPopFirst(cli, keys, max, false)
+	})
+}
+
+func (p *Processor) bzPopMin(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.bzPopGeneric(cli, tokens, false)
+}
+
+func (p *Processor) bzPopMax(cli *model.Client, tokens ...*token.Token) *token.Token {
+	return p.bzPopGeneric(cli, tokens, true)
+}
@@ -0,0 +1,185 @@
+package proc
+
+import (
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// zaddTokens returns the arguments of zadd whose scores are increasing
+// integers starting from 1.
+func zaddTokens(key *token.Token, members ...string) []*token.Token {
+	ts := []*token.Token{key}
+	for i, m := range members {
+		ts = append(ts, token.NewInteger(int64(i+1)), token.NewString(m))
+	}
+	return ts
+}
+
+func TestProcessor_zAdd(t *testing.T) {
+	key := token.NewString("t_zadd")
+	assert.Equal(t, token.NewInteger(2), proc.zAdd(cli, key,
+		token.NewInteger(1), token.NewString("a"), token.NewString("2.5"), token.NewString("b")))
+	assert.Equal(t, token.NewBulked([]byte("2.5")), proc.zScore(cli, key, token.NewString("b")))
+	assert.Equal(t, token.NewInteger(0), proc.zAdd(cli, key, token.NewString("nx"),
+		token.NewInteger(5), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(1), proc.zAdd(cli, key, token.NewString("xx"), token.NewString("ch"),
+		token.NewInteger(5), token.NewString("a"), token.NewInteger(1), token.NewString("c")))
+	assert.Equal(t, token.NewInteger(0), proc.zAdd(cli, key, token.NewString(cds.IfGreater), token.NewString(cds.Changed),
+		token.NewInteger(4), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(1), proc.zAdd(cli, key, token.NewString(cds.IfLess), token.NewString(cds.Changed),
+		token.NewInteger(4), token.NewString("a")))
+	assert.Equal(t, token.NewBulked([]byte("6")), proc.zAdd(cli, key, token.NewString("incr"),
+		token.NewInteger(2), token.NewString("a")))
+	assert.Equal(t, token.NewBulked(nil), proc.zAdd(cli, key, token.NewString("incr"), token.NewString("gt"),
+		token.NewInteger(-2), token.NewString("a")))
+	assert.Equal(t, token.NewBulked([]byte("5.5")), proc.zIncrBy(cli, key, token.NewString("-0.5"), token.NewString("a")))
+	assert.Equal(t, token.NewArray(token.NewBulked([]byte("5.5")), token.NewBulked(nil)),
+		proc.zMScore(cli, key, token.NewString("a"), token.NewString("c")))
+	assert.Equal(t, token.NewInteger(2), proc.zCard(cli, key))
+
+	assert.Equal(t, token.NewError(errNXAndXX.Error()), proc.zAdd(cli, key,
+		token.NewString("nx"), token.NewString("xx"), token.NewInteger(1), token.NewString("a")))
+	assert.Equal(t, token.NewError(errGTLTAndNX.Error()), proc.zAdd(cli, key,
+		token.NewString("nx"), token.NewString("gt"), token.NewInteger(1), token.NewString("a")))
+	assert.Equal(t, token.NewError(errIncrPair.Error()), proc.zAdd(cli, key, token.NewString("incr"),
+		token.NewInteger(1), token.NewString("a"), token.NewInteger(1), token.NewString("b")))
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.zAdd(cli, key,
+		token.NewInteger(1), token.NewString("a"), token.NewInteger(2)))
+	assert.Equal(t, token.NewError(errNotFloat.Error()), proc.zAdd(cli, key, token.NewString("x"), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(0), proc.zAdd(cli, token.NewString("t_zadd_none"), token.NewString("xx"),
+		token.NewInteger(1), token.NewString("a")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, token.NewString("t_zadd_none")))
+
+	assert.Equal(t, token.NewInteger(1), proc.zRem(cli, key, token.NewString("a"), token.NewString("c")))
+	assert.Equal(t, token.NewInteger(1), proc.zRem(cli, key, token.NewString("b")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+}
+
+func TestProcessor_zRank(t *testing.T) {
+	key := token.NewString("t_zrank")
+	proc.zAdd(cli, zaddTokens(key, "a", "b", "c")...)
+	assert.Equal(t, token.NewInteger(1), proc.zRank(cli, key, token.NewString("b")))
+	assert.Equal(t, token.NewInteger(0), proc.zRevRank(cli, key, token.NewString("c")))
+	assert.Equal(t, token.NewBulked(nil), proc.zRank(cli, key, token.NewString("d")))
+	assert.Equal(t, token.NewInteger(2), proc.zCount(cli, key, token.NewString("(1"), token.NewString("+inf")))
+	assert.Equal(t, token.NewInteger(3), proc.zLexCount(cli, key, token.NewString("-"), token.NewString("+")))
+	assert.Equal(t, token.NewError(errMinMaxFloat.Error()), proc.zCount(cli, key, token.NewString("a"), token.NewInteger(1)))
+	assert.Equal(t, token.NewError(errMinMaxLex.Error()), proc.zLexCount(cli, key, token.NewString("a"), token.NewString("+")))
+}
+
+func TestProcessor_zRange(t *testing.T) {
+	key := token.NewString("t_zrange")
+	proc.zAdd(cli, zaddTokens(key, "a", "b", "c", "d")...)
+	assert.Equal(t, bulkedArray("a", "b", "c", "d"), proc.zRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, bulkedArray("d", "4", "c", "3"), proc.zRange(cli, key, token.NewInteger(0), token.NewInteger(1),
+		token.NewString("rev"), token.NewString("withscores")))
+	assert.Equal(t, bulkedArray("b", "c"), proc.zRange(cli, key, token.NewString("(1"), token.NewInteger(3),
+		token.NewString(cds.ByScore)))
+	assert.Equal(t, bulkedArray("c", "b"), proc.zRange(cli, key, token.NewInteger(3), token.NewString("(1"),
+		token.NewString(cds.ByScore), token.NewString(cds.Reverse)))
+	assert.Equal(t, bulkedArray("b"), proc.zRange(cli, key, token.NewString("-inf"), token.NewString("+inf"),
+		token.NewString(cds.ByScore), token.NewString(cds.Limit), token.NewInteger(1), token.NewInteger(1)))
+	assert.Equal(t, bulkedArray("b", "c"), proc.zRange(cli, key, token.NewString("[b"), token.NewString("(d"),
+		token.NewString(cds.ByLex)))
+	assert.Equal(t, bulkedArray(), proc.zRange(cli, key, token.NewInteger(5), token.NewInteger(10)))
+	assert.Equal(t, bulkedArray(), proc.zRange(cli, token.NewString("t_zrange_none"), token.NewInteger(0), token.NewInteger(-1)))
+
+	assert.Equal(t, token.NewError(errLimitNoBy.Error()), proc.zRange(cli, key, token.NewInteger(0), token.NewInteger(1),
+		token.NewString(cds.Limit), token.NewInteger(0), token.NewInteger(1)))
+	assert.Equal(t, token.NewError(errLexWithScore.Error()), proc.zRange(cli, key, token.NewString("-"), token.NewString("+"),
+		token.NewString(cds.ByLex), token.NewString(cds.WithScores)))
+
+	dst := token.NewString("t_zrange_dst")
+	assert.Equal(t, token.NewInteger(2), proc.zRangeStore(cli, dst, key, token.NewInteger(2), token.NewString("+inf"),
+		token.NewString(cds.ByScore), token.NewString(cds.Limit), token.NewInteger(1), token.NewInteger(-1)))
+	assert.Equal(t, bulkedArray("c", "3", "d", "4"), proc.zRange(cli, dst, token.NewInteger(0), token.NewInteger(-1),
+		token.NewString(cds.WithScores)))
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.zRangeStore(cli, dst, key, token.NewInteger(0), token.NewInteger(-1),
+		token.NewString(cds.WithScores)))
+	assert.Equal(t, token.NewInteger(0), proc.zRangeStore(cli, dst, key, token.NewInteger(5), token.NewInteger(6)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, dst))
+}
+
+func TestProcessor_zPop(t *testing.T) {
+	key := token.NewString("t_zpop")
+	assert.Equal(t, bulkedArray(), proc.zPopMin(cli, key))
+	proc.zAdd(cli, zaddTokens(key, "a", "b", "c")...)
+	assert.Equal(t, bulkedArray("a", "1"), proc.zPopMin(cli, key))
+	assert.Equal(t, bulkedArray("c", "3", "b", "2"), proc.zPopMax(cli, key, token.NewInteger(5)))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+	assert.Equal(t, token.NewError(errNotPositive.Error()), proc.zPopMax(cli, key, token.NewInteger(-1)))
+}
+
+func TestProcessor_zRemRange(t *testing.T) {
+	key := token.NewString("t_zremrange")
+	proc.zAdd(cli, zaddTokens(key, "a", "b", "c", "d", "e")...)
+	assert.Equal(t, token.NewInteger(2), proc.zRemRangeByRank(cli, key, token.NewInteger(-2), token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(1), proc.zRemRangeByScore(cli, key, token.NewString("(1"), token.NewInteger(2)))
+	assert.Equal(t, bulkedArray("a", "c"), proc.zRange(cli, key, token.NewInteger(0), token.NewInteger(-1)))
+	assert.Equal(t, token.NewInteger(2), proc.zRemRangeByLex(cli, key, token.NewString("-"), token.NewString("[c")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, key))
+}
+
+func TestProcessor_zStore(t *testing.T) {
+	a, b, s := token.NewString("t_zstore_a"), token.NewString("t_zstore_b"), token.NewString("t_zstore_s")
+	dst := token.NewString("t_zstore_dst")
+	proc.zAdd(cli, zaddTokens(a, "x", "y", "z")...)
+	proc.zAdd(cli, zaddTokens(b, "y", "z")...)
+	proc.sAdd(cli, s, token.NewString("z"), token.NewString("w"))
+
+	assert.Equal(t, token.NewInteger(4), proc.zUnionStore(cli, dst, token.NewInteger(3), a, b, s))
+	assert.Equal(t, bulkedArray("w", "1", "x", "1", "y", "3", "z", "6"), proc.zRange(cli, dst,
+		token.NewInteger(0), token.NewInteger(-1), token.NewString(cds.WithScores)))
+	assert.Equal(t, token.NewInteger(2), proc.zInterStore(cli, dst, token.NewInteger(2), a, b,
+		token.NewString("weights"), token.NewInteger(2), token.NewString("0.5"), token.NewString("aggregate"), token.NewString("max")))
+	assert.Equal(t, bulkedArray("y", "4", "z", "6"), proc.zRange(cli, dst,
+		token.NewInteger(0), token.NewInteger(-1), token.NewString(cds.WithScores)))
+	assert.Equal(t, token.NewInteger(1), proc.zInterStore(cli, dst, token.NewInteger(3), a, b, s,
+		token.NewString(cds.Aggregate), token.NewString(cds.AggMin)))
+	assert.Equal(t, bulkedArray("z", "1"), proc.zRange(cli, dst,
+		token.NewInteger(0), token.NewInteger(-1), token.NewString(cds.WithScores)))
+	assert.Equal(t, token.NewInteger(0), proc.zInterStore(cli, dst, token.NewInteger(2), a, token.NewString("t_zstore_none")))
+	assert.Equal(t, token.NewInteger(0), proc.exists(cli, dst))
+
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.zUnionStore(cli, dst, token.NewInteger(3), a, b))
+	assert.Equal(t, token.NewError(errSyntax.Error()), proc.zUnionStore(cli, dst, token.NewInteger(2), a, b,
+		token.NewString(cds.Weights), token.NewInteger(1)))
+	assert.Equal(t, token.NewError(errWeightFloat.Error()), proc.zUnionStore(cli, dst, token.NewInteger(1), a,
+		token.NewString(cds.Weights), token.NewString("a")))
+	proc.set(cli, token.NewString("t_zstore_str"), token.NewString("a"))
+	assert.Equal(t, token.NewError(eStrWrongType), proc.zUnionStore(cli, dst, token.NewInteger(1), token.NewString("t_zstore_str")))
+}
+
+func TestProcessor_zScan(t *testing.T) {
+	key := token.NewString("t_zscan")
+	assert.Equal(t, scanReply(0, []*token.Token{}), proc.zScan(cli, key, token.NewInteger(0)))
+	proc.zAdd(cli, zaddTokens(key, "a", "b")...)
+	data := proc.zScan(cli, key, token.NewInteger(0)).Data.([]*token.Token)
+	assert.Equal(t, token.NewBulked([]byte("0")), data[0])
+	pairs := data[1].Data.([]*token.Token)
+	assert.Equal(t, 4, len(pairs))
+	for i := 0; i < len(pairs); i += 2 {
+		assert.Equal(t, proc.zScore(cli, key, pairs[i]), pairs[i+1])
+	}
+}
+
+func TestProcessor_GenBin_zset(t *testing.T) {
+	p := NewProcessor(1)
+	c := p.NewMockClient()
+	p.zAdd(c, token.NewString("zset"), token.NewString("1.5"), token.NewString("b"), token.NewInteger(1), token.NewString("a"))
+	ch := make(chan []byte)
+	_ = p.data[0].Freeze()
+	go p.GenBin(0, ch)
+	var dump []byte
+	for d := range ch {
+		dump = append(dump, d...)
+	}
+	_ = p.data[0].ToMove()
+	add, _ := token.NewArray(token.NewString(cds.ZAdd), token.NewString("zset"),
+		token.NewBulked([]byte("1")), token.NewBulked([]byte("a")),
+		token.NewBulked([]byte("1.5")), token.NewBulked([]byte("b"))).Serialize()
+	assert.Equal(t, string(add), string(dump))
+}
@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -105,29 +104,35 @@ func (s *Server) cloneData() (err error) {
 	return err
 }
 
+// replay executes the commands read until the end of file
+func (s *Server) replay(reader *token.Reader) {
+	cli := s.proc.NewMockClient()
+	ch := make(chan *token.Token)
+	for {
+		t, err := reader.ReadToken()
+		if err != nil {
+			if err != io.EOF {
+				glog.Error(err)
+			}
+			return
+		}
+		s.queue <- &model.CmdTask{Cli: cli, Req: t, Rsp: ch}
+		<-ch
+	}
+}
+
 // restore data from the aof and rcl
 func (s *Server) restoreData() {
 	rcl, err := os.OpenFile(s.option.Persist.CloneName, os.O_CREATE|os.O_RDONLY, 0644)
 	checkErr(err)
 	defer func() { _ = rcl.Close() }()
-	reader := bufio.NewReader(rcl)
-	cli := s.proc.NewMockClient()
 	// restore from the rcl
-	ch := make(chan *token.Token)
-	for t := range token.DeserializeGenerator(reader) {
-		s.queue <- &model.CmdTask{Cli: cli, Req: t.T, Rsp: ch}
-		<-ch
-	}
+	s.replay(s.newReader(rcl))
 	// then restore from the aof
 	aof, err := os.OpenFile(s.option.Persist.AppendName, os.O_CREATE|os.O_RDWR, 0644)
 	checkErr(err)
 	defer func() { _ = rcl.Close() }()
-	reader = bufio.NewReader(aof)
-	cli = s.proc.NewMockClient()
-	for t := range token.DeserializeGenerator(reader) {
-		s.queue <- &model.CmdTask{Cli: cli, Req: t.T, Rsp: ch}
-		<-ch
-	}
+	s.replay(s.newReader(aof))
 	//_ = aof.Truncate(0)
 	//_ = aof.Sync()
 }
@@ -167,12 +172,20 @@ func (s *Server) persistence() {
 			}
 		case m := <-s.proc.Msgs.Set:
 			// receive the set msgs from the model clients and sync them to the aof
-			d, _ := m.T.Serialize()
-			if m.Idx != idx {
-				d, _ = token.NewArray(token.NewString(cds.Select), token.NewInteger(int64(m.Idx))).Serialize()
-				buffer.Write(d)
-			}
-			buffer.Write(d)
+			idx = appendAOF(&buffer, idx, m)
 		}
 	}
 }
+
+// appendAOF writes the message to the buffer, preceded by SELECT if its
+// database isn't the one selected by idx, and returns the database selected.
+func appendAOF(buffer *bytes.Buffer, idx int, m *proc.SetMsg) int {
+	if m.Idx != idx {
+		d, _ := token.NewArray(token.NewString(cds.Select), token.NewInteger(int64(m.Idx))).Serialize()
+		buffer.Write(d)
+		idx = m.Idx
+	}
+	d, _ := m.T.Serialize()
+	buffer.Write(d)
+	return idx
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
+	"github.com/inhzus/go-redis-impl/internal/pkg/proc"
+	"github.com/inhzus/go-redis-impl/internal/pkg/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendAOF(t *testing.T) {
+	set := func(key string) *token.Token {
+		return token.NewArray(token.NewString(cds.Set), token.NewString(key), token.NewInteger(1))
+	}
+	sel := func(idx int64) *token.Token {
+		return token.NewArray(token.NewString(cds.Select), token.NewInteger(idx))
+	}
+	var buffer bytes.Buffer
+	idx := 0
+	for _, m := range []*proc.SetMsg{
+		{Idx: 0, T: set("a")}, {Idx: 1, T: set("b")}, {Idx: 1, T: set("c")}, {Idx: 0, T: set("d")},
+	} {
+		idx = appendAOF(&buffer, idx, m)
+	}
+	assert.Equal(t, 0, idx)
+	var expected bytes.Buffer
+	// the request is kept after switching the database, which is selected once
+	for _, tk := range []*token.Token{set("a"), sel(1), set("b"), set("c"), sel(0), set("d")} {
+		d, _ := tk.Serialize()
+		expected.Write(d)
+	}
+	assert.Equal(t, expected.String(), buffer.String())
+}
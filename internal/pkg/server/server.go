@@ -3,9 +3,11 @@ package server
 import (
 	"io"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/inhzus/go-redis-impl/internal/pkg/cds"
 	"github.com/inhzus/go-redis-impl/internal/pkg/model"
 	"github.com/inhzus/go-redis-impl/internal/pkg/proc"
 	"github.com/inhzus/go-redis-impl/internal/pkg/task"
@@ -25,6 +27,15 @@ type Option struct {
 		SaveCopy   bool
 	}
 	Proto string
+	// classes of keyspace events published, e.g. "KEA", see model.ParseNotifyFlags
+	NotifyKeyspaceEvents string
+	// frequency of background tasks like active expiration, 10 by default
+	Hz int
+	// limits of requests read, see token.Reader, the defaults of token are
+	// used if 0
+	MaxBulkLen  int64
+	MaxArrayLen int64
+	MaxDepth    int
 }
 
 // Server stores option, task queue & stop signal
@@ -60,41 +71,94 @@ func NewServer(option *Option) *Server {
 	if option.Persist.RewriteInr == 0 {
 		option.Persist.RewriteInr = time.Hour
 	}
+	if option.MaxBulkLen == 0 {
+		option.MaxBulkLen = token.DefaultMaxBulkLen
+	}
+	if option.MaxArrayLen == 0 {
+		option.MaxArrayLen = token.DefaultMaxArrayLen
+	}
+	if option.MaxDepth == 0 {
+		option.MaxDepth = token.DefaultMaxDepth
+	}
 	return &Server{option: option}
 }
 
+// newReader returns the reader of requests limited by the option
+func (s *Server) newReader(rd io.Reader) *token.Reader {
+	reader := token.NewReader(rd)
+	reader.MaxBulkLen = s.option.MaxBulkLen
+	reader.MaxArrayLen = s.option.MaxArrayLen
+	reader.MaxDepth = s.option.MaxDepth
+	return reader
+}
+
 func (s *Server) handleConnection(conn net.Conn) {
 	glog.Infof("client %v connection established", conn.RemoteAddr())
 	cli := s.proc.NewClient(conn)
+	done := make(chan struct{})
+	go func() {
+		s.writeConnection(conn, cli.Out)
+		close(done)
+	}()
+	defer func() {
+		// release subscriptions and watched keys of the client
+		c := make(chan struct{})
+		s.queue <- &model.CloseTask{Cli: cli, Rsp: c}
+		<-c
+		cli.Out.Close()
+		<-done
+		_ = conn.Close()
+	}()
+	reader := s.newReader(conn)
+	reader.Inline = true
 	for {
-		ts, err := token.Deserialize(conn)
-		var data []byte
-		if err != nil {
-			if err == io.EOF {
-				glog.Infof("client %v connection closed", conn.RemoteAddr())
-				return
-			}
-			glog.Error(err)
-			data, _ = token.NewError(err.Error()).Serialize()
-		}
+		ts, err := reader.ReadBatch()
+		// replies of the requests read at once are written at once
+		cli.Out.Cork()
 		for _, req := range ts {
 			glog.Infof("request: %v", req.Format())
 			c := make(chan *token.Token)
+			// the reply is pushed to the outbox of client by the processor
 			s.queue <- &model.CmdTask{Cli: cli, Req: req, Rsp: c}
-			reply := <-c
-			rsp, err := reply.Serialize()
+			<-c
+		}
+		if err != nil {
+			if _, ok := err.(*net.OpError); ok || err == io.EOF || err == io.ErrUnexpectedEOF {
+				glog.Infof("client %v connection closed", conn.RemoteAddr())
+			} else {
+				// the rest of the stream can't be parsed after a protocol error
+				glog.Error(err)
+				cli.Out.Push(proc.WithKind(token.NewError(err.Error())))
+			}
+			cli.Out.Uncork()
+			return
+		}
+		cli.Out.Uncork()
+	}
+}
+
+// writeConnection writes replies and messages pushed to the outbox until
+// the outbox is closed.
+func (s *Server) writeConnection(conn net.Conn, out *model.Outbox) {
+	for {
+		_, ok := <-out.Notify()
+		var data []byte
+		for _, t := range out.Pop() {
+			rsp, err := t.Serialize()
 			if err != nil {
 				glog.Error(err)
 				rsp, _ = token.NewError(err.Error()).Serialize()
 			}
 			data = append(data, rsp...)
 		}
-		go func(r []byte) {
-			_, err = conn.Write(r)
-			if err != nil {
+		if len(data) > 0 {
+			if _, err := conn.Write(data); err != nil {
 				glog.Error(err)
 			}
-		}(data)
+		}
+		if !ok {
+			return
+		}
 	}
 }
 
@@ -105,6 +169,10 @@ func (s *Server) Serve() {
 	s.queue = make(chan task.Task)
 	s.stop = make(chan struct{})
 	s.proc = proc.NewProcessor(s.option.DBCount)
+	checkErr(s.proc.ConfigSet(cds.NotifyKeyspaceEvents, s.option.NotifyKeyspaceEvents))
+	if s.option.Hz != 0 {
+		checkErr(s.proc.ConfigSet(cds.Hz, strconv.Itoa(s.option.Hz)))
+	}
 	go func() {
 		for {
 			select {
@@ -122,6 +190,7 @@ func (s *Server) Serve() {
 		s.restoreData()
 	}
 	go s.persistence()
+	go s.activeExpire()
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -136,3 +205,13 @@ func (s *Server) Close() {
 	s.stop <- struct{}{}
 	<-s.stop
 }
+
+// activeExpire triggers the active expiration cycle of the processor
+// periodically, so that expired keys are freed without client traffic.
+func (s *Server) activeExpire() {
+	c := make(chan time.Duration)
+	for {
+		s.queue <- &model.ExpireTask{Rsp: c}
+		<-time.After(<-c)
+	}
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -54,6 +56,32 @@ func TestServer_Serve(t *testing.T) {
 	} else {
 		t.Logf(err.Error())
 	}
+	// inline requests like the ones sent by telnet
+	conn, err := net.Dial("tcp", ":6389")
+	if err != nil {
+		t.Logf(err.Error())
+		return
+	}
+	_, _ = conn.Write([]byte("SET t_inline \"a b\"\r\nget t_inline\n"))
+	reader := token.NewReader(conn)
+	rsp, err := reader.ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, token.ReplyOk.Data, rsp.Data)
+	rsp, err = reader.ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("a b"), rsp.Data)
+	_ = conn.Close()
 	cli.Close()
 	s.Close()
 }
+
+func TestServer_newReader(t *testing.T) {
+	assert.Equal(t, int64(token.DefaultMaxBulkLen), s.option.MaxBulkLen)
+	srv := NewServer(&Option{MaxBulkLen: 3, MaxArrayLen: 1, MaxDepth: 1})
+	_, err := srv.newReader(strings.NewReader("$4\r\nabcd\r\n")).ReadToken()
+	assert.Equal(t, token.ErrBulkLength, err)
+	_, err = srv.newReader(strings.NewReader("*2\r\n")).ReadToken()
+	assert.Equal(t, token.ErrArrayLength, err)
+	_, err = srv.newReader(strings.NewReader("*1\r\n*1\r\n:1\r\n")).ReadToken()
+	assert.Equal(t, token.ErrNestingDepth, err)
+}
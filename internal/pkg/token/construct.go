@@ -2,6 +2,7 @@ package token
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/label"
 )
@@ -10,11 +11,17 @@ func NewString(s string) *Token {
 	return &Token{Label: label.String, Data: s}
 }
 
+// lineBreaks replaces newlines of error messages, which would be taken as
+// the end of the reply
+var lineBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+// NewError returns the error reply, where CR and LF quoted from arguments are
+// replaced by spaces like redis
 func NewError(format string, a ...interface{}) *Token {
 	if len(a) != 0 {
 		format = fmt.Sprintf(format, a...)
 	}
-	return &Token{Label: label.Error, Data: format}
+	return &Token{Label: label.Error, Data: lineBreaks.Replace(format)}
 }
 
 func NewInteger(num int64) *Token {
@@ -29,6 +36,55 @@ func NewArray(tokens ...*Token) *Token {
 	return &Token{Label: label.Array, Data: tokens}
 }
 
+// NewNilArray returns the nil array replied like "*-1" of RESP2
+func NewNilArray() *Token {
+	return &Token{Label: label.Array}
+}
+
+// NewNull returns the null of RESP3
+func NewNull() *Token {
+	return &Token{Label: label.Null}
+}
+
+func NewBoolean(b bool) *Token {
+	return &Token{Label: label.Boolean, Data: b}
+}
+
+func NewDouble(f float64) *Token {
+	return &Token{Label: label.Double, Data: f}
+}
+
+// NewBigNumber returns the big number token of the decimal digits
+func NewBigNumber(digits string) *Token {
+	return &Token{Label: label.BigNumber, Data: digits}
+}
+
+// NewVerbatim returns the verbatim string of the three-letter format like "txt"
+func NewVerbatim(format string, text []byte) *Token {
+	data := make([]byte, 0, len(format)+1+len(text))
+	data = append(append(append(data, format...), ':'), text...)
+	return &Token{Label: label.Verbatim, Data: data}
+}
+
+// NewMap returns the map of the tokens, which are keys and values alternately
+func NewMap(tokens ...*Token) *Token {
+	return &Token{Label: label.Map, Data: tokens}
+}
+
+func NewSet(tokens ...*Token) *Token {
+	return &Token{Label: label.Set, Data: tokens}
+}
+
+// NewAttribute returns the attribute of the tokens, which are keys and values alternately
+func NewAttribute(tokens ...*Token) *Token {
+	return &Token{Label: label.Attribute, Data: tokens}
+}
+
+// NewPush returns the out-of-band data pushed like messages of pub/sub
+func NewPush(tokens ...*Token) *Token {
+	return &Token{Label: label.Push, Data: tokens}
+}
+
 func NewToken(v interface{}) *Token {
 	switch r := v.(type) {
 	case []byte:
@@ -37,6 +93,10 @@ func NewToken(v interface{}) *Token {
 		return NewInteger(r)
 	case string:
 		return NewString(r)
+	case bool:
+		return NewBoolean(r)
+	case float64:
+		return NewDouble(r)
 	}
 	return nil
 }
@@ -3,34 +3,156 @@ package token
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
-	"net"
+	"io"
+	"math"
 	"strconv"
 
 	"github.com/inhzus/go-redis-impl/internal/pkg/label"
-	"github.com/inhzus/go-redis-impl/internal/pkg/task"
 )
 
-func readUntil(reader *bufio.Reader, seps []byte) (line []byte, err error) {
+// default limits of the reader, the same as redis
+const (
+	DefaultMaxBulkLen  = 512 << 20
+	DefaultMaxArrayLen = math.MaxInt32
+	DefaultMaxDepth    = 128
+	// arrays are allocated at most the capacity before elements are read,
+	// so that a huge length declared costs nothing until the elements arrive
+	maxArrayPrealloc = 1024
+	// bulked strings are read by chunks of the size at most like
+	// PROTO_IOBUF_LEN of redis, the buffer grows as the data arrives
+	bulkChunkSize = 16 << 10
+)
+
+var (
+	ErrRowEmpty     = errors.New("Protocol error: empty row")
+	ErrBulkLength   = errors.New("Protocol error: invalid bulk length")
+	ErrArrayLength  = errors.New("Protocol error: invalid multibulk length")
+	ErrNestingDepth = errors.New("Protocol error: nesting too deep")
+	ErrLineTooBig   = errors.New("Protocol error: too big line")
+	ErrBoolean      = errors.New("Protocol error: invalid boolean")
+	ErrDouble       = errors.New("Protocol error: invalid double")
+)
+
+// Reader parses tokens from a connection. It owns one buffered reader for
+// the lifetime of the connection, so that bytes read ahead are kept for the
+// following reads.
+type Reader struct {
+	reader *bufio.Reader
+	// MaxBulkLen limits the length of bulked strings
+	MaxBulkLen int64
+	// MaxArrayLen limits the number of elements of arrays
+	MaxArrayLen int64
+	// MaxDepth limits the nesting depth of arrays
+	MaxDepth int
+	// Inline allows requests in plain text like "SET key value", which are
+	// read as arrays of bulked strings
+	Inline bool
+}
+
+// NewReader returns a reader with default limits reading from rd
+func NewReader(rd io.Reader) *Reader {
+	return &Reader{
+		reader:      bufio.NewReader(rd),
+		MaxBulkLen:  DefaultMaxBulkLen,
+		MaxArrayLen: DefaultMaxArrayLen,
+		MaxDepth:    DefaultMaxDepth,
+	}
+}
+
+// Buffered returns the number of bytes read ahead
+func (r *Reader) Buffered() int {
+	return r.reader.Buffered()
+}
+
+// ReadToken reads a complete token, blocking until it arrives
+func (r *Reader) ReadToken() (*Token, error) {
+	if r.Inline {
+		sign, err := r.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if sign[0] != label.Array {
+			return r.parseInline()
+		}
+	}
+	return r.parseItem(0)
+}
+
+// ReadBatch reads a token and then the following ones which have arrived
+// already, so that pipelined requests are handled at once. Tokens read
+// before an error are returned with the error.
+func (r *Reader) ReadBatch() ([]*Token, error) {
+	var ts []*Token
 	for {
-		var s []byte
-		if s, err = reader.ReadBytes(seps[len(seps)-1]); err != nil {
-			return
+		t, err := r.ReadToken()
+		if err != nil {
+			return ts, err
 		}
+		ts = append(ts, t)
+		if r.reader.Buffered() <= 0 {
+			return ts, nil
+		}
+	}
+}
+
+// readLine reads a line without the protocol separators. The line is limited
+// to the length of inline requests like the headers of requests in redis, so
+// that a peer never sending separators is not buffered without end.
+func (r *Reader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		s, err := r.reader.ReadSlice(ProtocolSeps[len(ProtocolSeps)-1])
 		line = append(line, s...)
-		if bytes.HasSuffix(line, seps) {
-			return line[:len(line)-len(seps)], nil
+		if len(line) > maxInlineLen {
+			return nil, ErrLineTooBig
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasSuffix(line, ProtocolSeps) {
+			return line[:len(line)-len(ProtocolSeps)], nil
 		}
 	}
 }
 
-func parseItem(reader *bufio.Reader) (*Token, error) {
-	row, err := readUntil(reader, ProtocolSeps)
+// parseLength parses the length of bulked string or array, -1 is allowed as nil
+func parseLength(row []byte, max int64, lengthErr error) (int64, error) {
+	n, err := strconv.ParseInt(string(row), 10, 64)
+	if err != nil || n < NilBulkedLen || n > max {
+		return 0, lengthErr
+	}
+	return n, nil
+}
+
+// readBulk reads n bytes by chunks, so that the length declared is not
+// allocated before the data arrives
+func (r *Reader) readBulk(n int64) ([]byte, error) {
+	data := make([]byte, 0, minInt64(n, bulkChunkSize))
+	for int64(len(data)) < n {
+		start := len(data)
+		data = append(data, make([]byte, minInt64(n-int64(start), bulkChunkSize))...)
+		if _, err := io.ReadFull(r.reader, data[start:]); err != nil {
+			if err == io.EOF && start > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (r *Reader) parseItem(depth int) (*Token, error) {
+	row, err := r.readLine()
 	if err != nil {
 		return nil, err
 	}
 	if len(row) < 1 {
-		return nil, fmt.Errorf("row empty")
+		return nil, ErrRowEmpty
 	}
 	sign := row[0]
 	row = row[1:]
@@ -43,75 +165,88 @@ func parseItem(reader *bufio.Reader) (*Token, error) {
 			return nil, err
 		}
 		return &Token{Label: sign, Data: num}, nil
-	case label.Bulked:
-		n, err := strconv.ParseInt(string(row), 10, 64)
+	case label.Bulked, label.Verbatim:
+		n, err := parseLength(row, r.MaxBulkLen, ErrBulkLength)
 		if err != nil {
 			return nil, err
 		}
 		if n == NilBulkedLen {
 			return &Token{Label: sign, Data: nil}, nil
 		}
-		data := make([]byte, n+2)
-		if _, err = reader.Read(data); err != nil {
+		data, err := r.readBulk(n + int64(len(ProtocolSeps)))
+		if err != nil {
 			return nil, err
 		}
-		return &Token{Label: sign, Data: data[:len(data)-2]}, nil
-	case label.Array:
-		n, err := strconv.ParseInt(string(row), 10, 64)
+		if !bytes.HasSuffix(data, ProtocolSeps) {
+			return nil, ErrBulkLength
+		}
+		return &Token{Label: sign, Data: data[:n]}, nil
+	case label.Null:
+		return &Token{Label: sign}, nil
+	case label.Boolean:
+		switch string(row) {
+		case "t":
+			return &Token{Label: sign, Data: true}, nil
+		case "f":
+			return &Token{Label: sign, Data: false}, nil
+		}
+		return nil, ErrBoolean
+	case label.Double:
+		f, err := parseDouble(string(row))
 		if err != nil {
 			return nil, err
 		}
-		var i int64 = 0
-		var tokens []*Token
-		for ; i < n; i++ {
-			token, err := parseItem(reader)
-			if err != nil {
-				return nil, err
-			}
-			tokens = append(tokens, token)
+		return &Token{Label: sign, Data: f}, nil
+	case label.BigNumber:
+		return &Token{Label: sign, Data: string(row)}, nil
+	case label.Array, label.Set, label.Push, label.Map, label.Attribute:
+		if depth >= r.MaxDepth {
+			return nil, ErrNestingDepth
 		}
-		return &Token{Label: label.Array, Data: tokens}, nil
-	}
-	return nil, fmt.Errorf("unrecognized label")
-}
-
-func Deserialize(conn net.Conn) ([]*Token, error) {
-	reader := bufio.NewReader(conn)
-	var ts []*Token
-	for {
-		t, err := parseItem(reader)
+		n, err := parseLength(row, r.MaxArrayLen, ErrArrayLength)
 		if err != nil {
 			return nil, err
 		}
-		ts = append(ts, t)
-		if reader.Buffered() <= 0 {
-			break
+		if n == NilBulkedLen {
+			return &Token{Label: sign, Data: nil}, nil
 		}
-	}
-	return ts, nil
-}
-
-func DeserializeGenerator(reader *bufio.Reader) (ch chan *DeSerMsg) {
-	ch = make(chan *DeSerMsg)
-	go func() {
-		defer close(ch)
-		for {
-			t, err := parseItem(reader)
-			ch <- &DeSerMsg{T: t, Err: err}
+		// the length of maps is the number of pairs
+		if sign == label.Map || sign == label.Attribute {
+			n *= 2
+		}
+		tokens := make([]*Token, 0, minInt64(n, maxArrayPrealloc))
+		for i := int64(0); i < n; i++ {
+			token, err := r.parseItem(depth + 1)
 			if err != nil {
-				return
-			}
-			if reader.Buffered() <= 0 {
-				return
+				return nil, err
 			}
+			tokens = append(tokens, token)
 		}
-	}()
-	return
+		return &Token{Label: sign, Data: tokens}, nil
+	}
+	return nil, fmt.Errorf("Protocol error: unrecognized label '%c'", sign)
 }
 
-type DeSerMsg struct {
-	T   *Token
-	Err error
+// parseDouble parses the double of RESP3 which may be "inf", "-inf" or "nan"
+func parseDouble(s string) (float64, error) {
+	switch s {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, ErrDouble
+	}
+	return f, nil
 }
 
-func (m *DeSerMsg) Msg() task.Msg { return m }
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
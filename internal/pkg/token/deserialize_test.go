@@ -0,0 +1,167 @@
+package token
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_ReadToken(t *testing.T) {
+	var data []byte
+	reqs := []*Token{
+		NewArray(NewBulked([]byte("set")), NewBulked([]byte(strings.Repeat("k", 5000))), NewInteger(1)),
+		NewString("ok"),
+		NewError("ERR wrong"),
+		NewArray(NewArray(NewBulked([]byte("a"))), NewBulked([]byte{})),
+	}
+	for _, req := range reqs {
+		d, _ := req.Serialize()
+		data = append(data, d...)
+	}
+	// bytes arrive one by one, so that every read returns short
+	r := NewReader(iotest.OneByteReader(bytes.NewReader(data)))
+	for _, req := range reqs {
+		tk, err := r.ReadToken()
+		assert.Nil(t, err)
+		assert.Equal(t, req, tk)
+	}
+	_, err := r.ReadToken()
+	assert.Equal(t, io.EOF, err)
+
+	// bytes read ahead are kept for the following reads
+	r = NewReader(bytes.NewReader(data))
+	ts, err := r.ReadBatch()
+	assert.Nil(t, err)
+	assert.Equal(t, reqs, ts)
+
+	tk, err := NewReader(strings.NewReader("$-1\r\n")).ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, NewBulked(nil), tk)
+	_, err = NewReader(strings.NewReader("$3\r\nab")).ReadToken()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = NewReader(strings.NewReader("$2\r\nabc\r\n")).ReadToken()
+	assert.Equal(t, ErrBulkLength, err)
+	_, err = NewReader(strings.NewReader("\r\n")).ReadToken()
+	assert.Equal(t, ErrRowEmpty, err)
+	_, err = NewReader(strings.NewReader("?\r\n")).ReadToken()
+	assert.NotNil(t, err)
+}
+
+func TestReader_Limits(t *testing.T) {
+	r := NewReader(strings.NewReader("$4\r\nabcd\r\n*3\r\n"))
+	r.MaxBulkLen = 3
+	_, err := r.ReadToken()
+	assert.Equal(t, ErrBulkLength, err)
+
+	// the length declared is not allocated before the data arrives
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = NewReader(strings.NewReader(fmt.Sprintf("$%d\r\nabc", DefaultMaxBulkLen))).ReadToken()
+	runtime.ReadMemStats(&after)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	big := strings.Repeat("v", 3*bulkChunkSize+1)
+	tk, err := NewReader(strings.NewReader(fmt.Sprintf("$%d\r\n%s\r\n", len(big), big))).ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, NewBulked([]byte(big)), tk)
+	_, err = NewReader(strings.NewReader(fmt.Sprintf("$%d\r\n%s", len(big), big[:bulkChunkSize]))).ReadToken()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// lines never ending are not buffered without end
+	_, err = NewReader(strings.NewReader("$" + strings.Repeat("1", maxInlineLen+1))).ReadToken()
+	assert.Equal(t, ErrLineTooBig, err)
+	_, err = NewReader(strings.NewReader("+" + strings.Repeat("a\n", maxInlineLen) + "\r\n")).ReadToken()
+	assert.Equal(t, ErrLineTooBig, err)
+	tk, err = NewReader(strings.NewReader("+" + strings.Repeat("a", 5000) + "\r\n")).ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, NewString(strings.Repeat("a", 5000)), tk)
+
+	r = NewReader(strings.NewReader("*3\r\n"))
+	r.MaxArrayLen = 2
+	_, err = r.ReadToken()
+	assert.Equal(t, ErrArrayLength, err)
+	_, err = NewReader(strings.NewReader("*-2\r\n")).ReadToken()
+	assert.Equal(t, ErrArrayLength, err)
+
+	r = NewReader(strings.NewReader("*1\r\n*1\r\n*1\r\n:1\r\n"))
+	r.MaxDepth = 2
+	_, err = r.ReadToken()
+	assert.Equal(t, ErrNestingDepth, err)
+	r = NewReader(strings.NewReader("*1\r\n*1\r\n:1\r\n"))
+	r.MaxDepth = 2
+	tk, err = r.ReadToken()
+	assert.Nil(t, err)
+	assert.Equal(t, NewArray(NewArray(NewInteger(1))), tk)
+}
+
+func TestReader_Inline(t *testing.T) {
+	r := NewReader(strings.NewReader("\r\nSET k \"a b\\x41\\n\"\nget 'it\\'s'\r\n*1\r\n$4\r\nPING\r\n"))
+	r.Inline = true
+	ts, err := r.ReadBatch()
+	assert.Nil(t, err)
+	assert.Equal(t, []*Token{
+		NewArray(NewBulked([]byte("SET")), NewBulked([]byte("k")), NewBulked([]byte("a bA\n"))),
+		NewArray(NewBulked([]byte("get")), NewBulked([]byte("it's"))),
+		NewArray(NewBulked([]byte("PING"))),
+	}, ts)
+
+	r = NewReader(strings.NewReader("get \"a\"b\r\n"))
+	r.Inline = true
+	_, err = r.ReadToken()
+	assert.Equal(t, ErrUnbalancedQuotes, err)
+	r = NewReader(strings.NewReader(strings.Repeat("a", maxInlineLen+1) + "\r\n"))
+	r.Inline = true
+	_, err = r.ReadToken()
+	assert.Equal(t, ErrInlineTooBig, err)
+}
+
+func TestSplitArgs(t *testing.T) {
+	args, err := SplitArgs("  set  key \"\" 'x y' a\"b c\"  ")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"set", "key", "", "x y", "ab c"}, args)
+	args, err = SplitArgs("")
+	assert.Nil(t, err)
+	assert.Nil(t, args)
+	_, err = SplitArgs("\"abc")
+	assert.Equal(t, ErrUnbalancedQuotes, err)
+	_, err = SplitArgs("'abc")
+	assert.Equal(t, ErrUnbalancedQuotes, err)
+}
+
+func TestReader_Resp3(t *testing.T) {
+	reqs := []*Token{
+		NewNull(),
+		NewBoolean(false),
+		NewDouble(-0.25),
+		NewDouble(math.Inf(1)),
+		NewBigNumber("123456789012345678901234567890"),
+		NewVerbatim("txt", []byte("text")),
+		NewMap(NewBulked([]byte("k")), NewSet(NewInteger(1))),
+		NewAttribute(NewString("a"), NewString("b")),
+		NewPush(NewBulked([]byte("message")), NewBulked([]byte("ch"))),
+	}
+	var data []byte
+	for _, req := range reqs {
+		d, _ := req.Serialize()
+		data = append(data, d...)
+	}
+	r := NewReader(bytes.NewReader(data))
+	ts, err := r.ReadBatch()
+	assert.Nil(t, err)
+	assert.Equal(t, reqs, ts)
+
+	_, err = NewReader(strings.NewReader("#x\r\n")).ReadToken()
+	assert.Equal(t, ErrBoolean, err)
+	_, err = NewReader(strings.NewReader(",abc\r\n")).ReadToken()
+	assert.Equal(t, ErrDouble, err)
+	tk, err := NewReader(strings.NewReader(",nan\r\n")).ReadToken()
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(tk.Data.(float64)))
+}
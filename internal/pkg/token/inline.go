@@ -0,0 +1,167 @@
+package token
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+)
+
+// maxInlineLen limits the length of an inline request, the same as redis
+const maxInlineLen = 64 << 10
+
+var (
+	ErrInlineTooBig     = errors.New("Protocol error: too big inline request")
+	ErrUnbalancedQuotes = errors.New("Protocol error: unbalanced quotes in request")
+)
+
+// readInline reads a line of inline request, a single "\n" is accepted as
+// the separator as well so that it works with tools like nc.
+func (r *Reader) readInline() ([]byte, error) {
+	var line []byte
+	for {
+		s, err := r.reader.ReadSlice('\n')
+		line = append(line, s...)
+		if len(line) > maxInlineLen {
+			return nil, ErrInlineTooBig
+		}
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// parseInline parses the inline request into an array of bulked strings,
+// empty lines are skipped.
+func (r *Reader) parseInline() (*Token, error) {
+	for {
+		line, err := r.readInline()
+		if err != nil {
+			return nil, err
+		}
+		args, err := SplitArgs(string(line))
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		tokens := make([]*Token, 0, len(args))
+		for _, arg := range args {
+			tokens = append(tokens, NewBulked([]byte(arg)))
+		}
+		return &Token{Label: label.Array, Data: tokens}, nil
+	}
+}
+
+// SplitArgs splits the line into arguments separated by spaces like redis.
+// Arguments in double quotes support escapes like "\n" and "\x00", the ones
+// in single quotes support "\'" only. A closing quote must be followed by
+// a space or the end of line.
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	i, n := 0, len(line)
+	for {
+		for i < n && isSpace(line[i]) {
+			i++
+		}
+		if i >= n {
+			return args, nil
+		}
+		var arg []byte
+		inDouble, inSingle, done := false, false, false
+		for !done {
+			if i >= n {
+				if inDouble || inSingle {
+					return nil, ErrUnbalancedQuotes
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDouble:
+				switch {
+				case c == '\\' && i+3 < n && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					b, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					arg = append(arg, byte(b))
+					i += 3
+				case c == '\\' && i+1 < n:
+					i++
+					arg = append(arg, unescape(line[i]))
+				case c == '"':
+					// the closing quote must be followed by a space
+					if i+1 < n && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			case inSingle:
+				switch {
+				case c == '\\' && i+1 < n && line[i+1] == '\'':
+					i++
+					arg = append(arg, '\'')
+				case c == '\'':
+					if i+1 < n && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, c)
+				}
+			default:
+				switch {
+				case isSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					arg = append(arg, c)
+				}
+			}
+			i++
+		}
+		args = append(args, string(arg))
+	}
+}
+
+// unescape returns the character escaped by backslash in double quotes
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	}
+	return c
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
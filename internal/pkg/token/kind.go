@@ -0,0 +1,41 @@
+package token
+
+import "strings"
+
+// ErrKind is the prefix of error replies, which clients branch on
+type ErrKind string
+
+// kinds of errors, the same as redis
+const (
+	ErrKindErr       ErrKind = "ERR"
+	ErrKindWrongType ErrKind = "WRONGTYPE"
+	ErrKindExecAbort ErrKind = "EXECABORT"
+	ErrKindNoScript  ErrKind = "NOSCRIPT"
+	ErrKindBusy      ErrKind = "BUSY"
+	ErrKindNoAuth    ErrKind = "NOAUTH"
+	ErrKindOOM       ErrKind = "OOM"
+	ErrKindReadOnly  ErrKind = "READONLY"
+	ErrKindMoved     ErrKind = "MOVED"
+	ErrKindAsk       ErrKind = "ASK"
+	ErrKindLoading   ErrKind = "LOADING"
+	ErrKindNoProto   ErrKind = "NOPROTO"
+	ErrKindWrongPass ErrKind = "WRONGPASS"
+	ErrKindNoGroup   ErrKind = "NOGROUP"
+	ErrKindBusyGroup ErrKind = "BUSYGROUP"
+)
+
+// KindOf returns the kind of the error message, ErrKindErr if the message
+// is not prefixed with any kind
+func KindOf(msg string) ErrKind {
+	prefix := ErrKind(msg)
+	if i := strings.IndexByte(msg, ' '); i >= 0 {
+		prefix = ErrKind(msg[:i])
+	}
+	switch prefix {
+	case ErrKindErr, ErrKindWrongType, ErrKindExecAbort, ErrKindNoScript, ErrKindBusy,
+		ErrKindNoAuth, ErrKindOOM, ErrKindReadOnly, ErrKindMoved, ErrKindAsk, ErrKindLoading,
+		ErrKindNoProto, ErrKindWrongPass, ErrKindNoGroup, ErrKindBusyGroup:
+		return prefix
+	}
+	return ErrKindErr
+}
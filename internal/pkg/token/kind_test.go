@@ -0,0 +1,17 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, ErrKindWrongType, KindOf("WRONGTYPE Operation against a key holding the wrong kind of value"))
+	assert.Equal(t, ErrKindExecAbort, KindOf("EXECABORT Transaction discarded because of previous errors."))
+	assert.Equal(t, ErrKindLoading, KindOf("LOADING"))
+	assert.Equal(t, ErrKindErr, KindOf("ERR syntax error"))
+	// words in upper case are not kinds unless known
+	assert.Equal(t, ErrKindErr, KindOf("DB index is out of range"))
+	assert.Equal(t, ErrKindErr, KindOf(""))
+}
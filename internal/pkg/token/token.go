@@ -2,6 +2,7 @@ package token
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -21,6 +22,15 @@ const (
 	FlagSet = 1 << iota
 )
 
+// protocol versions
+const (
+	Resp2 = 2
+	Resp3 = 3
+)
+
+// lengths of the format prefix of verbatim strings like "txt:"
+const verbatimPrefixLen = 4
+
 type Token struct {
 	Data  interface{}
 	Flag  uint64
@@ -29,6 +39,12 @@ type Token struct {
 
 func (t *Token) Serialize() ([]byte, error) {
 	const ErrorMsg = "cast t Data to %v error, Data: %v"
+	if t != nil && t.Label == label.Null {
+		return []byte{label.Null, '\r', '\n'}, nil
+	}
+	if t != nil && t.Label == label.Array && t.Data == nil {
+		return []byte("*-1\r\n"), nil
+	}
 	if t == nil || t.Data == nil {
 		data := []byte{label.Bulked}
 		data = append(data, NilData...)
@@ -37,12 +53,20 @@ func (t *Token) Serialize() ([]byte, error) {
 	data := []byte{t.Label}
 	src := t.Data
 	switch t.Label {
-	case label.Array:
+	case label.Array, label.Set, label.Push, label.Map, label.Attribute:
 		array, ok := src.([]*Token)
 		if !ok {
 			return nil, fmt.Errorf(ErrorMsg, "array", src)
 		}
-		data = append(data, strconv.FormatInt(int64(len(array)), 10)...)
+		n := len(array)
+		// the length of maps is the number of pairs
+		if t.Label == label.Map || t.Label == label.Attribute {
+			if n%2 != 0 {
+				return nil, fmt.Errorf(ErrorMsg, "map", src)
+			}
+			n /= 2
+		}
+		data = append(data, strconv.FormatInt(int64(n), 10)...)
 		data = append(data, ProtocolSeps...)
 		for _, arg := range array {
 			item, err := arg.Serialize()
@@ -51,7 +75,7 @@ func (t *Token) Serialize() ([]byte, error) {
 			}
 			data = append(data, item...)
 		}
-	case label.Error, label.String:
+	case label.Error, label.String, label.BigNumber:
 		if s, ok := src.(string); ok {
 			data = append(data, s...)
 			data = append(data, ProtocolSeps...)
@@ -65,7 +89,25 @@ func (t *Token) Serialize() ([]byte, error) {
 		} else {
 			return nil, fmt.Errorf(ErrorMsg, "integer", src)
 		}
-	case label.Bulked:
+	case label.Boolean:
+		b, ok := src.(bool)
+		if !ok {
+			return nil, fmt.Errorf(ErrorMsg, "boolean", src)
+		}
+		if b {
+			data = append(data, 't')
+		} else {
+			data = append(data, 'f')
+		}
+		data = append(data, ProtocolSeps...)
+	case label.Double:
+		f, ok := src.(float64)
+		if !ok {
+			return nil, fmt.Errorf(ErrorMsg, "double", src)
+		}
+		data = append(data, FormatFloat(f)...)
+		data = append(data, ProtocolSeps...)
+	case label.Bulked, label.Verbatim:
 		val, ok := src.([]byte)
 		if !ok {
 			return nil, fmt.Errorf(ErrorMsg, "bulked string", src)
@@ -78,6 +120,92 @@ func (t *Token) Serialize() ([]byte, error) {
 	return data, nil
 }
 
+// ConvertTo returns the token to be replied to the client speaking the
+// protocol version. Types introduced by RESP3 are converted to the ones of
+// RESP2 for RESP2 clients, and nil bulked strings and nil arrays are converted
+// to null for RESP3 clients. The token itself is returned if nothing is
+// converted.
+func (t *Token) ConvertTo(proto int) *Token {
+	if t == nil {
+		return nil
+	}
+	switch t.Label {
+	case label.Array, label.Set, label.Push, label.Map, label.Attribute:
+		ts, ok := t.Data.([]*Token)
+		if !ok {
+			if t.Data == nil && proto >= Resp3 {
+				return NewNull()
+			}
+			return t
+		}
+		var converted []*Token
+		for i, c := range ts {
+			n := c.ConvertTo(proto)
+			if n != c && converted == nil {
+				converted = make([]*Token, len(ts))
+				copy(converted, ts[:i])
+			}
+			if converted != nil {
+				converted[i] = n
+			}
+		}
+		lbl := t.Label
+		if proto < Resp3 {
+			lbl = label.Array
+		}
+		if converted == nil {
+			if lbl == t.Label {
+				return t
+			}
+			converted = ts
+		}
+		return &Token{Label: lbl, Data: converted, Flag: t.Flag}
+	}
+	if proto >= Resp3 {
+		if t.Label == label.Bulked && t.Data == nil {
+			return NewNull()
+		}
+		return t
+	}
+	switch t.Label {
+	case label.Null:
+		return NewBulked(nil)
+	case label.Boolean:
+		if t.Data.(bool) {
+			return NewInteger(1)
+		}
+		return NewInteger(0)
+	case label.Double:
+		return NewBulked([]byte(FormatFloat(t.Data.(float64))))
+	case label.BigNumber:
+		return NewBulked([]byte(t.Data.(string)))
+	case label.Verbatim:
+		data := t.Data.([]byte)
+		if len(data) >= verbatimPrefixLen {
+			data = data[verbatimPrefixLen:]
+		}
+		return NewBulked(data)
+	}
+	return t
+}
+
+// FormatFloat formats float in the shortest representation, infinity is
+// formatted as "inf" or "-inf".
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	if a := math.Abs(f); a != 0 && (a < 1e-6 || a >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func (t *Token) Format() (s string) {
 	if row, err := t.Serialize(); err == nil {
 		return strings.ReplaceAll(string(row), "\r\n", " ")
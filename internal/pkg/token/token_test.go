@@ -0,0 +1,57 @@
+package token
+
+import (
+	"math"
+	"testing"
+
+	"github.com/inhzus/go-redis-impl/internal/pkg/label"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_Serialize(t *testing.T) {
+	cases := []struct {
+		t    *Token
+		want string
+	}{
+		{NewNull(), "_\r\n"},
+		{NewBulked(nil), "$-1\r\n"},
+		{NewNilArray(), "*-1\r\n"},
+		{NewBoolean(true), "#t\r\n"},
+		{NewBoolean(false), "#f\r\n"},
+		{NewDouble(1.5), ",1.5\r\n"},
+		{NewDouble(math.Inf(-1)), ",-inf\r\n"},
+		{NewBigNumber("3492890328409238509324850943850943825024385"), "(3492890328409238509324850943850943825024385\r\n"},
+		{NewVerbatim("txt", []byte("Some string")), "=15\r\ntxt:Some string\r\n"},
+		{NewMap(NewString("first"), NewInteger(1)), "%1\r\n+first\r\n:1\r\n"},
+		{NewSet(NewInteger(1), NewBoolean(true)), "~2\r\n:1\r\n#t\r\n"},
+		{NewAttribute(NewString("ttl"), NewInteger(3)), "|1\r\n+ttl\r\n:3\r\n"},
+		{NewPush(NewBulked([]byte("message"))), ">1\r\n$7\r\nmessage\r\n"},
+	}
+	for _, c := range cases {
+		data, err := c.t.Serialize()
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, string(data))
+	}
+	_, err := NewMap(NewString("odd")).Serialize()
+	assert.NotNil(t, err)
+}
+
+func TestToken_ConvertTo(t *testing.T) {
+	reply := NewArray(NewMap(NewBulked([]byte("a")), NewDouble(2.5)),
+		NewSet(NewBoolean(true), NewNull()), NewVerbatim("txt", []byte("v")), NewBigNumber("12"))
+	assert.Equal(t, NewArray(NewArray(NewBulked([]byte("a")), NewBulked([]byte("2.5"))),
+		NewArray(NewInteger(1), NewBulked(nil)), NewBulked([]byte("v")), NewBulked([]byte("12"))),
+		reply.ConvertTo(Resp2))
+	assert.Same(t, reply, reply.ConvertTo(Resp3))
+
+	// tokens are not copied if nothing is converted
+	arr := NewArray(NewBulked([]byte("a")), NewInteger(1))
+	assert.Same(t, arr, arr.ConvertTo(Resp2))
+	assert.Equal(t, NewArray(NewNull()), NewArray(NewBulked(nil)).ConvertTo(Resp3))
+	assert.Equal(t, NewNull(), NewNilArray().ConvertTo(Resp3))
+	assert.Equal(t, NewNilArray(), NewNilArray().ConvertTo(Resp2))
+	push := NewPush(NewBulked([]byte("message")))
+	assert.Equal(t, label.Array, push.ConvertTo(Resp2).Label)
+	assert.Same(t, push, push.ConvertTo(Resp3))
+	assert.Nil(t, (*Token)(nil).ConvertTo(Resp2))
+}